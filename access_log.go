@@ -0,0 +1,118 @@
+package mux
+
+import (
+	"os"
+	"time"
+
+	internalaccesslog "github.com/fgrzl/mux/internal/middleware/accesslog"
+)
+
+// Standard Apache access log templates for ParseAccessLogFormat.
+const (
+	// CommonLogFormat is the NCSA Common Log Format.
+	CommonLogFormat = internalaccesslog.CommonLogFormat
+	// CombinedLogFormat is the Common Log Format followed by the referer and
+	// user agent. UseAccessLog writes it by default.
+	CombinedLogFormat = internalaccesslog.CombinedLogFormat
+)
+
+// AccessLogFormat renders access log lines for WithAccessLogFormat.
+type AccessLogFormat struct {
+	inner internalaccesslog.Formatter
+}
+
+// ParseAccessLogFormat compiles an Apache mod_log_config style template such
+// as CombinedLogFormat. Besides the standard directives it supports %^FB for
+// the time to first byte in microseconds, %L for the request ID, and %R for
+// the route pattern. See the middleware guide for the full list.
+func ParseAccessLogFormat(template string) (AccessLogFormat, error) {
+	f, err := internalaccesslog.ParseTemplate(template)
+	if err != nil {
+		return AccessLogFormat{}, err
+	}
+	return AccessLogFormat{inner: f}, nil
+}
+
+// W3CAccessLogFormat returns the W3C Extended Log File Format with the given
+// fields, such as "date", "time", "c-ip", "cs-method", "cs-uri-stem",
+// "sc-status", "sc-bytes", "time-taken", and "cs(User-Agent)". Without
+// fields it logs a common IIS-like set.
+func W3CAccessLogFormat(fields ...string) AccessLogFormat {
+	return AccessLogFormat{inner: internalaccesslog.W3C(fields...)}
+}
+
+// JSONAccessLogFormat returns a format writing one JSON object per line.
+func JSONAccessLogFormat() AccessLogFormat {
+	return AccessLogFormat{inner: internalaccesslog.JSON()}
+}
+
+// RotatingFile is an io.Writer for access logs that rotates its file by size
+// and time and reopens it on SIGHUP, for logrotate's move-and-signal
+// rotation. It is safe for concurrent use.
+type RotatingFile struct {
+	inner *internalaccesslog.RotatingFile
+}
+
+// RotatingFileOption configures NewRotatingFile.
+type RotatingFileOption struct {
+	apply internalaccesslog.RotateOption
+}
+
+// WithRotateMaxSize rotates the file before it grows past n bytes.
+func WithRotateMaxSize(n int64) RotatingFileOption {
+	return RotatingFileOption{apply: internalaccesslog.WithMaxSize(n)}
+}
+
+// WithRotateInterval rotates the file when the write time crosses a UTC
+// multiple of d, so 24*time.Hour rotates at midnight UTC.
+func WithRotateInterval(d time.Duration) RotatingFileOption {
+	return RotatingFileOption{apply: internalaccesslog.WithInterval(d)}
+}
+
+// WithRotateMaxBackups keeps only the n most recent rotated files.
+func WithRotateMaxBackups(n int) RotatingFileOption {
+	return RotatingFileOption{apply: internalaccesslog.WithMaxBackups(n)}
+}
+
+// WithRotateReopenSignals replaces SIGHUP as the signals that reopen the
+// file. Passing none disables reopening on signals.
+func WithRotateReopenSignals(signals ...os.Signal) RotatingFileOption {
+	return RotatingFileOption{apply: internalaccesslog.WithReopenSignals(signals...)}
+}
+
+// NewRotatingFile opens path for appending, creating it and its directory if
+// needed. Rotated files are renamed with a timestamp before the extension,
+// such as access-20240115T103000.000.log.
+func NewRotatingFile(path string, opts ...RotatingFileOption) (*RotatingFile, error) {
+	internalOpts := make([]internalaccesslog.RotateOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	f, err := internalaccesslog.NewRotatingFile(path, internalOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingFile{inner: f}, nil
+}
+
+// Write appends p, rotating the file first when needed.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	return f.inner.Write(p)
+}
+
+// Rotate renames the current file aside and starts a new one.
+func (f *RotatingFile) Rotate() error {
+	return f.inner.Rotate()
+}
+
+// Reopen closes and reopens the file at its path without renaming it.
+func (f *RotatingFile) Reopen() error {
+	return f.inner.Reopen()
+}
+
+// Close stops reopening on signals and closes the file.
+func (f *RotatingFile) Close() error {
+	return f.inner.Close()
+}
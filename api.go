@@ -2,6 +2,7 @@ package mux
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/url"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	internalcookiekit "github.com/fgrzl/mux/internal/cookiekit"
 	internalauthentication "github.com/fgrzl/mux/internal/middleware/authentication"
 	internalrouting "github.com/fgrzl/mux/internal/routing"
+	internaltasks "github.com/fgrzl/mux/internal/tasks"
 	internaltokenizer "github.com/fgrzl/mux/internal/tokenizer"
 	"github.com/google/uuid"
 )
@@ -26,6 +28,7 @@ type MutableRouteContext interface {
 	SetRequest(*http.Request)
 	SetResponse(http.ResponseWriter)
 	SetUser(claims.Principal)
+	SetRequestID(id string)
 	SetContextValue(key, value any)
 }
 
@@ -52,10 +55,56 @@ type ProblemDetails struct {
 	Status   int     `json:"status"`
 	Detail   string  `json:"detail"`
 	Instance *string `json:"instance,omitempty"`
+	// Extensions holds RFC 9457 extension members, such as "errors", that are
+	// serialized alongside the standard members.
+	Extensions map[string]any `json:"-"`
+}
+
+// MarshalJSON writes the standard members followed by any extension members.
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.toInternal())
+}
+
+func (p ProblemDetails) toInternal() internalrouting.ProblemDetails {
+	return internalrouting.ProblemDetails{
+		Type:       p.Type,
+		Title:      p.Title,
+		Status:     p.Status,
+		Detail:     p.Detail,
+		Instance:   p.Instance,
+		Extensions: p.Extensions,
+	}
 }
 
 var DefaultProblem = &ProblemDetails{}
 
+// ErrMissingSource is returned by Bind when a field with explicit source tags
+// is required but none of its sources are present on the request.
+var ErrMissingSource = internalrouting.ErrMissingSource
+
+var (
+	// ErrUnsupportedPatchType is returned by BindPatch when the request is
+	// neither a JSON Merge Patch nor a JSON Patch document.
+	ErrUnsupportedPatchType = internalrouting.ErrUnsupportedPatchType
+	// ErrInvalidPatch is returned by BindPatch for malformed patch documents.
+	ErrInvalidPatch = internalrouting.ErrInvalidPatch
+	// ErrPatchPathNotFound is returned by BindPatch when a JSON Patch
+	// operation refers to a location that does not exist.
+	ErrPatchPathNotFound = internalrouting.ErrPatchPathNotFound
+	// ErrPatchTestFailed is returned by BindPatch when a JSON Patch "test"
+	// operation does not match.
+	ErrPatchTestFailed = internalrouting.ErrPatchTestFailed
+)
+
+var (
+	// ErrBackgroundQueueFull is returned by RouteContext.Go when the tasks
+	// waiting for a free slot reach the WithMaxQueuedBackgroundTasks limit.
+	ErrBackgroundQueueFull = internaltasks.ErrQueueFull
+	// ErrBackgroundTasksClosed is returned by RouteContext.Go once the router
+	// has started waiting for background tasks to finish on shutdown.
+	ErrBackgroundTasksClosed = internaltasks.ErrClosed
+)
+
 const ServiceKeyTokenProvider = ServiceKey(internaltokenizer.ServiceKeyTokenProvider)
 
 const (
@@ -63,6 +112,9 @@ const (
 	MimeOpenAPI     = internalcommon.MimeOpenAPI
 	MimeYAML        = internalcommon.MimeYAML
 	MimeProblemJSON = internalcommon.MimeProblemJSON
+
+	MimeMergePatchJSON = internalcommon.MimeMergePatchJSON
+	MimeJSONPatchJSON  = internalcommon.MimeJSONPatchJSON
 )
 
 const (
@@ -71,6 +123,10 @@ const (
 	HeaderContentType   = internalcommon.HeaderContentType
 	HeaderLocation      = internalcommon.HeaderLocation
 	HeaderRetryAfter    = internalcommon.HeaderRetryAfter
+	HeaderXRequestID    = internalcommon.HeaderXRequestID
+
+	HeaderRateLimit       = internalcommon.HeaderRateLimit
+	HeaderRateLimitPolicy = internalcommon.HeaderRateLimitPolicy
 )
 
 type CookieOption struct {
@@ -119,7 +175,13 @@ type RouteContext interface {
 	Request() *http.Request
 	Response() http.ResponseWriter
 	Bind(any) error
+	BindPatch(current any) error
+	Files() (*Uploads, error)
+	MultipartReader() (*MultipartReader, error)
+	Go(fn func(RouteContext)) error
 	User() claims.Principal
+	RequestID() string
+	CSPNonce() string
 	Services() *ServiceRegistry
 	Params() *ParamAccessor
 	Query() *QueryAccessor
@@ -183,6 +245,18 @@ func RouteContextFromRequest(r *http.Request) (RouteContext, bool) {
 	return wrapRouteContext(inner), true
 }
 
+// Detach returns a copy of c that is safe to use after the handler returns. The
+// copy keeps the principal, params, services, and route options, runs on a
+// background context, and discards response writes. Prefer RouteContext.Go,
+// which also bounds, recovers, and awaits the work.
+func Detach(c RouteContext) RouteContext {
+	detached := internalrouting.Detach(unwrapRouteContext(c))
+	if detached == nil {
+		return nil
+	}
+	return wrapRouteContext(detached)
+}
+
 func ClearCookieWithOptions(c RouteContext, name string, opts ...CookieOption) {
 	internalrouting.ClearCookieWithOptions(unwrapRouteContext(c), name, toInternalCookieOptions(opts)...)
 }
@@ -200,9 +274,30 @@ func (c *routeContext) SetRequest(r *http.Request)        { c.inner.SetRequest(r
 func (c *routeContext) Response() http.ResponseWriter     { return c.inner.Response() }
 func (c *routeContext) SetResponse(w http.ResponseWriter) { c.inner.SetResponse(w) }
 func (c *routeContext) Bind(target any) error             { return c.inner.Bind(target) }
+func (c *routeContext) BindPatch(current any) error       { return c.inner.BindPatch(current) }
 func (c *routeContext) User() claims.Principal            { return c.inner.User() }
 func (c *routeContext) SetUser(user claims.Principal)     { c.inner.SetUser(user) }
+func (c *routeContext) RequestID() string                 { return c.inner.RequestID() }
+func (c *routeContext) SetRequestID(id string)            { c.inner.SetRequestID(id) }
+func (c *routeContext) CSPNonce() string                  { return c.inner.CSPNonce() }
 func (c *routeContext) SetContextValue(key, value any)    { c.inner.SetContextValue(key, value) }
+func (c *routeContext) Files() (*Uploads, error) {
+	uploads, err := c.inner.Files()
+	return wrapUploads(uploads), err
+}
+func (c *routeContext) MultipartReader() (*MultipartReader, error) {
+	reader, err := c.inner.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartReader{inner: reader}, nil
+}
+func (c *routeContext) Go(fn func(RouteContext)) error {
+	if fn == nil {
+		return nil
+	}
+	return c.inner.Go(func(inner internalrouting.RouteContext) { fn(wrapRouteContext(inner)) })
+}
 func (c *routeContext) Services() *ServiceRegistry {
 	return newServiceRegistry(
 		func(key ServiceKey, svc any) {
@@ -236,13 +331,8 @@ func (c *routeContext) Problem(detail *ProblemDetails) {
 		c.inner.Problem(nil)
 		return
 	}
-	c.inner.Problem(&internalrouting.ProblemDetails{
-		Type:     detail.Type,
-		Title:    detail.Title,
-		Status:   detail.Status,
-		Detail:   detail.Detail,
-		Instance: detail.Instance,
-	})
+	problem := detail.toInternal()
+	c.inner.Problem(&problem)
 }
 func (c *routeContext) File(path string)                { c.inner.File(path) }
 func (c *routeContext) Download(path, filename string)  { c.inner.Download(path, filename) }
@@ -0,0 +1,48 @@
+package mux
+
+import (
+	"context"
+	"html/template"
+
+	internalcsrf "github.com/fgrzl/mux/internal/middleware/csrf"
+)
+
+// CSRFTokenStore holds synchronizer tokens for WithCSRFSynchronizerTokens,
+// one per session. Delete a session's token when it signs out or its
+// privileges change so a new one is issued.
+type CSRFTokenStore interface {
+	// Get returns the token stored for session and whether one exists.
+	Get(ctx context.Context, session string) (token string, ok bool, err error)
+	// Set stores token for session, replacing any previous token.
+	Set(ctx context.Context, session, token string) error
+	// Delete removes the token stored for session.
+	Delete(ctx context.Context, session string) error
+}
+
+// NewMemoryCSRFTokenStore returns an in-process CSRFTokenStore. It does not
+// share tokens across processes.
+func NewMemoryCSRFTokenStore() CSRFTokenStore {
+	return internalcsrf.NewMemoryTokenStore()
+}
+
+// CSRFToken returns the request's CSRF token for forms and scripts to send
+// back in the form field or header, issuing one if needed. With
+// double-submit tokens this sets the token cookie, so call it before writing
+// the response. It returns "" without UseCSRF or with
+// WithCSRFOriginCheckOnly.
+func CSRFToken(c RouteContext) string {
+	return internalcsrf.Token(unwrapRouteContext(c))
+}
+
+// CSRFField returns a hidden form input carrying CSRFToken, for
+// html/template pages.
+func CSRFField(c RouteContext) template.HTML {
+	return internalcsrf.Field(unwrapRouteContext(c))
+}
+
+// CSRFTemplateFuncs returns the csrfToken and csrfField template functions
+// bound to the request. Register placeholders with the same names when
+// parsing, then add these to a clone of the template before executing it.
+func CSRFTemplateFuncs(c RouteContext) template.FuncMap {
+	return internalcsrf.TemplateFuncs(unwrapRouteContext(c))
+}
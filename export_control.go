@@ -0,0 +1,217 @@
+package mux
+
+import (
+	"context"
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	internalexportcontrol "github.com/fgrzl/mux/internal/middleware/exportcontrol"
+)
+
+// ExportControlMode selects whether an ExportControlPolicy lists blocked or
+// allowed regions.
+type ExportControlMode string
+
+const (
+	// ExportControlDeny blocks requests from the listed regions.
+	ExportControlDeny = ExportControlMode(internalexportcontrol.ModeDeny)
+	// ExportControlAllow blocks requests from every region that is not
+	// listed.
+	ExportControlAllow = ExportControlMode(internalexportcontrol.ModeAllow)
+)
+
+// ExportControlPolicy lists the regions UseExportControl blocks or allows.
+// Policy files use the JSON field names.
+type ExportControlPolicy struct {
+	// Mode is ExportControlDeny or ExportControlAllow. Empty means
+	// ExportControlDeny.
+	Mode ExportControlMode `json:"mode,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes, such as "IR".
+	Countries []string `json:"countries,omitempty"`
+	// Subdivisions are ISO 3166-2 codes, such as "UA-43" for Crimea. They
+	// need a GeoIP City or Enterprise database.
+	Subdivisions []string `json:"subdivisions,omitempty"`
+	// FailClosed blocks requests whose location cannot be determined
+	// instead of letting them through.
+	FailClosed bool `json:"failClosed,omitempty"`
+}
+
+func (p ExportControlPolicy) toInternal() internalexportcontrol.Policy {
+	return internalexportcontrol.Policy{
+		Mode:         internalexportcontrol.PolicyMode(p.Mode),
+		Countries:    slices.Clone(p.Countries),
+		Subdivisions: slices.Clone(p.Subdivisions),
+		FailClosed:   p.FailClosed,
+	}
+}
+
+func fromInternalExportControlPolicy(p internalexportcontrol.Policy) ExportControlPolicy {
+	return ExportControlPolicy{
+		Mode:         ExportControlMode(p.Mode),
+		Countries:    slices.Clone(p.Countries),
+		Subdivisions: slices.Clone(p.Subdivisions),
+		FailClosed:   p.FailClosed,
+	}
+}
+
+// DefaultExportControlPolicy returns the policy UseExportControl applies
+// without WithExportControlPolicy: it denies the comprehensively sanctioned
+// countries and fails open. It lists no subdivisions.
+func DefaultExportControlPolicy() ExportControlPolicy {
+	return fromInternalExportControlPolicy(internalexportcontrol.DefaultPolicy())
+}
+
+// WithExportControlPolicy replaces DefaultExportControlPolicy. Unlike other
+// export control options, which ignore invalid entries, it panics on an
+// unknown mode or an invalid country or subdivision code, so a policy that
+// would not enforce what compliance asked for fails at startup. Validate
+// policies from untrusted input with LoadExportControlPolicy first.
+func WithExportControlPolicy(p ExportControlPolicy) ExportControlOption {
+	return ExportControlOption{apply: internalexportcontrol.WithPolicy(p.toInternal())}
+}
+
+// LoadExportControlPolicy reads and validates the JSON policy at path, for
+// example to check a policy file before deploying it.
+func LoadExportControlPolicy(path string) (ExportControlPolicy, error) {
+	p, err := internalexportcontrol.LoadPolicy(path)
+	if err != nil {
+		return ExportControlPolicy{}, err
+	}
+	return fromInternalExportControlPolicy(p), nil
+}
+
+// ExportControlLocation is where a client address is located.
+type ExportControlLocation struct {
+	// Country is the ISO 3166-1 alpha-2 code, or "" when unknown.
+	Country string
+	// Subdivisions are ISO 3166-2 codes, such as "UA-43".
+	Subdivisions []string
+}
+
+// ExportControlLocator resolves client addresses to locations, for sources
+// other than a MaxMind GeoIP database.
+type ExportControlLocator interface {
+	Locate(ip net.IP) (ExportControlLocation, error)
+}
+
+// ExportControlLocatorFunc adapts a function to an ExportControlLocator.
+type ExportControlLocatorFunc func(ip net.IP) (ExportControlLocation, error)
+
+// Locate implements ExportControlLocator.
+func (f ExportControlLocatorFunc) Locate(ip net.IP) (ExportControlLocation, error) {
+	return f(ip)
+}
+
+func toInternalLocator(l ExportControlLocator) internalexportcontrol.Locator {
+	return internalexportcontrol.LocatorFunc(func(ip net.IP) (internalexportcontrol.Location, error) {
+		loc, err := l.Locate(ip)
+		return internalexportcontrol.Location{Country: loc.Country, Subdivisions: loc.Subdivisions}, err
+	})
+}
+
+// ExportControlBlockReason says why UseExportControl blocked a request.
+type ExportControlBlockReason string
+
+const (
+	// ExportControlDenied is a location listed by an ExportControlDeny
+	// policy.
+	ExportControlDenied = ExportControlBlockReason(internalexportcontrol.ReasonDenied)
+	// ExportControlNotAllowed is a location not listed by an
+	// ExportControlAllow policy.
+	ExportControlNotAllowed = ExportControlBlockReason(internalexportcontrol.ReasonNotAllowed)
+	// ExportControlUnknownLocation is a request whose location could not be
+	// determined under a fail-closed policy.
+	ExportControlUnknownLocation = ExportControlBlockReason(internalexportcontrol.ReasonUnknownLocation)
+)
+
+// ExportControlEvent is the audit record of a blocked request.
+type ExportControlEvent struct {
+	Time     time.Time
+	ClientIP string
+	Location ExportControlLocation
+	Reason   ExportControlBlockReason
+	// Rule is the policy entry that matched, such as "IR" or "UA-43". It is
+	// empty unless Reason is ExportControlDenied.
+	Rule   string
+	Method string
+	Path   string
+	// Err is the lookup error behind ExportControlUnknownLocation, if any.
+	Err error
+}
+
+func fromInternalExportControlEvent(e internalexportcontrol.Event) ExportControlEvent {
+	return ExportControlEvent{
+		Time:     e.Time,
+		ClientIP: e.ClientIP,
+		Location: ExportControlLocation{Country: e.Location.Country, Subdivisions: e.Location.Subdivisions},
+		Reason:   ExportControlBlockReason(e.Reason),
+		Rule:     e.Rule,
+		Method:   e.Method,
+		Path:     e.Path,
+		Err:      e.Err,
+	}
+}
+
+// ExportControlPolicyFile is an ExportControlPolicy loaded from a JSON file
+// and reloaded on SIGHUP, on Reload, or when it changes. A reload that fails
+// keeps the previous policy. It is safe for concurrent use.
+type ExportControlPolicyFile struct {
+	inner *internalexportcontrol.PolicyFile
+}
+
+// ExportControlPolicyFileOption configures OpenExportControlPolicyFile.
+type ExportControlPolicyFileOption struct {
+	apply internalexportcontrol.PolicyFileOption
+}
+
+// WithExportControlReloadSignals replaces SIGHUP as the signals that reload
+// the policy file. Passing none disables reloading on signals.
+func WithExportControlReloadSignals(signals ...os.Signal) ExportControlPolicyFileOption {
+	return ExportControlPolicyFileOption{apply: internalexportcontrol.WithReloadSignals(signals...)}
+}
+
+// WithExportControlReloadInterval reloads the policy file when its
+// modification time changes, checked every d, such as for mounted
+// Kubernetes ConfigMaps.
+func WithExportControlReloadInterval(d time.Duration) ExportControlPolicyFileOption {
+	return ExportControlPolicyFileOption{apply: internalexportcontrol.WithReloadInterval(d)}
+}
+
+// OpenExportControlPolicyFile loads the policy at path. Pass it to
+// WithExportControlPolicyFile, and Close it to stop reloading.
+func OpenExportControlPolicyFile(path string, opts ...ExportControlPolicyFileOption) (*ExportControlPolicyFile, error) {
+	internalOpts := make([]internalexportcontrol.PolicyFileOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	f, err := internalexportcontrol.OpenPolicyFile(path, internalOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportControlPolicyFile{inner: f}, nil
+}
+
+// Reload reads the file again. On error the previous policy stays active.
+func (f *ExportControlPolicyFile) Reload() error {
+	return f.inner.Reload()
+}
+
+// Policy returns the active policy.
+func (f *ExportControlPolicyFile) Policy() ExportControlPolicy {
+	return fromInternalExportControlPolicy(f.inner.Policy())
+}
+
+// Close stops reloading. The last policy stays active.
+func (f *ExportControlPolicyFile) Close() error {
+	return f.inner.Close()
+}
+
+func exportControlAudit(fn func(ctx context.Context, e ExportControlEvent)) func(context.Context, internalexportcontrol.Event) {
+	return func(ctx context.Context, e internalexportcontrol.Event) {
+		fn(ctx, fromInternalExportControlEvent(e))
+	}
+}
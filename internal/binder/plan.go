@@ -0,0 +1,689 @@
+package binder
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// RawJSON marks a value that is already JSON encoded, such as a member of a
+// JSON request body. Plans decode it directly into the target field.
+type RawJSON []byte
+
+// Plan is a compiled, per-type description of how request values are written
+// into a struct. Field matching mirrors encoding/json (exact name first, then
+// case-insensitive) so binding through a plan behaves like the staging map and
+// JSON round-trip it replaces. Scalar and slice fields are set directly and
+// raw JSON members decode straight into their fields; other values are still
+// encoded and decoded by encoding/json, one field at a time.
+type Plan struct {
+	typ    reflect.Type
+	fields []planField
+	exact  map[string]int
+	folded map[string]int
+}
+
+type planField struct {
+	name     string
+	index    []int
+	typ      reflect.Type
+	tagged   bool
+	delegate bool
+	fast     bool
+}
+
+var (
+	planCache            sync.Map // reflect.Type -> *Plan
+	jsonUnmarshalerType  = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerIface = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// PlanFor returns the cached binding plan for struct type t, compiling it on
+// first use. It returns nil when t is not a struct.
+func PlanFor(t reflect.Type) *Plan {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := planCache.Load(t); ok {
+		return cached.(*Plan)
+	}
+	plan := compilePlan(t)
+	actual, _ := planCache.LoadOrStore(t, plan)
+	return actual.(*Plan)
+}
+
+func compilePlan(t reflect.Type) *Plan {
+	fields := typeFields(t)
+	plan := &Plan{
+		typ:    t,
+		fields: fields,
+		exact:  make(map[string]int, len(fields)),
+		folded: make(map[string]int, len(fields)),
+	}
+	for i := range fields {
+		plan.exact[fields[i].name] = i
+		fold := strings.ToLower(fields[i].name)
+		if _, ok := plan.folded[fold]; !ok {
+			plan.folded[fold] = i
+		}
+	}
+	return plan
+}
+
+func (p *Plan) lookup(key string) *planField {
+	if i, ok := p.exact[key]; ok {
+		return &p.fields[i]
+	}
+	if i, ok := p.folded[strings.ToLower(key)]; ok && strings.EqualFold(p.fields[i].name, key) {
+		return &p.fields[i]
+	}
+	return nil
+}
+
+// DeepValue is one leaf of a deepObject parameter, such as the value of
+// filter[status] addressed by Path ["status"].
+type DeepValue struct {
+	Path  []string
+	Value any
+}
+
+// Apply writes value into the field of root that encoding/json would match
+// for key; keys matching no field are ignored. root must be an addressable
+// struct value of the plan's type. Values are strings, string slices, typed
+// values from parameter converters, RawJSON, or nil.
+//
+// Type mismatches are reported as *json.UnmarshalTypeError, matching the
+// errors the JSON round-trip produced.
+func (p *Plan) Apply(root reflect.Value, key string, value any) error {
+	return p.apply(root, key, value, key)
+}
+
+// ApplyDeep writes the deepObject leaves for key into root. leaves must be
+// sorted by path so siblings are adjacent. Nested structs are walked through
+// their own plans; any other destination receives the leaves as one object.
+func (p *Plan) ApplyDeep(root reflect.Value, key string, leaves []DeepValue) error {
+	return p.applyDeep(root, key, leaves, key)
+}
+
+// apply implements Apply; fieldPath is the dotted key path reported in type
+// errors, as encoding/json reports it.
+func (p *Plan) apply(root reflect.Value, key string, value any, fieldPath string) error {
+	field := p.lookup(key)
+	if field == nil {
+		return nil
+	}
+	if field.delegate {
+		// Let encoding/json decode through the parent so ",string" options
+		// and unexported embedded pointers behave exactly as before. Raw
+		// members are passed as json.RawMessage so they are not re-encoded as
+		// base64 byte strings.
+		if raw, ok := value.(RawJSON); ok {
+			value = json.RawMessage(raw)
+		}
+		return decodeJSONInto(root, map[string]any{key: value})
+	}
+	dst := fieldByIndexAlloc(root, field.index)
+
+	if raw, ok := value.(RawJSON); ok {
+		if !field.fast {
+			return json.Unmarshal(raw, dst.Addr().Interface())
+		}
+		// Scalars are decoded generically first so numbers follow the same
+		// float64 path as the staging map did (e.g. 1.0 still binds to int).
+		decoded, err := decodeRawScalar(raw)
+		if err != nil {
+			return err
+		}
+		value = decoded
+	}
+	if field.fast && value != nil {
+		if handled, err := setFast(allocIndirect(dst), value, p.typ, fieldPath); handled {
+			return err
+		}
+	}
+	return decodeJSONInto(dst, value)
+}
+
+func (p *Plan) applyDeep(root reflect.Value, key string, leaves []DeepValue, fieldPath string) error {
+	field := p.lookup(key)
+	if field == nil {
+		return nil
+	}
+	nestedType := indirectType(field.typ)
+	if field.delegate || nestedType.Kind() != reflect.Struct || implementsUnmarshaler(nestedType) {
+		return p.apply(root, key, nestLeaves(leaves), fieldPath)
+	}
+	nested := PlanFor(nestedType)
+	dst := allocIndirect(fieldByIndexAlloc(root, field.index))
+
+	var saved error
+	for i := 0; i < len(leaves); {
+		segment := leaves[i].Path[0]
+		segmentPath := fieldPath + "." + segment
+		j := i
+		for j < len(leaves) && leaves[j].Path[0] == segment {
+			j++
+		}
+		var deeper []DeepValue
+		for _, leaf := range leaves[i:j] {
+			if len(leaf.Path) == 1 {
+				if abort := KeepFirstError(&saved, nested.apply(dst, segment, leaf.Value, segmentPath)); abort {
+					return saved
+				}
+				continue
+			}
+			deeper = append(deeper, DeepValue{Path: leaf.Path[1:], Value: leaf.Value})
+		}
+		if len(deeper) > 0 {
+			if abort := KeepFirstError(&saved, nested.applyDeep(dst, segment, deeper, segmentPath)); abort {
+				return saved
+			}
+		}
+		i = j
+	}
+	return saved
+}
+
+// decodeRawScalar decodes a raw JSON value the way decoding into any would,
+// short-circuiting literals that need no allocation-heavy decoding.
+func decodeRawScalar(raw RawJSON) (any, error) {
+	text := strings.TrimSpace(string(raw))
+	switch {
+	case text == "null":
+		return nil, nil
+	case text == "true":
+		return true, nil
+	case text == "false":
+		return false, nil
+	case len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' && !strings.ContainsAny(text[1:len(text)-1], "\\\"") && utf8.ValidString(text):
+		return text[1 : len(text)-1], nil
+	case len(text) > 0 && (text[0] == '-' || text[0] >= '0' && text[0] <= '9'):
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f, nil
+		}
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Accepts reports whether any field of the plan can hold values of struct
+// type t through Assign.
+func (p *Plan) Accepts(t reflect.Type) bool {
+	for i := range p.fields {
+		if !p.fields[i].delegate && acceptsConverted(p.fields[i].typ, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign stores values directly in the field matched by key when the field
+// is T, *T, []T, or []*T for a struct type T convertible from the values'
+// struct type, such as uploaded file headers. values must be pointers to
+// structs. It reports whether the field accepted the values.
+func (p *Plan) Assign(root reflect.Value, key string, values []reflect.Value) bool {
+	field := p.lookup(key)
+	if field == nil || field.delegate || len(values) == 0 || !acceptsConverted(field.typ, values[0].Type().Elem()) {
+		return false
+	}
+	return AssignConverted(fieldByIndexAlloc(root, field.index), values)
+}
+
+// AssignConverted sets dst from struct pointers, converting them to dst's T,
+// *T, []T, or []*T type. Scalar destinations take the first value.
+func AssignConverted(dst reflect.Value, values []reflect.Value) bool {
+	if len(values) == 0 {
+		return false
+	}
+	t := dst.Type()
+	if t.Kind() == reflect.Slice {
+		out := reflect.MakeSlice(t, 0, len(values))
+		for _, v := range values {
+			converted, ok := convertStructPointer(v, t.Elem())
+			if !ok {
+				return false
+			}
+			out = reflect.Append(out, converted)
+		}
+		dst.Set(out)
+		return true
+	}
+	converted, ok := convertStructPointer(values[0], t)
+	if !ok {
+		return false
+	}
+	dst.Set(converted)
+	return true
+}
+
+func convertStructPointer(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	switch {
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct && v.Type().ConvertibleTo(t):
+		return v.Convert(t), true
+	case t.Kind() == reflect.Struct && v.Elem().Type().ConvertibleTo(t):
+		return v.Elem().Convert(t), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+func acceptsConverted(fieldType, t reflect.Type) bool {
+	if fieldType.Kind() == reflect.Slice {
+		fieldType = fieldType.Elem()
+	}
+	fieldType = indirectType(fieldType)
+	return fieldType.Kind() == reflect.Struct && t.ConvertibleTo(fieldType)
+}
+
+// KeepFirstError records err when it is the first type mismatch and reports
+// whether binding must stop. Like encoding/json, type mismatches are
+// collected while any other error aborts immediately.
+func KeepFirstError(saved *error, err error) bool {
+	if err == nil {
+		return false
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		*saved = err
+		return true
+	}
+	if *saved == nil {
+		*saved = err
+	}
+	return false
+}
+
+// nestLeaves rebuilds the object described by leaves for destinations a plan
+// cannot walk, such as map fields.
+func nestLeaves(leaves []DeepValue) map[string]any {
+	out := map[string]any{}
+	for _, leaf := range leaves {
+		node := out
+		for _, segment := range leaf.Path[:len(leaf.Path)-1] {
+			next, _ := node[segment].(map[string]any)
+			if next == nil {
+				next = map[string]any{}
+				node[segment] = next
+			}
+			node = next
+		}
+		node[leaf.Path[len(leaf.Path)-1]] = leaf.Value
+	}
+	return out
+}
+
+func decodeJSONInto(dst reflect.Value, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dst.Addr().Interface())
+}
+
+func allocIndirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			v = allocIndirect(v)
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func implementsUnmarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonUnmarshalerType) || pt.Implements(jsonUnmarshalerType) ||
+		t.Implements(textUnmarshalerIface) || pt.Implements(textUnmarshalerIface)
+}
+
+// fastKind reports whether values of t can be written by direct setters with
+// the same outcome as encoding/json.
+func fastKind(t reflect.Type) bool {
+	t = indirectType(t)
+	if implementsUnmarshaler(t) {
+		return false
+	}
+	//exhaustive:ignore -- only scalar kinds and slices of them use direct setters
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		elem := t.Elem()
+		return elem.Kind() != reflect.Uint8 && elem.Kind() != reflect.Pointer && fastKind(elem) && elem.Kind() != reflect.Slice
+	default:
+		return false
+	}
+}
+
+// setFast writes value into dst using encoding/json conversion rules. It
+// reports false when the value shape needs the generic JSON path.
+func setFast(dst reflect.Value, value any, structType reflect.Type, field string) (bool, error) {
+	if dst.Kind() == reflect.Slice {
+		return setFastSlice(dst, value, structType, field)
+	}
+	switch v := value.(type) {
+	case string:
+		if dst.Kind() != reflect.String {
+			return true, typeError("string", dst.Type(), structType, field)
+		}
+		dst.SetString(v)
+		return true, nil
+	case bool:
+		if dst.Kind() != reflect.Bool {
+			return true, typeError("bool", dst.Type(), structType, field)
+		}
+		dst.SetBool(v)
+		return true, nil
+	case []string:
+		return true, typeError("array", dst.Type(), structType, field)
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return false, nil
+	}
+	//exhaustive:ignore -- integer and float sources only; others use the JSON path
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return setFastInt(dst, rv.Int(), structType, field)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > 1<<63-1 {
+			return false, nil
+		}
+		return setFastInt(dst, int64(u), structType, field)
+	case reflect.Float32, reflect.Float64:
+		return setFastFloat(dst, rv.Float(), rv.Type().Bits(), structType, field)
+	case reflect.Slice:
+		return true, typeError("array", dst.Type(), structType, field)
+	default:
+		return false, nil
+	}
+}
+
+func setFastInt(dst reflect.Value, n int64, structType reflect.Type, field string) (bool, error) {
+	//exhaustive:ignore -- destination kinds were vetted by fastKind
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dst.OverflowInt(n) {
+			return true, typeError("number "+strconv.FormatInt(n, 10), dst.Type(), structType, field)
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n < 0 || dst.OverflowUint(uint64(n)) {
+			return true, typeError("number "+strconv.FormatInt(n, 10), dst.Type(), structType, field)
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(float64(n))
+	default:
+		return true, typeError("number", dst.Type(), structType, field)
+	}
+	return true, nil
+}
+
+// setFastFloat mirrors encoding/json, which formats the float and parses the
+// literal back into the destination kind.
+func setFastFloat(dst reflect.Value, f float64, bits int, structType reflect.Type, field string) (bool, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return false, nil
+	}
+	literal := formatJSONFloat(f, bits)
+	//exhaustive:ignore -- destination kinds were vetted by fastKind
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(literal, 10, 64)
+		if err != nil || dst.OverflowInt(n) {
+			return true, typeError("number "+literal, dst.Type(), structType, field)
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(literal, 10, 64)
+		if err != nil || dst.OverflowUint(n) {
+			return true, typeError("number "+literal, dst.Type(), structType, field)
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(literal, dst.Type().Bits())
+		if err != nil || dst.OverflowFloat(n) {
+			return true, typeError("number "+literal, dst.Type(), structType, field)
+		}
+		dst.SetFloat(n)
+	default:
+		return true, typeError("number", dst.Type(), structType, field)
+	}
+	return true, nil
+}
+
+// formatJSONFloat formats f the way encoding/json encodes floats.
+func formatJSONFloat(f float64, bits int) string {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return string(b)
+}
+
+func setFastSlice(dst reflect.Value, value any, structType reflect.Type, field string) (bool, error) {
+	src := reflect.ValueOf(value)
+	if !src.IsValid() || src.Kind() != reflect.Slice {
+		if _, ok := value.(string); ok {
+			return true, typeError("string", dst.Type(), structType, field)
+		}
+		if _, ok := value.(bool); ok {
+			return true, typeError("bool", dst.Type(), structType, field)
+		}
+		return false, nil
+	}
+	out := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+	var firstErr error
+	for i := 0; i < src.Len(); i++ {
+		handled, err := setFast(out.Index(i), src.Index(i).Interface(), structType, field)
+		if !handled {
+			return false, nil
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	dst.Set(out)
+	return true, firstErr
+}
+
+func typeError(value string, t reflect.Type, structType reflect.Type, field string) error {
+	return &json.UnmarshalTypeError{Value: value, Type: t, Struct: structType.Name(), Field: field}
+}
+
+// typeFields returns the fields encoding/json would decode for t, applying
+// its embedding and name-dominance rules.
+func typeFields(t reflect.Type) []planField {
+	type queued struct {
+		typ      reflect.Type
+		index    []int
+		delegate bool
+	}
+	var fields []planField
+	current := []queued{}
+	next := []queued{{typ: t}}
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				if !validTagName(name) {
+					name = ""
+				}
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					tagged := name != ""
+					if name == "" {
+						name = sf.Name
+					}
+					fields = append(fields, planField{
+						name:     name,
+						index:    index,
+						typ:      sf.Type,
+						tagged:   tagged,
+						delegate: f.delegate || hasTagOption(opts, "string"),
+						fast:     fastKind(sf.Type),
+					})
+					if count[f.typ] > 1 {
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, queued{
+						typ:      ft,
+						index:    index,
+						delegate: f.delegate || !sf.IsExported() && sf.Type.Kind() == reflect.Pointer,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		if len(fields[i].index) != len(fields[j].index) {
+			return len(fields[i].index) < len(fields[j].index)
+		}
+		if fields[i].tagged != fields[j].tagged {
+			return fields[i].tagged
+		}
+		return indexLess(fields[i].index, fields[j].index)
+	})
+
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		fi := fields[i]
+		for advance = 1; i+advance < len(fields); advance++ {
+			if fields[i+advance].name != fi.name {
+				break
+			}
+		}
+		if advance == 1 {
+			out = append(out, fi)
+			continue
+		}
+		if dominant, ok := dominantField(fields[i : i+advance]); ok {
+			out = append(out, dominant)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return indexLess(out[i].index, out[j].index) })
+	return out
+}
+
+func dominantField(fields []planField) (planField, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) && fields[0].tagged == fields[1].tagged {
+		return planField{}, false
+	}
+	return fields[0], true
+}
+
+func indexLess(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func validTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c > 127):
+			return false
+		}
+	}
+	return true
+}
+
+func hasTagOption(opts, option string) bool {
+	for opts != "" {
+		var name string
+		name, opts, _ = strings.Cut(opts, ",")
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package binder
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type planBase struct {
+	Name  string `json:"name"`
+	Color string
+}
+
+type planOther struct {
+	Color string
+}
+
+type planTarget struct {
+	planBase
+	planOther
+	Name    string            `json:"title"`
+	Count   int               `json:"count"`
+	Ratio   *float32          `json:"ratio"`
+	Labels  []uint            `json:"labels"`
+	Nested  *planTarget       `json:"nested"`
+	Extras  map[string]string `json:"extras"`
+	Skipped string            `json:"-"`
+}
+
+func applyAll(t *testing.T, target *planTarget, values map[string]any) error {
+	t.Helper()
+	plan := PlanFor(reflect.TypeOf(*target))
+	require.NotNil(t, plan)
+	var saved error
+	for key, value := range values {
+		if KeepFirstError(&saved, plan.Apply(reflect.ValueOf(target).Elem(), key, value)) {
+			break
+		}
+	}
+	return saved
+}
+
+func TestShouldResolveFieldsLikeEncodingJSONGivenEmbeddedStructs(t *testing.T) {
+	// Arrange
+	var out planTarget
+
+	// Act
+	err := applyAll(t, &out, map[string]any{"name": "embedded", "TITLE": "outer", "Color": "ambiguous", "Skipped": "x"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "embedded", out.planBase.Name, "tagged promoted field wins for its name")
+	assert.Equal(t, "outer", out.Name, "names match case-insensitively")
+	assert.Empty(t, out.planBase.Color, "ambiguous promoted fields are dropped")
+	assert.Empty(t, out.Skipped)
+}
+
+func TestShouldConvertValuesGivenFastFields(t *testing.T) {
+	// Arrange
+	var out planTarget
+
+	// Act
+	err := applyAll(t, &out, map[string]any{
+		"count":  float64(3),
+		"ratio":  int64(2),
+		"labels": []int64{1, 2},
+		"extras": RawJSON(`{"a":"b"}`),
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 3, out.Count)
+	require.NotNil(t, out.Ratio)
+	assert.InDelta(t, 2, *out.Ratio, 0)
+	assert.Equal(t, []uint{1, 2}, out.Labels)
+	assert.Equal(t, map[string]string{"a": "b"}, out.Extras)
+}
+
+func TestShouldReturnUnmarshalTypeErrorGivenMismatchedValue(t *testing.T) {
+	// Arrange
+	var out planTarget
+
+	// Act
+	err := applyAll(t, &out, map[string]any{"count": "three"})
+
+	// Assert
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "string", typeErr.Value)
+	assert.Equal(t, "count", typeErr.Field)
+}
+
+func TestShouldRejectNegativeValueGivenUnsignedField(t *testing.T) {
+	// Arrange
+	var out planTarget
+
+	// Act
+	err := applyAll(t, &out, map[string]any{"labels": []int64{1, -1}})
+
+	// Assert
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr)
+	assert.Equal(t, "number -1", typeErr.Value)
+}
+
+func TestShouldApplyDeepValuesGivenNestedStructAndMap(t *testing.T) {
+	// Arrange
+	var out planTarget
+	plan := PlanFor(reflect.TypeOf(out))
+	root := reflect.ValueOf(&out).Elem()
+
+	// Act
+	errNested := plan.ApplyDeep(root, "nested", []DeepValue{
+		{Path: []string{"count"}, Value: int64(4)},
+		{Path: []string{"extras", "k"}, Value: "v"},
+		{Path: []string{"title"}, Value: "child"},
+	})
+	errMap := plan.ApplyDeep(root, "extras", []DeepValue{{Path: []string{"x"}, Value: "y"}})
+
+	// Assert
+	require.NoError(t, errNested)
+	require.NoError(t, errMap)
+	require.NotNil(t, out.Nested)
+	assert.Equal(t, 4, out.Nested.Count)
+	assert.Equal(t, "child", out.Nested.Name)
+	assert.Equal(t, map[string]string{"k": "v"}, out.Nested.Extras)
+	assert.Equal(t, map[string]string{"x": "y"}, out.Extras)
+}
+
+func TestShouldReturnNilPlanGivenNonStructType(t *testing.T) {
+	// Act & Assert
+	assert.Nil(t, PlanFor(reflect.TypeOf(map[string]any{})))
+	assert.Same(t, PlanFor(reflect.TypeOf(planTarget{})), PlanFor(reflect.TypeOf(planTarget{})))
+}
@@ -13,32 +13,41 @@ import (
 // It returns (true, nil) if it set a typed value on staging, (false, nil) if caller should fall back
 // to storing raw values, or (false, err) if a conversion error occurred.
 func ProcessParamAndSet(staging map[string]any, key string, values []string, location string, param *openapi.ParameterObject) (bool, error) {
+	typed, ok, err := ProcessParam(key, values, location, param)
+	if err != nil || !ok {
+		return false, err
+	}
+	staging[key] = typed
+	return true, nil
+}
+
+// ProcessParam converts raw parameter values using the same precedence as
+// ProcessParamAndSet (converter, then example/schema parsing) and returns the
+// typed value instead of writing it to a staging map.
+func ProcessParam(key string, values []string, location string, param *openapi.ParameterObject) (any, bool, error) {
 	if param == nil {
-		return false, nil
+		return nil, false, nil
 	}
 	// normalize values according to Schema/Example (split CSV into slice when needed)
 	values = normalizeValuesForParam(values, param)
 	// converter has highest precedence
 	if param.Converter != nil {
 		if typed, err := param.Converter(values); err != nil {
-			return false, fmt.Errorf("%s %q: %w", location, key, err)
+			return nil, false, fmt.Errorf("%s %q: %w", location, key, err)
 		} else if typed != nil {
-			staging[key] = typed
-			return true, nil
+			return typed, true, nil
 		}
 	}
 	if len(values) == 1 {
 		if parsed, ok := ParseByExample(values[0], param); ok {
-			staging[key] = parsed
-			return true, nil
+			return parsed, true, nil
 		}
 	} else {
 		if parsedSlice, ok := ParseSliceValues(values, param); ok {
-			staging[key] = parsedSlice
-			return true, nil
+			return parsedSlice, true, nil
 		}
 	}
-	return false, nil
+	return nil, false, nil
 }
 
 func splitAndTrim(s string) []string {
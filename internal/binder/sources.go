@@ -0,0 +1,192 @@
+package binder
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Request sources recognised in explicit binding struct tags.
+const (
+	SourcePath   = "path"
+	SourceQuery  = "query"
+	SourceHeader = "header"
+	SourceCookie = "cookie"
+	SourceBody   = "body"
+)
+
+// SourceTag names one request source a field binds from.
+type SourceTag struct {
+	In   string
+	Name string
+}
+
+// SourceField describes a struct field bound from explicit source tags such as
+// `path:"id"`, `query:"page"`, `header:"X-Tenant"`, `cookie:"sid"`, or
+// `body:""`. Sources are consulted in the order they appear in the tag.
+type SourceField struct {
+	Index       []int
+	Name        string
+	Type        reflect.Type
+	Sources     []SourceTag
+	Default     string
+	HasDefault  bool
+	Required    bool
+	Description string
+	Converter   func([]string) (any, error)
+}
+
+// WholeBody reports whether the field receives the entire decoded request body.
+func (f *SourceField) WholeBody() bool {
+	for _, src := range f.Sources {
+		if src.In == SourceBody && src.Name == "" {
+			return true
+		}
+	}
+	return false
+}
+
+var sourceFieldsCache sync.Map // reflect.Type -> []SourceField
+
+// SourceFields returns the explicitly tagged fields of struct type t, or nil
+// when t declares no source tags. Results are cached per type.
+func SourceFields(t reflect.Type) []SourceField {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	if cached, ok := sourceFieldsCache.Load(t); ok {
+		return cached.([]SourceField)
+	}
+	fields := collectSourceFields(t, nil)
+	sourceFieldsCache.Store(t, fields)
+	return fields
+}
+
+func collectSourceFields(t reflect.Type, parent []int) []SourceField {
+	var fields []SourceField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		sources := parseSourceTags(field.Tag)
+		if len(sources) == 0 {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				fields = append(fields, collectSourceFields(field.Type, index)...)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		def, hasDefault := field.Tag.Lookup("default")
+		fields = append(fields, SourceField{
+			Index:       index,
+			Name:        field.Name,
+			Type:        field.Type,
+			Sources:     sources,
+			Default:     def,
+			HasDefault:  hasDefault,
+			Required:    field.Tag.Get("required") == "true" || field.Tag.Get("binding") == "required",
+			Description: field.Tag.Get("description"),
+			Converter:   makeConverter(indirectType(field.Type), nil),
+		})
+	}
+	return fields
+}
+
+// parseSourceTags extracts source tags from a struct tag, preserving their
+// declaration order so the tag itself expresses precedence.
+func parseSourceTags(tag reflect.StructTag) []SourceTag {
+	var sources []SourceTag
+	raw := string(tag)
+	for raw != "" {
+		raw = strings.TrimLeft(raw, " ")
+		colon := strings.Index(raw, ":\"")
+		if colon <= 0 {
+			break
+		}
+		key := raw[:colon]
+		rest := raw[colon+1:]
+		end := 1
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) {
+			break
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			break
+		}
+		raw = rest[end+1:]
+		switch key {
+		case SourcePath, SourceQuery, SourceHeader, SourceCookie, SourceBody:
+			sources = append(sources, SourceTag{In: key, Name: value})
+		}
+	}
+	return sources
+}
+
+// Set converts raw request values and assigns them to v, which must be the
+// addressable field value. Pointers are allocated as needed; scalar fields use
+// the first value and slice fields accept repeated or comma-separated values.
+func (f *SourceField) Set(v reflect.Value, values []string) error {
+	target := v
+	for target.Kind() == reflect.Pointer {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+
+	if target.Kind() == reflect.Slice && target.Type().Elem().Kind() != reflect.Uint8 {
+		if len(values) == 1 && strings.Contains(values[0], ",") {
+			values = splitAndTrim(values[0])
+		}
+	} else if len(values) > 1 {
+		values = values[:1]
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	if f.Converter == nil {
+		if err := json.Unmarshal([]byte(values[0]), target.Addr().Interface()); err != nil {
+			return fmt.Errorf("cannot bind %q to %s: %w", values[0], target.Type(), err)
+		}
+		return nil
+	}
+
+	parsed, err := f.Converter(values)
+	if err != nil {
+		return err
+	}
+	if parsed == nil {
+		return fmt.Errorf("cannot bind %q to %s", strings.Join(values, ","), target.Type())
+	}
+	pv := reflect.ValueOf(parsed)
+	switch {
+	case pv.Type().AssignableTo(target.Type()):
+		target.Set(pv)
+	case pv.Kind() == target.Kind() && pv.Type().ConvertibleTo(target.Type()):
+		target.Set(pv.Convert(target.Type()))
+	default:
+		return fmt.Errorf("cannot bind %q to %s", strings.Join(values, ","), target.Type())
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
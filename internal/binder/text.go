@@ -0,0 +1,145 @@
+package binder
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TextOptions controls how ParseText converts raw parameter values.
+type TextOptions struct {
+	// Layouts are tried in order for time.Time targets. Empty means RFC 3339.
+	Layouts []string
+	// Enum lists the permitted raw values. Empty permits any value.
+	Enum []string
+	// Delimiter splits each raw value into slice elements. Empty keeps each
+	// raw value as one element.
+	Delimiter string
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// ParseText converts raw values into a value of type t. Slices other than
+// []byte take one element per value, after splitting on opts.Delimiter;
+// every other type takes the first value. Supported element types are
+// encoding.TextUnmarshaler implementations, time.Time, time.Duration, and
+// string, bool, integer, and float kinds, including pointers to them.
+func ParseText(t reflect.Type, raw []string, opts TextOptions) (reflect.Value, error) {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		items := splitValues(raw, opts.Delimiter)
+		out := reflect.MakeSlice(t, 0, len(items))
+		for _, item := range items {
+			v, err := parseScalar(t.Elem(), item, opts)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out = reflect.Append(out, v)
+		}
+		return out, nil
+	}
+	if len(raw) == 0 {
+		return reflect.Zero(t), nil
+	}
+	return parseScalar(t, raw[0], opts)
+}
+
+func splitValues(raw []string, delimiter string) []string {
+	if delimiter == "" {
+		return raw
+	}
+	items := make([]string, 0, len(raw))
+	for _, value := range raw {
+		for _, item := range strings.Split(value, delimiter) {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func parseScalar(t reflect.Type, raw string, opts TextOptions) (reflect.Value, error) {
+	if len(opts.Enum) > 0 && !slices.Contains(opts.Enum, raw) {
+		return reflect.Value{}, fmt.Errorf("must be one of %s", strings.Join(opts.Enum, ", "))
+	}
+	if t.Kind() == reflect.Pointer {
+		v, err := parseScalar(t.Elem(), raw, TextOptions{Layouts: opts.Layouts})
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	}
+	switch {
+	case t == timeType:
+		return parseTime(raw, opts.Layouts)
+	case t == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("must be a duration such as 1h30m")
+		}
+		return reflect.ValueOf(d), nil
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		ptr := reflect.New(t)
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return reflect.Value{}, err
+		}
+		return ptr.Elem(), nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			if t.Bits() == 64 {
+				return reflect.Value{}, fmt.Errorf("must be an integer")
+			}
+			return reflect.Value{}, fmt.Errorf("must be an integer between %d and %d", int64(-1)<<(t.Bits()-1), int64(1)<<(t.Bits()-1)-1)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("must be an integer between 0 and %d", uint64(1)<<(t.Bits()-1)*2-1)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported parameter type %s", t)
+	}
+	return v, nil
+}
+
+func parseTime(raw string, layouts []string) (reflect.Value, error) {
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+	for _, layout := range layouts {
+		if ts, err := time.Parse(layout, raw); err == nil {
+			return reflect.ValueOf(ts), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("must be a time in the format %s", strings.Join(layouts, " or "))
+}
@@ -0,0 +1,71 @@
+package binder
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sortOrder string
+
+func TestShouldParseTextGivenSupportedTypes(t *testing.T) {
+	id := uuid.MustParse("7b1c9b7e-4c1f-4b56-9b8e-2f5d4c3a1b0e")
+	cases := []struct {
+		name string
+		typ  reflect.Type
+		raw  []string
+		opts TextOptions
+		want any
+	}{
+		{"int", reflect.TypeOf(0), []string{"42", "7"}, TextOptions{}, 42},
+		{"uint8", reflect.TypeOf(uint8(0)), []string{"255"}, TextOptions{}, uint8(255)},
+		{"pointer", reflect.TypeOf((*float64)(nil)), []string{"1.5"}, TextOptions{}, func() *float64 { f := 1.5; return &f }()},
+		{"duration", reflect.TypeOf(time.Duration(0)), []string{"1h30m"}, TextOptions{}, 90 * time.Minute},
+		{"time layout", reflect.TypeOf(time.Time{}), []string{"2026-01-02"}, TextOptions{Layouts: []string{time.RFC3339, time.DateOnly}}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"text unmarshaler", reflect.TypeOf(netip.Addr{}), []string{"10.0.0.1"}, TextOptions{}, netip.MustParseAddr("10.0.0.1")},
+		{"uuid", reflect.TypeOf(uuid.UUID{}), []string{id.String()}, TextOptions{}, id},
+		{"enum", reflect.TypeOf(sortOrder("")), []string{"desc"}, TextOptions{Enum: []string{"asc", "desc"}}, sortOrder("desc")},
+		{"repeated slice", reflect.TypeOf([]int{}), []string{"1", "2"}, TextOptions{}, []int{1, 2}},
+		{"pipe delimited slice", reflect.TypeOf([]string{}), []string{"a|b", "c"}, TextOptions{Delimiter: "|"}, []string{"a", "b", "c"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			v, err := ParseText(tc.typ, tc.raw, tc.opts)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v.Interface())
+		})
+	}
+}
+
+func TestShouldDescribeProblemGivenInvalidText(t *testing.T) {
+	cases := []struct {
+		name string
+		typ  reflect.Type
+		raw  string
+		opts TextOptions
+		want string
+	}{
+		{"int range", reflect.TypeOf(int8(0)), "300", TextOptions{}, "must be an integer between -128 and 127"},
+		{"bool", reflect.TypeOf(false), "yes", TextOptions{}, "must be true or false"},
+		{"time", reflect.TypeOf(time.Time{}), "tomorrow", TextOptions{Layouts: []string{time.DateOnly}}, "must be a time in the format 2006-01-02"},
+		{"enum", reflect.TypeOf(""), "up", TextOptions{Enum: []string{"asc", "desc"}}, "must be one of asc, desc"},
+		{"slice element", reflect.TypeOf([]int{}), "1,x", TextOptions{Delimiter: ","}, "must be an integer"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, err := ParseText(tc.typ, []string{tc.raw}, tc.opts)
+
+			// Assert
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
@@ -129,6 +129,52 @@ func (rb *RouteBuilder) WithRateLimit(limit int, interval time.Duration) *RouteB
 	return rb
 }
 
+// WithRateLimitPolicy adds a rate limit policy to this route, replacing an
+// inherited policy with the same name.
+func (rb *RouteBuilder) WithRateLimitPolicy(policy routing.RateLimitPolicy) *RouteBuilder {
+	rb.Options.RateLimits = routing.AddRateLimitPolicy(rb.Options.RateLimits, policy)
+	return rb
+}
+
+// WithRateLimitQuota adds a quota tier to this route, replacing an inherited
+// quota with the same name.
+func (rb *RouteBuilder) WithRateLimitQuota(quota routing.RateLimitQuota) *RouteBuilder {
+	rb.Options.RateLimitQuotas = routing.AddRateLimitQuota(rb.Options.RateLimitQuotas, quota)
+	return rb
+}
+
+// WithPriority sets the load shedding priority of this route.
+func (rb *RouteBuilder) WithPriority(priority routing.Priority) *RouteBuilder {
+	rb.Options.Priority = priority
+	return rb
+}
+
+// WithMaxInFlight bounds the requests this route serves at once, replacing
+// any group limit. A value <= 0 leaves the route under the group limit.
+func (rb *RouteBuilder) WithMaxInFlight(n int) *RouteBuilder {
+	if n > 0 {
+		rb.Options.InFlight = &routing.InFlightLimit{Max: n}
+	}
+	return rb
+}
+
+// WithTimeout bounds how long this route may take to commit a response,
+// overriding the group timeout. A value <= 0 leaves the group timeout.
+func (rb *RouteBuilder) WithTimeout(d time.Duration) *RouteBuilder {
+	if d > 0 {
+		rb.Options.Timeout = d
+	}
+	return rb
+}
+
+// WithSecurityHeaders overrides the headers the security headers middleware
+// sets on this route, on top of any group overrides. An empty value omits
+// the header.
+func (rb *RouteBuilder) WithSecurityHeaders(headers map[string]string) *RouteBuilder {
+	rb.Options.SecurityHeaders = routing.MergeSecurityHeaders(rb.Options.SecurityHeaders, headers)
+	return rb
+}
+
 // WithMaxBodyBytes sets the maximum request-body size accepted by Bind on this
 // single route, overriding the router-wide limit. A value <= 0 leaves the
 // router-wide default in effect.
@@ -137,6 +183,24 @@ func (rb *RouteBuilder) WithMaxBodyBytes(n int64) *RouteBuilder {
 	return rb
 }
 
+// WithoutCompression opts this route out of response compression.
+func (rb *RouteBuilder) WithoutCompression() *RouteBuilder {
+	rb.Options.DisableCompression = true
+	return rb
+}
+
+// WithoutCSRF exempts this route from the CSRF middleware.
+func (rb *RouteBuilder) WithoutCSRF() *RouteBuilder {
+	rb.Options.DisableCSRF = true
+	return rb
+}
+
+// WithoutHTTPSEnforcement lets this route answer plain HTTP requests.
+func (rb *RouteBuilder) WithoutHTTPSEnforcement() *RouteBuilder {
+	rb.Options.DisableHTTPSEnforcement = true
+	return rb
+}
+
 // WithOperationID sets/validates the OpenAPI OperationID.
 func (rb *RouteBuilder) WithOperationID(id string) *RouteBuilder {
 	if _, err := rb.WithOperationIDErr(id); err != nil {
@@ -554,6 +618,44 @@ func (rb *RouteBuilder) WithMultipartBodyErr(example any) (*RouteBuilder, error)
 	return rb.withBodyErr(example, common.MimeMultipartFormData)
 }
 
+// WithPatchBody describes a PATCH body accepted by BindPatch: a JSON Merge
+// Patch of example and a JSON Patch operation list.
+func (rb *RouteBuilder) WithPatchBody(example any) *RouteBuilder {
+	if _, err := rb.WithPatchBodyErr(example); err != nil {
+		return rb.handleValidation(err)
+	}
+	return rb
+}
+
+// WithPatchBodyErr describes a PATCH body without panicking.
+func (rb *RouteBuilder) WithPatchBodyErr(example any) (*RouteBuilder, error) {
+	if _, err := rb.withBodyErr(example, common.MimeMergePatchJSON); err != nil {
+		return rb, err
+	}
+	if rb.Options.RequestBody == nil {
+		rb.Options.RequestBody = &openapi.RequestBodyObject{Content: map[string]*openapi.MediaType{}, Required: true}
+	}
+	rb.Options.RequestBody.Content[common.MimeJSONPatchJSON] = &openapi.MediaType{Schema: jsonPatchSchema()}
+	return rb, nil
+}
+
+// jsonPatchSchema describes an RFC 6902 operation list.
+func jsonPatchSchema() *openapi.Schema {
+	return &openapi.Schema{
+		Type: "array",
+		Items: &openapi.Schema{
+			Type:     "object",
+			Required: []string{"op", "path"},
+			Properties: map[string]*openapi.Schema{
+				"op":    {Type: "string", Enum: []any{"add", "remove", "replace", "move", "copy", "test"}},
+				"path":  {Type: "string", Format: "json-pointer"},
+				"from":  {Type: "string", Format: "json-pointer"},
+				"value": {},
+			},
+		},
+	}
+}
+
 func (rb *RouteBuilder) withBody(example any, ctype string) *RouteBuilder {
 	if _, err := rb.withBodyErr(example, ctype); err != nil {
 		return rb.handleValidation(err)
@@ -570,6 +672,9 @@ func (rb *RouteBuilder) withBodyErr(example any, ctype string) (*RouteBuilder, e
 	if example == nil {
 		return rb, nil
 	}
+	if ctype == common.MimeMultipartFormData {
+		registerFileSchemas(reflect.TypeOf(example))
+	}
 	schema, err := QuickSchema(reflect.TypeOf(example))
 	if err != nil {
 		return rb, err
@@ -0,0 +1,133 @@
+package builder
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/binder"
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// WithBinding documents the parameters and request body declared by the
+// explicit source tags (`path`, `query`, `header`, `cookie`, `body`) on
+// model's struct fields.
+func (rb *RouteBuilder) WithBinding(model any) *RouteBuilder {
+	if _, err := rb.WithBindingErr(model); err != nil {
+		return rb.handleValidation(err)
+	}
+	return rb
+}
+
+// WithBindingErr documents tag-derived parameters and request body without
+// panicking on validation failures.
+func (rb *RouteBuilder) WithBindingErr(model any) (*RouteBuilder, error) {
+	if model == nil {
+		return rb, fmt.Errorf("binding model cannot be nil")
+	}
+	fields := binder.SourceFields(reflect.TypeOf(model))
+	if len(fields) == 0 {
+		return rb, fmt.Errorf("binding model %T declares no source tags", model)
+	}
+
+	var body *openapi.Schema
+	var bodyExample any
+	bodyRequired := false
+	bodyType := common.MimeJSON
+	for i := range fields {
+		field := &fields[i]
+		for _, src := range field.Sources {
+			if src.In == binder.SourceBody {
+				if src.Name == "" {
+					bodyExample = reflect.Zero(field.Type).Interface()
+					bodyRequired = bodyRequired || field.Required
+					continue
+				}
+				if body == nil {
+					body = &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{}}
+				}
+				if isFileType(field.Type) {
+					registerFileSchemas(field.Type)
+					bodyType = common.MimeMultipartFormData
+				}
+				schema, err := QuickSchema(field.Type)
+				if err != nil {
+					return rb, fmt.Errorf("field %s: %w", field.Name, err)
+				}
+				body.Properties[src.Name] = schema
+				if field.Required && !field.HasDefault {
+					body.Required = append(body.Required, src.Name)
+					bodyRequired = true
+				}
+				continue
+			}
+			if err := rb.addBindingParam(field, src); err != nil {
+				return rb, err
+			}
+		}
+	}
+
+	if bodyExample == nil && body == nil {
+		return rb, nil
+	}
+	method := rb.Options.Method
+	if method == http.MethodHead || method == http.MethodGet || method == http.MethodDelete {
+		return rb, fmt.Errorf("HTTP method %s does not support a request body", method)
+	}
+	if bodyExample != nil {
+		schema, err := QuickSchema(reflect.TypeOf(bodyExample))
+		if err != nil {
+			return rb, err
+		}
+		rb.Options.RequestBody = openapi.CloneRequestBodyObject(&openapi.RequestBodyObject{
+			Content:  map[string]*openapi.MediaType{common.MimeJSON: {Schema: schema, Example: bodyExample}},
+			Required: true,
+		})
+		return rb, nil
+	}
+	rb.Options.RequestBody = openapi.CloneRequestBodyObject(&openapi.RequestBodyObject{
+		Content:  map[string]*openapi.MediaType{bodyType: {Schema: body}},
+		Required: bodyRequired,
+	})
+	return rb, nil
+}
+
+func (rb *RouteBuilder) addBindingParam(field *binder.SourceField, src binder.SourceTag) error {
+	if src.Name == "" {
+		return fmt.Errorf("field %s: %s tag requires a name", field.Name, src.In)
+	}
+	for _, existing := range rb.Options.Parameters {
+		if existing != nil && existing.In == src.In && strings.EqualFold(existing.Name, src.Name) {
+			return nil
+		}
+	}
+
+	example := reflect.Zero(field.Type).Interface()
+	schema, err := QuickSchema(field.Type)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", field.Name, err)
+	}
+	if field.HasDefault {
+		schema.Default = field.Default
+		if field.Converter != nil {
+			if parsed, err := field.Converter([]string{field.Default}); err == nil && parsed != nil {
+				schema.Default = parsed
+			}
+		}
+	}
+
+	rb.Options.Parameters = append(rb.Options.Parameters, openapi.CloneParameterObject(&openapi.ParameterObject{
+		Name:        src.Name,
+		In:          src.In,
+		Description: field.Description,
+		Required:    src.In == binder.SourcePath || (field.Required && !field.HasDefault),
+		Schema:      schema,
+		Example:     example,
+		Converter:   binder.MakeConverter(field.Type, schema),
+	}))
+	rb.Options.ParamIndex = routing.BuildParamIndex(rb.Options.Parameters)
+	return nil
+}
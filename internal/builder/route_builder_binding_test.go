@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fgrzl/mux/internal/common"
+)
+
+type bindingWidgetBody struct {
+	Name string `json:"name"`
+}
+
+type bindingWidgetRequest struct {
+	ID     string            `path:"id"`
+	Page   int               `query:"page" default:"20" description:"page size"`
+	Tenant string            `header:"X-Tenant" required:"true"`
+	Sid    string            `cookie:"sid"`
+	Body   bindingWidgetBody `body:""`
+}
+
+func TestShouldDeriveParametersGivenSourceTaggedModel(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodPut, pathUsersWithID)
+
+	// Act
+	_, err := rb.WithBindingErr(bindingWidgetRequest{})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, rb.Options.Parameters, 4)
+	id := findParam(rb.Options.Parameters, "id", "path")
+	require.NotNil(t, id)
+	assert.True(t, id.Required)
+	page := findParam(rb.Options.Parameters, "page", "query")
+	require.NotNil(t, page)
+	assert.False(t, page.Required)
+	assert.Equal(t, 20, page.Schema.Default)
+	assert.Equal(t, "page size", page.Description)
+	tenant := findParam(rb.Options.Parameters, "X-Tenant", "header")
+	require.NotNil(t, tenant)
+	assert.True(t, tenant.Required)
+	assert.NotNil(t, findParam(rb.Options.Parameters, "sid", "cookie"))
+	require.NotNil(t, rb.Options.RequestBody)
+	assert.Contains(t, rb.Options.RequestBody.Content, common.MimeJSON)
+}
+
+func TestShouldRejectBindingGivenModelWithoutSourceTags(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodGet, pathUsers)
+
+	// Act
+	_, err := rb.WithBindingErr(bindingWidgetBody{})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestShouldRejectBindingBodyGivenGetRoute(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodGet, pathUsersWithID)
+
+	// Act
+	_, err := rb.WithBindingErr(bindingWidgetRequest{})
+
+	// Assert
+	assert.ErrorContains(t, err, "does not support a request body")
+}
@@ -0,0 +1,43 @@
+package builder
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fgrzl/mux/internal/common"
+)
+
+func TestShouldDocumentBothPatchMediaTypesGivenPatchBody(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodPatch, pathUsersWithID)
+
+	// Act
+	_, err := rb.WithPatchBodyErr(bindingWidgetBody{})
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, rb.Options.RequestBody)
+	merge := rb.Options.RequestBody.Content[common.MimeMergePatchJSON]
+	require.NotNil(t, merge)
+	assert.Equal(t, bindingWidgetBody{}, merge.Example)
+	patch := rb.Options.RequestBody.Content[common.MimeJSONPatchJSON]
+	require.NotNil(t, patch)
+	assert.Equal(t, "array", patch.Schema.Type)
+	assert.Equal(t, []string{"op", "path"}, patch.Schema.Items.Required)
+	assert.Contains(t, patch.Schema.Items.Properties["op"].Enum, "move")
+}
+
+func TestShouldRejectPatchBodyGivenGetRoute(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodGet, pathUsersWithID)
+
+	// Act
+	_, err := rb.WithPatchBodyErr(bindingWidgetBody{})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, rb.Options.RequestBody)
+}
@@ -0,0 +1,80 @@
+package builder
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/fgrzl/json/jsonschema"
+	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+var (
+	fileHeaderType    = reflect.TypeOf(routing.FileHeader{})
+	binarySchemaTypes sync.Map
+	binarySchemaMu    sync.Mutex
+)
+
+// WithUploadLimits sets the streaming upload limits used by Files,
+// MultipartReader, and multipart Bind on this route.
+func (rb *RouteBuilder) WithUploadLimits(limits routing.UploadLimits) *RouteBuilder {
+	rb.Options.Uploads = &limits
+	return rb
+}
+
+// isFileType reports whether t (or its slice/pointer element) holds uploaded
+// files, i.e. whether routing.FileHeader converts to it.
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && fileHeaderType.ConvertibleTo(t)
+}
+
+// registerFileSchemas documents every file-typed field reachable from t as
+// `type: string, format: binary`, both for inline schemas and for the
+// component schemas built by the OpenAPI generator.
+func registerFileSchemas(t reflect.Type) {
+	registerFileSchemasSeen(t, map[reflect.Type]bool{})
+}
+
+func registerFileSchemasSeen(t reflect.Type, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	if isFileType(t) {
+		RegisterBinarySchema(t)
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() || f.Anonymous {
+			registerFileSchemasSeen(f.Type, seen)
+		}
+	}
+}
+
+// RegisterBinarySchema documents t as binary file content. Registration is
+// idempotent and process-wide.
+func RegisterBinarySchema(t reflect.Type) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if _, ok := binarySchemaTypes.Load(t); ok {
+		return
+	}
+	binarySchemaMu.Lock()
+	defer binarySchemaMu.Unlock()
+	if _, ok := binarySchemaTypes.Load(t); ok {
+		return
+	}
+	RegisterSchema(t, &openapi.Schema{Type: "string", Format: "binary"})
+	jsonschema.RegisterSchema(t, map[string]any{"type": "string", "format": "binary"})
+	binarySchemaTypes.Store(t, struct{}{})
+}
@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/fgrzl/json/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+type uploadAvatarForm struct {
+	Title  string               `json:"title"`
+	Avatar *routing.FileHeader  `json:"avatar"`
+	Docs   []routing.FileHeader `json:"docs"`
+}
+
+func TestShouldDocumentFilesAsBinaryGivenMultipartBody(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodPost, pathUsers)
+
+	// Act
+	_, err := rb.WithMultipartBodyErr(struct {
+		Title  string              `json:"title"`
+		Avatar *routing.FileHeader `json:"avatar"`
+	}{})
+	component := jsonschema.GenerateSchema(reflect.TypeOf(uploadAvatarForm{}))
+
+	// Assert
+	require.NoError(t, err)
+	schema := rb.Options.RequestBody.Content[common.MimeMultipartFormData].Schema
+	assert.Equal(t, "string", schema.Properties["avatar"].Type)
+	assert.Equal(t, "binary", schema.Properties["avatar"].Format)
+	properties, ok := component["properties"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"type": "string", "format": "binary"}, properties["avatar"])
+}
+
+func TestShouldUseMultipartGivenBindingWithFileSource(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodPost, pathUsers)
+
+	// Act
+	_, err := rb.WithBindingErr(struct {
+		Title  string              `body:"title"`
+		Avatar *routing.FileHeader `body:"avatar" required:"true"`
+	}{})
+
+	// Assert
+	require.NoError(t, err)
+	media := rb.Options.RequestBody.Content[common.MimeMultipartFormData]
+	require.NotNil(t, media)
+	assert.Equal(t, "binary", media.Schema.Properties["avatar"].Format)
+	assert.Equal(t, []string{"avatar"}, media.Schema.Required)
+}
+
+func TestShouldSetUploadLimitsGivenRoute(t *testing.T) {
+	// Arrange
+	rb := DetachedRoute(http.MethodPost, pathUsers)
+
+	// Act
+	rb.WithUploadLimits(routing.UploadLimits{MaxFiles: 2, AllowedTypes: []string{"image/*"}})
+
+	// Assert
+	require.NotNil(t, rb.Options.Uploads)
+	assert.Equal(t, 2, rb.Options.Uploads.MaxFiles)
+}
@@ -28,42 +28,61 @@ const (
 	MimeOpenAPI           = "application/vnd.oai.openapi"
 	MimeYAML              = "application/x-yaml"
 	MimeProblemJSON       = "application/problem+json"
+	MimeMergePatchJSON    = "application/merge-patch+json"
+	MimeJSONPatchJSON     = "application/json-patch+json"
 )
 
 // Common HTTP header names
 const (
-	HeaderAccept                        = "Accept"
-	HeaderAcceptEncoding                = "Accept-Encoding"
-	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
-	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
-	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
-	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
-	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
-	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
-	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
-	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
-	HeaderAuthorization                 = "Authorization"
-	HeaderCacheControl                  = "Cache-Control"
-	HeaderContentDisposition            = "Content-Disposition"
-	HeaderContentEncoding               = "Content-Encoding"
-	HeaderContentLength                 = "Content-Length"
-	HeaderContentType                   = "Content-Type"
-	HeaderCookie                        = "Cookie"
-	HeaderETag                          = "ETag"
-	HeaderForwarded                     = "Forwarded" // RFC 7239
-	HeaderHost                          = "Host"
-	HeaderLocation                      = "Location"
-	HeaderOrigin                        = "Origin"
-	HeaderRetryAfter                    = "Retry-After"
-	HeaderSetCookie                     = "Set-Cookie"
-	HeaderTransferEncoding              = "Transfer-Encoding"
-	HeaderUserAgent                     = "User-Agent"
-	HeaderVary                          = "Vary"
-	HeaderXForwardedFor                 = "X-Forwarded-For"
-	HeaderXForwardedHost                = "X-Forwarded-Host"
-	HeaderXForwardedPort                = "X-Forwarded-Port"
-	HeaderXForwardedProto               = "X-Forwarded-Proto"
-	HeaderXRealIP                       = "X-Real-IP"
+	HeaderAccept                          = "Accept"
+	HeaderAcceptEncoding                  = "Accept-Encoding"
+	HeaderAccessControlAllowCredentials   = "Access-Control-Allow-Credentials"
+	HeaderAccessControlAllowHeaders       = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowMethods       = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowOrigin        = "Access-Control-Allow-Origin"
+	HeaderAccessControlExposeHeaders      = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge             = "Access-Control-Max-Age"
+	HeaderAccessControlRequestHeaders     = "Access-Control-Request-Headers"
+	HeaderAccessControlRequestMethod      = "Access-Control-Request-Method"
+	HeaderAuthorization                   = "Authorization"
+	HeaderCacheControl                    = "Cache-Control"
+	HeaderContentDisposition              = "Content-Disposition"
+	HeaderContentEncoding                 = "Content-Encoding"
+	HeaderContentLength                   = "Content-Length"
+	HeaderContentSecurityPolicy           = "Content-Security-Policy"
+	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+	HeaderContentType                     = "Content-Type"
+	HeaderCookie                          = "Cookie"
+	HeaderCrossOriginEmbedderPolicy       = "Cross-Origin-Embedder-Policy"
+	HeaderCrossOriginOpenerPolicy         = "Cross-Origin-Opener-Policy"
+	HeaderCrossOriginResourcePolicy       = "Cross-Origin-Resource-Policy"
+	HeaderETag                            = "ETag"
+	HeaderForwarded                       = "Forwarded" // RFC 7239
+	HeaderHost                            = "Host"
+	HeaderLocation                        = "Location"
+	HeaderOrigin                          = "Origin"
+	HeaderPermissionsPolicy               = "Permissions-Policy"
+	HeaderRateLimit                       = "RateLimit"        // draft-ietf-httpapi-ratelimit-headers
+	HeaderRateLimitPolicy                 = "RateLimit-Policy" // draft-ietf-httpapi-ratelimit-headers
+	HeaderReferer                         = "Referer"
+	HeaderReferrerPolicy                  = "Referrer-Policy"
+	HeaderReportingEndpoints              = "Reporting-Endpoints"
+	HeaderRetryAfter                      = "Retry-After"
+	HeaderSecFetchSite                    = "Sec-Fetch-Site"
+	HeaderSetCookie                       = "Set-Cookie"
+	HeaderStrictTransportSecurity         = "Strict-Transport-Security"
+	HeaderTransferEncoding                = "Transfer-Encoding"
+	HeaderUpgrade                         = "Upgrade"
+	HeaderUserAgent                       = "User-Agent"
+	HeaderVary                            = "Vary"
+	HeaderXContentTypeOptions             = "X-Content-Type-Options"
+	HeaderXForwardedFor                   = "X-Forwarded-For"
+	HeaderXForwardedHost                  = "X-Forwarded-Host"
+	HeaderXForwardedPort                  = "X-Forwarded-Port"
+	HeaderXForwardedProto                 = "X-Forwarded-Proto"
+	HeaderXFrameOptions                   = "X-Frame-Options"
+	HeaderXRealIP                         = "X-Real-IP"
+	HeaderXRequestID                      = "X-Request-ID"
 	// Project-specific common headers
 	HeaderXCorrelationID = "X-Correlation-Id"
 	HeaderXEcho          = "X-Echo"
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"net"
+	"strings"
+)
+
+// MatchPath reports whether path matches one of patterns. A pattern ending in
+// "*" matches every path with that prefix; any other pattern matches exactly.
+func MatchPath(path string, patterns []string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNet parses a CIDR range or a single IP address, which becomes a range
+// holding only that address. It returns nil when s is neither.
+func ParseNet(s string) *net.IPNet {
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldMatchPathGivenExactOrPrefixPattern(t *testing.T) {
+	// Arrange
+	patterns := []string{"/healthz", "/static/*"}
+
+	// Act & Assert
+	assert.True(t, MatchPath("/healthz", patterns))
+	assert.True(t, MatchPath("/static/app.js", patterns))
+	assert.False(t, MatchPath("/healthz/live", patterns))
+	assert.False(t, MatchPath("/api", patterns))
+}
+
+func TestShouldParseNetGivenAddressOrRange(t *testing.T) {
+	// Act
+	single := ParseNet("10.0.0.1")
+	ipv6 := ParseNet("::1")
+	cidr := ParseNet("192.168.0.0/16")
+	invalid := ParseNet("proxy")
+
+	// Assert
+	assert.Equal(t, "10.0.0.1/32", single.String())
+	assert.Equal(t, "::1/128", ipv6.String())
+	assert.Equal(t, "192.168.0.0/16", cidr.String())
+	assert.Nil(t, invalid)
+}
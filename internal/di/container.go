@@ -0,0 +1,394 @@
+// Package di implements the typed service container behind mux.Resolve:
+// singleton, scoped, and transient registrations built by factory functions
+// whose parameters declare their dependencies.
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Lifetime controls how long a resolved service instance is reused.
+type Lifetime int
+
+const (
+	// Singleton instances are created once per container.
+	Singleton Lifetime = iota
+	// Scoped instances are created once per scope, typically one request.
+	Scoped
+	// Transient instances are created on every resolution.
+	Transient
+)
+
+func (l Lifetime) String() string {
+	switch l {
+	case Singleton:
+		return "singleton"
+	case Scoped:
+		return "scoped"
+	case Transient:
+		return "transient"
+	default:
+		return fmt.Sprintf("Lifetime(%d)", int(l))
+	}
+}
+
+var (
+	// ErrNotRegistered is returned when resolving a type with no provider.
+	ErrNotRegistered = errors.New("service not registered")
+	// ErrMissingDependency is reported when a provider depends on a type
+	// that has no provider.
+	ErrMissingDependency = errors.New("missing service dependency")
+	// ErrDependencyCycle is reported when providers depend on each other.
+	ErrDependencyCycle = errors.New("service dependency cycle")
+	// ErrLifetimeMismatch is reported when a singleton depends on a scoped
+	// service, directly or through transient services, which would capture
+	// one request's instance for all requests.
+	ErrLifetimeMismatch = errors.New("service lifetime mismatch")
+	// ErrInvalidProvider is reported for factories with an unsupported
+	// signature or duplicate registrations.
+	ErrInvalidProvider = errors.New("invalid service provider")
+)
+
+// Committer is implemented by scoped services that complete a unit of work,
+// such as *sql.Tx. When a scope closes, Commit is called if the request
+// succeeded and Rollback otherwise.
+type Committer interface {
+	Commit() error
+	Rollback() error
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type provider struct {
+	typ      reflect.Type
+	lifetime Lifetime
+	factory  reflect.Value
+	deps     []reflect.Type
+
+	mu       sync.Mutex
+	instance reflect.Value
+}
+
+// Container holds service providers and singleton instances.
+type Container struct {
+	mu        sync.RWMutex
+	providers map[reflect.Type]*provider
+	order     []*provider
+}
+
+// New returns an empty container.
+func New() *Container {
+	return &Container{providers: map[reflect.Type]*provider{}}
+}
+
+// Len reports the number of registered providers.
+func (c *Container) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.order)
+}
+
+// Provide registers factory for the type it returns. factory must be a
+// function returning T or (T, error); its parameters are resolved from the
+// container, except context.Context, which receives the scope's context.
+func (c *Container) Provide(lifetime Lifetime, factory any) error {
+	fn := reflect.ValueOf(factory)
+	if !fn.IsValid() || fn.Kind() != reflect.Func || fn.IsNil() {
+		return fmt.Errorf("%w: factory must be a function, got %T", ErrInvalidProvider, factory)
+	}
+	if lifetime < Singleton || lifetime > Transient {
+		return fmt.Errorf("%w: unknown lifetime %d", ErrInvalidProvider, int(lifetime))
+	}
+	ft := fn.Type()
+	if ft.IsVariadic() {
+		return fmt.Errorf("%w: factory %s must not be variadic", ErrInvalidProvider, ft)
+	}
+	switch {
+	case ft.NumOut() == 1 && ft.Out(0) != errorType:
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+	default:
+		return fmt.Errorf("%w: factory %s must return T or (T, error)", ErrInvalidProvider, ft)
+	}
+	p := &provider{typ: ft.Out(0), lifetime: lifetime, factory: fn}
+	for i := 0; i < ft.NumIn(); i++ {
+		if ft.In(i) != contextType {
+			p.deps = append(p.deps, ft.In(i))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.providers[p.typ]; exists {
+		return fmt.Errorf("%w: %s is already registered", ErrInvalidProvider, p.typ)
+	}
+	c.providers[p.typ] = p
+	c.order = append(c.order, p)
+	return nil
+}
+
+// Validate checks that every dependency is registered, that providers form
+// no cycles, and that singletons do not depend on scoped services, directly
+// or through transient services.
+func (c *Container) Validate() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := map[reflect.Type]int{}
+	// captured memoizes the chain of dependencies from a provider to the first
+	// scoped service it reaches through transient providers, or nil.
+	captured := map[reflect.Type][]reflect.Type{}
+	var capture func(p *provider) []reflect.Type
+	capture = func(p *provider) []reflect.Type {
+		if chain, ok := captured[p.typ]; ok {
+			return chain
+		}
+		// Cycles are reported by visit; stop here while p is in progress.
+		captured[p.typ] = nil
+		for _, dep := range p.deps {
+			next, ok := c.providers[dep]
+			if !ok {
+				continue
+			}
+			var chain []reflect.Type
+			switch next.lifetime {
+			case Scoped:
+				chain = []reflect.Type{dep}
+			case Transient:
+				if rest := capture(next); rest != nil {
+					chain = append([]reflect.Type{dep}, rest...)
+				}
+			}
+			if chain != nil {
+				captured[p.typ] = chain
+				return chain
+			}
+		}
+		return nil
+	}
+	var visit func(p *provider, path []reflect.Type)
+	visit = func(p *provider, path []reflect.Type) {
+		switch state[p.typ] {
+		case done:
+			return
+		case visiting:
+			errs = append(errs, fmt.Errorf("%w: %s", ErrDependencyCycle, formatPath(append(path, p.typ), p.typ)))
+			return
+		}
+		state[p.typ] = visiting
+		path = append(path, p.typ)
+		if p.lifetime == Singleton {
+			if chain := capture(p); chain != nil {
+				errs = append(errs, lifetimeMismatch(p.typ, chain))
+			}
+		}
+		for _, dep := range p.deps {
+			next, ok := c.providers[dep]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%w: %s requires %s", ErrMissingDependency, p.typ, dep))
+				continue
+			}
+			visit(next, path)
+		}
+		state[p.typ] = done
+	}
+	for _, p := range c.order {
+		visit(p, nil)
+	}
+	return errors.Join(errs...)
+}
+
+// lifetimeMismatch reports singleton depending on the scoped service at the
+// end of chain, naming the transient services in between.
+func lifetimeMismatch(singleton reflect.Type, chain []reflect.Type) error {
+	scoped := chain[len(chain)-1]
+	if len(chain) == 1 {
+		return fmt.Errorf("%w: singleton %s depends on scoped %s", ErrLifetimeMismatch, singleton, scoped)
+	}
+	names := make([]string, len(chain)-1)
+	for i, t := range chain[:len(chain)-1] {
+		names[i] = t.String()
+	}
+	return fmt.Errorf("%w: singleton %s depends on scoped %s through transient %s", ErrLifetimeMismatch, singleton, scoped, strings.Join(names, " -> "))
+}
+
+// formatPath renders the cycle portion of path starting at its first
+// occurrence of start.
+func formatPath(path []reflect.Type, start reflect.Type) string {
+	names := []string{}
+	for i, t := range path {
+		if t == start {
+			for _, cyc := range path[i:] {
+				names = append(names, cyc.String())
+			}
+			break
+		}
+	}
+	return strings.Join(names, " -> ")
+}
+
+func (c *Container) lookup(t reflect.Type) (*provider, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.providers[t]
+	return p, ok
+}
+
+// Scope resolves scoped services once and disposes them when closed. A scope
+// is not safe for concurrent use.
+type Scope struct {
+	container *Container
+	ctx       context.Context
+	instances map[reflect.Type]reflect.Value
+	created   []any
+	closed    bool
+}
+
+// NewScope starts a scope whose factories receive ctx.
+func (c *Container) NewScope(ctx context.Context) *Scope {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Scope{container: c, ctx: ctx}
+}
+
+// Resolve returns the instance registered for t.
+func (s *Scope) Resolve(t reflect.Type) (any, error) {
+	if s == nil || s.container == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotRegistered, t)
+	}
+	if s.closed {
+		return nil, fmt.Errorf("resolve %s: scope is closed", t)
+	}
+	v, err := s.resolve(t, nil)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func (s *Scope) resolve(t reflect.Type, path []reflect.Type) (reflect.Value, error) {
+	p, ok := s.container.lookup(t)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%w: %s", ErrNotRegistered, t)
+	}
+	for _, seen := range path {
+		if seen == t {
+			return reflect.Value{}, fmt.Errorf("%w: %s", ErrDependencyCycle, formatPath(append(path, t), t))
+		}
+	}
+	path = append(path, t)
+
+	switch p.lifetime {
+	case Singleton:
+		// Failed constructions are not cached so a later request can retry.
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !p.instance.IsValid() {
+			root := &Scope{container: s.container, ctx: context.Background()}
+			v, err := root.build(p, path)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			p.instance = v
+		}
+		return p.instance, nil
+	case Scoped:
+		if v, ok := s.instances[t]; ok {
+			return v, nil
+		}
+		v, err := s.build(p, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if s.instances == nil {
+			s.instances = map[reflect.Type]reflect.Value{}
+		}
+		s.instances[t] = v
+		s.track(v)
+		return v, nil
+	default:
+		v, err := s.build(p, path)
+		if err == nil {
+			s.track(v)
+		}
+		return v, err
+	}
+}
+
+func (s *Scope) build(p *provider, path []reflect.Type) (reflect.Value, error) {
+	ft := p.factory.Type()
+	args := make([]reflect.Value, ft.NumIn())
+	for i := range args {
+		if ft.In(i) == contextType {
+			args[i] = reflect.ValueOf(&s.ctx).Elem()
+			continue
+		}
+		dep, err := s.resolve(ft.In(i), path)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("resolve %s: %w", p.typ, err)
+		}
+		args[i] = dep
+	}
+	out := p.factory.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("construct %s: %w", p.typ, out[1].Interface().(error))
+	}
+	return out[0], nil
+}
+
+func (s *Scope) track(v reflect.Value) {
+	if !v.IsValid() || !v.CanInterface() {
+		return
+	}
+	switch svc := v.Interface().(type) {
+	case Committer, io.Closer:
+		s.created = append(s.created, svc)
+	}
+}
+
+// Close completes the scope. Services implementing Committer are committed
+// when succeeded is true and rolled back otherwise; services implementing
+// io.Closer are then closed. Services are finished in reverse creation order.
+func (s *Scope) Close(succeeded bool) error {
+	if s == nil || s.closed {
+		return nil
+	}
+	s.closed = true
+	var errs []error
+	for i := len(s.created) - 1; i >= 0; i-- {
+		svc := s.created[i]
+		if tx, ok := svc.(Committer); ok {
+			if succeeded {
+				errs = append(errs, tx.Commit())
+			} else {
+				errs = append(errs, tx.Rollback())
+			}
+		}
+		if closer, ok := svc.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	s.created = nil
+	s.instances = nil
+	return errors.Join(errs...)
+}
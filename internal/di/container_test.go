@@ -0,0 +1,174 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type config struct{ dsn string }
+type database struct{ cfg *config }
+type unitOfWork struct {
+	db     *database
+	events *[]string
+	id     int
+}
+
+func (u *unitOfWork) Commit() error   { *u.events = append(*u.events, "commit"); return nil }
+func (u *unitOfWork) Rollback() error { *u.events = append(*u.events, "rollback"); return nil }
+func (u *unitOfWork) Close() error    { *u.events = append(*u.events, "close"); return nil }
+
+type requestID string
+
+func resolveAs[T any](t *testing.T, s *Scope) T {
+	t.Helper()
+	v, err := s.Resolve(reflect.TypeOf((*T)(nil)).Elem())
+	require.NoError(t, err)
+	return v.(T)
+}
+
+func newTestContainer(t *testing.T, events *[]string) *Container {
+	t.Helper()
+	c := New()
+	next := 0
+	require.NoError(t, c.Provide(Singleton, func() *config { return &config{dsn: "mem"} }))
+	require.NoError(t, c.Provide(Singleton, func(cfg *config) (*database, error) { return &database{cfg: cfg}, nil }))
+	require.NoError(t, c.Provide(Scoped, func(db *database) *unitOfWork {
+		next++
+		return &unitOfWork{db: db, events: events, id: next}
+	}))
+	require.NoError(t, c.Provide(Transient, func(ctx context.Context) requestID {
+		id, _ := ctx.Value(requestID("key")).(string)
+		return requestID(id)
+	}))
+	require.NoError(t, c.Validate())
+	return c
+}
+
+func TestShouldShareInstancesByLifetimeGivenScopes(t *testing.T) {
+	// Arrange
+	var events []string
+	c := newTestContainer(t, &events)
+	first := c.NewScope(context.Background())
+	second := c.NewScope(context.WithValue(context.Background(), requestID("key"), "req-2"))
+
+	// Act
+	a1, a2 := resolveAs[*unitOfWork](t, first), resolveAs[*unitOfWork](t, first)
+	b := resolveAs[*unitOfWork](t, second)
+	id := resolveAs[requestID](t, second)
+
+	// Assert
+	assert.Same(t, a1, a2, "scoped instances are reused within a scope")
+	assert.NotSame(t, a1, b, "scopes do not share scoped instances")
+	assert.Same(t, a1.db, b.db, "singletons are shared across scopes")
+	assert.Equal(t, "mem", a1.db.cfg.dsn)
+	assert.Equal(t, requestID("req-2"), id, "factories receive the scope context")
+}
+
+func TestShouldCommitOrRollbackGivenScopeOutcome(t *testing.T) {
+	// Arrange
+	var events []string
+	c := newTestContainer(t, &events)
+	ok := c.NewScope(context.Background())
+	failed := c.NewScope(context.Background())
+	resolveAs[*unitOfWork](t, ok)
+	resolveAs[*unitOfWork](t, failed)
+
+	// Act
+	require.NoError(t, ok.Close(true))
+	require.NoError(t, failed.Close(false))
+	require.NoError(t, ok.Close(true))
+
+	// Assert
+	assert.Equal(t, []string{"commit", "close", "rollback", "close"}, events)
+	_, err := ok.Resolve(reflect.TypeOf(&unitOfWork{}))
+	assert.Error(t, err)
+}
+
+func TestShouldReportGraphErrorsGivenInvalidRegistrations(t *testing.T) {
+	type a struct{}
+	type b struct{}
+	cases := []struct {
+		name    string
+		provide func(c *Container)
+		want    error
+	}{
+		{"missing dependency", func(c *Container) {
+			_ = c.Provide(Scoped, func(*config) *database { return nil })
+		}, ErrMissingDependency},
+		{"cycle", func(c *Container) {
+			_ = c.Provide(Scoped, func(*b) *a { return nil })
+			_ = c.Provide(Scoped, func(*a) *b { return nil })
+		}, ErrDependencyCycle},
+		{"captive scoped dependency", func(c *Container) {
+			_ = c.Provide(Scoped, func() *config { return nil })
+			_ = c.Provide(Singleton, func(*config) *database { return nil })
+		}, ErrLifetimeMismatch},
+		{"captive scoped dependency through transient", func(c *Container) {
+			_ = c.Provide(Scoped, func() *config { return nil })
+			_ = c.Provide(Transient, func(*config) *a { return nil })
+			_ = c.Provide(Transient, func(*a) *b { return nil })
+			_ = c.Provide(Singleton, func(*b) *database { return nil })
+		}, ErrLifetimeMismatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			c := New()
+			tc.provide(c)
+
+			// Act
+			err := c.Validate()
+
+			// Assert
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestShouldRejectProviderGivenInvalidFactory(t *testing.T) {
+	// Arrange
+	c := New()
+	require.NoError(t, c.Provide(Singleton, func() *config { return nil }))
+
+	// Act & Assert
+	assert.ErrorIs(t, c.Provide(Singleton, "not a func"), ErrInvalidProvider)
+	assert.ErrorIs(t, c.Provide(Singleton, func() {}), ErrInvalidProvider)
+	assert.ErrorIs(t, c.Provide(Singleton, func() error { return nil }), ErrInvalidProvider)
+	assert.ErrorIs(t, c.Provide(Singleton, func() *config { return nil }), ErrInvalidProvider, "duplicate")
+	assert.ErrorIs(t, c.Provide(Lifetime(7), func() *database { return nil }), ErrInvalidProvider)
+}
+
+func TestShouldRetrySingletonGivenFailedConstruction(t *testing.T) {
+	// Arrange
+	c := New()
+	calls := 0
+	require.NoError(t, c.Provide(Singleton, func() (*config, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("not ready")
+		}
+		return &config{dsn: "ok"}, nil
+	}))
+	scope := c.NewScope(context.Background())
+
+	// Act
+	_, firstErr := scope.Resolve(reflect.TypeOf(&config{}))
+	second := resolveAs[*config](t, scope)
+
+	// Assert
+	assert.ErrorContains(t, firstErr, "not ready")
+	assert.Equal(t, "ok", second.dsn)
+}
+
+func TestShouldReturnNotRegisteredGivenUnknownType(t *testing.T) {
+	// Act
+	_, err := New().NewScope(context.Background()).Resolve(reflect.TypeOf(config{}))
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotRegistered)
+}
@@ -0,0 +1,90 @@
+// Package filewatch reloads files used by middleware, such as IP filter
+// rules, export control policies, and access logs, when the process receives
+// a signal or a file's modification time changes.
+package filewatch
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// Options says when a Watcher reloads.
+type Options struct {
+	// Signals trigger a reload, such as SIGHUP. Go delivers a signal to every
+	// watcher registered for it.
+	Signals []os.Signal
+	// Path is checked every Interval and reloaded when its modification
+	// time changes. An empty Path or zero Interval disables polling.
+	Path     string
+	Interval time.Duration
+	// ModTime is the modification time of the version already loaded.
+	ModTime time.Time
+}
+
+// Watcher calls a reload function until Stop is called.
+type Watcher struct {
+	options  Options
+	reload   func()
+	signals  chan os.Signal
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Start calls reload on a goroutine whenever Options says to. It returns nil
+// when there is nothing to watch.
+func Start(o Options, reload func()) *Watcher {
+	if o.Path == "" {
+		o.Interval = 0
+	}
+	if len(o.Signals) == 0 && o.Interval <= 0 {
+		return nil
+	}
+	w := &Watcher{options: o, reload: reload, done: make(chan struct{})}
+	if len(o.Signals) > 0 {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, o.Signals...)
+	}
+	go w.run()
+	return w
+}
+
+// Stop stops reloading. It is safe to call more than once and on a nil
+// Watcher.
+func (w *Watcher) Stop() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() {
+		if w.signals != nil {
+			signal.Stop(w.signals)
+		}
+		close(w.done)
+	})
+}
+
+func (w *Watcher) run() {
+	var tick <-chan time.Time
+	if w.options.Interval > 0 {
+		ticker := time.NewTicker(w.options.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	// seen is the last modification time checked, so a file that fails to
+	// load is reported once per change rather than on every tick.
+	seen := w.options.ModTime
+	for {
+		select {
+		case <-w.signals:
+			w.reload()
+		case <-tick:
+			if info, err := os.Stat(w.options.Path); err == nil && !info.ModTime().Equal(seen) {
+				seen = info.ModTime()
+				w.reload()
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
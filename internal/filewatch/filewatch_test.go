@@ -0,0 +1,43 @@
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReloadOnceGivenModificationTimeChange(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0o600))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	var reloads atomic.Int32
+	w := Start(Options{Path: path, Interval: time.Millisecond, ModTime: info.ModTime()}, func() { reloads.Add(1) })
+	defer w.Stop()
+
+	// Act
+	time.Sleep(10 * time.Millisecond)
+	unchanged := reloads.Load()
+	require.NoError(t, os.Chtimes(path, time.Now(), info.ModTime().Add(time.Minute)))
+
+	// Assert
+	assert.Zero(t, unchanged)
+	assert.Eventually(t, func() bool { return reloads.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(1), reloads.Load())
+}
+
+func TestShouldReturnNilGivenNothingToWatch(t *testing.T) {
+	// Act
+	w := Start(Options{Interval: time.Second}, func() {})
+
+	// Assert
+	assert.Nil(t, w)
+	assert.NotPanics(t, w.Stop)
+}
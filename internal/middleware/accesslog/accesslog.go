@@ -0,0 +1,194 @@
+package accesslog
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// ---- Functional Options ----
+
+// AccessLogOptions configures the access log middleware behavior.
+type AccessLogOptions struct {
+	// Writer receives one line per request. Nil uses os.Stdout.
+	Writer io.Writer
+	// Format renders the lines. Nil uses CombinedLogFormat.
+	Format Formatter
+}
+
+// AccessLogOption is a function type for configuring access log options.
+type AccessLogOption func(*AccessLogOptions)
+
+// WithWriter sets where lines are written, such as a RotatingFile. Each line
+// is written with a single Write call.
+func WithWriter(w io.Writer) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		if w != nil {
+			o.Writer = w
+		}
+	}
+}
+
+// WithFormat sets the line format, such as a template from ParseTemplate,
+// W3C, or JSON.
+func WithFormat(f Formatter) AccessLogOption {
+	return func(o *AccessLogOptions) {
+		if f != nil {
+			o.Format = f
+		}
+	}
+}
+
+// ---- Middleware ----
+
+// UseAccessLog adds access log middleware to the router.
+func UseAccessLog(rtr *router.Router, opts ...AccessLogOption) {
+	rtr.Use(NewAccessLogMiddleware(opts...))
+}
+
+// NewAccessLogMiddleware constructs the access log middleware with optional
+// configuration.
+func NewAccessLogMiddleware(opts ...AccessLogOption) routing.Middleware {
+	o := AccessLogOptions{
+		Writer: os.Stdout,
+		Format: MustParseTemplate(CombinedLogFormat),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	m := &accessLogMiddleware{options: o}
+	if h, ok := o.Format.(headerFormatter); ok {
+		if s, ok := o.Writer.(headerSetter); ok {
+			s.SetHeader(h.Header)
+		} else {
+			m.header = h.Header
+		}
+	}
+	return m
+}
+
+// headerSetter is implemented by writers, such as RotatingFile, that start
+// new files and write the format's header at the top of each.
+type headerSetter interface {
+	SetHeader(fn func(now time.Time) []byte)
+}
+
+type accessLogMiddleware struct {
+	options AccessLogOptions
+
+	mu sync.Mutex
+	// header, when set, is written once before the first line.
+	header func(now time.Time) []byte
+	buf    []byte
+}
+
+// Invoke records the response and writes a line once next returns.
+func (m *accessLogMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	start := time.Now()
+	peer := c.Request().RemoteAddr
+	rec := &responseRecorder{ResponseWriter: c.Response(), start: start}
+	c.SetResponse(rec)
+
+	next(c)
+
+	r := c.Request()
+	if timeout := routing.TimeoutStatus(r.Context()); timeout != 0 {
+		rec.status = timeout
+	}
+	entry := Entry{
+		Time:            start,
+		Request:         r,
+		PeerAddr:        peer,
+		ClientIP:        hostOnly(r.RemoteAddr),
+		RequestID:       c.RequestID(),
+		Status:          rec.status,
+		Bytes:           rec.bytes,
+		Duration:        time.Since(start),
+		TimeToFirstByte: rec.ttfb,
+		ResponseHeader:  rec.Header(),
+	}
+	if entry.Status == 0 {
+		entry.Status = http.StatusOK
+	}
+	if user := c.User(); user != nil {
+		entry.User = user.Subject()
+	}
+	if options := c.Options(); options != nil {
+		entry.Route = options.Pattern
+	}
+	m.write(&entry)
+}
+
+// write renders and writes the entry. Write errors are dropped so a failing
+// log never fails a request.
+func (m *accessLogMiddleware) write(e *Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.header != nil {
+		_, _ = m.options.Writer.Write(m.header(time.Now()))
+		m.header = nil
+	}
+	m.buf = m.options.Format.Append(m.buf[:0], e)
+	_, _ = m.options.Writer.Write(m.buf)
+}
+
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// ---- Recorder ----
+
+// responseRecorder captures the status, body size, and time to first byte.
+type responseRecorder struct {
+	http.ResponseWriter
+	start  time.Time
+	status int
+	bytes  int64
+	ttfb   time.Duration
+}
+
+func (r *responseRecorder) firstByte() {
+	if r.ttfb == 0 {
+		r.ttfb = max(time.Since(r.start), 1)
+	}
+}
+
+// WriteHeader records the status and forwards it.
+func (r *responseRecorder) WriteHeader(status int) {
+	r.firstByte()
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body size and forwards p.
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.firstByte()
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying writer when it supports flushing.
+func (r *responseRecorder) Flush() {
+	r.firstByte()
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
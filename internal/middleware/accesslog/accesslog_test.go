@@ -0,0 +1,144 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/claims"
+	"github.com/fgrzl/mux/internal/middleware/forwardheaders"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoggedRouter(buf *bytes.Buffer, opts ...AccessLogOption) *router.Router {
+	rtr := router.NewRouter()
+	UseAccessLog(rtr, append([]AccessLogOption{WithWriter(buf)}, opts...)...)
+	rtr.GET("/orders/{id}", func(c routing.RouteContext) {
+		c.Response().Header().Set("Content-Type", "text/plain")
+		c.Response().WriteHeader(http.StatusCreated)
+		_, _ = c.Response().Write([]byte("hello"))
+	})
+	rtr.GET("/empty", func(c routing.RouteContext) { c.NoContent() })
+	return rtr
+}
+
+func serve(rtr *router.Router, target string, headers ...string) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "192.0.2.10:5555"
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rtr.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+type identifyMiddleware struct{}
+
+func (identifyMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	c.SetUser(claims.NewPrincipal(claims.NewClaimsSet("user-1")))
+	c.SetRequestID("req-1")
+	next(c)
+}
+
+func TestShouldWriteCombinedFormatGivenDefaults(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	rtr := newLoggedRouter(&buf)
+
+	// Act
+	serve(rtr, "/orders/7?x=1", "Referer", "https://example.com/", "User-Agent", `curl/8.0 "quoted"`)
+	serve(rtr, "/empty")
+
+	// Assert
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^192\.0\.2\.10 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /orders/7\?x=1 HTTP/1\.1" 201 5 "https://example\.com/" "curl/8\.0 \\"quoted\\""$`, lines[0])
+	assert.Contains(t, lines[1], `"GET /empty HTTP/1.1" 204 - "-" "-"`)
+}
+
+func TestShouldRenderCustomTemplateGivenDirectives(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	format, err := ParseTemplate(`%a %{c}a %m %U%q %R %>s %B %{Content-Type}o %{X-Tenant}i %u %L %D %^FB 100%%`)
+	require.NoError(t, err)
+	rtr := router.NewRouter()
+	UseAccessLog(rtr, WithWriter(&buf), WithFormat(format))
+	forwardheaders.UseForwardedHeaders(rtr, forwardheaders.WithTrustAll())
+	rtr.Use(identifyMiddleware{})
+	rtr.GET("/orders/{id}", func(c routing.RouteContext) {
+		time.Sleep(2 * time.Millisecond)
+		c.Response().Header().Set("Content-Type", "text/plain")
+		_, _ = c.Response().Write([]byte("hello"))
+	})
+
+	// Act
+	serve(rtr, "/orders/7?x=1", "X-Forwarded-For", "203.0.113.5", "X-Tenant", "acme\nforged")
+
+	// Assert
+	fields := strings.Fields(buf.String())
+	require.Len(t, fields, 14)
+	assert.Equal(t, []string{"203.0.113.5", "192.0.2.10", "GET", "/orders/7?x=1", "/orders/{id}", "200", "5", "text/plain", `acme\x0aforged`, "user-1", "req-1"}, fields[:11])
+	duration, err := strconv.Atoi(fields[11])
+	require.NoError(t, err)
+	ttfb, err := strconv.Atoi(fields[12])
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, duration, ttfb)
+	assert.GreaterOrEqual(t, ttfb, 2000)
+	assert.Equal(t, "100%", fields[13])
+}
+
+func TestShouldRejectInvalidTemplates(t *testing.T) {
+	for _, format := range []string{"%", "%Z", "%{Referer", "%{x}m"} {
+		// Act
+		_, err := ParseTemplate(format)
+
+		// Assert
+		assert.Error(t, err, format)
+	}
+}
+
+func TestShouldWriteW3CHeaderOnceGivenPlainWriter(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	rtr := newLoggedRouter(&buf, WithFormat(W3C("date", "c-ip", "cs-uri-stem", "sc-status", "sc-bytes", "cs(User-Agent)", "x-unknown")))
+
+	// Act
+	serve(rtr, "/orders/7", "User-Agent", "Mozilla/5.0 (X11)")
+	serve(rtr, "/empty")
+
+	// Assert
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "#Version: 1.0", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "#Date: "))
+	assert.Equal(t, "#Fields: date c-ip cs-uri-stem sc-status sc-bytes cs(User-Agent) x-unknown", lines[2])
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2} 192\.0\.2\.10 /orders/7 201 5 Mozilla/5\.0\+\(X11\) -$`, lines[3])
+	assert.Regexp(t, ` /empty 204 0 - -$`, lines[4])
+}
+
+func TestShouldWriteJSONLinesGivenJSONFormat(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	rtr := newLoggedRouter(&buf, WithFormat(JSON()))
+
+	// Act
+	serve(rtr, "/orders/7", "User-Agent", "test")
+
+	// Assert
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "192.0.2.10", entry["client_ip"])
+	assert.Equal(t, "/orders/7", entry["uri"])
+	assert.Equal(t, "/orders/{id}", entry["route"])
+	assert.Equal(t, float64(201), entry["status"])
+	assert.Equal(t, float64(5), entry["bytes"])
+	assert.Equal(t, "test", entry["user_agent"])
+	assert.Contains(t, entry, "ttfb_ms")
+}
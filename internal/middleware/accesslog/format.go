@@ -0,0 +1,435 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard Apache log templates.
+const (
+	// CommonLogFormat is the NCSA Common Log Format.
+	CommonLogFormat = `%h %l %u %t "%r" %>s %b`
+	// CombinedLogFormat is the Common Log Format followed by the referer and
+	// user agent.
+	CombinedLogFormat = CommonLogFormat + ` "%{Referer}i" "%{User-Agent}i"`
+)
+
+// DefaultW3CFields are the fields W3C logs without explicit fields.
+var DefaultW3CFields = []string{
+	"date", "time", "c-ip", "cs-username", "cs-method", "cs-uri-stem", "cs-uri-query",
+	"sc-status", "sc-bytes", "time-taken", "cs(User-Agent)", "cs(Referer)",
+}
+
+// Entry describes one completed request.
+type Entry struct {
+	// Time is when the request started.
+	Time    time.Time
+	Request *http.Request
+	// PeerAddr is the connection's remote address. It is the address before
+	// UseForwardedHeaders replaced it when the access log runs first.
+	PeerAddr string
+	// ClientIP is the client address, the upstream client when
+	// UseForwardedHeaders trusts the request's forwarding headers.
+	ClientIP  string
+	User      string
+	RequestID string
+	Route     string
+	Status    int
+	// Bytes is the number of response body bytes written.
+	Bytes           int64
+	Duration        time.Duration
+	TimeToFirstByte time.Duration
+	ResponseHeader  http.Header
+}
+
+// Formatter appends one log line for e, including the trailing newline, to
+// buf.
+type Formatter interface {
+	Append(buf []byte, e *Entry) []byte
+}
+
+// FormatterFunc adapts a function to Formatter.
+type FormatterFunc func(buf []byte, e *Entry) []byte
+
+// Append calls f.
+func (f FormatterFunc) Append(buf []byte, e *Entry) []byte {
+	return f(buf, e)
+}
+
+// headerFormatter is implemented by formats, such as W3C, whose files start
+// with directives.
+type headerFormatter interface {
+	Header(now time.Time) []byte
+}
+
+// ---- Templates ----
+
+type directive func(buf []byte, e *Entry) []byte
+
+type template []directive
+
+func (t template) Append(buf []byte, e *Entry) []byte {
+	for _, d := range t {
+		buf = d(buf, e)
+	}
+	return append(buf, '\n')
+}
+
+// ParseTemplate compiles an Apache mod_log_config style template. Supported
+// directives are:
+//
+//	%%          a literal percent sign
+//	%a, %h      client IP
+//	%{c}a       peer IP of the connection
+//	%l          always "-"
+//	%u          authenticated subject
+//	%t          request start time, [02/Jan/2006:15:04:05 -0700]
+//	%r          request line
+//	%m, %U, %q  method, path, and query string with its leading "?"
+//	%H          protocol
+//	%s, %>s     status
+//	%b, %B      response body bytes, "-" or 0 when empty
+//	%D, %T      duration in microseconds or whole seconds
+//	%{ms}T      duration in milliseconds
+//	%^FB        time to first byte in microseconds
+//	%{Name}i    request header
+//	%{Name}o    response header
+//	%L          request ID
+//	%R          route pattern
+//
+// Request-controlled values are escaped as Apache does, so lines cannot be
+// split or forged.
+func ParseTemplate(format string) (Formatter, error) {
+	var t template
+	literal := strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			s := literal.String()
+			t = append(t, func(buf []byte, _ *Entry) []byte { return append(buf, s...) })
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			literal.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i >= len(format) {
+			return nil, fmt.Errorf("accesslog: trailing %% in %q", format)
+		}
+		if format[i] == '%' {
+			literal.WriteByte('%')
+			continue
+		}
+		arg := ""
+		if format[i] == '{' {
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("accesslog: unterminated %%{ in %q", format)
+			}
+			arg = format[i+1 : i+end]
+			i += end + 1
+		}
+		name := ""
+		switch {
+		case i < len(format) && format[i] == '>':
+			name = ">"
+			i++
+		case strings.HasPrefix(format[i:], "^FB"):
+			name = "^F"
+			i += 2
+		}
+		if i >= len(format) {
+			return nil, fmt.Errorf("accesslog: incomplete directive in %q", format)
+		}
+		name += string(format[i])
+		d, err := compileDirective(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		flush()
+		t = append(t, d)
+	}
+	flush()
+	return t, nil
+}
+
+// MustParseTemplate is ParseTemplate that panics on an invalid template.
+func MustParseTemplate(format string) Formatter {
+	f, err := ParseTemplate(format)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func compileDirective(name, arg string) (directive, error) {
+	switch {
+	case name == "i" && arg != "":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.Request.Header.Get(arg)) }, nil
+	case name == "o" && arg != "":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.ResponseHeader.Get(arg)) }, nil
+	case name == "a" && arg == "c":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, hostOnly(e.PeerAddr)) }, nil
+	case name == "T" && arg == "ms":
+		return func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, e.Duration.Milliseconds(), 10) }, nil
+	case arg != "":
+		return nil, fmt.Errorf("accesslog: unsupported directive %%{%s}%s", arg, name)
+	}
+	switch name {
+	case "a", "h":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.ClientIP) }, nil
+	case "l":
+		return func(buf []byte, _ *Entry) []byte { return append(buf, '-') }, nil
+	case "u":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.User) }, nil
+	case "t":
+		return func(buf []byte, e *Entry) []byte {
+			buf = append(buf, '[')
+			buf = e.Time.AppendFormat(buf, "02/Jan/2006:15:04:05 -0700")
+			return append(buf, ']')
+		}, nil
+	case "r":
+		return func(buf []byte, e *Entry) []byte {
+			buf = appendEscaped(buf, e.Request.Method)
+			buf = append(buf, ' ')
+			buf = appendEscaped(buf, requestURI(e.Request))
+			buf = append(buf, ' ')
+			return appendEscaped(buf, e.Request.Proto)
+		}, nil
+	case "m":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.Request.Method) }, nil
+	case "U":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.Request.URL.Path) }, nil
+	case "q":
+		return func(buf []byte, e *Entry) []byte {
+			if e.Request.URL.RawQuery == "" {
+				return buf
+			}
+			return appendEscaped(append(buf, '?'), e.Request.URL.RawQuery)
+		}, nil
+	case "H":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.Request.Proto) }, nil
+	case "s", ">s":
+		return func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, int64(e.Status), 10) }, nil
+	case "b":
+		return func(buf []byte, e *Entry) []byte {
+			if e.Bytes == 0 {
+				return append(buf, '-')
+			}
+			return strconv.AppendInt(buf, e.Bytes, 10)
+		}, nil
+	case "B":
+		return func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, e.Bytes, 10) }, nil
+	case "D":
+		return func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, e.Duration.Microseconds(), 10) }, nil
+	case "T":
+		return func(buf []byte, e *Entry) []byte { return strconv.AppendInt(buf, int64(e.Duration/time.Second), 10) }, nil
+	case "^FB":
+		return func(buf []byte, e *Entry) []byte {
+			return strconv.AppendInt(buf, e.TimeToFirstByte.Microseconds(), 10)
+		}, nil
+	case "L":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.RequestID) }, nil
+	case "R":
+		return func(buf []byte, e *Entry) []byte { return appendEscaped(buf, e.Route) }, nil
+	}
+	return nil, fmt.Errorf("accesslog: unsupported directive %%%s", name)
+}
+
+// appendEscaped appends s, or "-" when empty, escaping quotes, backslashes,
+// and bytes outside printable ASCII as \xhh.
+func appendEscaped(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, '-')
+	}
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			buf = append(buf, '\\', ch)
+		case ch < 0x20 || ch >= 0x7f:
+			buf = append(buf, '\\', 'x', hex[ch>>4], hex[ch&0xf])
+		default:
+			buf = append(buf, ch)
+		}
+	}
+	return buf
+}
+
+func requestURI(r *http.Request) string {
+	if r.RequestURI != "" {
+		return r.RequestURI
+	}
+	return r.URL.RequestURI()
+}
+
+// ---- W3C ----
+
+type w3c struct {
+	fields []string
+}
+
+// W3C returns the W3C Extended Log File Format with the given fields, or
+// DefaultW3CFields when none are given. Supported fields are date, time,
+// c-ip, s-ip, cs-username, cs-method, cs-uri, cs-uri-stem, cs-uri-query,
+// cs-version, cs-host, sc-status, sc-bytes, time-taken, x-ttfb, x-request-id,
+// x-route, cs(Header), and sc(Header). Other fields are logged as "-".
+// Dates and times are UTC and time-taken and x-ttfb are in seconds.
+//
+// Writers that implement SetHeader, such as RotatingFile, start each file
+// with the #Version, #Date, and #Fields directives; other writers get them
+// once before the first entry.
+func W3C(fields ...string) Formatter {
+	if len(fields) == 0 {
+		fields = DefaultW3CFields
+	}
+	return &w3c{fields: fields}
+}
+
+func (f *w3c) Header(now time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("#Version: 1.0\n#Date: ")
+	b.WriteString(now.UTC().Format("2006-01-02 15:04:05"))
+	b.WriteString("\n#Fields: ")
+	b.WriteString(strings.Join(f.fields, " "))
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+func (f *w3c) Append(buf []byte, e *Entry) []byte {
+	for i, field := range f.fields {
+		if i > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = appendW3C(buf, f.value(field, e))
+	}
+	return append(buf, '\n')
+}
+
+func (f *w3c) value(field string, e *Entry) string {
+	r := e.Request
+	switch field {
+	case "date":
+		return e.Time.UTC().Format("2006-01-02")
+	case "time":
+		return e.Time.UTC().Format("15:04:05")
+	case "c-ip":
+		return e.ClientIP
+	case "s-ip":
+		if addr, ok := r.Context().Value(http.LocalAddrContextKey).(interface{ String() string }); ok {
+			return hostOnly(addr.String())
+		}
+		return ""
+	case "cs-username":
+		return e.User
+	case "cs-method":
+		return r.Method
+	case "cs-uri":
+		return requestURI(r)
+	case "cs-uri-stem":
+		return r.URL.Path
+	case "cs-uri-query":
+		return r.URL.RawQuery
+	case "cs-version":
+		return r.Proto
+	case "cs-host":
+		return r.Host
+	case "sc-status":
+		return strconv.Itoa(e.Status)
+	case "sc-bytes":
+		return strconv.FormatInt(e.Bytes, 10)
+	case "time-taken":
+		return strconv.FormatFloat(e.Duration.Seconds(), 'f', 3, 64)
+	case "x-ttfb":
+		return strconv.FormatFloat(e.TimeToFirstByte.Seconds(), 'f', 3, 64)
+	case "x-request-id":
+		return e.RequestID
+	case "x-route":
+		return e.Route
+	}
+	if name, ok := strings.CutPrefix(field, "cs("); ok {
+		return r.Header.Get(strings.TrimSuffix(name, ")"))
+	}
+	if name, ok := strings.CutPrefix(field, "sc("); ok {
+		return e.ResponseHeader.Get(strings.TrimSuffix(name, ")"))
+	}
+	return ""
+}
+
+// appendW3C appends s, or "-" when empty, replacing spaces with "+" as
+// W3C logs do and escaping other separators.
+func appendW3C(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, '-')
+	}
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == ' ':
+			buf = append(buf, '+')
+		case ch < 0x20 || ch >= 0x7f || ch == '"' || ch == '\\':
+			buf = append(buf, '\\', 'x', hex[ch>>4], hex[ch&0xf])
+		default:
+			buf = append(buf, ch)
+		}
+	}
+	return buf
+}
+
+// ---- JSON ----
+
+type jsonEntry struct {
+	Time      string  `json:"time"`
+	ClientIP  string  `json:"client_ip"`
+	User      string  `json:"user,omitempty"`
+	Method    string  `json:"method"`
+	URI       string  `json:"uri"`
+	Route     string  `json:"route,omitempty"`
+	Proto     string  `json:"proto"`
+	Status    int     `json:"status"`
+	Bytes     int64   `json:"bytes"`
+	Duration  float64 `json:"duration_ms"`
+	TTFB      float64 `json:"ttfb_ms"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+// JSON returns a format writing one JSON object per line with time,
+// client_ip, user, method, uri, route, proto, status, bytes, duration_ms,
+// ttfb_ms, referer, user_agent, and request_id members.
+func JSON() Formatter {
+	return FormatterFunc(func(buf []byte, e *Entry) []byte {
+		r := e.Request
+		data, err := json.Marshal(jsonEntry{
+			Time:      e.Time.UTC().Format(time.RFC3339Nano),
+			ClientIP:  e.ClientIP,
+			User:      e.User,
+			Method:    r.Method,
+			URI:       requestURI(r),
+			Route:     e.Route,
+			Proto:     r.Proto,
+			Status:    e.Status,
+			Bytes:     e.Bytes,
+			Duration:  milliseconds(e.Duration),
+			TTFB:      milliseconds(e.TimeToFirstByte),
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+			RequestID: e.RequestID,
+		})
+		if err != nil {
+			return buf
+		}
+		return append(append(buf, data...), '\n')
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,269 @@
+package accesslog
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fgrzl/mux/internal/filewatch"
+)
+
+// ---- Rotating File ----
+
+// RotateOptions configures a RotatingFile.
+type RotateOptions struct {
+	// MaxSize rotates the file before a write would grow it past this many
+	// bytes. Zero disables size rotation.
+	MaxSize int64
+	// Interval rotates the file when the write time crosses a UTC multiple
+	// of Interval, so 24*time.Hour rotates at midnight UTC. Zero disables
+	// time rotation.
+	Interval time.Duration
+	// MaxBackups is the number of rotated files kept. Zero keeps all.
+	MaxBackups int
+	// ReopenSignals reopen the file, for logrotate's move-and-signal
+	// rotation. The default is SIGHUP.
+	ReopenSignals []os.Signal
+}
+
+// RotateOption is a function type for configuring rotating file options.
+type RotateOption func(*RotateOptions)
+
+// WithMaxSize rotates the file before it grows past n bytes.
+func WithMaxSize(n int64) RotateOption {
+	return func(o *RotateOptions) {
+		o.MaxSize = max(n, 0)
+	}
+}
+
+// WithInterval rotates the file every d, aligned to UTC.
+func WithInterval(d time.Duration) RotateOption {
+	return func(o *RotateOptions) {
+		o.Interval = max(d, 0)
+	}
+}
+
+// WithMaxBackups keeps only the n most recent rotated files.
+func WithMaxBackups(n int) RotateOption {
+	return func(o *RotateOptions) {
+		o.MaxBackups = max(n, 0)
+	}
+}
+
+// WithReopenSignals sets the signals that reopen the file. Passing none
+// disables reopening on signals.
+func WithReopenSignals(signals ...os.Signal) RotateOption {
+	return func(o *RotateOptions) {
+		o.ReopenSignals = signals
+	}
+}
+
+// RotatingFile is an io.Writer appending to a file that it rotates by size
+// and time. Rotated files are renamed with a timestamp before the extension,
+// such as access-20240115T103000.000.log. It is safe for concurrent use.
+type RotatingFile struct {
+	path    string
+	options RotateOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	header func(now time.Time) []byte
+	now    func() time.Time
+
+	watcher *filewatch.Watcher
+}
+
+// NewRotatingFile opens path for appending, creating it and its directory if
+// needed. Close stops the signal handling and closes the file.
+func NewRotatingFile(path string, opts ...RotateOption) (*RotatingFile, error) {
+	o := RotateOptions{ReopenSignals: []os.Signal{syscall.SIGHUP}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	f := &RotatingFile{path: path, options: o, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.watcher = filewatch.Start(filewatch.Options{Signals: o.ReopenSignals}, f.reopenLogged)
+	return f, nil
+}
+
+func (f *RotatingFile) reopenLogged() {
+	if err := f.Reopen(); err != nil {
+		slog.Error("failed to reopen access log", "path", f.path, "error", err)
+	}
+}
+
+// SetHeader sets a function whose result is written at the top of every
+// empty file, such as the W3C directives.
+func (f *RotatingFile) SetHeader(fn func(now time.Time) []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.header = fn
+}
+
+// Write appends p, rotating the file first when p would exceed the size
+// limit or the rotation interval has passed.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := f.now()
+	if f.shouldRotate(now, len(p)) {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+	if f.size == 0 && f.header != nil {
+		n, err := f.file.Write(f.header(now))
+		f.size += int64(n)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) shouldRotate(now time.Time, n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.options.MaxSize > 0 && f.size+int64(n) > f.options.MaxSize {
+		return true
+	}
+	interval := f.options.Interval
+	return interval > 0 && !now.UTC().Truncate(interval).Equal(f.opened.UTC().Truncate(interval))
+}
+
+// Rotate renames the current file aside and starts a new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate(f.now())
+}
+
+// Reopen closes and reopens the file at its path without renaming it, for
+// when an external tool such as logrotate has moved it.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	err := f.file.Close()
+	return errors.Join(err, f.open())
+}
+
+// Close stops reopening on signals and closes the file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	f.watcher.Stop()
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		f.file = nil
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		f.file = nil
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = info.ModTime()
+	return nil
+}
+
+func (f *RotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	backup := base + "-" + now.UTC().Format(backupLayout) + ext
+	if err := os.Rename(f.path, backup); err != nil {
+		return errors.Join(err, f.open())
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.opened = now
+	f.prune(base, ext)
+	return nil
+}
+
+// backupLayout is the timestamp in rotated file names. It sorts
+// chronologically.
+const backupLayout = "20060102T150405.000"
+
+// prune removes the oldest rotated files beyond MaxBackups. Only names of the
+// form <base>-<backupLayout><ext> are counted, so sibling files such as
+// access-errors.log are left alone.
+func (f *RotatingFile) prune(base, ext string) {
+	if f.options.MaxBackups <= 0 {
+		return
+	}
+	dir, prefix := filepath.Dir(base), filepath.Base(base)+"-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && isBackup(name, prefix, ext) {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= f.options.MaxBackups {
+		return
+	}
+	slices.Sort(backups)
+	for _, name := range backups[:len(backups)-f.options.MaxBackups] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			slog.Warn("failed to remove rotated access log", "path", name, "error", err)
+		}
+	}
+}
+
+// isBackup reports whether name is prefix, a backupLayout timestamp, and ext.
+func isBackup(name, prefix, ext string) bool {
+	stamp, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return false
+	}
+	if stamp, ok = strings.CutSuffix(stamp, ext); !ok {
+		return false
+	}
+	_, err := time.Parse(backupLayout, stamp)
+	return err == nil
+}
@@ -0,0 +1,171 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	require.NoError(t, err)
+	return matches
+}
+
+func TestShouldRotateGivenMaxSize(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "access.log")
+	f, err := NewRotatingFile(path, WithMaxSize(10), WithMaxBackups(2), WithReopenSignals())
+	require.NoError(t, err)
+	defer f.Close()
+	clock := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	// Act
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// Assert
+	assert.Equal(t, "fourth\n", readFile(t, path))
+	rotated := backups(t, filepath.Dir(path))
+	require.Len(t, rotated, 2)
+	assert.Equal(t, "second\n", readFile(t, rotated[0]))
+	assert.Equal(t, "third\n", readFile(t, rotated[1]))
+	assert.Equal(t, "access-20240115T103003.000.log", filepath.Base(rotated[0]))
+}
+
+func TestShouldKeepSiblingFilesGivenPruning(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	siblings := []string{"access-errors.log", "access-2.log", "access-20240115.log"}
+	for _, name := range siblings {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("keep\n"), 0o600))
+	}
+	f, err := NewRotatingFile(path, WithMaxSize(10), WithMaxBackups(1), WithReopenSignals())
+	require.NoError(t, err)
+	defer f.Close()
+	clock := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	// Act
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// Assert
+	for _, name := range siblings {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	assert.NoFileExists(t, filepath.Join(dir, "access-20240115T103002.000.log"))
+	assert.FileExists(t, filepath.Join(dir, "access-20240115T103003.000.log"))
+}
+
+func TestShouldRotateGivenIntervalBoundary(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	clock := time.Date(2024, 1, 15, 23, 59, 0, 0, time.UTC)
+	f, err := NewRotatingFile(path, WithInterval(24*time.Hour), WithReopenSignals())
+	require.NoError(t, err)
+	defer f.Close()
+	f.now = func() time.Time { return clock }
+	f.opened = clock
+
+	// Act
+	_, _ = f.Write([]byte("before midnight\n"))
+	clock = clock.Add(30 * time.Second)
+	_, _ = f.Write([]byte("still before\n"))
+	clock = clock.Add(time.Minute)
+	_, _ = f.Write([]byte("after midnight\n"))
+
+	// Assert
+	assert.Equal(t, "after midnight\n", readFile(t, path))
+	rotated := backups(t, dir)
+	require.Len(t, rotated, 1)
+	assert.Equal(t, "before midnight\nstill before\n", readFile(t, rotated[0]))
+}
+
+func TestShouldWriteHeaderAtTopOfEachFileGivenSetHeader(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	f, err := NewRotatingFile(path, WithReopenSignals())
+	require.NoError(t, err)
+	defer f.Close()
+	f.SetHeader(func(time.Time) []byte { return []byte("#Fields: x\n") })
+
+	// Act
+	_, _ = f.Write([]byte("1\n"))
+	_, _ = f.Write([]byte("2\n"))
+	require.NoError(t, f.Rotate())
+	_, _ = f.Write([]byte("3\n"))
+
+	// Assert
+	assert.Equal(t, "#Fields: x\n3\n", readFile(t, path))
+	rotated := backups(t, dir)
+	require.Len(t, rotated, 1)
+	assert.Equal(t, "#Fields: x\n1\n2\n", readFile(t, rotated[0]))
+}
+
+func TestShouldReopenGivenSIGHUP(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	f, err := NewRotatingFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, _ = f.Write([]byte("old\n"))
+	require.NoError(t, os.Rename(path, path+".1"))
+
+	// Act
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+	_, _ = f.Write([]byte("new\n"))
+
+	// Assert
+	assert.Equal(t, "new\n", readFile(t, path))
+	assert.Equal(t, "old\n", readFile(t, path+".1"))
+}
+
+func TestShouldRejectWritesGivenClosedFile(t *testing.T) {
+	// Arrange
+	f, err := NewRotatingFile(filepath.Join(t.TempDir(), "access.log"), WithReopenSignals())
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Act
+	_, err = f.Write([]byte("late\n"))
+
+	// Assert
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.NoError(t, f.Close())
+}
@@ -279,6 +279,7 @@ func (m *authenticationMiddleware) Invoke(c routing.RouteContext, next router.Ha
 		if m.options != nil && m.options.EnableCSRF && isStateChangingMethod(c.Request().Method) {
 			if !m.validateCSRFToken(c) {
 				slog.WarnContext(c, "CSRF validation failed")
+				routing.ReportRejection(c, routing.RejectionForbidden)
 				c.JSON(http.StatusForbidden, map[string]string{
 					"error": "CSRF token validation failed",
 				})
@@ -302,6 +303,7 @@ func (m *authenticationMiddleware) Invoke(c routing.RouteContext, next router.Ha
 		return
 	}
 	slog.DebugContext(c, "authentication failed: no valid token found")
+	routing.ReportRejection(c, routing.RejectionUnauthenticated)
 	c.Unauthorized()
 }
 
@@ -316,6 +318,7 @@ func (m *authenticationMiddleware) rateLimitFailure(c routing.RouteContext) bool
 	}
 
 	slog.WarnContext(c, "authentication rate limited", "client", clientID)
+	routing.ReportRejection(c, routing.RejectionRateLimited)
 	c.JSON(http.StatusTooManyRequests, map[string]string{
 		"error": "too many authentication failures, please try again later",
 	})
@@ -98,15 +98,8 @@ type authorizationMiddleware struct {
 
 func (m *authorizationMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
 	// Be defensive: if middleware was constructed without options, treat as no-op config
-	if !m.checkRoles(c) {
-		c.Forbidden(forbiddenMessage)
-		return
-	}
-	if !m.checkScopes(c) {
-		c.Forbidden(forbiddenMessage)
-		return
-	}
-	if !m.checkPermission(c) {
+	if !m.checkRoles(c) || !m.checkScopes(c) || !m.checkPermission(c) {
+		routing.ReportRejection(c, routing.RejectionForbidden)
 		c.Forbidden(forbiddenMessage)
 		return
 	}
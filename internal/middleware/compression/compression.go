@@ -4,7 +4,9 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -15,15 +17,85 @@ import (
 
 // ---- Functional Options ----
 
+// DefaultMinSize is the smallest response body, in bytes, that UseCompression
+// compresses unless WithMinSize says otherwise.
+const DefaultMinSize = 1024
+
+// DefaultExcludedContentTypes lists media types that are already compressed
+// and are therefore never compressed again unless WithExcludedContentTypes
+// replaces the list. A "type/*" entry matches every subtype.
+var DefaultExcludedContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/vnd.rar",
+	"application/x-rar-compressed",
+}
+
 // CompressionOptions configures the compression middleware behavior.
-type CompressionOptions struct{}
+type CompressionOptions struct {
+	// MinSize is the smallest body, in bytes, worth compressing. Smaller
+	// bodies are buffered and written uncompressed.
+	MinSize int
+	// Level is a compress/flate level, from flate.HuffmanOnly to
+	// flate.BestCompression. Invalid levels use flate.DefaultCompression.
+	Level int
+	// ContentTypes, when non-empty, limits compression to these media types.
+	ContentTypes []string
+	// ExcludedContentTypes lists media types that are never compressed.
+	ExcludedContentTypes []string
+}
 
 // CompressionOption is a function type for configuring compression options.
 type CompressionOption func(*CompressionOptions)
 
+// WithMinSize sets the smallest response body, in bytes, that is compressed.
+func WithMinSize(n int) CompressionOption {
+	return func(o *CompressionOptions) {
+		o.MinSize = max(n, 0)
+	}
+}
+
+// WithLevel sets the gzip and deflate compression level.
+func WithLevel(level int) CompressionOption {
+	return func(o *CompressionOptions) {
+		o.Level = level
+	}
+}
+
+// WithContentTypes limits compression to the given media types, such as
+// "application/json" or "text/*".
+func WithContentTypes(types ...string) CompressionOption {
+	return func(o *CompressionOptions) {
+		o.ContentTypes = append([]string(nil), types...)
+	}
+}
+
+// WithExcludedContentTypes replaces DefaultExcludedContentTypes with types.
+func WithExcludedContentTypes(types ...string) CompressionOption {
+	return func(o *CompressionOptions) {
+		o.ExcludedContentTypes = append([]string(nil), types...)
+	}
+}
+
 // UseCompression adds response compression middleware that supports gzip and deflate encoding.
 func UseCompression(rtr *router.Router, opts ...CompressionOption) {
-	options := &CompressionOptions{}
+	options := &CompressionOptions{
+		MinSize:              DefaultMinSize,
+		Level:                flate.DefaultCompression,
+		ExcludedContentTypes: DefaultExcludedContentTypes,
+	}
 	for _, opt := range opts {
 		opt(options)
 	}
@@ -37,27 +109,10 @@ func UseCompression(rtr *router.Router, opts ...CompressionOption) {
 // compressionMiddleware handles response compression using gzip or deflate.
 type compressionMiddleware struct {
 	options *CompressionOptions
-}
-
-// compressionWriter wraps an http.ResponseWriter to provide compression.
-type compressionWriter struct {
-	w http.ResponseWriter
-	c io.WriteCloser
-}
-
-// Write implements io.Writer, writing compressed data to the underlying writer.
-func (cw *compressionWriter) Write(p []byte) (int, error) {
-	return cw.c.Write(p)
-}
-
-// Header returns the header map of the underlying ResponseWriter.
-func (cw *compressionWriter) Header() http.Header {
-	return cw.w.Header()
-}
 
-// WriteHeader sends an HTTP response header with the provided status code.
-func (cw *compressionWriter) WriteHeader(statusCode int) {
-	cw.w.WriteHeader(statusCode)
+	poolsOnce   sync.Once
+	gzipPool    sync.Pool
+	deflatePool sync.Pool
 }
 
 // package-level constants to avoid duplicate string literals
@@ -66,6 +121,7 @@ const (
 	websocketProto  = "websocket"
 	gzipEncoding    = "gzip"
 	deflateEncoding = "deflate"
+	noTransform     = "no-transform"
 )
 
 // applyEncodingHeaders centralizes the header updates applied when compressing
@@ -84,52 +140,301 @@ func (m *compressionMiddleware) Invoke(c routing.RouteContext, next router.Handl
 		next(c)
 		return
 	}
-
-	acceptEncoding := c.Request().Header.Get(common.HeaderAcceptEncoding)
-	if acceptEncoding == "" {
+	if opts := c.Options(); opts != nil && opts.DisableCompression {
 		next(c)
 		return
 	}
 
-	var compressor io.WriteCloser
-	res := c.Response()
-	hdr := res.Header()
-	switch {
-	case strings.Contains(acceptEncoding, gzipEncoding):
-		applyEncodingHeaders(hdr, gzipEncoding)
-		gw := gzipPool.Get().(*gzip.Writer)
-		gw.Reset(res)
-		compressor = gw
-	case strings.Contains(acceptEncoding, deflateEncoding):
-		applyEncodingHeaders(hdr, deflateEncoding)
-		dw := deflatePool.Get().(*flate.Writer)
-		dw.Reset(res)
-		compressor = dw
-	default:
+	encoding := negotiateEncoding(c.Request().Header.Get(common.HeaderAcceptEncoding))
+	if encoding == "" {
 		next(c)
 		return
 	}
 
-	c.SetResponse(&compressionWriter{
-		w: c.Response(),
-		c: compressor,
+	cw := &compressionWriter{
+		w:        c.Response(),
+		m:        m,
+		req:      c.Request(),
+		encoding: encoding,
+	}
+	c.SetResponse(cw)
+	defer cw.finish()
+	next(c)
+}
+
+// negotiateEncoding returns the supported encoding with the highest q-value
+// in an Accept-Encoding header, preferring gzip on ties, or "" when the
+// client accepts neither. Encodings with q=0 are refused, and "*" covers
+// encodings the header does not name.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+	qualities := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = gzipEncoding
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{gzipEncoding, deflateEncoding} {
+		q, ok := qualities[encoding]
+		if !ok {
+			if wildcard < 0 {
+				continue
+			}
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+func (m *compressionMiddleware) initPools() {
+	m.poolsOnce.Do(func() {
+		level := flate.DefaultCompression
+		if m.options != nil && m.options.Level >= flate.HuffmanOnly && m.options.Level <= flate.BestCompression {
+			level = m.options.Level
+		}
+		m.gzipPool.New = func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}
+		m.deflatePool.New = func() any {
+			w, _ := flate.NewWriter(io.Discard, level)
+			return w
+		}
 	})
-	defer func() {
-		// Always close to flush
-		_ = compressor.Close()
-		switch z := compressor.(type) {
-		case *gzip.Writer:
-			gzipPool.Put(z)
-		case *flate.Writer:
-			deflatePool.Put(z)
+}
+
+// compressor returns a pooled writer for encoding that writes to w.
+func (m *compressionMiddleware) compressor(encoding string, w io.Writer) io.WriteCloser {
+	m.initPools()
+	if encoding == deflateEncoding {
+		dw := m.deflatePool.Get().(*flate.Writer)
+		dw.Reset(w)
+		return dw
+	}
+	gw := m.gzipPool.Get().(*gzip.Writer)
+	gw.Reset(w)
+	return gw
+}
+
+// release closes compressor and returns it to its pool.
+func (m *compressionMiddleware) release(compressor io.WriteCloser) {
+	_ = compressor.Close()
+	switch z := compressor.(type) {
+	case *gzip.Writer:
+		m.gzipPool.Put(z)
+	case *flate.Writer:
+		m.deflatePool.Put(z)
+	}
+}
+
+// shouldCompress reports whether a response with the given status, headers,
+// and leading body bytes may be compressed. It sniffs and sets Content-Type
+// when the handler left it empty, because net/http would otherwise sniff the
+// compressed bytes.
+func (m *compressionMiddleware) shouldCompress(req *http.Request, status int, h http.Header, body []byte) bool {
+	switch {
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusPartialContent, status == http.StatusNotModified:
+		return false
+	case req != nil && req.Method == http.MethodHead:
+		return false
+	case h.Get(common.HeaderContentEncoding) != "":
+		return false
+	case strings.Contains(strings.ToLower(h.Get(common.HeaderCacheControl)), noTransform):
+		return false
+	}
+
+	contentType := h.Get(common.HeaderContentType)
+	if contentType == "" {
+		if len(body) == 0 {
+			return false
 		}
-	}()
-	next(c)
+		contentType = http.DetectContentType(body)
+		h.Set(common.HeaderContentType, contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if m.options == nil {
+		return true
+	}
+	if len(m.options.ContentTypes) > 0 && !matchesMediaType(mediaType, m.options.ContentTypes) {
+		return false
+	}
+	return !matchesMediaType(mediaType, m.options.ExcludedContentTypes)
+}
+
+// matchesMediaType reports whether mediaType equals a pattern or falls under
+// a "type/*" pattern.
+func matchesMediaType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if pattern == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// ---- Writer ----
+
+// compressionWriter wraps an http.ResponseWriter to provide compression. It
+// buffers the body until MinSize bytes arrive, a Flush, or the handler
+// returns, then either starts the compressor c or writes through to w.
+type compressionWriter struct {
+	w http.ResponseWriter
+	c io.WriteCloser
+
+	m        *compressionMiddleware
+	req      *http.Request
+	encoding string
+	status   int
+	buf      []byte
+	decided  bool
+}
+
+// Write implements io.Writer, writing compressed data to the underlying writer.
+func (cw *compressionWriter) Write(p []byte) (int, error) {
+	if cw.c != nil {
+		return cw.c.Write(p)
+	}
+	if cw.decided {
+		return cw.w.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize() {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Header returns the header map of the underlying ResponseWriter.
+func (cw *compressionWriter) Header() http.Header {
+	return cw.w.Header()
 }
 
-// Pools for gzip and deflate writers to reduce per-request allocations.
-var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
-var deflatePool = sync.Pool{New: func() any {
-	w, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
-	return w
-}}
+// WriteHeader sends an HTTP response header with the provided status code.
+// Until the compression decision is made, the status is held back so the
+// encoding headers can still be set.
+func (cw *compressionWriter) WriteHeader(statusCode int) {
+	if cw.c != nil || cw.decided || (statusCode >= 100 && statusCode < 200) {
+		cw.w.WriteHeader(statusCode)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = statusCode
+	}
+}
+
+// Flush starts the response, compressing it if it is eligible regardless of
+// its size so far, and flushes both the compressor and the underlying writer.
+func (cw *compressionWriter) Flush() {
+	_ = cw.FlushError()
+}
+
+// FlushError is Flush for http.ResponseController, reporting any error.
+func (cw *compressionWriter) FlushError() error {
+	if !cw.decided && cw.c == nil {
+		if err := cw.decide(true); err != nil {
+			return err
+		}
+	}
+	if f, ok := cw.c.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.w).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (cw *compressionWriter) Unwrap() http.ResponseWriter {
+	return cw.w
+}
+
+func (cw *compressionWriter) minSize() int {
+	if cw.m == nil || cw.m.options == nil {
+		return 0
+	}
+	return cw.m.options.MinSize
+}
+
+// decide chooses between compressing and writing through, sends the held
+// status, and drains the buffer. eligible is false when the body turned out
+// to be smaller than the threshold.
+func (cw *compressionWriter) decide(eligible bool) error {
+	cw.decided = true
+	status := cw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if eligible && cw.m != nil && cw.m.shouldCompress(cw.req, status, cw.w.Header(), cw.buf) {
+		applyEncodingHeaders(cw.w.Header(), cw.encoding)
+		cw.c = cw.m.compressor(cw.encoding, cw.w)
+	}
+	if cw.status != 0 || len(cw.buf) > 0 || cw.c != nil {
+		cw.w.WriteHeader(status)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.c != nil {
+		_, err = cw.c.Write(buf)
+	} else {
+		_, err = cw.w.Write(buf)
+	}
+	return err
+}
+
+// finish completes the response after the handler returns.
+func (cw *compressionWriter) finish() {
+	if !cw.decided && cw.c == nil {
+		_ = cw.decide(len(cw.buf) > 0 && len(cw.buf) >= cw.minSize())
+	}
+	if cw.c != nil && cw.m != nil {
+		cw.m.release(cw.c)
+		cw.c = nil
+	}
+}
@@ -1,12 +1,14 @@
 package compression
 
 import (
+	"bytes"
 	"context"
 
 	"compress/gzip"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/fgrzl/mux/internal/common"
@@ -135,7 +137,7 @@ func TestShouldAddCompressionMiddlewareToRouter(t *testing.T) {
 
 	// To verify it was added, register a handler that writes a response and make a request
 	rtr.GET("/test", func(c routing.RouteContext) {
-		_, _ = c.Response().Write([]byte("hello"))
+		_, _ = c.Response().Write([]byte(strings.Repeat("hello ", DefaultMinSize)))
 	})
 
 	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/test", nil)
@@ -175,3 +177,233 @@ func TestCompressionWriterShouldImplementResponseWriter(t *testing.T) {
 
 	_ = compressor.Close()
 }
+
+func serveCompressed(t *testing.T, acceptEncoding string, handler routing.HandlerFunc, opts ...CompressionOption) *httptest.ResponseRecorder {
+	t.Helper()
+	rtr := router.NewRouter()
+	UseCompression(rtr, opts...)
+	rtr.GET("/test", handler)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/test", nil)
+	if acceptEncoding != "" {
+		req.Header.Set(common.HeaderAcceptEncoding, acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func writeText(body string) routing.HandlerFunc {
+	return func(c routing.RouteContext) {
+		c.Response().Header().Set(common.HeaderContentType, "text/plain; charset=utf-8")
+		_, _ = c.Response().Write([]byte(body))
+	}
+}
+
+func TestShouldNegotiateEncodingGivenQValues(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"gzip;q=0, deflate", "deflate"},
+		{"gzip;q=0.5, deflate;q=0.8", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"GZIP ; Q=1", "gzip"},
+		{"x-gzip", "gzip"},
+		{"*", "gzip"},
+		{"*;q=0", ""},
+		{"gzip;q=0, *", "deflate"},
+		{"br, identity", ""},
+		{"gzip;q=abc", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.header, func(t *testing.T) {
+			// Act
+			got := negotiateEncoding(tc.header)
+
+			// Assert
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestShouldSkipCompressionGivenBodyBelowMinSize(t *testing.T) {
+	// Arrange
+	body := strings.Repeat("a", 100)
+
+	// Act
+	rec := serveCompressed(t, "gzip", writeText(body), WithMinSize(101))
+
+	// Assert
+	assert.Empty(t, rec.Header().Get(common.HeaderContentEncoding))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestShouldCompressGivenBodySpanningWritesReachesMinSize(t *testing.T) {
+	// Arrange
+	handler := func(c routing.RouteContext) {
+		c.Response().Header().Set(common.HeaderContentType, "application/json")
+		c.Response().WriteHeader(http.StatusCreated)
+		for range 10 {
+			_, _ = c.Response().Write([]byte(strings.Repeat("b", 20)))
+		}
+	}
+
+	// Act
+	rec := serveCompressed(t, "gzip", handler, WithMinSize(100))
+
+	// Assert
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get(common.HeaderContentEncoding))
+	assert.Equal(t, common.HeaderAcceptEncoding, rec.Header().Get(common.HeaderVary))
+	assert.Equal(t, strings.Repeat("b", 200), gunzip(t, rec.Body.Bytes()))
+}
+
+func TestShouldSkipCompressionGivenExcludedOrUnlistedContentType(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		opts        []CompressionOption
+		compressed  bool
+	}{
+		{"default excludes images", "image/png", nil, false},
+		{"default excludes video wildcard", "video/mp4", nil, false},
+		{"default allows svg", "image/svg+xml", nil, true},
+		{"allow list matches wildcard", "text/html; charset=utf-8", []CompressionOption{WithContentTypes("text/*")}, true},
+		{"allow list rejects others", "application/json", []CompressionOption{WithContentTypes("text/*")}, false},
+		{"custom deny list", "application/json", []CompressionOption{WithExcludedContentTypes("application/json")}, false},
+		{"custom deny list replaces default", "image/png", []CompressionOption{WithExcludedContentTypes("application/json")}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			handler := func(c routing.RouteContext) {
+				c.Response().Header().Set(common.HeaderContentType, tc.contentType)
+				_, _ = c.Response().Write([]byte(strings.Repeat("c", 2*DefaultMinSize)))
+			}
+
+			// Act
+			rec := serveCompressed(t, "gzip", handler, tc.opts...)
+
+			// Assert
+			assert.Equal(t, tc.compressed, rec.Header().Get(common.HeaderContentEncoding) == "gzip")
+		})
+	}
+}
+
+func TestShouldSniffContentTypeGivenCompressedResponseWithoutOne(t *testing.T) {
+	// Arrange
+	body := "<html><body>" + strings.Repeat("d", 2*DefaultMinSize) + "</body></html>"
+	handler := func(c routing.RouteContext) {
+		_, _ = c.Response().Write([]byte(body))
+	}
+
+	// Act
+	rec := serveCompressed(t, "gzip", handler)
+
+	// Assert
+	assert.Equal(t, "gzip", rec.Header().Get(common.HeaderContentEncoding))
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get(common.HeaderContentType))
+	assert.Equal(t, body, gunzip(t, rec.Body.Bytes()))
+}
+
+func TestShouldNotCompressGivenContentEncodingAlreadySet(t *testing.T) {
+	// Arrange
+	body := strings.Repeat("e", 2*DefaultMinSize)
+	handler := func(c routing.RouteContext) {
+		c.Response().Header().Set(common.HeaderContentEncoding, "br")
+		c.Response().Header().Set(common.HeaderContentType, "text/plain")
+		_, _ = c.Response().Write([]byte(body))
+	}
+
+	// Act
+	rec := serveCompressed(t, "gzip", handler)
+
+	// Assert
+	assert.Equal(t, "br", rec.Header().Get(common.HeaderContentEncoding))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestShouldNotCompressGivenRouteOptOut(t *testing.T) {
+	// Arrange
+	body := strings.Repeat("f", 2*DefaultMinSize)
+	rtr := router.NewRouter()
+	UseCompression(rtr)
+	rtr.GET("/raw", writeText(body)).WithoutCompression()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/raw", nil)
+	req.Header.Set(common.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+
+	// Act
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Empty(t, rec.Header().Get(common.HeaderContentEncoding))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestShouldFlushCompressedChunksGivenResponseController(t *testing.T) {
+	// Arrange
+	var flushedBeforeReturn int
+	var rec *httptest.ResponseRecorder
+	handler := func(c routing.RouteContext) {
+		c.Response().Header().Set(common.HeaderContentType, "text/event-stream")
+		_, _ = c.Response().Write([]byte("data: one\n\n"))
+		require.NoError(t, http.NewResponseController(c.Response()).Flush())
+		flushedBeforeReturn = rec.Body.Len()
+		_, _ = c.Response().Write([]byte("data: two\n\n"))
+	}
+	rtr := router.NewRouter()
+	UseCompression(rtr)
+	rtr.GET("/events", handler)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/events", nil)
+	req.Header.Set(common.HeaderAcceptEncoding, "gzip")
+	rec = httptest.NewRecorder()
+
+	// Act
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	assert.True(t, rec.Flushed)
+	assert.Positive(t, flushedBeforeReturn, "flush must reach the client before the handler returns")
+	assert.Equal(t, "gzip", rec.Header().Get(common.HeaderContentEncoding))
+	assert.Equal(t, "data: one\n\ndata: two\n\n", gunzip(t, rec.Body.Bytes()))
+}
+
+func TestShouldUnwrapToUnderlyingWriter(t *testing.T) {
+	// Arrange
+	recorder := httptest.NewRecorder()
+	writer := &compressionWriter{w: recorder}
+
+	// Act
+	unwrapped := writer.Unwrap()
+
+	// Assert
+	assert.Same(t, recorder, unwrapped)
+}
+
+func TestShouldApplyCompressionLevel(t *testing.T) {
+	// Arrange
+	body := strings.Repeat("level test ", 500)
+
+	// Act
+	stored := serveCompressed(t, "gzip", writeText(body), WithLevel(gzip.NoCompression))
+	best := serveCompressed(t, "gzip", writeText(body), WithLevel(gzip.BestCompression))
+
+	// Assert
+	assert.Greater(t, stored.Body.Len(), len(body))
+	assert.Less(t, best.Body.Len(), len(body)/10)
+	assert.Equal(t, body, gunzip(t, stored.Body.Bytes()))
+	assert.Equal(t, body, gunzip(t, best.Body.Bytes()))
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer func() { _ = reader.Close() }()
+	out, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(out)
+}
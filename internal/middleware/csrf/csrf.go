@@ -0,0 +1,479 @@
+package csrf
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// Defaults for where tokens are carried.
+const (
+	DefaultCookieName = "csrf_token"
+	DefaultHeaderName = "X-CSRF-Token" //nolint:gosec // G101: HTTP header name, not a secret
+	DefaultFieldName  = "csrf_token"
+)
+
+// Mode selects how requests prove they are not forged.
+type Mode int
+
+const (
+	// ModeDoubleSubmit compares a signed token in a cookie with the token
+	// sent in the header or form field.
+	ModeDoubleSubmit Mode = iota
+	// ModeSynchronizer compares the token sent in the header or form field
+	// with the token stored server-side for the session.
+	ModeSynchronizer
+	// ModeOrigin relies on the Fetch Metadata, Origin, and Referer checks
+	// alone, without tokens.
+	ModeOrigin
+)
+
+const tokenBytes = 32
+
+// ---- Functional Options ----
+
+// CSRFOptions configures the CSRF middleware.
+type CSRFOptions struct {
+	// Mode selects the token scheme. The default is ModeDoubleSubmit.
+	Mode Mode
+	// Secret signs double-submit tokens. Without one a random key is
+	// generated at startup, so tokens do not survive restarts or work
+	// across instances.
+	Secret []byte
+	// SessionID identifies the session tokens are bound to. It is required
+	// by ModeSynchronizer and optional for ModeDoubleSubmit.
+	SessionID func(c routing.RouteContext) string
+	// Store holds synchronizer tokens.
+	Store TokenStore
+	// TrustedOrigins are other origins, such as "https://app.example.com",
+	// allowed to send unsafe requests. The request's own origin is always
+	// allowed; its scheme is https only when r.TLS is set, which
+	// UseForwardedHeaders does for requests from trusted TLS-terminating
+	// proxies.
+	TrustedOrigins []string
+	// CookieName, HeaderName, and FieldName locate the token.
+	CookieName string
+	HeaderName string
+	FieldName  string
+	// Skip exempts requests for which any function reports true.
+	Skip []func(c routing.RouteContext) bool
+}
+
+// CSRFOption is a function type for configuring CSRF options.
+type CSRFOption func(*CSRFOptions)
+
+// WithSecret signs double-submit tokens with key. Share it across instances
+// behind one load balancer.
+func WithSecret(key []byte) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Secret = key
+	}
+}
+
+// WithSessionID binds tokens to the session fn returns, such as the
+// principal's subject, so a token issued to one session is rejected in
+// another.
+func WithSessionID(fn func(c routing.RouteContext) string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.SessionID = fn
+	}
+}
+
+// WithSynchronizerTokens stores one token per session in store instead of a
+// cookie. It requires WithSessionID.
+func WithSynchronizerTokens(store TokenStore) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Mode = ModeSynchronizer
+		o.Store = store
+	}
+}
+
+// WithOriginCheckOnly disables tokens and relies on the Fetch Metadata,
+// Origin, and Referer checks alone.
+func WithOriginCheckOnly() CSRFOption {
+	return func(o *CSRFOptions) {
+		o.Mode = ModeOrigin
+	}
+}
+
+// WithTrustedOrigins allows unsafe requests from other origins, given as
+// scheme://host[:port].
+func WithTrustedOrigins(origins ...string) CSRFOption {
+	return func(o *CSRFOptions) {
+		for _, origin := range origins {
+			o.TrustedOrigins = append(o.TrustedOrigins, strings.ToLower(strings.TrimRight(origin, "/")))
+		}
+	}
+}
+
+// WithCookieName sets the double-submit cookie name.
+func WithCookieName(name string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.CookieName = name
+	}
+}
+
+// WithHeaderName sets the request header carrying the token.
+func WithHeaderName(name string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.HeaderName = name
+	}
+}
+
+// WithFieldName sets the form field carrying the token.
+func WithFieldName(name string) CSRFOption {
+	return func(o *CSRFOptions) {
+		o.FieldName = name
+	}
+}
+
+// WithSkip exempts requests for which fn reports true.
+func WithSkip(fn func(c routing.RouteContext) bool) CSRFOption {
+	return func(o *CSRFOptions) {
+		if fn != nil {
+			o.Skip = append(o.Skip, fn)
+		}
+	}
+}
+
+// WithExemptPaths exempts requests to the given paths, such as webhooks. A
+// path ending in "*" matches every path with that prefix.
+func WithExemptPaths(paths ...string) CSRFOption {
+	return WithSkip(func(c routing.RouteContext) bool {
+		return common.MatchPath(c.Request().URL.Path, paths)
+	})
+}
+
+// ---- Middleware ----
+
+// UseCSRF adds middleware rejecting forged unsafe requests with 403.
+func UseCSRF(rtr *router.Router, opts ...CSRFOption) {
+	rtr.Use(NewCSRFMiddleware(opts...))
+}
+
+// NewCSRFMiddleware builds the CSRF middleware.
+func NewCSRFMiddleware(opts ...CSRFOption) routing.Middleware {
+	o := CSRFOptions{
+		CookieName: DefaultCookieName,
+		HeaderName: DefaultHeaderName,
+		FieldName:  DefaultFieldName,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.Secret) == 0 {
+		o.Secret = make([]byte, 32)
+		_, _ = rand.Read(o.Secret)
+	}
+	if o.Mode == ModeSynchronizer && (o.Store == nil || o.SessionID == nil) {
+		panic("csrf: synchronizer tokens require a token store and WithSessionID")
+	}
+	return &csrfMiddleware{options: o}
+}
+
+type csrfMiddleware struct {
+	options CSRFOptions
+}
+
+type stateKey struct{}
+
+// state is the per-request token, created on first use.
+type state struct {
+	m     *csrfMiddleware
+	token string
+}
+
+// Invoke checks unsafe requests before calling next.
+func (m *csrfMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	c.SetContextValue(stateKey{}, &state{m: m})
+	if isSafeMethod(c.Request().Method) || m.exempt(c) {
+		next(c)
+		return
+	}
+	if reason := m.checkOrigin(c.Request()); reason != "" {
+		m.reject(c, reason)
+		return
+	}
+	if m.options.Mode != ModeOrigin {
+		if reason := m.checkToken(c); reason != "" {
+			m.reject(c, reason)
+			return
+		}
+	}
+	next(c)
+}
+
+func (m *csrfMiddleware) exempt(c routing.RouteContext) bool {
+	if opts := c.Options(); opts != nil && opts.DisableCSRF {
+		return true
+	}
+	for _, skip := range m.options.Skip {
+		if skip(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *csrfMiddleware) reject(c routing.RouteContext, reason string) {
+	slog.WarnContext(c, "CSRF validation failed", "reason", reason)
+	routing.ReportRejection(c, routing.RejectionForbidden)
+	instance := c.Request().RequestURI
+	c.Problem(&routing.ProblemDetails{
+		Title:    "CSRF Validation Failed",
+		Detail:   reason,
+		Status:   http.StatusForbidden,
+		Type:     routing.ProblemTypeAboutBlank,
+		Instance: &instance,
+	})
+}
+
+// ---- Origin Checks ----
+
+// checkOrigin rejects browser requests from other origins. Fetch Metadata is
+// preferred, then Origin, then Referer; requests without any of them, such
+// as from non-browser clients, pass on to the token check.
+func (m *csrfMiddleware) checkOrigin(r *http.Request) string {
+	origin := r.Header.Get(common.HeaderOrigin)
+	switch r.Header.Get(common.HeaderSecFetchSite) {
+	case "same-origin", "none":
+		return ""
+	case "same-site", "cross-site":
+		if origin != "" && m.trusted(origin) {
+			return ""
+		}
+		return "cross-origin request"
+	}
+	if origin != "" && origin != "null" {
+		if m.sameOrigin(r, origin) || m.trusted(origin) {
+			return ""
+		}
+		return "origin does not match"
+	}
+	if referer := r.Header.Get(common.HeaderReferer); referer != "" {
+		u, err := url.Parse(referer)
+		if err != nil || u.Host == "" {
+			return "referer does not match"
+		}
+		refererOrigin := u.Scheme + "://" + u.Host
+		if m.sameOrigin(r, refererOrigin) || m.trusted(refererOrigin) {
+			return ""
+		}
+		return "referer does not match"
+	}
+	if origin == "null" {
+		return "origin does not match"
+	}
+	return ""
+}
+
+// sameOrigin compares origin with the request's own origin. The scheme
+// comes from r.TLS, so behind a TLS-terminating proxy UseForwardedHeaders
+// must run first to set it for trusted proxies; otherwise list the public
+// origin with WithTrustedOrigins.
+func (m *csrfMiddleware) sameOrigin(r *http.Request, origin string) bool {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return strings.EqualFold(origin, scheme+"://"+r.Host)
+}
+
+func (m *csrfMiddleware) trusted(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, t := range m.options.TrustedOrigins {
+		if origin == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ---- Tokens ----
+
+func (m *csrfMiddleware) checkToken(c routing.RouteContext) string {
+	sent := m.sentToken(c)
+	if sent == "" {
+		return "missing token"
+	}
+	if m.options.Mode == ModeSynchronizer {
+		session := m.options.SessionID(c)
+		if session == "" {
+			return "no session"
+		}
+		stored, ok, err := m.options.Store.Get(c, session)
+		if err != nil {
+			slog.ErrorContext(c, "failed to load CSRF token", "error", err)
+			return "token unavailable"
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(stored), []byte(sent)) != 1 {
+			return "invalid token"
+		}
+		return ""
+	}
+	cookie, err := c.Request().Cookie(m.options.CookieName)
+	if err != nil || cookie.Value == "" {
+		return "missing cookie"
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(sent)) != 1 || !m.verify(c, sent) {
+		return "invalid token"
+	}
+	return ""
+}
+
+// sentToken reads the token from the header, or from the form field of
+// urlencoded and multipart bodies.
+func (m *csrfMiddleware) sentToken(c routing.RouteContext) string {
+	r := c.Request()
+	if token := r.Header.Get(m.options.HeaderName); token != "" {
+		return token
+	}
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get(common.HeaderContentType))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err == nil {
+			return r.PostForm.Get(m.options.FieldName)
+		}
+	case "multipart/form-data":
+		return m.multipartToken(r, params["boundary"])
+	}
+	return ""
+}
+
+// maxMultipartScan bounds how much of a multipart body is read looking for
+// the token field.
+const maxMultipartScan = 64 << 10
+
+// multipartToken reads the token field from the start of a multipart body
+// without consuming it or writing a response: at most maxMultipartScan bytes
+// are read and replayed ahead of the rest of the body, so handlers still
+// stream it with Files or MultipartReader. The field must come before any
+// file part, as it does when the form lists it first.
+func (m *csrfMiddleware) multipartToken(r *http.Request, boundary string) string {
+	if boundary == "" || r.Body == nil || r.Body == http.NoBody {
+		return ""
+	}
+	var scanned bytes.Buffer
+	mr := multipart.NewReader(io.TeeReader(io.LimitReader(r.Body, maxMultipartScan), &scanned), boundary)
+	token := ""
+	for {
+		part, err := mr.NextPart()
+		if err != nil || part.FileName() != "" {
+			break
+		}
+		if part.FormName() != m.options.FieldName {
+			continue
+		}
+		if b, err := io.ReadAll(part); err == nil {
+			token = string(b)
+		}
+		break
+	}
+	r.Body = io.NopCloser(io.MultiReader(&scanned, r.Body))
+	return token
+}
+
+func (m *csrfMiddleware) session(c routing.RouteContext) string {
+	if m.options.SessionID == nil {
+		return ""
+	}
+	return m.options.SessionID(c)
+}
+
+// sign returns a token for random bound to the session:
+// base64(random) "." base64(HMAC-SHA256(secret, session "|" base64(random))).
+func (m *csrfMiddleware) sign(session, random string) string {
+	mac := hmac.New(sha256.New, m.options.Secret)
+	mac.Write([]byte(session))
+	mac.Write([]byte{'|'})
+	mac.Write([]byte(random))
+	return random + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *csrfMiddleware) verify(c routing.RouteContext, token string) bool {
+	random, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(m.sign(m.session(c), random)))
+}
+
+func newRandom() string {
+	b := make([]byte, tokenBytes)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Token returns the request's CSRF token for forms and scripts to send
+// back, issuing one when needed: a signed cookie for double-submit tokens or
+// a stored token for synchronizer tokens. Call it before writing the
+// response. It returns "" without the middleware, in ModeOrigin, or when a
+// synchronizer session is missing.
+func Token(c routing.RouteContext) string {
+	s, ok := c.Value(stateKey{}).(*state)
+	if !ok || s == nil {
+		return ""
+	}
+	if s.token == "" {
+		s.token = s.m.issue(c)
+	}
+	return s.token
+}
+
+func (m *csrfMiddleware) issue(c routing.RouteContext) string {
+	switch m.options.Mode {
+	case ModeOrigin:
+		return ""
+	case ModeSynchronizer:
+		session := m.options.SessionID(c)
+		if session == "" {
+			return ""
+		}
+		token, ok, err := m.options.Store.Get(c, session)
+		if err == nil && ok {
+			return token
+		}
+		token = newRandom()
+		if err := m.options.Store.Set(c, session, token); err != nil {
+			slog.ErrorContext(c, "failed to store CSRF token", "error", err)
+			return ""
+		}
+		return token
+	}
+	if cookie, err := c.Request().Cookie(m.options.CookieName); err == nil && m.verify(c, cookie.Value) {
+		return cookie.Value
+	}
+	token := m.sign(m.session(c), newRandom())
+	//nolint:gosec // G124: scripts read the cookie to send the header; Secure tracks the request scheme.
+	http.SetCookie(c.Response(), &http.Cookie{
+		Name:     m.options.CookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   c.Request().TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
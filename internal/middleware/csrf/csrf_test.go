@@ -0,0 +1,378 @@
+package csrf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouter(opts ...CSRFOption) *router.Router {
+	rtr := router.NewRouter()
+	UseCSRF(rtr, opts...)
+	rtr.GET("/form", func(c routing.RouteContext) { c.OK(Token(c)) })
+	rtr.POST("/submit", func(c routing.RouteContext) { c.NoContent() })
+	return rtr
+}
+
+func headerSession(c routing.RouteContext) string {
+	return c.Request().Header.Get("X-Session")
+}
+
+// issueToken fetches a token and the cookie carrying it, if any.
+func issueToken(t *testing.T, rtr *router.Router, session string) (string, *http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.Header.Set("X-Session", session)
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var token string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &token))
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == DefaultCookieName {
+			return token, cookie
+		}
+	}
+	return token, nil
+}
+
+func post(rtr *router.Router, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldAcceptDoubleSubmitTokenGivenHeader(t *testing.T) {
+	// Arrange
+	rtr := newRouter()
+	token, cookie := issueToken(t, rtr, "")
+	require.NotNil(t, cookie)
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(DefaultHeaderName, token)
+
+	// Act
+	rec := post(rtr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, token, cookie.Value)
+	assert.False(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+}
+
+func TestShouldRejectGivenMissingOrMismatchedToken(t *testing.T) {
+	// Arrange
+	rtr := newRouter()
+	token, cookie := issueToken(t, rtr, "")
+	other, _ := issueToken(t, rtr, "")
+	forged := &http.Cookie{Name: DefaultCookieName, Value: "abc.def"}
+
+	cases := map[string]*http.Request{
+		"no token":  httptest.NewRequest(http.MethodPost, "/submit", nil),
+		"no cookie": httptest.NewRequest(http.MethodPost, "/submit", nil),
+		"mismatch":  httptest.NewRequest(http.MethodPost, "/submit", nil),
+		"unsigned":  httptest.NewRequest(http.MethodPost, "/submit", nil),
+	}
+	cases["no token"].AddCookie(cookie)
+	cases["no cookie"].Header.Set(DefaultHeaderName, token)
+	cases["mismatch"].AddCookie(cookie)
+	cases["mismatch"].Header.Set(DefaultHeaderName, other)
+	cases["unsigned"].AddCookie(forged)
+	cases["unsigned"].Header.Set(DefaultHeaderName, forged.Value)
+
+	for name, req := range cases {
+		// Act
+		rec := post(rtr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), "CSRF Validation Failed", name)
+	}
+}
+
+func TestShouldRejectTokenFromOtherSecretOrSession(t *testing.T) {
+	// Arrange
+	rtr := newRouter(WithSecret([]byte("secret")), WithSessionID(headerSession))
+	token, cookie := issueToken(t, rtr, "alice")
+	otherRouter := newRouter(WithSecret([]byte("other")), WithSessionID(headerSession))
+
+	same := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	other := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	otherSecret := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	for session, req := range map[string]*http.Request{"alice": same, "mallory": other, "": otherSecret} {
+		req.AddCookie(cookie)
+		req.Header.Set(DefaultHeaderName, token)
+		req.Header.Set("X-Session", session)
+	}
+	otherSecret.Header.Set("X-Session", "alice")
+
+	// Act
+	sameRec := post(rtr, same)
+	otherRec := post(rtr, other)
+	otherSecretRec := post(otherRouter, otherSecret)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, sameRec.Code)
+	assert.Equal(t, http.StatusForbidden, otherRec.Code)
+	assert.Equal(t, http.StatusForbidden, otherSecretRec.Code)
+}
+
+func TestShouldReuseValidCookieGivenLaterPage(t *testing.T) {
+	// Arrange
+	rtr := newRouter()
+	_, cookie := issueToken(t, rtr, "")
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.AddCookie(cookie)
+
+	// Act
+	rec := post(rtr, req)
+
+	// Assert
+	assert.Contains(t, rec.Body.String(), cookie.Value)
+	assert.Empty(t, rec.Result().Cookies())
+}
+
+func TestShouldAcceptTokenGivenFormField(t *testing.T) {
+	// Arrange
+	rtr := newRouter()
+	rtr.POST("/upload", func(c routing.RouteContext) {
+		uploads, err := c.Files()
+		require.NoError(t, err)
+		c.OK(uploads.Value("title"))
+	})
+	token, cookie := issueToken(t, rtr, "")
+
+	form := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(url.Values{DefaultFieldName: {token}}.Encode()))
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	form.AddCookie(cookie)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField(DefaultFieldName, token))
+	require.NoError(t, mw.WriteField("title", "report"))
+	require.NoError(t, mw.Close())
+	upload := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	upload.Header.Set("Content-Type", mw.FormDataContentType())
+	upload.AddCookie(cookie)
+
+	// Act
+	formRec := post(rtr, form)
+	uploadRec := post(rtr, upload)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, formRec.Code)
+	assert.Equal(t, http.StatusOK, uploadRec.Code)
+	assert.Contains(t, uploadRec.Body.String(), "report")
+}
+
+func TestShouldReadMultipartTokenWithoutConsumingBody(t *testing.T) {
+	// Arrange
+	rtr := newRouter()
+	rtr.POST("/stream", func(c routing.RouteContext) {
+		reader, err := c.MultipartReader()
+		require.NoError(t, err)
+		var names []string
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			b, _ := io.ReadAll(part)
+			names = append(names, part.FormName+"="+string(b))
+		}
+		c.OK(names)
+	})
+	token, cookie := issueToken(t, rtr, "")
+	newUpload := func(fields ...string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for i := 0; i < len(fields); i += 2 {
+			if fields[i] == "file" {
+				fw, err := mw.CreateFormFile("file", "report.csv")
+				require.NoError(t, err)
+				_, _ = fw.Write([]byte(fields[i+1]))
+				continue
+			}
+			require.NoError(t, mw.WriteField(fields[i], fields[i+1]))
+		}
+		require.NoError(t, mw.Close())
+		req := httptest.NewRequest(http.MethodPost, "/stream", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.AddCookie(cookie)
+		return req
+	}
+
+	// Act
+	tokenFirst := post(rtr, newUpload(DefaultFieldName, token, "file", "a,b"))
+	fileFirst := post(rtr, newUpload("file", "a,b", DefaultFieldName, token))
+	missing := post(rtr, newUpload("title", "report", "file", "a,b"))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, tokenFirst.Code)
+	assert.JSONEq(t, `["`+DefaultFieldName+`=`+token+`","file=a,b"]`, tokenFirst.Body.String())
+	assert.Equal(t, http.StatusForbidden, fileFirst.Code)
+	assert.Contains(t, fileFirst.Body.String(), "missing token")
+	assert.Equal(t, http.StatusForbidden, missing.Code)
+	assert.Contains(t, missing.Body.String(), "missing token")
+}
+
+func TestShouldValidateSynchronizerTokenAgainstStore(t *testing.T) {
+	// Arrange
+	store := NewMemoryTokenStore()
+	rtr := newRouter(WithSynchronizerTokens(store), WithSessionID(headerSession))
+	token, cookie := issueToken(t, rtr, "alice")
+	again, _ := issueToken(t, rtr, "alice")
+
+	valid := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	valid.Header.Set("X-Session", "alice")
+	valid.Header.Set(DefaultHeaderName, token)
+	otherSession := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	otherSession.Header.Set("X-Session", "mallory")
+	otherSession.Header.Set(DefaultHeaderName, token)
+
+	// Act
+	validRec := post(rtr, valid)
+	otherRec := post(rtr, otherSession)
+	require.NoError(t, store.Delete(context.Background(), "alice"))
+	revokedRec := post(rtr, valid)
+
+	// Assert
+	assert.Nil(t, cookie)
+	assert.Equal(t, token, again)
+	assert.Equal(t, http.StatusNoContent, validRec.Code)
+	assert.Equal(t, http.StatusForbidden, otherRec.Code)
+	assert.Equal(t, http.StatusForbidden, revokedRec.Code)
+}
+
+func TestShouldPanicGivenSynchronizerWithoutSession(t *testing.T) {
+	assert.Panics(t, func() { NewCSRFMiddleware(WithSynchronizerTokens(NewMemoryTokenStore())) })
+}
+
+func TestShouldCheckFetchMetadataOriginAndReferer(t *testing.T) {
+	// Arrange
+	rtr := newRouter(WithOriginCheckOnly(), WithTrustedOrigins("https://app.example.com/"))
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"same-origin fetch", map[string]string{"Sec-Fetch-Site": "same-origin"}, http.StatusNoContent},
+		{"cross-site fetch", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"same-site fetch", map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "http://sub.example.com"}, http.StatusForbidden},
+		{"trusted cross-site fetch", map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://APP.example.com"}, http.StatusNoContent},
+		{"same origin", map[string]string{"Origin": "http://example.com"}, http.StatusNoContent},
+		{"other origin", map[string]string{"Origin": "https://example.com"}, http.StatusForbidden},
+		{"null origin", map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"same referer", map[string]string{"Referer": "http://example.com/form?x=1"}, http.StatusNoContent},
+		{"other referer", map[string]string{"Referer": "https://evil.example/page"}, http.StatusForbidden},
+		{"trusted referer", map[string]string{"Referer": "https://app.example.com/page"}, http.StatusNoContent},
+		{"no headers", map[string]string{}, http.StatusNoContent},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+		for name, value := range tc.headers {
+			req.Header.Set(name, value)
+		}
+
+		// Act
+		rec := post(rtr, req)
+
+		// Assert
+		assert.Equal(t, tc.want, rec.Code, tc.name)
+	}
+}
+
+func TestShouldRejectCrossOriginRequestGivenValidToken(t *testing.T) {
+	// Arrange
+	rtr := newRouter()
+	token, cookie := issueToken(t, rtr, "")
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set(DefaultHeaderName, token)
+	req.Header.Set("Origin", "https://evil.example")
+
+	// Act
+	rec := post(rtr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "origin does not match")
+}
+
+func TestShouldSkipExemptRoutesGroupsAndPaths(t *testing.T) {
+	// Arrange
+	rtr := newRouter(WithExemptPaths("/hooks/*"))
+	rtr.POST("/webhook", func(c routing.RouteContext) { c.NoContent() }).WithoutCSRF()
+	rtr.NewRouteGroup("/public").WithoutCSRF().POST("/contact", func(c routing.RouteContext) { c.NoContent() })
+	rtr.POST("/hooks/github", func(c routing.RouteContext) { c.NoContent() })
+
+	for _, path := range []string{"/webhook", "/public/contact", "/hooks/github", "/submit"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+
+		// Act
+		rec := post(rtr, req)
+
+		// Assert
+		if path == "/submit" {
+			assert.Equal(t, http.StatusForbidden, rec.Code, path)
+		} else {
+			assert.Equal(t, http.StatusNoContent, rec.Code, path)
+		}
+	}
+}
+
+func TestShouldRenderTokenWithTemplateFuncs(t *testing.T) {
+	// Arrange
+	rtr := newRouter(WithFieldName("_csrf"))
+	page := template.Must(template.New("page").Funcs(TemplateFuncs(nil)).Parse(
+		`<form>{{ csrfField }}</form><meta name="csrf" content="{{ csrfToken }}">`))
+	rtr.GET("/page", func(c routing.RouteContext) {
+		var out strings.Builder
+		tmpl := template.Must(page.Clone()).Funcs(TemplateFuncs(c))
+		require.NoError(t, tmpl.Execute(&out, nil))
+		c.HTML(http.StatusOK, out.String())
+	})
+
+	// Act
+	rec := post(rtr, httptest.NewRequest(http.MethodGet, "/page", nil))
+
+	// Assert
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Contains(t, rec.Body.String(), `<input type="hidden" name="_csrf" value="`+cookies[0].Value+`">`)
+	assert.Contains(t, rec.Body.String(), `content="`+cookies[0].Value+`"`)
+}
+
+func TestShouldReturnEmptyTokenWithoutMiddleware(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	var token string
+	var field template.HTML
+	rtr.GET("/", func(c routing.RouteContext) {
+		token = Token(c)
+		field = Field(c)
+		c.NoContent()
+	})
+
+	// Act
+	post(rtr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	assert.Empty(t, token)
+	assert.Empty(t, field)
+}
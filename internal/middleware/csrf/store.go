@@ -0,0 +1,53 @@
+package csrf
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenStore holds synchronizer tokens, one per session. Delete the token
+// when the session ends or its privileges change so a new one is issued.
+type TokenStore interface {
+	// Get returns the token stored for session and whether one exists.
+	Get(ctx context.Context, session string) (token string, ok bool, err error)
+	// Set stores token for session, replacing any previous token.
+	Set(ctx context.Context, session, token string) error
+	// Delete removes the token stored for session.
+	Delete(ctx context.Context, session string) error
+}
+
+// MemoryTokenStore is an in-process TokenStore. It does not share tokens
+// across processes.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]string{}}
+}
+
+// Get implements TokenStore.
+func (s *MemoryTokenStore) Get(_ context.Context, session string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[session]
+	return token, ok, nil
+}
+
+// Set implements TokenStore.
+func (s *MemoryTokenStore) Set(_ context.Context, session, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[session] = token
+	return nil
+}
+
+// Delete implements TokenStore.
+func (s *MemoryTokenStore) Delete(_ context.Context, session string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, session)
+	return nil
+}
@@ -0,0 +1,32 @@
+package csrf
+
+import (
+	"html/template"
+
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// Field returns a hidden form input carrying the request's token, or "" when
+// there is none.
+func Field(c routing.RouteContext) template.HTML {
+	token := Token(c)
+	if token == "" {
+		return ""
+	}
+	name := ""
+	if s, ok := c.Value(stateKey{}).(*state); ok {
+		name = s.m.options.FieldName
+	}
+	//nolint:gosec // G203: name and token are escaped.
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) +
+		`" value="` + template.HTMLEscapeString(token) + `">`)
+}
+
+// TemplateFuncs returns template functions for the request: csrfToken
+// returns the token and csrfField the hidden input from Field.
+func TemplateFuncs(c routing.RouteContext) template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": func() string { return Token(c) },
+		"csrfField": func() template.HTML { return Field(c) },
+	}
+}
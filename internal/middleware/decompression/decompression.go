@@ -0,0 +1,302 @@
+package decompression
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// ErrDecompressionLimit is returned while reading a decoded request body that
+// exceeds the decompressed-size or compression-ratio limit. The error also
+// wraps an *http.MaxBytesError, so Bind and Files report it as too large.
+var ErrDecompressionLimit = errors.New("decompressed request body exceeds limit")
+
+// DefaultMaxRatio is the largest decompressed-to-compressed size ratio
+// accepted unless WithMaxRatio says otherwise.
+const DefaultMaxRatio = 100
+
+// ratioFloor is the decoded size below which the ratio limit is not applied,
+// so small, highly repetitive payloads are not rejected.
+const ratioFloor = 64 << 10
+
+// Decoder wraps a request body encoded with one content coding.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+// ---- Functional Options ----
+
+// DecompressionOptions configures the decompression middleware behavior.
+type DecompressionOptions struct {
+	// MaxBytes caps the decoded body size. Zero uses the route's body limit
+	// (WithMaxBodyBytes, SetMaxBodyBytes, or the 1MB default).
+	MaxBytes int64
+	// MaxRatio caps decoded bytes per encoded byte. Zero disables the check.
+	MaxRatio int
+	// Decoders maps lower-case content codings to their decoders.
+	Decoders map[string]Decoder
+}
+
+// DecompressionOption is a function type for configuring decompression options.
+type DecompressionOption func(*DecompressionOptions)
+
+// WithMaxBytes caps the decoded size of a request body.
+func WithMaxBytes(n int64) DecompressionOption {
+	return func(o *DecompressionOptions) {
+		o.MaxBytes = max(n, 0)
+	}
+}
+
+// WithMaxRatio caps the decompressed-to-compressed size ratio. A value <= 0
+// disables the check.
+func WithMaxRatio(ratio int) DecompressionOption {
+	return func(o *DecompressionOptions) {
+		o.MaxRatio = max(ratio, 0)
+	}
+}
+
+// WithDecoder registers decoder for the content coding encoding, replacing
+// any existing decoder for it.
+func WithDecoder(encoding string, decoder Decoder) DecompressionOption {
+	return func(o *DecompressionOptions) {
+		if encoding = strings.ToLower(strings.TrimSpace(encoding)); encoding == "" || decoder == nil {
+			return
+		}
+		o.Decoders[encoding] = decoder
+	}
+}
+
+// UseDecompression adds middleware that decodes gzip and deflate request
+// bodies, plus any encodings registered with WithDecoder, and rejects other
+// encodings with 415 Unsupported Media Type.
+func UseDecompression(rtr *router.Router, opts ...DecompressionOption) {
+	options := &DecompressionOptions{
+		MaxRatio: DefaultMaxRatio,
+		Decoders: map[string]Decoder{
+			"gzip":    decodeGzip,
+			"x-gzip":  decodeGzip,
+			"deflate": decodeDeflate,
+		},
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	m := &decompressionMiddleware{options: options}
+	rtr.Use(m)
+	rtr.DocumentOperations(m.document)
+}
+
+func decodeGzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// decodeDeflate accepts both the zlib-wrapped stream that RFC 9110 calls
+// "deflate" and the raw deflate stream some clients send instead.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// ---- Middleware ----
+
+type decompressionMiddleware struct {
+	options *DecompressionOptions
+}
+
+const (
+	identityEncoding       = "identity"
+	unsupportedTitle       = "Unsupported Content-Encoding"
+	invalidEncodingTitle   = "Invalid encoded body"
+	supportedEncodingsNote = "Supported encodings: "
+)
+
+// Invoke implements the Middleware interface, replacing an encoded request
+// body with a size-limited decoded stream.
+func (m *decompressionMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	req := c.Request()
+	codings := parseContentEncoding(req.Header.Values(common.HeaderContentEncoding))
+	if len(codings) == 0 || req.Body == nil || req.Body == http.NoBody {
+		next(c)
+		return
+	}
+	for _, coding := range codings {
+		if _, ok := m.options.Decoders[coding]; !ok {
+			c.Response().Header().Set(common.HeaderAcceptEncoding, strings.Join(m.supported(), ", "))
+			m.problem(c, http.StatusUnsupportedMediaType, unsupportedTitle,
+				fmt.Sprintf("Content-Encoding %q is not supported. %s%s.", coding, supportedEncodingsNote, strings.Join(m.supported(), ", ")))
+			return
+		}
+	}
+
+	limit := m.options.MaxBytes
+	if limit <= 0 {
+		limit = c.MaxBodyBytes()
+	}
+	encoded := &countingReader{r: req.Body}
+	var body io.Reader = encoded
+	closers := []io.Closer{req.Body}
+	// Codings are listed in the order they were applied, so decode in reverse.
+	for i := len(codings) - 1; i >= 0; i-- {
+		decoded, err := m.options.Decoders[codings[i]](body)
+		if err != nil {
+			closeAll(closers)
+			m.problem(c, http.StatusBadRequest, invalidEncodingTitle,
+				fmt.Sprintf("The request body is not valid %s data.", codings[i]))
+			return
+		}
+		closers = append(closers, decoded)
+		body = decoded
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = &limitedBody{
+		r:       body,
+		encoded: encoded,
+		limit:   limit,
+		ratio:   int64(m.options.MaxRatio),
+		closers: closers,
+	}
+	clone.ContentLength = -1
+	clone.Header.Del(common.HeaderContentEncoding)
+	clone.Header.Del(common.HeaderContentLength)
+	c.SetRequest(clone)
+	next(c)
+}
+
+func (m *decompressionMiddleware) problem(c routing.RouteContext, status int, title, detail string) {
+	instance := c.Request().RequestURI
+	c.Problem(&routing.ProblemDetails{
+		Title:    title,
+		Detail:   detail,
+		Status:   status,
+		Type:     routing.ProblemTypeAboutBlank,
+		Instance: &instance,
+	})
+}
+
+// supported returns the registered encodings in sorted order.
+func (m *decompressionMiddleware) supported() []string {
+	names := make([]string, 0, len(m.options.Decoders))
+	for name := range m.options.Decoders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// document adds an optional Content-Encoding header parameter and a 415
+// response to operations that accept a request body.
+func (m *decompressionMiddleware) document(_ *routing.RouteOptions, op *openapi.Operation) {
+	if op == nil || op.RequestBody == nil {
+		return
+	}
+	for _, p := range op.Parameters {
+		if p != nil && strings.EqualFold(p.In, "header") && strings.EqualFold(p.Name, common.HeaderContentEncoding) {
+			return
+		}
+	}
+	enum := []any{identityEncoding}
+	for _, name := range m.supported() {
+		enum = append(enum, name)
+	}
+	op.Parameters = append(op.Parameters, &openapi.ParameterObject{
+		Name:        common.HeaderContentEncoding,
+		In:          "header",
+		Description: "Content coding applied to the request body.",
+		Schema:      &openapi.Schema{Type: "string", Enum: enum},
+	})
+	if op.Responses == nil {
+		op.Responses = map[string]*openapi.ResponseObject{}
+	}
+	if _, ok := op.Responses["415"]; !ok {
+		op.Responses["415"] = &openapi.ResponseObject{Description: unsupportedTitle}
+	}
+}
+
+// parseContentEncoding returns the lower-case codings in the order they were
+// applied, omitting identity.
+func parseContentEncoding(values []string) []string {
+	var codings []string
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" && coding != identityEncoding {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	return codings
+}
+
+func closeAll(closers []io.Closer) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		_ = closers[i].Close()
+	}
+}
+
+// countingReader counts the encoded bytes read from the original body.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// limitedBody is the decoded request body. It fails once the decoded size
+// exceeds limit or, past ratioFloor, ratio times the encoded size.
+type limitedBody struct {
+	r       io.Reader
+	encoded *countingReader
+	limit   int64
+	ratio   int64
+	n       int64
+	err     error
+	closers []io.Closer
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if remaining := b.limit - b.n + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	switch {
+	case b.n > b.limit:
+		b.err = fmt.Errorf("%w: more than %d bytes: %w", ErrDecompressionLimit, b.limit, &http.MaxBytesError{Limit: b.limit})
+	case b.ratio > 0 && b.n > ratioFloor && b.n > b.ratio*max(b.encoded.n, 1):
+		b.err = fmt.Errorf("%w: compression ratio above %d:1: %w", ErrDecompressionLimit, b.ratio, &http.MaxBytesError{Limit: b.ratio * b.encoded.n})
+	default:
+		return n, err
+	}
+	return 0, b.err
+}
+
+func (b *limitedBody) Close() error {
+	var errs []error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		errs = append(errs, b.closers[i].Close())
+	}
+	b.closers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,207 @@
+package decompression
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newEchoRouter(opts ...DecompressionOption) (*router.Router, *error) {
+	var readErr error
+	rtr := router.NewRouter()
+	UseDecompression(rtr, opts...)
+	rtr.POST("/echo", func(c routing.RouteContext) {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			readErr = err
+			c.Response().WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		_, _ = c.Response().Write(body)
+	})
+	return rtr, &readErr
+}
+
+func post(rtr http.Handler, encoding string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/echo", bytes.NewReader(body))
+	if encoding != "" {
+		req.Header.Set(common.HeaderContentEncoding, encoding)
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldDecodeRequestBodyGivenSupportedEncoding(t *testing.T) {
+	payload := []byte(`{"temperature":21.5}`)
+	var zlibBuf, rawBuf bytes.Buffer
+	zw := zlib.NewWriter(&zlibBuf)
+	_, _ = zw.Write(payload)
+	_ = zw.Close()
+	fw, _ := flate.NewWriter(&rawBuf, flate.DefaultCompression)
+	_, _ = fw.Write(payload)
+	_ = fw.Close()
+
+	cases := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"gzip", "gzip", gzipBytes(t, payload)},
+		{"x-gzip", "X-GZIP", gzipBytes(t, payload)},
+		{"zlib deflate", "deflate", zlibBuf.Bytes()},
+		{"raw deflate", "deflate", rawBuf.Bytes()},
+		{"stacked", "gzip, gzip", gzipBytes(t, gzipBytes(t, payload))},
+		{"identity", "identity", payload},
+		{"none", "", payload},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			rtr, _ := newEchoRouter()
+
+			// Act
+			rec := post(rtr, tc.encoding, tc.body)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, string(payload), rec.Body.String())
+		})
+	}
+}
+
+func TestShouldRejectUnsupportedEncodingWith415(t *testing.T) {
+	// Arrange
+	rtr, _ := newEchoRouter()
+
+	// Act
+	rec := post(rtr, "br", []byte("whatever"))
+
+	// Assert
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	assert.Equal(t, "deflate, gzip, x-gzip", rec.Header().Get(common.HeaderAcceptEncoding))
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Contains(t, problem["detail"], `"br"`)
+}
+
+func TestShouldRejectCorruptBodyWith400(t *testing.T) {
+	// Arrange
+	rtr, _ := newEchoRouter()
+
+	// Act
+	rec := post(rtr, "gzip", []byte("not gzip at all"))
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestShouldFailReadGivenDecodedBodyAboveMaxBytes(t *testing.T) {
+	// Arrange
+	rtr, readErr := newEchoRouter(WithMaxBytes(1000), WithMaxRatio(0))
+
+	// Act
+	rec := post(rtr, "gzip", gzipBytes(t, bytes.Repeat([]byte("a"), 1001)))
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.ErrorIs(t, *readErr, ErrDecompressionLimit)
+	var maxErr *http.MaxBytesError
+	assert.True(t, errors.As(*readErr, &maxErr))
+}
+
+func TestShouldFailReadGivenCompressionRatioAboveLimit(t *testing.T) {
+	// Arrange
+	rtr, readErr := newEchoRouter(WithMaxBytes(100<<20), WithMaxRatio(50))
+	bomb := gzipBytes(t, make([]byte, 10<<20))
+
+	// Act
+	rec := post(rtr, "gzip", bomb)
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.ErrorContains(t, *readErr, "compression ratio above 50:1")
+}
+
+func TestShouldUseRouteBodyLimitGivenNoMaxBytes(t *testing.T) {
+	// Arrange
+	rtr, readErr := newEchoRouter(WithMaxRatio(0))
+	body := gzipBytes(t, bytes.Repeat([]byte("a"), int(routing.DefaultMaxBodyBytes)+1))
+
+	// Act
+	rec := post(rtr, "gzip", body)
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.ErrorIs(t, *readErr, ErrDecompressionLimit)
+}
+
+func TestShouldUseCustomDecoderGivenRegisteredEncoding(t *testing.T) {
+	// Arrange
+	upper := func(r io.Reader) (io.ReadCloser, error) {
+		data, err := io.ReadAll(r)
+		return io.NopCloser(strings.NewReader(strings.ToUpper(string(data)))), err
+	}
+	rtr, _ := newEchoRouter(WithDecoder("x-upper", upper))
+
+	// Act
+	rec := post(rtr, "x-upper", []byte("hello"))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HELLO", rec.Body.String())
+}
+
+func TestShouldDocumentContentEncodingGivenOperationWithBody(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseDecompression(rtr)
+	rtr.POST("/items", func(routing.RouteContext) {}).WithJSONBody(struct {
+		Name string `json:"name"`
+	}{})
+	rtr.GET("/items", func(routing.RouteContext) {})
+
+	// Act
+	routes, err := rtr.Routes()
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	for _, route := range routes {
+		if route.Method == http.MethodGet {
+			assert.Empty(t, route.Options.Parameters)
+			continue
+		}
+		require.Len(t, route.Options.Parameters, 1)
+		param := route.Options.Parameters[0]
+		assert.Equal(t, "Content-Encoding", param.Name)
+		assert.Equal(t, "header", param.In)
+		assert.Equal(t, []any{"identity", "deflate", "gzip", "x-gzip"}, param.Schema.Enum)
+		assert.Contains(t, route.Options.Responses, "415")
+	}
+}
@@ -1,54 +1,266 @@
 package enforcehttps
 
 import (
+	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/middleware/forwardheaders"
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
 )
 
-// enforceHTTPSMiddleware redirects HTTP requests to HTTPS.
-type enforceHTTPSMiddleware struct{}
+// ---- Functional Options ----
+
+// EnforceHTTPSOptions configures the HTTPS enforcement middleware.
+type EnforceHTTPSOptions struct {
+	// RedirectStatus is the status of redirects to HTTPS. The default is
+	// 301 Moved Permanently; 308 Permanent Redirect also preserves the
+	// method and body of unsafe requests.
+	RedirectStatus int
+	// Reject answers plain HTTP requests with a 403 problem instead of
+	// redirecting them, for APIs whose clients should not silently retry.
+	Reject bool
+	// HSTS is the Strict-Transport-Security value sent on HTTPS responses.
+	// Empty omits the header.
+	HSTS string
+	// Ports maps HTTP ports to the HTTPS ports redirects target, such as
+	// 8080 to 8443. A request without a port uses port 80, and a target of
+	// 443 is omitted from the redirect.
+	Ports map[int]int
+	// TrustedProxies lists the peers whose X-Forwarded-Proto and Forwarded
+	// headers are honored. When empty, the headers are ignored and only the
+	// connection's TLS state counts, which UseForwardedHeaders sets for
+	// trusted proxies.
+	TrustedProxies []*net.IPNet
+	// Skip exempts requests for which any function reports true.
+	Skip []func(c routing.RouteContext) bool
+}
+
+// EnforceHTTPSOption is a function type for configuring HTTPS enforcement
+// options.
+type EnforceHTTPSOption func(*EnforceHTTPSOptions)
+
+// WithRedirectStatus sets the redirect status, such as
+// http.StatusPermanentRedirect.
+func WithRedirectStatus(status int) EnforceHTTPSOption {
+	return func(o *EnforceHTTPSOptions) {
+		o.RedirectStatus = status
+	}
+}
+
+// WithReject answers plain HTTP requests with 403 Forbidden instead of
+// redirecting them.
+func WithReject() EnforceHTTPSOption {
+	return func(o *EnforceHTTPSOptions) {
+		o.Reject = true
+	}
+}
+
+// WithHSTS sends Strict-Transport-Security on HTTPS responses. A maxAge <= 0
+// omits the header.
+func WithHSTS(maxAge time.Duration, includeSubDomains, preload bool) EnforceHTTPSOption {
+	return func(o *EnforceHTTPSOptions) {
+		if maxAge <= 0 {
+			o.HSTS = ""
+			return
+		}
+		o.HSTS = "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+		if includeSubDomains {
+			o.HSTS += "; includeSubDomains"
+		}
+		if preload {
+			o.HSTS += "; preload"
+		}
+	}
+}
+
+// WithPortMapping redirects requests on httpPort to httpsPort.
+func WithPortMapping(httpPort, httpsPort int) EnforceHTTPSOption {
+	return func(o *EnforceHTTPSOptions) {
+		if o.Ports == nil {
+			o.Ports = make(map[int]int)
+		}
+		o.Ports[httpPort] = httpsPort
+	}
+}
+
+// WithTrustedProxies honors X-Forwarded-Proto and Forwarded only from the
+// given CIDR ranges or IPs. Invalid entries are ignored.
+func WithTrustedProxies(proxies ...string) EnforceHTTPSOption {
+	return func(o *EnforceHTTPSOptions) {
+		for _, p := range proxies {
+			if n := common.ParseNet(strings.TrimSpace(p)); n != nil {
+				o.TrustedProxies = append(o.TrustedProxies, n)
+			}
+		}
+	}
+}
+
+// WithSkip exempts requests for which fn reports true.
+func WithSkip(fn func(c routing.RouteContext) bool) EnforceHTTPSOption {
+	return func(o *EnforceHTTPSOptions) {
+		if fn != nil {
+			o.Skip = append(o.Skip, fn)
+		}
+	}
+}
+
+// WithExemptPaths exempts requests to the given paths, such as health
+// probes. A path ending in "*" matches every path with that prefix.
+func WithExemptPaths(paths ...string) EnforceHTTPSOption {
+	return WithSkip(func(c routing.RouteContext) bool {
+		return common.MatchPath(c.Request().URL.Path, paths)
+	})
+}
+
+// ---- Middleware ----
+
+// enforceHTTPSMiddleware redirects or rejects HTTP requests.
+type enforceHTTPSMiddleware struct {
+	options EnforceHTTPSOptions
+}
+
+// UseEnforceHTTPS adds middleware that redirects HTTP requests to HTTPS.
+func UseEnforceHTTPS(rtr *router.Router, opts ...EnforceHTTPSOption) {
+	rtr.Use(NewEnforceHTTPSMiddleware(opts...))
+}
+
+// NewEnforceHTTPSMiddleware builds the HTTPS enforcement middleware.
+func NewEnforceHTTPSMiddleware(opts ...EnforceHTTPSOption) routing.Middleware {
+	var o EnforceHTTPSOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &enforceHTTPSMiddleware{options: o}
+}
 
 // isHTTPS checks if the request is over HTTPS by examining:
 // 1. The TLS connection state (most reliable)
 // 2. X-Forwarded-Proto header (for reverse proxy scenarios)
 // 3. Forwarded header proto= directive (RFC 7239)
-func isHTTPS(r *http.Request) bool {
+//
+// The headers are ignored once UseForwardedHeaders has trust-gated them, or
+// when the peer is not one of the trusted proxies. Without trusted proxies
+// they are never honored.
+func (m *enforceHTTPSMiddleware) isHTTPS(r *http.Request) bool {
 	// Direct TLS connection is the most reliable indicator
 	if r.TLS != nil {
 		return true
 	}
 
+	if routing.ForwardedHeadersHandled(r.Context()) || !m.trustedPeer(r) {
+		return false
+	}
+
 	// Check X-Forwarded-Proto header (commonly set by reverse proxies)
-	if proto := r.Header.Get("X-Forwarded-Proto"); strings.EqualFold(proto, "https") {
+	if proto := r.Header.Get(common.HeaderXForwardedProto); strings.EqualFold(proto, "https") {
 		return true
 	}
 
 	// Check RFC 7239 Forwarded header for proto=https
-	if fwd := r.Header.Get("Forwarded"); fwd != "" {
-		// Simple parse: look for proto=https or proto="https"
-		lower := strings.ToLower(fwd)
-		if strings.Contains(lower, "proto=https") || strings.Contains(lower, "proto=\"https\"") {
+	if fwd := r.Header.Get(common.HeaderForwarded); fwd != "" {
+		_, proto, _ := forwardheaders.ParseForwardedRFC(fwd)
+		return strings.EqualFold(proto, "https")
+	}
+
+	return false
+}
+
+func (m *enforceHTTPSMiddleware) trustedPeer(r *http.Request) bool {
+	if len(m.options.TrustedProxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range m.options.TrustedProxies {
+		if n.Contains(ip) {
 			return true
 		}
 	}
+	return false
+}
 
+func (m *enforceHTTPSMiddleware) exempt(c routing.RouteContext) bool {
+	if opts := c.Options(); opts != nil && opts.DisableHTTPSEnforcement {
+		return true
+	}
+	for _, skip := range m.options.Skip {
+		if skip(c) {
+			return true
+		}
+	}
 	return false
 }
 
-// Invoke implements the Middleware interface, redirecting HTTP requests to HTTPS.
+// Invoke implements the Middleware interface, redirecting or rejecting HTTP
+// requests and adding HSTS to HTTPS responses.
 func (m *enforceHTTPSMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
-	if !isHTTPS(c.Request()) {
-		target := "https://" + c.Request().Host + c.Request().URL.RequestURI()
-		http.Redirect(c.Response(), c.Request(), target, http.StatusMovedPermanently)
+	r := c.Request()
+	if m.isHTTPS(r) {
+		if m.options.HSTS != "" {
+			c.Response().Header().Set(common.HeaderStrictTransportSecurity, m.options.HSTS)
+		}
+		next(c)
+		return
+	}
+	if m.exempt(c) {
+		next(c)
+		return
+	}
+	if m.options.Reject {
+		slog.DebugContext(c, "rejected plain HTTP request", "path", r.URL.Path)
+		routing.ReportRejection(c, routing.RejectionForbidden)
+		instance := r.RequestURI
+		c.Problem(&routing.ProblemDetails{
+			Title:    "HTTPS Required",
+			Detail:   "This resource is only available over HTTPS.",
+			Status:   http.StatusForbidden,
+			Type:     routing.ProblemTypeAboutBlank,
+			Instance: &instance,
+		})
 		return
 	}
-	next(c)
+	status := m.options.RedirectStatus
+	if status == 0 {
+		status = http.StatusMovedPermanently
+	}
+	target := "https://" + m.host(r.Host) + r.URL.RequestURI()
+	http.Redirect(c.Response(), r, target, status)
 }
 
-// UseEnforceHTTPS adds middleware that redirects HTTP requests to HTTPS.
-func UseEnforceHTTPS(rtr *router.Router) {
-	rtr.Use(&enforceHTTPSMiddleware{})
+// host maps the port of host through Ports. Without a mapping the host is
+// kept as is.
+func (m *enforceHTTPSMiddleware) host(host string) string {
+	if len(m.options.Ports) == 0 {
+		return host
+	}
+	name, portText, err := net.SplitHostPort(host)
+	port := 80
+	if err != nil {
+		name = strings.Trim(host, "[]")
+	} else if port, err = strconv.Atoi(portText); err != nil {
+		return host
+	}
+	httpsPort, ok := m.options.Ports[port]
+	if !ok {
+		return host
+	}
+	if httpsPort == 443 {
+		if strings.Contains(name, ":") {
+			return "[" + name + "]"
+		}
+		return name
+	}
+	return net.JoinHostPort(name, strconv.Itoa(httpsPort))
 }
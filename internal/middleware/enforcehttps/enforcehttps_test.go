@@ -4,11 +4,14 @@ import (
 	"context"
 
 	"crypto/tls"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/middleware/forwardheaders"
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +22,8 @@ const (
 	testHTTPURL  = "http://example.com/test"
 	testHTTPQURL = "http://example.com/test?param=value&other=123"
 	testHTTPSURL = "https://example.com/test"
+	// testPeer is the peer address of httptest requests.
+	testPeer = "192.0.2.1"
 )
 
 // newCtx creates a routing context and recorder for tests to reduce duplication.
@@ -79,7 +84,7 @@ func TestShouldAllowHTTPSRequestsViaTLS(t *testing.T) {
 
 func TestShouldAllowHTTPSViaXForwardedProto(t *testing.T) {
 	// Arrange
-	middleware := &enforceHTTPSMiddleware{}
+	middleware := &enforceHTTPSMiddleware{options: EnforceHTTPSOptions{TrustedProxies: []*net.IPNet{common.ParseNet(testPeer)}}}
 	ctx, rec := newCtxWithHeader(http.MethodGet, testHTTPURL, "X-Forwarded-Proto", "https")
 
 	nextCalled := false
@@ -95,7 +100,7 @@ func TestShouldAllowHTTPSViaXForwardedProto(t *testing.T) {
 
 func TestShouldAllowHTTPSViaForwardedHeader(t *testing.T) {
 	// Arrange
-	middleware := &enforceHTTPSMiddleware{}
+	middleware := &enforceHTTPSMiddleware{options: EnforceHTTPSOptions{TrustedProxies: []*net.IPNet{common.ParseNet(testPeer)}}}
 	ctx, rec := newCtxWithHeader(http.MethodGet, testHTTPURL, "Forwarded", "for=192.0.2.60;proto=https;by=203.0.113.43")
 
 	nextCalled := false
@@ -109,6 +114,45 @@ func TestShouldAllowHTTPSViaForwardedHeader(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
+func TestShouldRedirectGivenForwardedProtoWithoutTrustedProxies(t *testing.T) {
+	for _, header := range [][2]string{
+		{common.HeaderXForwardedProto, "https"},
+		{common.HeaderForwarded, "proto=https"},
+	} {
+		// Arrange
+		middleware := &enforceHTTPSMiddleware{}
+		ctx, rec := newCtxWithHeader(http.MethodGet, testHTTPURL, header[0], header[1])
+		nextCalled := false
+
+		// Act
+		middleware.Invoke(ctx, func(c routing.RouteContext) { nextCalled = true })
+
+		// Assert
+		assert.False(t, nextCalled, header[0])
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, header[0])
+	}
+}
+
+func TestShouldRedirectGivenForwardedWithoutHTTPSProto(t *testing.T) {
+	for _, value := range []string{
+		`host="proto=https.example.com";proto=http`,
+		`proto=https-tunnel`,
+		`for=192.0.2.60;proto=http, for=198.51.100.17;proto=https`,
+	} {
+		// Arrange
+		middleware := &enforceHTTPSMiddleware{options: EnforceHTTPSOptions{TrustedProxies: []*net.IPNet{common.ParseNet(testPeer)}}}
+		ctx, rec := newCtxWithHeader(http.MethodGet, testHTTPURL, common.HeaderForwarded, value)
+		nextCalled := false
+
+		// Act
+		middleware.Invoke(ctx, func(c routing.RouteContext) { nextCalled = true })
+
+		// Assert
+		assert.False(t, nextCalled, value)
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code, value)
+	}
+}
+
 func TestShouldRedirectWhenXForwardedProtoIsHTTP(t *testing.T) {
 	// Arrange
 	middleware := &enforceHTTPSMiddleware{}
@@ -162,3 +206,138 @@ func TestShouldPreserveQueryParametersInRedirect(t *testing.T) {
 	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
 	assert.Equal(t, testBaseURL+"?param=value&other=123", rec.Header().Get(common.HeaderLocation))
 }
+
+func serveHTTP(rtr *router.Router, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldRedirectWithConfiguredStatus(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseEnforceHTTPS(rtr, WithRedirectStatus(http.StatusPermanentRedirect))
+	rtr.POST("/test", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := serveHTTP(rtr, httptest.NewRequestWithContext(context.Background(), http.MethodPost, testHTTPURL, nil))
+
+	// Assert
+	assert.Equal(t, http.StatusPermanentRedirect, rec.Code)
+	assert.Equal(t, testBaseURL, rec.Header().Get(common.HeaderLocation))
+}
+
+func TestShouldRejectWithProblemGivenWithReject(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseEnforceHTTPS(rtr, WithReject())
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := serveHTTP(rtr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, testHTTPURL, nil))
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get(common.HeaderLocation))
+	assert.Contains(t, rec.Body.String(), "HTTPS Required")
+}
+
+func TestShouldSendHSTSOnlyOverHTTPS(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseEnforceHTTPS(rtr, WithHSTS(2*365*24*time.Hour, true, true), WithExemptPaths("/healthz"))
+	rtr.GET("/healthz", func(c routing.RouteContext) { c.NoContent() })
+	secure := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "https://example.com/healthz", nil)
+	secure.TLS = &tls.ConnectionState{}
+
+	// Act
+	secureRec := serveHTTP(rtr, secure)
+	plainRec := serveHTTP(rtr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com/healthz", nil))
+
+	// Assert
+	assert.Equal(t, "max-age=63072000; includeSubDomains; preload", secureRec.Header().Get(common.HeaderStrictTransportSecurity))
+	assert.Equal(t, http.StatusNoContent, plainRec.Code)
+	assert.Empty(t, plainRec.Header().Get(common.HeaderStrictTransportSecurity))
+}
+
+func TestShouldMapPortsGivenWithPortMapping(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseEnforceHTTPS(rtr, WithPortMapping(8080, 8443), WithPortMapping(80, 443))
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+	cases := map[string]string{
+		"http://example.com:8080/test?q=1": "https://example.com:8443/test?q=1",
+		"http://example.com/test":          "https://example.com/test",
+		"http://example.com:80/test":       "https://example.com/test",
+		"http://[::1]:8080/test":           "https://[::1]:8443/test",
+		"http://example.com:9000/test":     "https://example.com:9000/test",
+	}
+
+	for source, want := range cases {
+		// Act
+		rec := serveHTTP(rtr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, source, nil))
+
+		// Assert
+		assert.Equal(t, want, rec.Header().Get(common.HeaderLocation), source)
+	}
+}
+
+func TestShouldExemptRoutesAndGroups(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseEnforceHTTPS(rtr)
+	rtr.GET("/healthz", func(c routing.RouteContext) { c.NoContent() }).WithoutHTTPSEnforcement()
+	rtr.NewRouteGroup("/probes").WithoutHTTPSEnforcement().GET("/ready", func(c routing.RouteContext) { c.NoContent() })
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+
+	for path, want := range map[string]int{"/healthz": http.StatusNoContent, "/probes/ready": http.StatusNoContent, "/test": http.StatusMovedPermanently} {
+		// Act
+		rec := serveHTTP(rtr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com"+path, nil))
+
+		// Assert
+		assert.Equal(t, want, rec.Code, path)
+	}
+}
+
+func TestShouldHonorForwardedProtoOnlyFromTrustedProxies(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseEnforceHTTPS(rtr, WithTrustedProxies("10.0.0.0/8", "not-an-ip"))
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+	fromProxy := httptest.NewRequestWithContext(context.Background(), http.MethodGet, testHTTPURL, nil)
+	fromProxy.RemoteAddr = "10.1.2.3:4567"
+	fromProxy.Header.Set(common.HeaderXForwardedProto, "https")
+	spoofed := httptest.NewRequestWithContext(context.Background(), http.MethodGet, testHTTPURL, nil)
+	spoofed.RemoteAddr = "203.0.113.7:4567"
+	spoofed.Header.Set(common.HeaderXForwardedProto, "https")
+
+	// Act
+	proxyRec := serveHTTP(rtr, fromProxy)
+	spoofedRec := serveHTTP(rtr, spoofed)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, proxyRec.Code)
+	assert.Equal(t, http.StatusMovedPermanently, spoofedRec.Code)
+}
+
+func TestShouldDeferToForwardedHeadersTrust(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	forwardheaders.UseForwardedHeaders(rtr, forwardheaders.WithTrustedProxies("10.0.0.0/8"))
+	UseEnforceHTTPS(rtr)
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+	fromProxy := httptest.NewRequestWithContext(context.Background(), http.MethodGet, testHTTPURL, nil)
+	fromProxy.RemoteAddr = "10.1.2.3:4567"
+	fromProxy.Header.Set(common.HeaderXForwardedProto, "https")
+	spoofed := httptest.NewRequestWithContext(context.Background(), http.MethodGet, testHTTPURL, nil)
+	spoofed.RemoteAddr = "203.0.113.7:4567"
+	spoofed.Header.Set(common.HeaderForwarded, "proto=https")
+
+	// Act
+	proxyRec := serveHTTP(rtr, fromProxy)
+	spoofedRec := serveHTTP(rtr, spoofed)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, proxyRec.Code)
+	assert.Equal(t, http.StatusMovedPermanently, spoofedRec.Code)
+}
@@ -1,9 +1,14 @@
 package exportcontrol
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fgrzl/mux/internal/common"
 	"github.com/fgrzl/mux/internal/router"
@@ -16,18 +21,88 @@ import (
 // ExportControlOptions configures the export control middleware.
 type ExportControlOptions struct {
 	DB *geoip2.Reader
+	// Locator resolves client addresses. It defaults to DB.
+	Locator Locator
+	// Policy is the active policy when PolicyFile is nil. It defaults to
+	// DefaultPolicy.
+	Policy *Policy
+	// PolicyFile supplies a policy that is reloaded at runtime.
+	PolicyFile *PolicyFile
+	// TrustedProxies are the peers whose X-Forwarded-For and X-Real-IP
+	// headers are honored. Without them only the peer address is located.
+	TrustedProxies []*net.IPNet
+	// Audit receives an event for each blocked request. It defaults to a
+	// warning log.
+	Audit func(ctx context.Context, e Event)
+	// Response writes the response to a blocked request. It defaults to a
+	// 403 problem.
+	Response func(c routing.RouteContext, e Event)
 }
 
 // ExportControlOption configures ExportControlOptions via functional options.
 type ExportControlOption func(*ExportControlOptions)
 
 // WithGeoIPDatabase sets the GeoIP database to use when determining the request's country.
+// City and Enterprise databases also resolve subdivisions.
 func WithGeoIPDatabase(db *geoip2.Reader) ExportControlOption {
 	return func(o *ExportControlOptions) {
 		o.DB = db
 	}
 }
 
+// WithLocator resolves client addresses with l instead of a GeoIP database.
+func WithLocator(l Locator) ExportControlOption {
+	return func(o *ExportControlOptions) {
+		o.Locator = l
+	}
+}
+
+// WithPolicy replaces DefaultPolicy. It panics if the policy is invalid.
+func WithPolicy(p Policy) ExportControlOption {
+	if _, err := compile(p); err != nil {
+		panic(err)
+	}
+	return func(o *ExportControlOptions) {
+		o.Policy = &p
+	}
+}
+
+// WithPolicyFile applies the policy of f, including later reloads.
+func WithPolicyFile(f *PolicyFile) ExportControlOption {
+	return func(o *ExportControlOptions) {
+		o.PolicyFile = f
+	}
+}
+
+// WithTrustedProxies honors X-Forwarded-For and X-Real-IP only from the
+// given CIDR ranges or IPs, using the right-most address that is not a
+// trusted proxy. Invalid entries are ignored.
+func WithTrustedProxies(proxies ...string) ExportControlOption {
+	return func(o *ExportControlOptions) {
+		for _, p := range proxies {
+			if n := common.ParseNet(strings.TrimSpace(p)); n != nil {
+				o.TrustedProxies = append(o.TrustedProxies, n)
+			}
+		}
+	}
+}
+
+// WithAudit sends an event for each blocked request to fn instead of the
+// log.
+func WithAudit(fn func(ctx context.Context, e Event)) ExportControlOption {
+	return func(o *ExportControlOptions) {
+		o.Audit = fn
+	}
+}
+
+// WithResponse writes the response to blocked requests with fn, such as a
+// 451 Unavailable For Legal Reasons page.
+func WithResponse(fn func(c routing.RouteContext, e Event)) ExportControlOption {
+	return func(o *ExportControlOptions) {
+		o.Response = fn
+	}
+}
+
 // UseExportControl adds middleware that denies requests originating from restricted countries.
 func UseExportControl(rtr *router.Router, opts ...ExportControlOption) {
 	options := &ExportControlOptions{}
@@ -35,7 +110,37 @@ func UseExportControl(rtr *router.Router, opts ...ExportControlOption) {
 		opt(options)
 	}
 	// Register middleware using the exported API.
-	rtr.Use(&exportControlMiddleware{options: options})
+	rtr.Use(newExportControlMiddleware(options))
+}
+
+// ---- Audit Events ----
+
+// BlockReason says why a request was blocked.
+type BlockReason string
+
+const (
+	// ReasonDenied is a location listed by a ModeDeny policy.
+	ReasonDenied BlockReason = "denied"
+	// ReasonNotAllowed is a location not listed by a ModeAllow policy.
+	ReasonNotAllowed BlockReason = "not_allowed"
+	// ReasonUnknownLocation is a request whose location could not be
+	// determined under a fail-closed policy.
+	ReasonUnknownLocation BlockReason = "unknown_location"
+)
+
+// Event describes a blocked request.
+type Event struct {
+	Time     time.Time
+	ClientIP string
+	Location Location
+	Reason   BlockReason
+	// Rule is the policy entry that matched, such as "IR" or "UA-43". It is
+	// empty unless Reason is ReasonDenied.
+	Rule   string
+	Method string
+	Path   string
+	// Err is the lookup error behind ReasonUnknownLocation, if any.
+	Err error
 }
 
 // ---- Middleware ----
@@ -43,24 +148,153 @@ func UseExportControl(rtr *router.Router, opts ...ExportControlOption) {
 // exportControlMiddleware enforces export control restrictions based on GeoIP lookup.
 type exportControlMiddleware struct {
 	options *ExportControlOptions
+	locator Locator
+	policy  *compiledPolicy
+	// untrustedProxyOnce limits the untrusted proxy warning to one per
+	// middleware.
+	untrustedProxyOnce sync.Once
+}
+
+func newExportControlMiddleware(options *ExportControlOptions) *exportControlMiddleware {
+	m := &exportControlMiddleware{options: options, locator: options.Locator}
+	if m.locator == nil && options.DB != nil {
+		m.locator = newGeoIPLocator(options.DB)
+	}
+	if options.Policy != nil {
+		m.policy, _ = compile(*options.Policy)
+	}
+	return m
 }
 
 const restrictedMessage = "Access from your country is restricted due to export control policies."
 
-// Invoke implements the Middleware interface, denying access when the client IP resolves to a restricted country.
+// defaultPolicy is DefaultPolicy compiled.
+var defaultPolicy, _ = compile(DefaultPolicy())
+
+func (m *exportControlMiddleware) currentPolicy() *compiledPolicy {
+	if f := m.options.PolicyFile; f != nil {
+		if p := f.current(); p != nil {
+			return p
+		}
+	}
+	if m.policy != nil {
+		return m.policy
+	}
+	return defaultPolicy
+}
+
+// currentLocator falls back to the options for middleware built without
+// newExportControlMiddleware.
+func (m *exportControlMiddleware) currentLocator() Locator {
+	switch {
+	case m.locator != nil:
+		return m.locator
+	case m.options.Locator != nil:
+		return m.options.Locator
+	case m.options.DB != nil:
+		return newGeoIPLocator(m.options.DB)
+	}
+	return nil
+}
+
+// Invoke implements the Middleware interface, denying access when the client IP resolves to a restricted region.
 func (m *exportControlMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
-	ip := getRealIP(c.Request())
-	if ip != "" && m.options.DB != nil {
-		if parsed := net.ParseIP(ip); parsed != nil {
-			if record, err := m.options.DB.Country(parsed); err == nil {
-				if _, blocked := exportRestrictedCountries[record.Country.IsoCode]; blocked {
-					c.Forbidden(restrictedMessage)
-					return
-				}
+	policy := m.currentPolicy()
+	locator := m.currentLocator()
+	if locator == nil && !policy.failClosed {
+		next(c)
+		return
+	}
+	r := c.Request()
+	ip, ignored := clientIP(r, m.options.TrustedProxies)
+	parsed := net.ParseIP(ip)
+	internal := parsed != nil && (parsed.IsLoopback() || parsed.IsPrivate())
+	// Internal clients have no location; they are never blocked. An internal
+	// peer whose forwarded client address was ignored is an untrusted proxy
+	// rather than the client, so its location is unknown.
+	if internal && !ignored {
+		next(c)
+		return
+	}
+
+	e := Event{ClientIP: ip, Method: r.Method, Path: r.URL.Path}
+	var err error
+	switch {
+	case internal:
+		err = errUntrustedProxy
+	case parsed == nil || locator == nil:
+		err = errUnlocated
+	default:
+		e.Location, err = locator.Locate(parsed)
+	}
+	switch {
+	case err != nil || e.Location.Country == "":
+		if !policy.failClosed {
+			if errors.Is(err, errUntrustedProxy) {
+				m.warnUntrustedProxy(c, ip)
 			}
+			next(c)
+			return
+		}
+		e.Reason, e.Err = ReasonUnknownLocation, err
+	case policy.allow:
+		if policy.match(e.Location) != "" {
+			next(c)
+			return
 		}
+		e.Reason = ReasonNotAllowed
+	default:
+		if e.Rule = policy.match(e.Location); e.Rule == "" {
+			next(c)
+			return
+		}
+		e.Reason = ReasonDenied
 	}
-	next(c)
+	m.block(c, e)
+}
+
+// warnUntrustedProxy tells operators, once, that a private peer forwarded
+// client addresses that were ignored, so those clients are not located.
+func (m *exportControlMiddleware) warnUntrustedProxy(ctx context.Context, peer string) {
+	m.untrustedProxyOnce.Do(func() {
+		slog.WarnContext(ctx, "export control ignored a forwarded client address from an untrusted private peer; configure trusted proxies so proxied clients are located",
+			"peer", peer)
+	})
+}
+
+func (m *exportControlMiddleware) block(c routing.RouteContext, e Event) {
+	e.Time = time.Now()
+	if m.options.Audit != nil {
+		m.options.Audit(c, e)
+	} else {
+		logEvent(c, e)
+	}
+	routing.ReportRejection(c, routing.RejectionForbidden)
+	if m.options.Response != nil {
+		m.options.Response(c, e)
+		return
+	}
+	c.Forbidden(restrictedMessage)
+}
+
+func logEvent(ctx context.Context, e Event) {
+	attrs := []any{
+		"client_ip", e.ClientIP,
+		"country", e.Location.Country,
+		"reason", string(e.Reason),
+		"method", e.Method,
+		"path", e.Path,
+	}
+	if len(e.Location.Subdivisions) > 0 {
+		attrs = append(attrs, "subdivisions", e.Location.Subdivisions)
+	}
+	if e.Rule != "" {
+		attrs = append(attrs, "rule", e.Rule)
+	}
+	if e.Err != nil {
+		attrs = append(attrs, "error", e.Err)
+	}
+	slog.WarnContext(ctx, "export control blocked request", attrs...)
 }
 
 // ---- Helpers ----
@@ -74,17 +308,55 @@ var exportRestrictedCountries = map[string]struct{}{
 	"RU": {},
 }
 
-// getRealIP attempts to determine the client's IP address from headers or the remote address.
-func getRealIP(r *http.Request) string {
-	if xff := r.Header.Get(common.HeaderXForwardedFor); xff != "" {
-		return strings.TrimSpace(strings.Split(xff, ",")[0])
+// errUnlocated reports an address that could not be parsed or located.
+var errUnlocated = errors.New("client address cannot be located")
+
+// errUntrustedProxy reports an internal peer forwarding a client address it
+// is not trusted to forward, such as a load balancer missing from the trusted
+// proxies.
+var errUntrustedProxy = errors.New("forwarded client address from an untrusted internal proxy was ignored; configure trusted proxies")
+
+// clientIP returns the address to locate. Forwarded headers are only read
+// when the peer is a trusted proxy and UseForwardedHeaders has not already
+// resolved the client address into RemoteAddr. ignored reports that the
+// request carried a forwarded client address that was not used.
+func clientIP(r *http.Request, trusted []*net.IPNet) (ip string, ignored bool) {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
 	}
-	if xrip := r.Header.Get(common.HeaderXRealIP); xrip != "" {
-		return xrip
+	xff := r.Header.Get(common.HeaderXForwardedFor)
+	xrip := strings.TrimSpace(r.Header.Get(common.HeaderXRealIP))
+	forwarded := xff != "" || xrip != "" || r.Header.Get(common.HeaderForwarded) != ""
+	if routing.ForwardedHeadersHandled(r.Context()) {
+		return peer, forwarded && routing.ForwardedHeadersIgnored(r.Context())
 	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err == nil {
-		return host
+	if !contains(trusted, net.ParseIP(peer)) {
+		return peer, forwarded
+	}
+	if xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if !contains(trusted, net.ParseIP(hop)) || i == 0 {
+				return hop, false
+			}
+		}
+	}
+	if xrip != "" {
+		return xrip, false
+	}
+	return peer, false
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
 	}
-	return r.RemoteAddr
+	return false
 }
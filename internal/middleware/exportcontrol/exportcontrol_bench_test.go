@@ -73,8 +73,8 @@ func BenchmarkExportControlRouterPipeline(b *testing.B) {
 	middlewarebench.BenchmarkRouterPipelines(b, setupRouter, cases, http.MethodGet, benchURL)
 }
 
-// BenchmarkGetRealIP measures the IP extraction helper performance.
-func BenchmarkGetRealIP(b *testing.B) {
+// BenchmarkClientIP measures the IP extraction helper performance.
+func BenchmarkClientIP(b *testing.B) {
 	b.Run("RemoteAddr", func(b *testing.B) {
 		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, benchURL, nil)
 		req.RemoteAddr = benchRemoteAddr
@@ -82,7 +82,7 @@ func BenchmarkGetRealIP(b *testing.B) {
 		b.ReportAllocs()
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			_ = getRealIP(req)
+			_, _ = clientIP(req, trustedProxies)
 		}
 	})
 
@@ -94,7 +94,7 @@ func BenchmarkGetRealIP(b *testing.B) {
 		b.ReportAllocs()
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			_ = getRealIP(req)
+			_, _ = clientIP(req, trustedProxies)
 		}
 	})
 
@@ -106,7 +106,7 @@ func BenchmarkGetRealIP(b *testing.B) {
 		b.ReportAllocs()
 		b.ResetTimer()
 		for i := 0; i < b.N; i++ {
-			_ = getRealIP(req)
+			_, _ = clientIP(req, trustedProxies)
 		}
 	})
 }
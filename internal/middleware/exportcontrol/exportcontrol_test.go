@@ -2,6 +2,7 @@ package exportcontrol
 
 import (
 	"context"
+	"net"
 
 	"net/http"
 	"net/http/httptest"
@@ -26,6 +27,9 @@ const (
 	invalidIP          = "invalid-ip"
 )
 
+// trustedProxies covers the proxies in front of the test clients.
+var trustedProxies = []*net.IPNet{common.ParseNet("127.0.0.0/8"), common.ParseNet("192.168.0.0/16")}
+
 var (
 	expectedCountries      = []string{"IR", "KP", "SY", "CU", "RU"}
 	nonRestrictedCountries = []string{"US", "CA", "GB", "FR", "DE"}
@@ -116,7 +120,7 @@ func TestShouldReturnXForwardedForGivenGetRealIP(t *testing.T) {
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, forwardedForIP, ip) // Should return first IP from X-Forwarded-For
@@ -130,7 +134,7 @@ func TestShouldReturnXRealIPGivenGetRealIP(t *testing.T) {
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, realIP, ip) // Should return X-Real-IP
@@ -145,7 +149,7 @@ func TestShouldPreferXForwardedForGivenBothHeadersWhenGettingRealIP(t *testing.T
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, forwardedForIP, ip) // Should prefer X-Forwarded-For
@@ -158,7 +162,7 @@ func TestShouldReturnRemoteAddrGivenNoHeadersWhenGettingRealIP(t *testing.T) {
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, loopbackIP, ip) // Should extract IP from RemoteAddr
@@ -171,7 +175,7 @@ func TestShouldReturnRemoteAddrGivenCannotSplitWhenGettingRealIP(t *testing.T) {
 	req.RemoteAddr = loopbackIP // No port
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, loopbackIP, ip) // Should return as-is when can't split
@@ -185,7 +189,7 @@ func TestShouldHandleXForwardedForWithSpacesGivenGetRealIP(t *testing.T) {
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, "203.0.113.1", ip) // Should trim spaces
@@ -244,7 +248,7 @@ func TestShouldHandleEmptyXForwardedFor(t *testing.T) {
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, realIP, ip) // Should fall back to X-Real-IP
@@ -258,7 +262,7 @@ func TestShouldHandleEmptyXRealIP(t *testing.T) {
 	req.RemoteAddr = loopbackAddr
 
 	// Act
-	ip := getRealIP(req)
+	ip, _ := clientIP(req, trustedProxies)
 
 	// Assert
 	assert.Equal(t, loopbackIP, ip) // Should fall back to RemoteAddr
@@ -0,0 +1,63 @@
+package exportcontrol
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is where a client address is located.
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code, or "" when unknown.
+	Country string
+	// Subdivisions are ISO 3166-2 codes, such as "UA-43", from largest to
+	// smallest.
+	Subdivisions []string
+}
+
+// Locator resolves client addresses to locations.
+type Locator interface {
+	Locate(ip net.IP) (Location, error)
+}
+
+// LocatorFunc adapts a function to a Locator.
+type LocatorFunc func(ip net.IP) (Location, error)
+
+// Locate implements Locator.
+func (f LocatorFunc) Locate(ip net.IP) (Location, error) {
+	return f(ip)
+}
+
+// geoIPLocator locates addresses with a MaxMind database. City and
+// Enterprise databases also resolve subdivisions.
+type geoIPLocator struct {
+	db   *geoip2.Reader
+	city bool
+}
+
+func newGeoIPLocator(db *geoip2.Reader) *geoIPLocator {
+	kind := db.Metadata().DatabaseType
+	return &geoIPLocator{db: db, city: strings.Contains(kind, "City") || strings.Contains(kind, "Enterprise")}
+}
+
+func (l *geoIPLocator) Locate(ip net.IP) (Location, error) {
+	if !l.city {
+		record, err := l.db.Country(ip)
+		if err != nil {
+			return Location{}, err
+		}
+		return Location{Country: record.Country.IsoCode}, nil
+	}
+	record, err := l.db.City(ip)
+	if err != nil {
+		return Location{}, err
+	}
+	loc := Location{Country: record.Country.IsoCode}
+	for _, sub := range record.Subdivisions {
+		if sub.IsoCode != "" && loc.Country != "" {
+			loc.Subdivisions = append(loc.Subdivisions, loc.Country+"-"+sub.IsoCode)
+		}
+	}
+	return loc, nil
+}
@@ -0,0 +1,248 @@
+package exportcontrol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fgrzl/mux/internal/filewatch"
+)
+
+// PolicyMode selects whether a policy lists blocked or allowed regions.
+type PolicyMode string
+
+const (
+	// ModeDeny blocks requests from the listed regions.
+	ModeDeny PolicyMode = "deny"
+	// ModeAllow blocks requests from every region that is not listed.
+	ModeAllow PolicyMode = "allow"
+)
+
+// Policy lists the regions requests are blocked or allowed from. Files use
+// the JSON field names.
+type Policy struct {
+	// Mode is ModeDeny or ModeAllow. Empty means ModeDeny.
+	Mode PolicyMode `json:"mode,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes, such as "IR".
+	Countries []string `json:"countries,omitempty"`
+	// Subdivisions are ISO 3166-2 codes, such as "UA-43" for Crimea. They
+	// need a GeoIP City or Enterprise database.
+	Subdivisions []string `json:"subdivisions,omitempty"`
+	// FailClosed blocks requests whose location cannot be determined
+	// instead of letting them through.
+	FailClosed bool `json:"failClosed,omitempty"`
+}
+
+// DefaultPolicy returns the built-in policy: the comprehensively sanctioned
+// countries, failing open.
+func DefaultPolicy() Policy {
+	countries := make([]string, 0, len(exportRestrictedCountries))
+	for code := range exportRestrictedCountries {
+		countries = append(countries, code)
+	}
+	slices.Sort(countries)
+	return Policy{Mode: ModeDeny, Countries: countries}
+}
+
+// ParsePolicy decodes and validates a JSON policy.
+func ParsePolicy(data []byte) (Policy, error) {
+	var p Policy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return Policy{}, fmt.Errorf("export control policy: %w", err)
+	}
+	if _, err := compile(p); err != nil {
+		return Policy{}, err
+	}
+	return p, nil
+}
+
+// LoadPolicy reads and validates the JSON policy at path.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	return ParsePolicy(data)
+}
+
+// compiledPolicy is a validated policy with normalized lookup sets.
+type compiledPolicy struct {
+	allow        bool
+	failClosed   bool
+	countries    map[string]struct{}
+	subdivisions map[string]struct{}
+}
+
+func compile(p Policy) (*compiledPolicy, error) {
+	c := &compiledPolicy{
+		failClosed:   p.FailClosed,
+		countries:    make(map[string]struct{}, len(p.Countries)),
+		subdivisions: make(map[string]struct{}, len(p.Subdivisions)),
+	}
+	switch p.Mode {
+	case ModeDeny, "":
+	case ModeAllow:
+		c.allow = true
+	default:
+		return nil, fmt.Errorf("export control policy: unknown mode %q", p.Mode)
+	}
+	for _, code := range p.Countries {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) != 2 || !isAlnum(code) {
+			return nil, fmt.Errorf("export control policy: invalid country code %q", code)
+		}
+		c.countries[code] = struct{}{}
+	}
+	for _, code := range p.Subdivisions {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		country, sub, ok := strings.Cut(code, "-")
+		if !ok || len(country) != 2 || !isAlnum(country) || sub == "" || len(sub) > 3 || !isAlnum(sub) {
+			return nil, fmt.Errorf("export control policy: invalid subdivision code %q", code)
+		}
+		c.subdivisions[code] = struct{}{}
+	}
+	return c, nil
+}
+
+func isAlnum(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns the entry of the policy matching loc: a country code, a
+// subdivision code, or "" when none does.
+func (c *compiledPolicy) match(loc Location) string {
+	for _, sub := range loc.Subdivisions {
+		if _, ok := c.subdivisions[sub]; ok {
+			return sub
+		}
+	}
+	if _, ok := c.countries[loc.Country]; ok {
+		return loc.Country
+	}
+	return ""
+}
+
+// ---- Policy File ----
+
+// PolicyFileOptions configures a PolicyFile.
+type PolicyFileOptions struct {
+	// ReloadSignals reload the file. The default is SIGHUP.
+	ReloadSignals []os.Signal
+	// ReloadInterval reloads the file when its modification time changes,
+	// checked every interval. Zero disables polling.
+	ReloadInterval time.Duration
+}
+
+// PolicyFileOption is a function type for configuring policy file options.
+type PolicyFileOption func(*PolicyFileOptions)
+
+// WithReloadSignals sets the signals that reload the file. Passing none
+// disables reloading on signals.
+func WithReloadSignals(signals ...os.Signal) PolicyFileOption {
+	return func(o *PolicyFileOptions) {
+		o.ReloadSignals = signals
+	}
+}
+
+// WithReloadInterval reloads the file when it changes, checked every d.
+func WithReloadInterval(d time.Duration) PolicyFileOption {
+	return func(o *PolicyFileOptions) {
+		o.ReloadInterval = max(d, 0)
+	}
+}
+
+// PolicyFile is a Policy loaded from a JSON file that can be reloaded while
+// serving. A reload that fails keeps the previous policy. It is safe for
+// concurrent use.
+type PolicyFile struct {
+	path    string
+	options PolicyFileOptions
+
+	mu       sync.Mutex
+	modTime  time.Time
+	policy   Policy
+	compiled atomic.Pointer[compiledPolicy]
+
+	watcher *filewatch.Watcher
+}
+
+// OpenPolicyFile loads the policy at path. Close stops reloading.
+func OpenPolicyFile(path string, opts ...PolicyFileOption) (*PolicyFile, error) {
+	o := PolicyFileOptions{ReloadSignals: []os.Signal{syscall.SIGHUP}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	f := &PolicyFile{path: path, options: o}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	f.watcher = filewatch.Start(filewatch.Options{
+		Signals:  o.ReloadSignals,
+		Path:     path,
+		Interval: o.ReloadInterval,
+		ModTime:  f.modTime,
+	}, f.reloadLogged)
+	return f, nil
+}
+
+func (f *PolicyFile) reloadLogged() {
+	if err := f.Reload(); err != nil {
+		slog.Error("failed to reload export control policy", "path", f.path, "error", err)
+		return
+	}
+	slog.Info("reloaded export control policy", "path", f.path)
+}
+
+// Reload reads the file again. On error the previous policy stays active.
+func (f *PolicyFile) Reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	p, err := LoadPolicy(f.path)
+	if err != nil {
+		return err
+	}
+	compiled, err := compile(p)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.modTime = info.ModTime()
+	f.policy = p
+	f.compiled.Store(compiled)
+	return nil
+}
+
+// Policy returns the active policy.
+func (f *PolicyFile) Policy() Policy {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.policy
+}
+
+// Close stops reloading. The last policy stays active.
+func (f *PolicyFile) Close() error {
+	f.watcher.Stop()
+	return nil
+}
+
+func (f *PolicyFile) current() *compiledPolicy {
+	return f.compiled.Load()
+}
@@ -0,0 +1,330 @@
+package exportcontrol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/middleware/forwardheaders"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLocations maps documentation addresses to locations.
+var testLocations = map[string]Location{
+	"198.51.100.1": {Country: "IR"},
+	"198.51.100.2": {Country: "UA", Subdivisions: []string{"UA-43"}},
+	"198.51.100.3": {Country: "UA", Subdivisions: []string{"UA-30"}},
+	"198.51.100.4": {Country: "US"},
+	"198.51.100.5": {},
+}
+
+var testLocator = LocatorFunc(func(ip net.IP) (Location, error) {
+	if loc, ok := testLocations[ip.String()]; ok {
+		return loc, nil
+	}
+	return Location{}, errors.New("address not found")
+})
+
+func newPolicyRouter(opts ...ExportControlOption) *router.Router {
+	rtr := router.NewRouter()
+	UseExportControl(rtr, append([]ExportControlOption{WithLocator(testLocator)}, opts...)...)
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+	return rtr
+}
+
+func requestFrom(rtr *router.Router, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/test", nil)
+	req.RemoteAddr = remoteAddr + ":1234"
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldBlockCountriesGivenDefaultPolicy(t *testing.T) {
+	// Arrange
+	var events []Event
+	rtr := newPolicyRouter(WithAudit(func(_ context.Context, e Event) { events = append(events, e) }))
+
+	// Act
+	iran := requestFrom(rtr, "198.51.100.1", nil)
+	crimea := requestFrom(rtr, "198.51.100.2", nil)
+	us := requestFrom(rtr, "198.51.100.4", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, iran.Code)
+	assert.Equal(t, http.StatusNoContent, crimea.Code)
+	assert.Equal(t, http.StatusNoContent, us.Code)
+	require.Len(t, events, 1)
+	assert.Equal(t, "IR", events[0].Rule)
+	assert.Equal(t, ReasonDenied, events[0].Reason)
+	assert.Equal(t, "198.51.100.1", events[0].ClientIP)
+	assert.Equal(t, "/test", events[0].Path)
+	assert.False(t, events[0].Time.IsZero())
+	assert.Empty(t, DefaultPolicy().Subdivisions)
+}
+
+func TestShouldBlockSubdivisionsGivenPolicy(t *testing.T) {
+	// Arrange
+	var events []Event
+	rtr := newPolicyRouter(
+		WithPolicy(Policy{Countries: []string{"IR"}, Subdivisions: []string{"ua-43", "UA-40"}}),
+		WithAudit(func(_ context.Context, e Event) { events = append(events, e) }),
+	)
+
+	// Act
+	crimea := requestFrom(rtr, "198.51.100.2", nil)
+	kyiv := requestFrom(rtr, "198.51.100.3", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, crimea.Code)
+	assert.Equal(t, http.StatusNoContent, kyiv.Code)
+	require.Len(t, events, 1)
+	assert.Equal(t, "UA-43", events[0].Rule)
+	assert.Equal(t, []string{"UA-43"}, events[0].Location.Subdivisions)
+}
+
+func TestShouldBlockUnlistedRegionsGivenAllowlist(t *testing.T) {
+	// Arrange
+	var events []Event
+	rtr := newPolicyRouter(
+		WithPolicy(Policy{Mode: ModeAllow, Countries: []string{"us"}, Subdivisions: []string{"UA-30"}}),
+		WithAudit(func(_ context.Context, e Event) { events = append(events, e) }),
+	)
+
+	// Act
+	us := requestFrom(rtr, "198.51.100.4", nil)
+	kyiv := requestFrom(rtr, "198.51.100.3", nil)
+	crimea := requestFrom(rtr, "198.51.100.2", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, us.Code)
+	assert.Equal(t, http.StatusNoContent, kyiv.Code)
+	assert.Equal(t, http.StatusForbidden, crimea.Code)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReasonNotAllowed, events[0].Reason)
+	assert.Empty(t, events[0].Rule)
+}
+
+func TestShouldBlockUnknownLocationsGivenFailClosed(t *testing.T) {
+	// Arrange
+	var events []Event
+	audit := WithAudit(func(_ context.Context, e Event) { events = append(events, e) })
+	open := newPolicyRouter(audit)
+	closed := newPolicyRouter(audit, WithPolicy(Policy{Countries: []string{"IR"}, FailClosed: true}))
+
+	// Act
+	openMissing := requestFrom(open, "203.0.113.9", nil)
+	closedMissing := requestFrom(closed, "203.0.113.9", nil)
+	closedUnknown := requestFrom(closed, "198.51.100.5", nil)
+	closedPrivate := requestFrom(closed, "10.0.0.1", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, openMissing.Code)
+	assert.Equal(t, http.StatusForbidden, closedMissing.Code)
+	assert.Equal(t, http.StatusForbidden, closedUnknown.Code)
+	assert.Equal(t, http.StatusNoContent, closedPrivate.Code)
+	require.Len(t, events, 2)
+	assert.Equal(t, ReasonUnknownLocation, events[0].Reason)
+	assert.Error(t, events[0].Err)
+}
+
+func TestShouldFailClosedGivenNoLocator(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseExportControl(rtr, WithPolicy(Policy{FailClosed: true}))
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := requestFrom(rtr, "198.51.100.4", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestShouldIgnoreForwardedForGivenUntrustedPeer(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter(WithTrustedProxies("10.0.0.0/8"))
+	spoof := map[string]string{forwardedForHeader: "198.51.100.4"}
+	proxied := map[string]string{forwardedForHeader: "198.51.100.1, 10.0.0.7"}
+
+	// Act
+	spoofed := requestFrom(rtr, "198.51.100.1", spoof)
+	fromProxy := requestFrom(rtr, "10.0.0.2", proxied)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, spoofed.Code)
+	assert.Equal(t, http.StatusForbidden, fromProxy.Code)
+}
+
+func TestShouldBlockPrivatePeerGivenIgnoredForwardedForWhenFailClosed(t *testing.T) {
+	// Arrange
+	var events []Event
+	rtr := newPolicyRouter(
+		WithPolicy(Policy{Countries: []string{"IR"}, FailClosed: true}),
+		WithAudit(func(_ context.Context, e Event) { events = append(events, e) }),
+	)
+
+	// Act
+	proxied := requestFrom(rtr, "10.0.0.2", map[string]string{forwardedForHeader: "198.51.100.4"})
+	internal := requestFrom(rtr, "10.0.0.3", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, proxied.Code)
+	assert.Equal(t, http.StatusNoContent, internal.Code)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReasonUnknownLocation, events[0].Reason)
+	assert.ErrorIs(t, events[0].Err, errUntrustedProxy)
+}
+
+func TestShouldAllowPrivatePeerAndWarnOnceGivenIgnoredForwardedForWhenFailOpen(t *testing.T) {
+	// Arrange
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	var events []Event
+	rtr := newPolicyRouter(WithAudit(func(_ context.Context, e Event) { events = append(events, e) }))
+
+	// Act
+	first := requestFrom(rtr, "10.0.0.2", map[string]string{forwardedForHeader: "198.51.100.1"})
+	second := requestFrom(rtr, "10.0.0.2", map[string]string{forwardedForHeader: "198.51.100.1"})
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, first.Code)
+	assert.Equal(t, http.StatusNoContent, second.Code)
+	assert.Empty(t, events)
+	assert.Equal(t, 1, strings.Count(logs.String(), "configure trusted proxies"))
+	assert.Contains(t, logs.String(), "peer=10.0.0.2")
+}
+
+func TestShouldBlockPrivatePeerGivenForwardedHeadersMiddlewareIgnoredIt(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	forwardheaders.UseForwardedHeaders(rtr, forwardheaders.WithTrustedProxies("192.168.0.0/16"))
+	UseExportControl(rtr, WithLocator(testLocator), WithPolicy(Policy{Countries: []string{"IR"}, FailClosed: true}))
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	untrusted := requestFrom(rtr, "10.0.0.2", map[string]string{forwardedForHeader: "198.51.100.4"})
+	trusted := requestFrom(rtr, "192.168.0.2", map[string]string{forwardedForHeader: "10.0.0.9"})
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, untrusted.Code)
+	assert.Equal(t, http.StatusNoContent, trusted.Code)
+}
+
+func TestShouldUseClientAddressFromForwardedHeadersMiddleware(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	forwardheaders.UseForwardedHeaders(rtr, forwardheaders.WithTrustedProxies("10.0.0.0/8"))
+	UseExportControl(rtr, WithLocator(testLocator), WithTrustedProxies("0.0.0.0/0"))
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	fromProxy := requestFrom(rtr, "10.0.0.2", map[string]string{forwardedForHeader: "198.51.100.1"})
+	spoofed := requestFrom(rtr, "198.51.100.1", map[string]string{forwardedForHeader: "198.51.100.4"})
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, fromProxy.Code)
+	assert.Equal(t, http.StatusForbidden, spoofed.Code)
+}
+
+func TestShouldWriteConfiguredResponse(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter(WithResponse(func(c routing.RouteContext, e Event) {
+		c.Response().WriteHeader(http.StatusUnavailableForLegalReasons)
+		_, _ = c.Response().Write([]byte(e.Rule))
+	}))
+
+	// Act
+	rec := requestFrom(rtr, "198.51.100.1", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, rec.Code)
+	assert.Equal(t, "IR", rec.Body.String())
+}
+
+func TestShouldRejectInvalidPolicies(t *testing.T) {
+	for _, body := range []string{
+		`{"mode":"block"}`,
+		`{"countries":["IRN"]}`,
+		`{"subdivisions":["UA43"]}`,
+		`{"countries":["IR"],"failOpen":true}`,
+		`not json`,
+	} {
+		// Act
+		_, err := ParsePolicy([]byte(body))
+
+		// Assert
+		assert.Error(t, err, body)
+	}
+	assert.Panics(t, func() { WithPolicy(Policy{Countries: []string{"Iran"}}) })
+}
+
+func TestShouldReloadPolicyFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"countries":["IR"]}`), 0o600))
+	file, err := OpenPolicyFile(path, WithReloadSignals())
+	require.NoError(t, err)
+	defer file.Close()
+	rtr := newPolicyRouter(WithPolicyFile(file))
+	before := requestFrom(rtr, "198.51.100.4", nil)
+
+	// Act
+	require.NoError(t, os.WriteFile(path, []byte(`{"mode":"deny","countries":["US"]}`), 0o600))
+	require.NoError(t, file.Reload())
+	after := requestFrom(rtr, "198.51.100.4", nil)
+	require.NoError(t, os.WriteFile(path, []byte(`{"countries":["USA"]}`), 0o600))
+	reloadErr := file.Reload()
+	kept := requestFrom(rtr, "198.51.100.4", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, before.Code)
+	assert.Equal(t, http.StatusForbidden, after.Code)
+	assert.Error(t, reloadErr)
+	assert.Equal(t, http.StatusForbidden, kept.Code)
+	assert.Equal(t, []string{"US"}, file.Policy().Countries)
+}
+
+func TestShouldReloadPolicyFileGivenReloadInterval(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"countries":["IR"]}`), 0o600))
+	file, err := OpenPolicyFile(path, WithReloadSignals(), WithReloadInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer file.Close()
+
+	// Act
+	require.NoError(t, os.WriteFile(path, []byte(`{"countries":["CU"]}`), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		countries := file.Policy().Countries
+		return len(countries) == 1 && countries[0] == "CU"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestShouldFailToOpenMissingPolicyFile(t *testing.T) {
+	// Act
+	_, err := OpenPolicyFile(filepath.Join(t.TempDir(), "missing.json"))
+
+	// Assert
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -143,10 +143,10 @@ func firstCSV(v string) string {
 	return strings.TrimSpace(v)
 }
 
-// parseForwardedRFC parses an RFC 7239 Forwarded header entry and extracts for/proto/host.
+// ParseForwardedRFC parses an RFC 7239 Forwarded header entry and extracts for/proto/host.
 // The header can be a list; we care about the first element (original client) for host/proto,
 // and the left-most for= as the client ip.
-func parseForwardedRFC(v string) (forAddr, proto, host string) {
+func ParseForwardedRFC(v string) (forAddr, proto, host string) {
 	if v == "" {
 		return "", "", ""
 	}
@@ -291,8 +291,14 @@ func (m *forwardedHeadersMiddleware) Invoke(c routing.RouteContext, next router.
 		m.opts.RespectForwarded = true
 	}
 
+	// Record that the headers were trust-gated so later middleware, such as
+	// HTTPS enforcement, do not honor them on their own.
+	apply := m.shouldApplyHeaders(r)
+	routing.MarkForwardedHeaders(c, apply)
+	r = c.Request()
+
 	// Trust-gate: if we don't trust headers from this sender, skip parsing entirely
-	if !m.shouldApplyHeaders(r) {
+	if !apply {
 		next(c)
 		return
 	}
@@ -337,7 +343,7 @@ func (m *forwardedHeadersMiddleware) shouldApplyHeaders(r *http.Request) bool {
 // extractForwarded centralizes header parsing and fallback logic, returning proto, host and clientIP.
 func (m *forwardedHeadersMiddleware) extractForwarded(r *http.Request, fwd, xproto, xhost, xport, xffRaw, xreal string) (proto, host, clientIP string) {
 	if m.opts.RespectForwarded && fwd != "" {
-		fip, p, h := parseForwardedRFC(fwd)
+		fip, p, h := ParseForwardedRFC(fwd)
 		clientIP, proto, host = fip, p, h
 	}
 	// Apply X-Forwarded-* fallbacks and determine client IP from headers
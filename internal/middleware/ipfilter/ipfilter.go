@@ -0,0 +1,287 @@
+package ipfilter
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fgrzl/mux/internal/filewatch"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// ---- Functional Options ----
+
+// IPFilterOptions configures an IPFilter.
+type IPFilterOptions struct {
+	// Name identifies the filter in decision logs, such as "admin".
+	Name string
+	// Rules are applied together with the rules of RulesFile.
+	Rules Rules
+	// RulesFile is a JSON Rules file that is reloaded at runtime.
+	RulesFile string
+	// ReloadSignals reload RulesFile. The default is SIGHUP.
+	ReloadSignals []os.Signal
+	// ReloadInterval reloads RulesFile when its modification time changes,
+	// checked every interval. Zero disables polling.
+	ReloadInterval time.Duration
+	// Logger receives decisions: denials at warning level and allowed
+	// requests at debug level. It defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// IPFilterOption is a function type for configuring IP filter options.
+type IPFilterOption func(*IPFilterOptions)
+
+// WithName names the filter in decision logs.
+func WithName(name string) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.Name = name
+	}
+}
+
+// WithAllow allows only clients in the given CIDR ranges or addresses.
+func WithAllow(cidrs ...string) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.Rules.Allow = append(o.Rules.Allow, cidrs...)
+	}
+}
+
+// WithDeny blocks clients in the given CIDR ranges or addresses.
+func WithDeny(cidrs ...string) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.Rules.Deny = append(o.Rules.Deny, cidrs...)
+	}
+}
+
+// WithRulesFile adds the rules of a JSON file, reloaded on SIGHUP by default.
+func WithRulesFile(path string) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.RulesFile = path
+	}
+}
+
+// WithReloadSignals sets the signals that reload the rules file. Passing
+// none disables reloading on signals.
+func WithReloadSignals(signals ...os.Signal) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.ReloadSignals = signals
+	}
+}
+
+// WithReloadInterval reloads the rules file when it changes, checked every d.
+func WithReloadInterval(d time.Duration) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.ReloadInterval = max(d, 0)
+	}
+}
+
+// WithLogger logs decisions to logger instead of slog.Default.
+func WithLogger(logger *slog.Logger) IPFilterOption {
+	return func(o *IPFilterOptions) {
+		o.Logger = logger
+	}
+}
+
+// ---- Decisions ----
+
+// Reason says why a request was allowed or blocked.
+type Reason string
+
+const (
+	// ReasonAllowed is a client in an allow range.
+	ReasonAllowed Reason = "allowed"
+	// ReasonUnlisted is a client outside the deny ranges of a filter without
+	// allow ranges.
+	ReasonUnlisted Reason = "unlisted"
+	// ReasonDenied is a client in a deny range.
+	ReasonDenied Reason = "denied"
+	// ReasonNotAllowed is a client outside the allow ranges.
+	ReasonNotAllowed Reason = "not_allowed"
+	// ReasonInvalidAddress is a client address that cannot be parsed while
+	// allow ranges are set.
+	ReasonInvalidAddress Reason = "invalid_address"
+)
+
+// Decision is the outcome of filtering a client address.
+type Decision struct {
+	Allowed bool
+	Reason  Reason
+	// Rule is the matching range, such as "10.0.0.0/8", or "" when none did.
+	Rule string
+}
+
+// ---- Middleware ----
+
+// IPFilter is middleware that allows or blocks requests by client address.
+// It uses the connection's peer address, which UseForwardedHeaders replaces
+// with the client address forwarded by trusted proxies. It is safe for
+// concurrent use.
+type IPFilter struct {
+	options IPFilterOptions
+
+	mu       sync.Mutex
+	modTime  time.Time
+	rules    Rules
+	compiled atomic.Pointer[compiledRules]
+
+	watcher *filewatch.Watcher
+}
+
+// UseIPFilter adds f to every route of rtr.
+func UseIPFilter(rtr *router.Router, f *IPFilter) {
+	rtr.Use(f)
+}
+
+// NewIPFilter builds a filter. It fails on invalid ranges or an unreadable
+// rules file. Close stops reloading the rules file.
+func NewIPFilter(opts ...IPFilterOption) (*IPFilter, error) {
+	o := IPFilterOptions{ReloadSignals: []os.Signal{syscall.SIGHUP}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	f := &IPFilter{options: o}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	if o.RulesFile == "" {
+		return f, nil
+	}
+	f.watcher = filewatch.Start(filewatch.Options{
+		Signals:  o.ReloadSignals,
+		Path:     o.RulesFile,
+		Interval: o.ReloadInterval,
+		ModTime:  f.modTime,
+	}, f.reloadLogged)
+	return f, nil
+}
+
+// Reload reads the rules file again. On error the previous rules stay
+// active.
+func (f *IPFilter) Reload() error {
+	rules := f.options.Rules
+	var modTime time.Time
+	if path := f.options.RulesFile; path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fileRules, err := LoadRules(path)
+		if err != nil {
+			return err
+		}
+		modTime = info.ModTime()
+		rules = Rules{
+			Allow: slices.Concat(rules.Allow, fileRules.Allow),
+			Deny:  slices.Concat(rules.Deny, fileRules.Deny),
+		}
+	}
+	compiled, err := compile(rules)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.modTime = modTime
+	f.rules = rules
+	f.compiled.Store(compiled)
+	return nil
+}
+
+// Rules returns the active rules, including those of the rules file.
+func (f *IPFilter) Rules() Rules {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rules
+}
+
+// Close stops reloading. The last rules stay active.
+func (f *IPFilter) Close() error {
+	f.watcher.Stop()
+	return nil
+}
+
+func (f *IPFilter) reloadLogged() {
+	if err := f.Reload(); err != nil {
+		f.logger().Error("failed to reload ip filter rules", "filter", f.options.Name, "path", f.options.RulesFile, "error", err)
+		return
+	}
+	f.logger().Info("reloaded ip filter rules", "filter", f.options.Name, "path", f.options.RulesFile)
+}
+
+func (f *IPFilter) logger() *slog.Logger {
+	if f.options.Logger != nil {
+		return f.options.Logger
+	}
+	return slog.Default()
+}
+
+// Decide applies the active rules to a client address.
+func (f *IPFilter) Decide(ip string) Decision {
+	rules := f.compiled.Load()
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		if rules.allow.len > 0 {
+			return Decision{Reason: ReasonInvalidAddress}
+		}
+		return Decision{Allowed: true, Reason: ReasonUnlisted}
+	}
+	return rules.decide(addr.WithZone(""))
+}
+
+// Invoke implements the Middleware interface, blocking requests whose client
+// address the rules do not allow.
+func (f *IPFilter) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	r := c.Request()
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	d := f.Decide(ip)
+	f.log(c, r, ip, d)
+	if d.Allowed {
+		next(c)
+		return
+	}
+	routing.ReportRejection(c, routing.RejectionForbidden)
+	instance := r.RequestURI
+	c.Problem(&routing.ProblemDetails{
+		Title:    "Forbidden",
+		Detail:   "Access from your network is not permitted.",
+		Status:   http.StatusForbidden,
+		Type:     routing.ProblemTypeAboutBlank,
+		Instance: &instance,
+	})
+}
+
+func (f *IPFilter) log(ctx context.Context, r *http.Request, ip string, d Decision) {
+	level, msg := slog.LevelDebug, "ip filter allowed request"
+	if !d.Allowed {
+		level, msg = slog.LevelWarn, "ip filter blocked request"
+	}
+	logger := f.logger()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("client_ip", ip),
+		slog.String("reason", string(d.Reason)),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+	}
+	if f.options.Name != "" {
+		attrs = append(attrs, slog.String("filter", f.options.Name))
+	}
+	if d.Rule != "" {
+		attrs = append(attrs, slog.String("rule", d.Rule))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,46 @@
+package ipfilter
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/middlewarebench"
+)
+
+// BenchmarkIPFilterInvoke measures the middleware overhead against a large
+// rule set.
+func BenchmarkIPFilterInvoke(b *testing.B) {
+	var deny []string
+	for i := range 10000 {
+		deny = append(deny, fmt.Sprintf("%d.%d.%d.0/24", 100+i%100, i/100%256, i%256))
+	}
+	f, err := NewIPFilter(
+		WithAllow("10.0.0.0/8", "2001:db8::/32"),
+		WithDeny(deny...),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("AllowedIPv4", func(b *testing.B) {
+		middlewarebench.BenchmarkMiddlewareInvoke(b, f.Invoke, func(r *http.Request) {
+			r.RemoteAddr = "10.1.2.3:12345"
+		})
+	})
+
+	b.Run("AllowedIPv6", func(b *testing.B) {
+		middlewarebench.BenchmarkMiddlewareInvoke(b, f.Invoke, func(r *http.Request) {
+			r.RemoteAddr = "[2001:db8::1]:12345"
+		})
+	})
+
+	b.Run("Denied", func(b *testing.B) {
+		middlewarebench.BenchmarkMiddlewareInvoke(b, f.Invoke, func(r *http.Request) {
+			r.RemoteAddr = "150.10.10.9:12345"
+		})
+	})
+}
@@ -0,0 +1,262 @@
+package ipfilter
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/middleware/forwardheaders"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTrie(t *testing.T, cidrs ...string) *prefixTrie {
+	t.Helper()
+	trie := &prefixTrie{}
+	for _, s := range cidrs {
+		p, err := parsePrefix(s)
+		require.NoError(t, err)
+		trie.insert(p)
+	}
+	return trie
+}
+
+func TestShouldMatchLongestPrefixGivenNestedRanges(t *testing.T) {
+	// Arrange
+	trie := newTrie(t, "10.0.0.0/8", "10.1.0.0/16", "2001:db8::/32", "2001:db8:1::/48", "192.0.2.7")
+
+	// Act & Assert
+	for addr, want := range map[string]string{
+		"10.2.3.4":          "10.0.0.0/8",
+		"10.1.3.4":          "10.1.0.0/16",
+		"::ffff:10.1.3.4":   "10.1.0.0/16",
+		"2001:db8:2::1":     "2001:db8::/32",
+		"2001:db8:1::1":     "2001:db8:1::/48",
+		"192.0.2.7":         "192.0.2.7/32",
+		"192.0.2.8":         "",
+		"11.0.0.1":          "",
+		"2001:db9::1":       "",
+		"::":                "",
+		"255.255.255.255":   "",
+		"2001:db8:1:ffff::": "2001:db8:1::/48",
+	} {
+		p, ok := trie.lookup(netip.MustParseAddr(addr))
+		assert.Equal(t, want != "", ok, addr)
+		if ok {
+			assert.Equal(t, want, p.String(), addr)
+		}
+	}
+	assert.Equal(t, 5, trie.len)
+}
+
+func TestShouldMatchEverythingGivenDefaultRoutes(t *testing.T) {
+	// Arrange
+	trie := newTrie(t, "0.0.0.0/0", "::/0")
+
+	// Act
+	v4, ok4 := trie.lookup(netip.MustParseAddr("203.0.113.1"))
+	v6, ok6 := trie.lookup(netip.MustParseAddr("2001:db8::1"))
+
+	// Assert
+	assert.True(t, ok4)
+	assert.Equal(t, "0.0.0.0/0", v4.String())
+	assert.True(t, ok6)
+	assert.Equal(t, "::/0", v6.String())
+}
+
+func TestShouldNormalizeMappedAndUnmaskedPrefixes(t *testing.T) {
+	for input, want := range map[string]string{
+		"10.1.2.3/8":          "10.0.0.0/8",
+		"::ffff:10.0.0.0/104": "10.0.0.0/8",
+		"::ffff:192.0.2.1":    "192.0.2.1/32",
+		" 2001:db8::1 ":       "2001:db8::1/128",
+	} {
+		// Act
+		p, err := parsePrefix(input)
+
+		// Assert
+		require.NoError(t, err, input)
+		assert.Equal(t, want, p.String(), input)
+	}
+}
+
+func TestShouldDecideByDenyThenAllowRules(t *testing.T) {
+	// Arrange
+	rules, err := compile(Rules{Allow: []string{"10.0.0.0/8", "2001:db8::/32"}, Deny: []string{"10.6.0.0/16"}})
+	require.NoError(t, err)
+
+	// Act
+	vpn := rules.decide(netip.MustParseAddr("10.1.0.1"))
+	abusive := rules.decide(netip.MustParseAddr("10.6.0.1"))
+	outside := rules.decide(netip.MustParseAddr("203.0.113.1"))
+	v6 := rules.decide(netip.MustParseAddr("2001:db8::5"))
+
+	// Assert
+	assert.Equal(t, Decision{Allowed: true, Reason: ReasonAllowed, Rule: "10.0.0.0/8"}, vpn)
+	assert.Equal(t, Decision{Reason: ReasonDenied, Rule: "10.6.0.0/16"}, abusive)
+	assert.Equal(t, Decision{Reason: ReasonNotAllowed}, outside)
+	assert.True(t, v6.Allowed)
+}
+
+func TestShouldAllowUnlistedClientsGivenOnlyDenyRules(t *testing.T) {
+	// Arrange
+	f, err := NewIPFilter(WithDeny("203.0.113.0/24"))
+	require.NoError(t, err)
+
+	// Act
+	denied := f.Decide("203.0.113.9")
+	unlisted := f.Decide("198.51.100.1")
+	invalid := f.Decide("not-an-ip")
+
+	// Assert
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, Decision{Allowed: true, Reason: ReasonUnlisted}, unlisted)
+	assert.True(t, invalid.Allowed)
+}
+
+func TestShouldBlockInvalidAddressGivenAllowRules(t *testing.T) {
+	// Arrange
+	f, err := NewIPFilter(WithAllow("10.0.0.0/8"))
+	require.NoError(t, err)
+
+	// Act
+	d := f.Decide("not-an-ip")
+
+	// Assert
+	assert.Equal(t, Decision{Reason: ReasonInvalidAddress}, d)
+}
+
+func TestShouldRejectInvalidRules(t *testing.T) {
+	for _, body := range []string{
+		`{"allow":["10.0.0.0/33"]}`,
+		`{"deny":["vpn"]}`,
+		`{"allowed":["10.0.0.0/8"]}`,
+		`not json`,
+	} {
+		// Act
+		_, err := ParseRules([]byte(body))
+
+		// Assert
+		assert.Error(t, err, body)
+	}
+	_, err := NewIPFilter(WithAllow("10.0.0.0/8", "10.0.0.1/40"))
+	assert.Error(t, err)
+}
+
+func serve(rtr *router.Router, path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldFilterGroupRoutesGivenRouteScopedFilter(t *testing.T) {
+	// Arrange
+	var logs bytes.Buffer
+	vpn, err := NewIPFilter(
+		WithName("admin"),
+		WithAllow("10.8.0.0/16", "fd00:8::/32"),
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	require.NoError(t, err)
+	rtr := router.NewRouter()
+	rtr.GET("/public", func(c routing.RouteContext) { c.NoContent() })
+	admin := rtr.NewRouteGroup("/admin").Use(vpn)
+	admin.GET("/users", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	fromVPN := serve(rtr, "/admin/users", "10.8.1.2:1234", nil)
+	fromVPN6 := serve(rtr, "/admin/users", "[fd00:8::2]:1234", nil)
+	outside := serve(rtr, "/admin/users", "203.0.113.5:1234", nil)
+	public := serve(rtr, "/public", "203.0.113.5:1234", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, fromVPN.Code)
+	assert.Equal(t, http.StatusNoContent, fromVPN6.Code)
+	assert.Equal(t, http.StatusForbidden, outside.Code)
+	assert.Equal(t, http.StatusNoContent, public.Code)
+	assert.Contains(t, logs.String(), `"msg":"ip filter blocked request","client_ip":"203.0.113.5","reason":"not_allowed"`)
+	assert.Contains(t, logs.String(), `"filter":"admin","rule":"10.8.0.0/16"`)
+}
+
+func TestShouldUseForwardedClientAddressGivenForwardedHeaders(t *testing.T) {
+	// Arrange
+	f, err := NewIPFilter(WithDeny("198.51.100.0/24"))
+	require.NoError(t, err)
+	rtr := router.NewRouter()
+	forwardheaders.UseForwardedHeaders(rtr, forwardheaders.WithTrustedProxies("10.0.0.0/8"))
+	UseIPFilter(rtr, f)
+	rtr.GET("/test", func(c routing.RouteContext) { c.NoContent() })
+	forwarded := map[string]string{"X-Forwarded-For": "198.51.100.7"}
+
+	// Act
+	proxied := serve(rtr, "/test", "10.0.0.2:1234", forwarded)
+	spoofed := serve(rtr, "/test", "203.0.113.1:1234", forwarded)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, proxied.Code)
+	assert.Equal(t, http.StatusNoContent, spoofed.Code)
+}
+
+func TestShouldReloadRulesFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "ip-rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["203.0.113.0/24"]}`), 0o600))
+	f, err := NewIPFilter(WithRulesFile(path), WithDeny("192.0.2.1"), WithReloadSignals())
+	require.NoError(t, err)
+	defer f.Close()
+	before := f.Decide("198.51.100.1")
+
+	// Act
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["198.51.100.0/24"]}`), 0o600))
+	require.NoError(t, f.Reload())
+	after := f.Decide("198.51.100.1")
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["198.51.100.0/99"]}`), 0o600))
+	reloadErr := f.Reload()
+	kept := f.Decide("198.51.100.1")
+
+	// Assert
+	assert.True(t, before.Allowed)
+	assert.False(t, after.Allowed)
+	assert.Error(t, reloadErr)
+	assert.False(t, kept.Allowed)
+	assert.False(t, f.Decide("192.0.2.1").Allowed)
+	assert.Equal(t, []string{"192.0.2.1", "198.51.100.0/24"}, f.Rules().Deny)
+}
+
+func TestShouldReloadRulesFileGivenReloadInterval(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "ip-rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"allow":["10.0.0.0/8"]}`), 0o600))
+	f, err := NewIPFilter(WithRulesFile(path), WithReloadSignals(), WithReloadInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer f.Close()
+
+	// Act
+	require.NoError(t, os.WriteFile(path, []byte(`{"allow":["172.16.0.0/12"]}`), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	// Assert
+	assert.Eventually(t, func() bool { return f.Decide("172.16.0.1").Allowed }, time.Second, 10*time.Millisecond)
+}
+
+func TestShouldFailGivenMissingRulesFile(t *testing.T) {
+	// Act
+	_, err := NewIPFilter(WithRulesFile(filepath.Join(t.TempDir(), "missing.json")))
+
+	// Assert
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,83 @@
+package ipfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+)
+
+// Rules are the CIDR ranges or single addresses a filter allows and denies.
+// Files use the JSON field names.
+type Rules struct {
+	// Allow, when not empty, blocks every client outside these ranges.
+	Allow []string `json:"allow,omitempty"`
+	// Deny blocks clients in these ranges, even when they are allowed.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// ParseRules decodes and validates JSON rules.
+func ParseRules(data []byte) (Rules, error) {
+	var r Rules
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil {
+		return Rules{}, fmt.Errorf("ip filter rules: %w", err)
+	}
+	if _, err := compile(r); err != nil {
+		return Rules{}, err
+	}
+	return r, nil
+}
+
+// LoadRules reads and validates the JSON rules at path.
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	return ParseRules(data)
+}
+
+// compiledRules are validated rules indexed for lookups.
+type compiledRules struct {
+	allow prefixTrie
+	deny  prefixTrie
+}
+
+func compile(rules ...Rules) (*compiledRules, error) {
+	c := &compiledRules{}
+	for _, r := range rules {
+		for _, s := range r.Allow {
+			p, err := parsePrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			c.allow.insert(p)
+		}
+		for _, s := range r.Deny {
+			p, err := parsePrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			c.deny.insert(p)
+		}
+	}
+	return c, nil
+}
+
+// decide applies the rules to addr. Deny rules take precedence over allow
+// rules.
+func (c *compiledRules) decide(addr netip.Addr) Decision {
+	if p, ok := c.deny.lookup(addr); ok {
+		return Decision{Reason: ReasonDenied, Rule: p.String()}
+	}
+	if c.allow.len == 0 {
+		return Decision{Allowed: true, Reason: ReasonUnlisted}
+	}
+	if p, ok := c.allow.lookup(addr); ok {
+		return Decision{Allowed: true, Reason: ReasonAllowed, Rule: p.String()}
+	}
+	return Decision{Reason: ReasonNotAllowed}
+}
@@ -0,0 +1,96 @@
+package ipfilter
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// prefixTrie is a binary trie of IP prefixes. Lookups walk one bit of the
+// address per level, so they cost at most 32 or 128 steps however many
+// prefixes are stored.
+type prefixTrie struct {
+	v4  trieNode
+	v6  trieNode
+	len int
+}
+
+type trieNode struct {
+	child [2]*trieNode
+	// prefix is set when a stored prefix ends at this node.
+	prefix netip.Prefix
+}
+
+func (t *prefixTrie) root(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+func (t *prefixTrie) insert(p netip.Prefix) {
+	p = p.Masked()
+	addr := p.Addr()
+	n := t.root(addr)
+	bytes, offset := addrBits(addr)
+	for i := range p.Bits() {
+		b := bit(bytes, offset+i)
+		if n.child[b] == nil {
+			n.child[b] = &trieNode{}
+		}
+		n = n.child[b]
+	}
+	if !n.prefix.IsValid() {
+		t.len++
+	}
+	n.prefix = p
+}
+
+// lookup returns the longest stored prefix containing addr.
+func (t *prefixTrie) lookup(addr netip.Addr) (netip.Prefix, bool) {
+	addr = addr.Unmap()
+	n := t.root(addr)
+	bytes, offset := addrBits(addr)
+	var match netip.Prefix
+	for i := 0; n != nil; i++ {
+		if n.prefix.IsValid() {
+			match = n.prefix
+		}
+		if i == addr.BitLen() {
+			break
+		}
+		n = n.child[bit(bytes, offset+i)]
+	}
+	return match, match.IsValid()
+}
+
+// addrBits returns the 16-byte form of addr and the bit its address starts
+// at, which skips the IPv4-mapped prefix of IPv4 addresses.
+func addrBits(addr netip.Addr) ([16]byte, int) {
+	if addr.Is4() {
+		return addr.As16(), 96
+	}
+	return addr.As16(), 0
+}
+
+func bit(b [16]byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// parsePrefix parses a CIDR range or a single address, which matches only
+// itself. IPv4-mapped IPv6 entries are stored as IPv4.
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("ip filter: invalid CIDR %q", s)
+	}
+	if p.Addr().Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p.Masked(), nil
+}
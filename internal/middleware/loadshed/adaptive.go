@@ -0,0 +1,79 @@
+package loadshed
+
+import (
+	"math"
+	"time"
+)
+
+// adaptive adjusts a concurrency limit from observed request latency.
+type adaptive interface {
+	// update returns the limit after a request completed in latency while
+	// inFlight requests, including it, were being served.
+	update(limit float64, latency time.Duration, inFlight int) float64
+	clamp(limit float64) float64
+}
+
+type bounds struct {
+	min, max float64
+}
+
+func (b bounds) clamp(limit float64) float64 {
+	return min(max(limit, b.min), b.max)
+}
+
+// aimd grows the limit by one per limit's worth of requests completing
+// within target, and multiplies it by backoff when a request is slower.
+type aimd struct {
+	bounds
+	target  time.Duration
+	backoff float64
+}
+
+func (a *aimd) update(limit float64, latency time.Duration, inFlight int) float64 {
+	if latency > a.target {
+		return limit * a.backoff
+	}
+	// Only grow when the limit is being used; an idle service says
+	// nothing about how much more it could take.
+	if float64(inFlight)*2 >= limit {
+		return limit + 1/limit
+	}
+	return limit
+}
+
+// gradient scales the limit by the ratio of the long-term to the recent
+// latency, so it shrinks as queues build inside the service and grows by a
+// small queue allowance while latency holds steady.
+type gradient struct {
+	bounds
+	long, short float64
+	tolerance   float64
+	smoothing   float64
+}
+
+// Smoothing factors for the latency averages.
+const (
+	gradientLongWeight  = 0.01
+	gradientShortWeight = 0.1
+)
+
+func (g *gradient) update(limit float64, latency time.Duration, inFlight int) float64 {
+	sample := float64(latency)
+	if g.long == 0 {
+		g.long, g.short = sample, sample
+	}
+	g.short += (sample - g.short) * gradientShortWeight
+	g.long += (sample - g.long) * gradientLongWeight
+	// Let the long-term average drift down quickly once the service
+	// recovers so it does not hold the limit low.
+	if g.long > g.short*2 {
+		g.long = g.short * 2
+	}
+
+	ratio := min(max(g.tolerance*g.long/g.short, 0.5), 1)
+	next := limit*ratio + math.Sqrt(limit)
+	if float64(inFlight)*2 < limit {
+		next = min(next, limit)
+	}
+	return limit*(1-g.smoothing) + next*g.smoothing
+}
@@ -0,0 +1,164 @@
+package loadshed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// numPriorities is the number of queued priority classes, PriorityLow
+// through PriorityHigh. PriorityCritical is never queued.
+const numPriorities = int(routing.PriorityCritical - routing.PriorityLow)
+
+// outcome explains why acquire did not admit a request.
+type outcome int
+
+const (
+	admitted outcome = iota
+	queueFull
+	timedOut
+	evicted
+	canceled
+)
+
+// waiter is a queued request. ready receives true when the request is
+// admitted and false when a higher priority request evicts it.
+type waiter struct {
+	ready chan bool
+}
+
+// limiter bounds in-flight requests, queueing the excess by priority.
+type limiter struct {
+	mu        sync.Mutex
+	limit     float64
+	inFlight  int
+	queues    [numPriorities][]*waiter
+	waiting   int
+	queueSize int
+	adapt     adaptive
+}
+
+func newLimiter(limit, queueSize int, adapt adaptive) *limiter {
+	l := &limiter{limit: float64(limit), queueSize: queueSize, adapt: adapt}
+	if adapt != nil {
+		l.limit = adapt.clamp(l.limit)
+	}
+	return l
+}
+
+// acquire waits until the request may proceed, the queue has no room, wait
+// elapses, or ctx ends. Waiting requests are admitted highest priority
+// first, and a full queue sheds its oldest lowest priority request to make
+// room for a higher priority one.
+func (l *limiter) acquire(ctx context.Context, priority routing.Priority, wait time.Duration) (outcome, bool) {
+	l.mu.Lock()
+	if priority >= routing.PriorityCritical {
+		l.inFlight++
+		l.mu.Unlock()
+		return admitted, false
+	}
+	if l.inFlight < l.capacity() && l.waiting == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return admitted, false
+	}
+	class := classOf(priority)
+	if l.waiting >= l.queueSize && !l.evictBelow(class) {
+		l.mu.Unlock()
+		return queueFull, false
+	}
+	w := &waiter{ready: make(chan bool, 1)}
+	l.queues[class] = append(l.queues[class], w)
+	l.waiting++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case ok := <-w.ready:
+		if ok {
+			return admitted, true
+		}
+		return evicted, true
+	case <-timer.C:
+		return l.abandon(w, class, timedOut), true
+	case <-ctx.Done():
+		return l.abandon(w, class, canceled), true
+	}
+}
+
+// abandon removes w from its queue after its wait ended. If w was admitted
+// or evicted concurrently, that outcome wins.
+func (l *limiter) abandon(w *waiter, class int, reason outcome) outcome {
+	l.mu.Lock()
+	for i, queued := range l.queues[class] {
+		if queued == w {
+			l.queues[class] = append(l.queues[class][:i], l.queues[class][i+1:]...)
+			l.waiting--
+			l.mu.Unlock()
+			return reason
+		}
+	}
+	l.mu.Unlock()
+	if <-w.ready {
+		return admitted
+	}
+	return evicted
+}
+
+// evictBelow sheds the oldest waiter of the lowest class below class. It
+// reports whether a waiter was evicted.
+func (l *limiter) evictBelow(class int) bool {
+	for lower := 0; lower < class; lower++ {
+		if len(l.queues[lower]) == 0 {
+			continue
+		}
+		w := l.queues[lower][0]
+		l.queues[lower] = l.queues[lower][1:]
+		l.waiting--
+		w.ready <- false
+		return true
+	}
+	return false
+}
+
+// release ends a request admitted by acquire. latency is how long it was
+// served and adjusts an adaptive limit; critical requests pass zero.
+func (l *limiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.adapt != nil && latency > 0 {
+		l.limit = l.adapt.clamp(l.adapt.update(l.limit, latency, l.inFlight))
+	}
+	l.inFlight--
+	for l.waiting > 0 && l.inFlight < l.capacity() {
+		for class := numPriorities - 1; class >= 0; class-- {
+			if len(l.queues[class]) == 0 {
+				continue
+			}
+			w := l.queues[class][0]
+			l.queues[class] = l.queues[class][1:]
+			l.waiting--
+			l.inFlight++
+			w.ready <- true
+			break
+		}
+	}
+}
+
+// capacity is the whole number of requests the limit allows at once.
+func (l *limiter) capacity() int {
+	return max(int(l.limit), 1)
+}
+
+func (l *limiter) stats() (limit, inFlight, waiting int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.capacity(), l.inFlight, l.waiting
+}
+
+func classOf(priority routing.Priority) int {
+	return int(min(max(priority, routing.PriorityLow), routing.PriorityHigh) - routing.PriorityLow)
+}
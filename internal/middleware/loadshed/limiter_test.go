@@ -0,0 +1,178 @@
+package loadshed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync starts acquire in a goroutine and waits until the request
+// is queued.
+func acquireAsync(t *testing.T, l *limiter, priority routing.Priority, wait time.Duration) <-chan outcome {
+	t.Helper()
+	class := classOf(priority)
+	l.mu.Lock()
+	before := len(l.queues[class])
+	l.mu.Unlock()
+	done := make(chan outcome, 1)
+	go func() {
+		result, _ := l.acquire(context.Background(), priority, wait)
+		done <- result
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.queues[class]) > before
+	}, time.Second, time.Millisecond)
+	return done
+}
+
+func TestShouldAdmitUpToLimitGivenFreeCapacity(t *testing.T) {
+	// Arrange
+	l := newLimiter(2, 0, nil)
+
+	// Act
+	first, _ := l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+	second, _ := l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+	third, queued := l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+
+	// Assert
+	assert.Equal(t, admitted, first)
+	assert.Equal(t, admitted, second)
+	assert.Equal(t, queueFull, third)
+	assert.False(t, queued)
+}
+
+func TestShouldAdmitCriticalRequestsGivenNoCapacity(t *testing.T) {
+	// Arrange
+	l := newLimiter(1, 0, nil)
+	_, _ = l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+
+	// Act
+	result, _ := l.acquire(context.Background(), routing.PriorityCritical, time.Second)
+
+	// Assert
+	assert.Equal(t, admitted, result)
+}
+
+func TestShouldAdmitHighestPriorityFirstGivenRelease(t *testing.T) {
+	// Arrange
+	l := newLimiter(1, 10, nil)
+	_, _ = l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+	low := acquireAsync(t, l, routing.PriorityLow, time.Second)
+	high := acquireAsync(t, l, routing.PriorityHigh, time.Second)
+
+	// Act
+	l.release(0)
+
+	// Assert
+	assert.Equal(t, admitted, <-high)
+	select {
+	case <-low:
+		t.Fatal("low priority request admitted before the high priority one finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.release(0)
+	assert.Equal(t, admitted, <-low)
+}
+
+func TestShouldEvictLowerPriorityGivenFullQueue(t *testing.T) {
+	// Arrange
+	l := newLimiter(1, 1, nil)
+	_, _ = l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+	low := acquireAsync(t, l, routing.PriorityLow, time.Second)
+
+	// Act
+	high := acquireAsync(t, l, routing.PriorityHigh, time.Second)
+	same, queued := l.acquire(context.Background(), routing.PriorityHigh, time.Second)
+
+	// Assert
+	assert.Equal(t, evicted, <-low)
+	assert.Equal(t, queueFull, same, "equal priorities do not evict each other")
+	assert.False(t, queued)
+	l.release(0)
+	assert.Equal(t, admitted, <-high)
+}
+
+func TestShouldTimeOutGivenNoCapacityWithinWait(t *testing.T) {
+	// Arrange
+	l := newLimiter(1, 1, nil)
+	_, _ = l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+
+	// Act
+	result, queued := l.acquire(context.Background(), routing.PriorityNormal, 10*time.Millisecond)
+
+	// Assert
+	assert.Equal(t, timedOut, result)
+	assert.True(t, queued)
+	_, _, waiting := l.stats()
+	assert.Zero(t, waiting)
+}
+
+func TestShouldStopWaitingGivenCanceledContext(t *testing.T) {
+	// Arrange
+	l := newLimiter(1, 1, nil)
+	_, _ = l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	result, _ := l.acquire(ctx, routing.PriorityNormal, time.Second)
+
+	// Assert
+	assert.Equal(t, canceled, result)
+}
+
+func TestShouldShrinkAndGrowGivenAIMD(t *testing.T) {
+	// Arrange
+	a := &aimd{bounds: newBounds(2, 100), target: 100 * time.Millisecond, backoff: aimdBackoff}
+
+	// Act
+	slow := a.update(50, 200*time.Millisecond, 50)
+	fast := a.update(50, 10*time.Millisecond, 50)
+	idle := a.update(50, 10*time.Millisecond, 5)
+
+	// Assert
+	assert.InDelta(t, 45, slow, 0.001)
+	assert.InDelta(t, 50.02, fast, 0.001)
+	assert.InDelta(t, 50, idle, 0.001, "an underused limit does not grow")
+	assert.Equal(t, 2.0, a.clamp(1))
+	assert.Equal(t, 100.0, a.clamp(1000))
+}
+
+func TestShouldShrinkGivenGradientLatencyRises(t *testing.T) {
+	// Arrange
+	g := &gradient{bounds: newBounds(1, 1000), tolerance: gradientTolerance, smoothing: gradientSmoothing}
+	limit := 100.0
+	for range 50 {
+		limit = g.clamp(g.update(limit, 10*time.Millisecond, int(limit)))
+	}
+	steady := limit
+
+	// Act
+	for range 50 {
+		limit = g.clamp(g.update(limit, 200*time.Millisecond, int(limit)))
+	}
+
+	// Assert
+	assert.Greater(t, steady, 100.0, "steady latency grows a busy limit")
+	assert.Less(t, limit, steady/2)
+}
+
+func TestShouldAdaptLimitGivenReleasedLatency(t *testing.T) {
+	// Arrange
+	l := newLimiter(10, 0, &aimd{bounds: newBounds(1, 20), target: time.Millisecond, backoff: 0.5})
+	_, _ = l.acquire(context.Background(), routing.PriorityNormal, time.Second)
+
+	// Act
+	l.release(time.Second)
+
+	// Assert
+	limit, inFlight, _ := l.stats()
+	assert.Equal(t, 5, limit)
+	assert.Zero(t, inFlight)
+}
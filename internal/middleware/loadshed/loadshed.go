@@ -0,0 +1,278 @@
+package loadshed
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// Defaults applied by NewLoadShedder.
+const (
+	DefaultMaxInFlight  = 1000
+	DefaultQueueSize    = 100
+	DefaultQueueTimeout = 500 * time.Millisecond
+	DefaultRetryAfter   = time.Second
+)
+
+// aimdBackoff is the factor the AIMD limit shrinks by after a slow request.
+const aimdBackoff = 0.9
+
+// gradientTolerance is how many times the long-term latency a recent
+// latency may reach before the gradient limit shrinks, and gradientSmoothing
+// how far each update moves the limit toward its new estimate.
+const (
+	gradientTolerance = 2.0
+	gradientSmoothing = 0.2
+)
+
+// ---- Functional Options ----
+
+// LoadSheddingOptions configures the load shedding middleware behavior.
+type LoadSheddingOptions struct {
+	// MaxInFlight bounds the requests served at once across the router, and
+	// is the starting point of an adaptive limit. Zero disables the global
+	// limit unless an adaptive limit is configured.
+	MaxInFlight int
+	// QueueSize bounds the requests waiting for each limit.
+	QueueSize int
+	// QueueTimeout is the longest a request waits before it is shed.
+	QueueTimeout time.Duration
+	// RetryAfter is sent with shed requests.
+	RetryAfter time.Duration
+	// Priority classifies requests, overriding the route's priority when it
+	// reports ok.
+	Priority func(c routing.RouteContext) (routing.Priority, bool)
+
+	adapt adaptive
+}
+
+// LoadSheddingOption is a function type for configuring load shedding options.
+type LoadSheddingOption func(*LoadSheddingOptions)
+
+// WithMaxInFlight bounds the requests served at once across the router. A
+// value <= 0 disables the global limit, leaving group and route limits.
+func WithMaxInFlight(n int) LoadSheddingOption {
+	return func(o *LoadSheddingOptions) {
+		o.MaxInFlight = max(n, 0)
+	}
+}
+
+// WithQueue bounds how many requests wait for capacity and for how long.
+// A size <= 0 sheds requests as soon as a limit is reached.
+func WithQueue(size int, timeout time.Duration) LoadSheddingOption {
+	return func(o *LoadSheddingOptions) {
+		o.QueueSize = max(size, 0)
+		if timeout > 0 {
+			o.QueueTimeout = timeout
+		}
+	}
+}
+
+// WithRetryAfter sets the Retry-After sent with shed requests.
+func WithRetryAfter(d time.Duration) LoadSheddingOption {
+	return func(o *LoadSheddingOptions) {
+		if d > 0 {
+			o.RetryAfter = d
+		}
+	}
+}
+
+// WithPriorityFunc classifies requests, overriding the route's priority
+// whenever fn reports ok.
+func WithPriorityFunc(fn func(c routing.RouteContext) (routing.Priority, bool)) LoadSheddingOption {
+	return func(o *LoadSheddingOptions) {
+		o.Priority = fn
+	}
+}
+
+// WithAIMD adapts the global limit between minLimit and maxLimit: it grows
+// additively while requests complete within target and shrinks
+// multiplicatively when they take longer.
+func WithAIMD(minLimit, maxLimit int, target time.Duration) LoadSheddingOption {
+	return func(o *LoadSheddingOptions) {
+		o.adapt = &aimd{bounds: newBounds(minLimit, maxLimit), target: target, backoff: aimdBackoff}
+	}
+}
+
+// WithGradient adapts the global limit between minLimit and maxLimit by
+// comparing recent latency with its long-term average, shrinking the limit
+// as requests queue up inside the service.
+func WithGradient(minLimit, maxLimit int) LoadSheddingOption {
+	return func(o *LoadSheddingOptions) {
+		o.adapt = &gradient{bounds: newBounds(minLimit, maxLimit), tolerance: gradientTolerance, smoothing: gradientSmoothing}
+	}
+}
+
+func newBounds(minLimit, maxLimit int) bounds {
+	lo := float64(max(minLimit, 1))
+	return bounds{min: lo, max: max(float64(maxLimit), lo)}
+}
+
+// ---- Middleware ----
+
+// Stats is a snapshot of the load shedder's state and counters.
+type Stats struct {
+	// Limit is the current global limit, or zero without one.
+	Limit int
+	// InFlight is the number of requests being served.
+	InFlight int
+	// Waiting is the number of requests queued for capacity.
+	Waiting int
+	// Admitted counts requests served.
+	Admitted uint64
+	// Queued counts requests that waited for capacity.
+	Queued uint64
+	// Shed counts requests rejected with 503.
+	Shed uint64
+}
+
+// LoadShedder bounds in-flight requests globally and per group, queueing the
+// excess by priority and shedding what cannot be served in time.
+type LoadShedder struct {
+	opts     LoadSheddingOptions
+	global   *limiter
+	groups   sync.Map // *routing.InFlightLimit -> *limiter
+	inFlight atomic.Int64
+	admitted atomic.Uint64
+	queued   atomic.Uint64
+	shed     atomic.Uint64
+}
+
+// UseLoadShedding adds load shedding middleware to the router.
+func UseLoadShedding(r *router.Router, opts ...LoadSheddingOption) {
+	r.Use(NewLoadShedder(opts...))
+}
+
+// NewLoadShedder constructs a LoadShedder with optional configuration.
+func NewLoadShedder(opts ...LoadSheddingOption) *LoadShedder {
+	o := LoadSheddingOptions{
+		MaxInFlight:  DefaultMaxInFlight,
+		QueueSize:    DefaultQueueSize,
+		QueueTimeout: DefaultQueueTimeout,
+		RetryAfter:   DefaultRetryAfter,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	m := &LoadShedder{opts: o}
+	switch {
+	case o.adapt != nil:
+		start := o.MaxInFlight
+		if start <= 0 {
+			start = math.MaxInt
+		}
+		m.global = newLimiter(start, o.QueueSize, o.adapt)
+	case o.MaxInFlight > 0:
+		m.global = newLimiter(o.MaxInFlight, o.QueueSize, nil)
+	}
+	return m
+}
+
+// Stats returns a snapshot of the load shedder's state and counters.
+func (m *LoadShedder) Stats() Stats {
+	s := Stats{
+		InFlight: int(m.inFlight.Load()),
+		Admitted: m.admitted.Load(),
+		Queued:   m.queued.Load(),
+		Shed:     m.shed.Load(),
+	}
+	if m.global != nil {
+		s.Limit, _, s.Waiting = m.global.stats()
+	}
+	m.groups.Range(func(_, value any) bool {
+		_, _, waiting := value.(*limiter).stats()
+		s.Waiting += waiting
+		return true
+	})
+	return s
+}
+
+const overloadedTitle = "Service Unavailable"
+const overloadedDetail = "The server is handling too many requests. Please try again later."
+
+func (m *LoadShedder) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	priority := routing.PriorityNormal
+	var group *routing.InFlightLimit
+	if opts := c.Options(); opts != nil {
+		priority = opts.Priority
+		group = opts.InFlight
+	}
+	if m.opts.Priority != nil {
+		if p, ok := m.opts.Priority(c); ok {
+			priority = p
+		}
+	}
+
+	// Wait for the group before the global limit so a request queued on a
+	// busy group does not hold global capacity.
+	deadline := time.Now().Add(m.opts.QueueTimeout)
+	if group != nil && group.Max > 0 {
+		l := m.groupLimiter(group)
+		if !m.enter(c, l, priority, deadline) {
+			return
+		}
+		defer l.release(0)
+	}
+	if m.global != nil {
+		if !m.enter(c, m.global, priority, deadline) {
+			return
+		}
+		start := time.Now()
+		defer func() {
+			var latency time.Duration
+			if priority < routing.PriorityCritical {
+				latency = max(time.Since(start), time.Nanosecond)
+			}
+			m.global.release(latency)
+		}()
+	}
+
+	m.admitted.Add(1)
+	m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	next(c)
+}
+
+// enter acquires l for the request, shedding it when that fails.
+func (m *LoadShedder) enter(c routing.RouteContext, l *limiter, priority routing.Priority, deadline time.Time) bool {
+	result, queued := l.acquire(c, priority, time.Until(deadline))
+	if queued {
+		m.queued.Add(1)
+	}
+	if result == admitted {
+		return true
+	}
+	m.shed.Add(1)
+	m.reject(c)
+	return false
+}
+
+func (m *LoadShedder) groupLimiter(group *routing.InFlightLimit) *limiter {
+	if l, ok := m.groups.Load(group); ok {
+		return l.(*limiter)
+	}
+	l, _ := m.groups.LoadOrStore(group, newLimiter(group.Max, m.opts.QueueSize, nil))
+	return l.(*limiter)
+}
+
+// reject writes the 503 problem with Retry-After.
+func (m *LoadShedder) reject(c routing.RouteContext) {
+	routing.ReportRejection(c, routing.RejectionLoadShed)
+	seconds := int64(math.Ceil(m.opts.RetryAfter.Seconds()))
+	c.Response().Header().Set(common.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+	instance := c.Request().RequestURI
+	c.Problem(&routing.ProblemDetails{
+		Title:    overloadedTitle,
+		Detail:   overloadedDetail,
+		Status:   http.StatusServiceUnavailable,
+		Type:     routing.ProblemTypeAboutBlank,
+		Instance: &instance,
+	})
+}
@@ -0,0 +1,243 @@
+package loadshed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gate holds requests in their handlers until it is opened.
+type gate struct {
+	entered chan struct{}
+	open    chan struct{}
+	once    sync.Once
+}
+
+func newGate() *gate {
+	return &gate{entered: make(chan struct{}, 100), open: make(chan struct{})}
+}
+
+func (g *gate) handler(c routing.RouteContext) {
+	g.entered <- struct{}{}
+	<-g.open
+	c.OK("done")
+}
+
+func (g *gate) release() { g.once.Do(func() { close(g.open) }) }
+
+func okHandler(c routing.RouteContext) { c.OK("ok") }
+
+func get(rtr *router.Router, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+// hold starts a request to path that blocks in g's handler.
+func hold(t *testing.T, rtr *router.Router, g *gate, path string) <-chan *httptest.ResponseRecorder {
+	t.Helper()
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- get(rtr, path) }()
+	select {
+	case <-g.entered:
+	case <-time.After(time.Second):
+		t.Fatal("request did not reach the handler")
+	}
+	return done
+}
+
+func TestShouldShedWithRetryAfterGivenGlobalLimitReached(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	shedder := NewLoadShedder(WithMaxInFlight(1), WithQueue(0, 0), WithRetryAfter(3*time.Second))
+	rtr.Use(shedder)
+	g := newGate()
+	t.Cleanup(g.release)
+	rtr.GET("/slow", g.handler)
+	rtr.GET("/fast", okHandler)
+	held := hold(t, rtr, g, "/slow")
+
+	// Act
+	rec := get(rtr, "/fast")
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), overloadedTitle)
+	g.release()
+	assert.Equal(t, http.StatusOK, (<-held).Code)
+	assert.Equal(t, Stats{Limit: 1, Admitted: 1, Shed: 1}, shedder.Stats())
+}
+
+func TestShouldServeQueuedRequestGivenCapacityFreesInTime(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	shedder := NewLoadShedder(WithMaxInFlight(1), WithQueue(1, time.Second))
+	rtr.Use(shedder)
+	g := newGate()
+	t.Cleanup(g.release)
+	rtr.GET("/slow", g.handler)
+	rtr.GET("/fast", okHandler)
+	held := hold(t, rtr, g, "/slow")
+
+	// Act
+	queued := make(chan *httptest.ResponseRecorder, 1)
+	go func() { queued <- get(rtr, "/fast") }()
+	require.Eventually(t, func() bool { return shedder.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	g.release()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, (<-held).Code)
+	assert.Equal(t, http.StatusOK, (<-queued).Code)
+	stats := shedder.Stats()
+	assert.Equal(t, uint64(2), stats.Admitted)
+	assert.Equal(t, uint64(1), stats.Queued)
+	assert.Zero(t, stats.Shed)
+}
+
+func TestShouldShedGivenQueueDeadlinePasses(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	shedder := NewLoadShedder(WithMaxInFlight(1), WithQueue(1, 10*time.Millisecond))
+	rtr.Use(shedder)
+	g := newGate()
+	t.Cleanup(g.release)
+	rtr.GET("/slow", g.handler)
+	rtr.GET("/fast", okHandler)
+	hold(t, rtr, g, "/slow")
+
+	// Act
+	rec := get(rtr, "/fast")
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	stats := shedder.Stats()
+	assert.Equal(t, uint64(1), stats.Queued)
+	assert.Equal(t, uint64(1), stats.Shed)
+}
+
+func TestShouldLimitGroupSeparatelyGivenGroupLimit(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	rtr.Use(NewLoadShedder(WithQueue(0, 0)))
+	g := newGate()
+	t.Cleanup(g.release)
+	reports := rtr.NewRouteGroup("/reports").WithMaxInFlight(1)
+	reports.GET("/slow", g.handler)
+	reports.GET("/fast", okHandler)
+	rtr.GET("/other", okHandler)
+	hold(t, rtr, g, "/reports/slow")
+
+	// Act
+	sameGroup := get(rtr, "/reports/fast")
+	otherRoute := get(rtr, "/other")
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, sameGroup.Code, "the group's routes share one budget")
+	assert.Equal(t, http.StatusOK, otherRoute.Code)
+}
+
+func TestShouldServeProbesGivenSaturatedLimit(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	rtr.Use(NewLoadShedder(WithMaxInFlight(1), WithQueue(0, 0)))
+	g := newGate()
+	t.Cleanup(g.release)
+	rtr.GET("/slow", g.handler)
+	rtr.Healthz()
+	rtr.Livez()
+	hold(t, rtr, g, "/slow")
+
+	// Act
+	healthz := get(rtr, "/healthz")
+	livez := get(rtr, "/livez")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, healthz.Code)
+	assert.Equal(t, http.StatusOK, livez.Code)
+}
+
+func TestShouldAdmitHighPriorityFirstGivenQueuedRequests(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	shedder := NewLoadShedder(WithMaxInFlight(1), WithQueue(10, time.Second))
+	rtr.Use(shedder)
+	g := newGate()
+	t.Cleanup(g.release)
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) routing.HandlerFunc {
+		return func(c routing.RouteContext) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			c.OK(name)
+		}
+	}
+	rtr.GET("/slow", g.handler)
+	rtr.GET("/batch", record("batch")).WithPriority(routing.PriorityLow)
+	rtr.GET("/admin", record("admin")).WithPriority(routing.PriorityHigh)
+	held := hold(t, rtr, g, "/slow")
+
+	// Act
+	var wg sync.WaitGroup
+	for i, path := range []string{"/batch", "/admin"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(rtr, path)
+		}()
+		require.Eventually(t, func() bool { return shedder.Stats().Waiting == i+1 }, time.Second, time.Millisecond)
+	}
+	g.release()
+	<-held
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, []string{"admin", "batch"}, order)
+}
+
+func TestShouldClassifyRequestsGivenPriorityFunc(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	rtr.Use(NewLoadShedder(
+		WithMaxInFlight(1),
+		WithQueue(0, 0),
+		WithPriorityFunc(func(c routing.RouteContext) (routing.Priority, bool) {
+			return routing.PriorityCritical, c.Request().Header.Get("X-Probe") != ""
+		}),
+	))
+	g := newGate()
+	t.Cleanup(g.release)
+	rtr.GET("/slow", g.handler)
+	rtr.GET("/fast", okHandler)
+	hold(t, rtr, g, "/slow")
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+	req.Header.Set("X-Probe", "1")
+	probe := httptest.NewRecorder()
+	rtr.ServeHTTP(probe, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, probe.Code)
+}
+
+func TestShouldStartAtMaxInFlightGivenAdaptiveLimit(t *testing.T) {
+	// Act
+	aimdShedder := NewLoadShedder(WithMaxInFlight(50), WithAIMD(10, 40, time.Second))
+	gradientShedder := NewLoadShedder(WithMaxInFlight(0), WithGradient(10, 200))
+	static := NewLoadShedder(WithMaxInFlight(0))
+
+	// Assert
+	assert.Equal(t, 40, aimdShedder.Stats().Limit, "the starting limit is clamped to the bounds")
+	assert.Equal(t, 200, gradientShedder.Stats().Limit)
+	assert.Zero(t, static.Stats().Limit)
+}
@@ -2,12 +2,17 @@ package logging
 
 import (
 	"html"
+	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/fgrzl/mux/internal/common"
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
 	"go.opentelemetry.io/otel/trace"
@@ -15,15 +20,186 @@ import (
 
 // ---- Functional Options ----
 
-// LoggingOptions configures the logging middleware behavior.
-type LoggingOptions struct{}
+// LoggingOptions configures the logging middleware behavior. The zero value
+// logs every request with slog.Default.
+type LoggingOptions struct {
+	// Logger receives the request logs. Nil uses slog.Default.
+	Logger *slog.Logger
+	// Skip reports requests that are not logged.
+	Skip []func(c routing.RouteContext) bool
+	// SampleRate is the fraction of successful, fast requests logged when
+	// Sample is set. Client errors, server errors, and slow requests are
+	// always logged.
+	SampleRate float64
+	Sample     bool
+	// Subject logs the authenticated principal's subject.
+	Subject bool
+	// Claims are principal claims logged by name, such as a tenant claim.
+	Claims []string
+	// PathParams logs the path parameters in a "params" group.
+	PathParams bool
+	// Attributes adds caller-defined attributes.
+	Attributes []func(c routing.RouteContext) []slog.Attr
+	// Headers are request headers logged in a "headers" group.
+	Headers []string
+	// RedactedHeaders are logged as "[redacted]".
+	RedactedHeaders []string
+	// Query logs the query string.
+	Query bool
+	// RedactedQuery are query parameters whose values are logged as
+	// "[redacted]".
+	RedactedQuery []string
+	// MaxBodyBytes, when positive, logs up to that many bytes of textual
+	// request and response bodies.
+	MaxBodyBytes int
+	// SlowThreshold, when positive, logs requests taking at least that long
+	// at warning level or above with slow=true.
+	SlowThreshold time.Duration
+}
 
 // LoggingOption is a function type for configuring logging options.
 type LoggingOption func(*LoggingOptions)
 
+// DefaultRedactedHeaders are the headers redacted unless WithRedactedHeaders
+// replaces them.
+var DefaultRedactedHeaders = []string{
+	common.HeaderAuthorization,
+	common.HeaderCookie,
+	"Proxy-Authorization",
+	common.HeaderSetCookie,
+	"X-Api-Key",
+}
+
+// DefaultRedactedQuery are the query parameters redacted unless
+// WithRedactedQuery replaces them.
+var DefaultRedactedQuery = []string{
+	"access_token",
+	"api_key",
+	"code",
+	"password",
+	"secret",
+	"sig",
+	"signature",
+	"token",
+}
+
+// WithLogger sends request logs to logger instead of slog.Default.
+func WithLogger(logger *slog.Logger) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.Logger = logger
+	}
+}
+
+// WithSkip skips logging requests for which fn reports true.
+func WithSkip(fn func(c routing.RouteContext) bool) LoggingOption {
+	return func(o *LoggingOptions) {
+		if fn != nil {
+			o.Skip = append(o.Skip, fn)
+		}
+	}
+}
+
+// WithSkipPaths skips logging requests to the given paths, such as health
+// probes. A path ending in "*" matches every path with that prefix, such as
+// "/static/*".
+func WithSkipPaths(paths ...string) LoggingOption {
+	return WithSkip(func(c routing.RouteContext) bool {
+		return common.MatchPath(c.Request().URL.Path, paths)
+	})
+}
+
+// WithSampling logs only rate (0 to 1) of the successful, fast requests.
+// Client errors, server errors, and slow requests are always logged.
+func WithSampling(rate float64) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.Sample = true
+		o.SampleRate = min(max(rate, 0), 1)
+	}
+}
+
+// WithSubject logs the authenticated principal's subject.
+func WithSubject() LoggingOption {
+	return func(o *LoggingOptions) {
+		o.Subject = true
+	}
+}
+
+// WithClaims logs the named claims of the authenticated principal, such as a
+// tenant claim. Each claim is logged under its name.
+func WithClaims(names ...string) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.Claims = append(o.Claims, names...)
+	}
+}
+
+// WithPathParams logs the path parameters in a "params" group.
+func WithPathParams() LoggingOption {
+	return func(o *LoggingOptions) {
+		o.PathParams = true
+	}
+}
+
+// WithAttributes adds the attributes fn returns to each request log. fn runs
+// after the handler.
+func WithAttributes(fn func(c routing.RouteContext) []slog.Attr) LoggingOption {
+	return func(o *LoggingOptions) {
+		if fn != nil {
+			o.Attributes = append(o.Attributes, fn)
+		}
+	}
+}
+
+// WithHeaders logs the named request headers in a "headers" group.
+func WithHeaders(names ...string) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.Headers = append(o.Headers, names...)
+	}
+}
+
+// WithRedactedHeaders replaces the headers logged as "[redacted]".
+func WithRedactedHeaders(names ...string) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.RedactedHeaders = names
+	}
+}
+
+// WithQuery logs the query string.
+func WithQuery() LoggingOption {
+	return func(o *LoggingOptions) {
+		o.Query = true
+	}
+}
+
+// WithRedactedQuery replaces the query parameters logged as "[redacted]".
+func WithRedactedQuery(names ...string) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.RedactedQuery = names
+	}
+}
+
+// WithBodyCapture logs up to maxBytes of textual request and response
+// bodies, such as JSON, XML, form, and text content. Bodies can hold
+// personal data and credentials, so enable it with care.
+func WithBodyCapture(maxBytes int) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.MaxBodyBytes = max(maxBytes, 0)
+	}
+}
+
+// WithSlowThreshold logs requests taking at least d at warning level or above
+// with slow=true.
+func WithSlowThreshold(d time.Duration) LoggingOption {
+	return func(o *LoggingOptions) {
+		o.SlowThreshold = max(d, 0)
+	}
+}
+
 // UseLogging adds structured request/response logging middleware.
 func UseLogging(rtr *router.Router, opts ...LoggingOption) {
-	options := &LoggingOptions{}
+	options := &LoggingOptions{
+		RedactedHeaders: DefaultRedactedHeaders,
+		RedactedQuery:   DefaultRedactedQuery,
+	}
 	for _, opt := range opts {
 		opt(options)
 	}
@@ -35,22 +211,36 @@ func UseLogging(rtr *router.Router, opts ...LoggingOption) {
 // loggingMiddleware provides structured HTTP request/response logging.
 type loggingMiddleware struct {
 	options *LoggingOptions
-	logger  *slog.Logger
 }
 
 // Invoke implements the Middleware interface, logging request details with structured logging.
 func (m *loggingMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	o := m.options
+	for _, skip := range o.Skip {
+		if skip(c) {
+			next(c)
+			return
+		}
+	}
+
 	start := time.Now()
 	rec := &statusRecorder{ResponseWriter: c.Response()}
+	var requestBody *bodyCapture
+	if o.MaxBodyBytes > 0 {
+		rec.body = &bodyCapture{max: o.MaxBodyBytes}
+		if req := c.Request(); req.Body != nil && req.Body != http.NoBody && isTextual(req.Header.Get(common.HeaderContentType)) {
+			requestBody = &bodyCapture{max: o.MaxBodyBytes}
+			req.Body = &captureReader{ReadCloser: req.Body, capture: requestBody}
+		}
+	}
 	c.SetResponse(rec)
 
 	next(c)
 
-	// Use pooled attribute slice and LogAttrs to minimize allocations.
-	// Avoid writing to m.logger here (would be a race when middleware is used
-	// concurrently). Use the default logger when none is set without caching
-	// it on the middleware struct.
-	logger := m.logger
+	// Avoid writing to the middleware here (would be a race when middleware
+	// is used concurrently). Use the default logger when none is set without
+	// caching it on the middleware struct.
+	logger := o.Logger
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -59,6 +249,13 @@ func (m *loggingMiddleware) Invoke(c routing.RouteContext, next router.HandlerFu
 	duration := time.Since(start)
 	statusCode := rec.StatusCode()
 	level := requestLogLevel(statusCode)
+	slow := o.SlowThreshold > 0 && duration >= o.SlowThreshold
+	if slow {
+		level = max(level, slog.LevelWarn)
+	}
+	if o.Sample && level < slog.LevelWarn && rand.Float64() >= o.SampleRate {
+		return
+	}
 	if !logger.Enabled(c, level) {
 		return
 	}
@@ -86,18 +283,116 @@ func (m *loggingMiddleware) Invoke(c routing.RouteContext, next router.HandlerFu
 		slog.String("user_agent", safeUA),
 		slog.Duration("duration", duration),
 	)
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
 	if traceID, spanID, ok := traceIDsForLog(req); ok {
 		attrs = append(attrs,
 			slog.String("trace_id", traceID),
 			slog.String("span_id", spanID),
 		)
 	}
+	attrs = m.appendOptionalAttrs(attrs, c, rec, requestBody)
 	logger.LogAttrs(c, level, requestLogMessage(req.Method, routePattern, safePath, statusCode), attrs...)
 	// reset and return to pool
 	*bufp = attrs[:0]
 	attrPool.Put(bufp)
 }
 
+// appendOptionalAttrs adds the attributes enabled by options.
+func (m *loggingMiddleware) appendOptionalAttrs(attrs []slog.Attr, c routing.RouteContext, rec *statusRecorder, requestBody *bodyCapture) []slog.Attr {
+	o := m.options
+	req := c.Request()
+	if o.Query && req.URL.RawQuery != "" {
+		attrs = append(attrs, slog.String("query", redactQuery(req.URL.Query(), o.RedactedQuery)))
+	}
+	if len(o.Headers) > 0 {
+		headers := make([]any, 0, len(o.Headers))
+		for _, name := range o.Headers {
+			value := req.Header.Get(name)
+			if value == "" {
+				continue
+			}
+			if containsFold(o.RedactedHeaders, name) {
+				value = redacted
+			}
+			headers = append(headers, slog.String(name, sanitizeForLog(value)))
+		}
+		if len(headers) > 0 {
+			attrs = append(attrs, slog.Group("headers", headers...))
+		}
+	}
+	if user := c.User(); user != nil {
+		if o.Subject && user.Subject() != "" {
+			attrs = append(attrs, slog.String("subject", sanitizeForLog(user.Subject())))
+		}
+		for _, name := range o.Claims {
+			if value := user.CustomClaimValue(name); value != "" {
+				attrs = append(attrs, slog.String(name, sanitizeForLog(value)))
+			}
+		}
+	}
+	if o.PathParams {
+		if params := c.ParamsSlice(); params != nil && params.Len() > 0 {
+			group := make([]any, 0, params.Len())
+			for _, p := range *params {
+				group = append(group, slog.String(p.Key, sanitizeForLog(p.Value)))
+			}
+			attrs = append(attrs, slog.Group("params", group...))
+		}
+	}
+	if requestBody != nil && len(requestBody.buf) > 0 {
+		attrs = append(attrs, slog.String("request_body", requestBody.String()))
+	}
+	if rec.body != nil && len(rec.body.buf) > 0 && isTextual(rec.Header().Get(common.HeaderContentType)) {
+		attrs = append(attrs, slog.String("response_body", rec.body.String()))
+	}
+	for _, fn := range o.Attributes {
+		attrs = append(attrs, fn(c)...)
+	}
+	return attrs
+}
+
+const redacted = "[redacted]"
+
+// redactQuery re-encodes the query with redacted values. Encoding escapes
+// every control and HTML special character, so the result is safe to log.
+func redactQuery(values url.Values, names []string) string {
+	for name := range values {
+		if containsFold(names, name) {
+			values[name] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTextual reports whether a Content-Type holds text safe to log.
+func isTextual(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == common.MimeFormURLEncoded:
+		return true
+	case strings.HasSuffix(mediaType, "/json"), strings.HasSuffix(mediaType, "+json"):
+		return true
+	case strings.HasSuffix(mediaType, "/xml"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	default:
+		return false
+	}
+}
+
 // sanitizeForLog applies HTML escaping and truncates long values to a sane length.
 func sanitizeForLog(s string) string {
 	const maxSanitizeLen = 256
@@ -154,6 +449,7 @@ func traceIDsForLog(req *http.Request) (traceID string, spanID string, ok bool)
 type statusRecorder struct {
 	http.ResponseWriter
 	Status int
+	body   *bodyCapture
 }
 
 // WriteHeader captures the status code and forwards it to the underlying ResponseWriter.
@@ -167,9 +463,30 @@ func (r *statusRecorder) Write(p []byte) (int, error) {
 	if r.Status == 0 {
 		r.Status = http.StatusOK
 	}
+	if r.body != nil {
+		r.body.write(p)
+	}
 	return r.ResponseWriter.Write(p)
 }
 
+// Flush commits a 200 status if none was written and flushes the underlying
+// ResponseWriter when it supports flushing.
+func (r *statusRecorder) Flush() {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+// and for writers found through the wrapper chain, such as the route timeout
+// writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // StatusCode returns the captured status code, defaulting to 200 if none was written.
 func (r *statusRecorder) StatusCode() int {
 	if r.Status == 0 {
@@ -178,6 +495,41 @@ func (r *statusRecorder) StatusCode() int {
 	return r.Status
 }
 
+// bodyCapture keeps the first max bytes of a body.
+type bodyCapture struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (b *bodyCapture) write(p []byte) {
+	room := b.max - len(b.buf)
+	if len(p) > room {
+		b.truncated = true
+		p = p[:max(room, 0)]
+	}
+	b.buf = append(b.buf, p...)
+}
+
+func (b *bodyCapture) String() string {
+	if b.truncated {
+		return string(b.buf) + "...(truncated)"
+	}
+	return string(b.buf)
+}
+
+// captureReader records what the handler reads from the request body.
+type captureReader struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.capture.write(p[:n])
+	return n, err
+}
+
 // attrPool reuses []slog.Attr buffers to avoid per-request slice allocations.
 var attrPool = sync.Pool{New: func() any {
 	b := make([]slog.Attr, 0, 8)
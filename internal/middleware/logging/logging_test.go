@@ -2,10 +2,14 @@ package logging
 
 import (
 	"bytes"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fgrzl/claims"
 	"github.com/fgrzl/mux/internal/common"
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
@@ -199,6 +203,21 @@ func TestStatusRecorderShouldImplementResponseWriter(t *testing.T) {
 	assert.Implements(t, (*http.ResponseWriter)(nil), statusRec)
 }
 
+func TestStatusRecorderShouldFlushThroughResponseController(t *testing.T) {
+	// Arrange
+	_, recorder := testhelpers.NewRequestRecorder(http.MethodGet, "/test", nil)
+	statusRec := &statusRecorder{ResponseWriter: recorder}
+
+	// Act
+	err := http.NewResponseController(statusRec).Flush()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, http.StatusOK, statusRec.StatusCode())
+	assert.Same(t, recorder, statusRec.Unwrap())
+}
+
 func TestShouldAddLoggingMiddlewareToRouter(t *testing.T) {
 	// Arrange
 	rtr := router.NewRouter()
@@ -273,3 +292,184 @@ func TestLoggingMiddlewareShouldIncludeTraceAndSpanIDsWhenPresent(t *testing.T)
 	assert.Contains(t, logOutput, "trace_id=000102030405060708090a0b0c0d0e0f")
 	assert.Contains(t, logOutput, "span_id=0001020304050607")
 }
+
+func serveLogged(t *testing.T, rtr *router.Router, method, target, body string, headers ...string) {
+	t.Helper()
+	req, rec := testhelpers.NewRequestRecorder(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rtr.ServeHTTP(rec, req)
+}
+
+func TestShouldUseInjectedLoggerGivenWithLogger(t *testing.T) {
+	// Arrange
+	defaultBuffer, defaultLogger := newTestLogger(slog.LevelDebug)
+	slog.SetDefault(defaultLogger)
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr, WithLogger(logger))
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	serveLogged(t, rtr, http.MethodGet, "/", "")
+
+	// Assert
+	assert.Contains(t, logBuffer.String(), "GET / -> 204")
+	assert.Empty(t, defaultBuffer.String())
+}
+
+func TestShouldNotLogGivenSkippedPaths(t *testing.T) {
+	// Arrange
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr, WithLogger(logger), WithSkipPaths("/healthz", "/static/*"))
+	rtr.GET("/healthz", func(c routing.RouteContext) { c.NoContent() })
+	rtr.GET("/static/{file}", func(c routing.RouteContext) { c.NoContent() })
+	rtr.GET("/api", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	serveLogged(t, rtr, http.MethodGet, "/healthz", "")
+	serveLogged(t, rtr, http.MethodGet, "/static/app.js", "")
+	serveLogged(t, rtr, http.MethodGet, "/api", "")
+
+	// Assert
+	assert.Equal(t, 1, strings.Count(logBuffer.String(), "msg="))
+	assert.Contains(t, logBuffer.String(), "path=/api")
+}
+
+func TestShouldSampleOnlySuccessfulRequestsGivenSampling(t *testing.T) {
+	// Arrange
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr, WithLogger(logger), WithSampling(0))
+	rtr.GET("/ok", func(c routing.RouteContext) { c.NoContent() })
+	rtr.GET("/bad", func(c routing.RouteContext) { c.BadRequest("Bad Request", "nope") })
+	rtr.GET("/fail", func(c routing.RouteContext) { c.ServerError("Internal Server Error", "boom") })
+
+	// Act
+	for range 10 {
+		serveLogged(t, rtr, http.MethodGet, "/ok", "")
+	}
+	serveLogged(t, rtr, http.MethodGet, "/bad", "")
+	serveLogged(t, rtr, http.MethodGet, "/fail", "")
+
+	// Assert
+	logOutput := logBuffer.String()
+	assert.NotContains(t, logOutput, "path=/ok")
+	assert.Contains(t, logOutput, "path=/bad")
+	assert.Contains(t, logOutput, "path=/fail")
+}
+
+func TestShouldLogPrincipalAndPathParamsGivenAttributeOptions(t *testing.T) {
+	// Arrange
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr,
+		WithLogger(logger),
+		WithSubject(),
+		WithClaims("tenant"),
+		WithPathParams(),
+		WithAttributes(func(c routing.RouteContext) []slog.Attr {
+			return []slog.Attr{slog.String("region", "eu")}
+		}),
+	)
+	rtr.Use(setUserMiddleware{})
+	rtr.GET("/orders/{id}", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	serveLogged(t, rtr, http.MethodGet, "/orders/42", "", "X-Subject", "user-1", "X-Tenant", "acme")
+
+	// Assert
+	logOutput := logBuffer.String()
+	assert.Contains(t, logOutput, "subject=user-1")
+	assert.Contains(t, logOutput, "tenant=acme")
+	assert.Contains(t, logOutput, "params.id=42")
+	assert.Contains(t, logOutput, "region=eu")
+}
+
+func TestShouldRedactHeadersAndQueryGivenRedactionLists(t *testing.T) {
+	// Arrange
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr,
+		WithLogger(logger),
+		WithHeaders(common.HeaderAuthorization, "X-Tenant", "X-Internal"),
+		WithQuery(),
+	)
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	serveLogged(t, rtr, http.MethodGet, "/?page=2&token=s3cret", "",
+		common.HeaderAuthorization, "Bearer s3cret", "X-Tenant", "acme")
+
+	// Assert
+	logOutput := logBuffer.String()
+	assert.NotContains(t, logOutput, "s3cret")
+	assert.Contains(t, logOutput, "headers.Authorization=[redacted]")
+	assert.Contains(t, logOutput, "headers.X-Tenant=acme")
+	assert.NotContains(t, logOutput, "X-Internal")
+	assert.Contains(t, logOutput, "page=2")
+	assert.Contains(t, logOutput, "token=%5Bredacted%5D")
+}
+
+func TestShouldCaptureTextualBodiesGivenBodyCapture(t *testing.T) {
+	// Arrange
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr, WithLogger(logger), WithBodyCapture(8))
+	rtr.POST("/echo", func(c routing.RouteContext) {
+		body, _ := io.ReadAll(c.Request().Body)
+		c.Response().Header().Set(common.HeaderContentType, common.MimeJSON)
+		_, _ = c.Response().Write(body)
+	})
+	rtr.POST("/binary", func(c routing.RouteContext) {
+		_, _ = io.ReadAll(c.Request().Body)
+		c.Response().Header().Set(common.HeaderContentType, "application/octet-stream")
+		_, _ = c.Response().Write([]byte("binary"))
+	})
+
+	// Act
+	serveLogged(t, rtr, http.MethodPost, "/echo", `{"a":1}`, common.HeaderContentType, common.MimeJSON)
+	serveLogged(t, rtr, http.MethodPost, "/echo", `{"name":"long"}`, common.HeaderContentType, common.MimeJSON)
+	serveLogged(t, rtr, http.MethodPost, "/binary", "raw", common.HeaderContentType, "application/octet-stream")
+
+	// Assert
+	lines := strings.Split(strings.TrimSpace(logBuffer.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `request_body="{\"a\":1}"`)
+	assert.Contains(t, lines[0], `response_body="{\"a\":1}"`)
+	assert.Contains(t, lines[1], `request_body="{\"name\":...(truncated)"`)
+	assert.NotContains(t, lines[2], "request_body")
+	assert.NotContains(t, lines[2], "response_body")
+}
+
+func TestShouldEscalateLevelGivenSlowRequest(t *testing.T) {
+	// Arrange
+	logBuffer, logger := newTestLogger(slog.LevelDebug)
+	rtr := router.NewRouter()
+	UseLogging(rtr, WithLogger(logger), WithSampling(0), WithSlowThreshold(10*time.Millisecond))
+	rtr.GET("/slow", func(c routing.RouteContext) {
+		time.Sleep(20 * time.Millisecond)
+		c.NoContent()
+	})
+	rtr.GET("/fast", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	serveLogged(t, rtr, http.MethodGet, "/slow", "")
+	serveLogged(t, rtr, http.MethodGet, "/fast", "")
+
+	// Assert
+	logOutput := logBuffer.String()
+	assert.Contains(t, logOutput, "level=WARN")
+	assert.Contains(t, logOutput, "slow=true")
+	assert.NotContains(t, logOutput, "path=/fast")
+}
+
+type setUserMiddleware struct{}
+
+func (setUserMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	set := claims.NewClaimsSet(c.Request().Header.Get("X-Subject")).Set("tenant", c.Request().Header.Get("X-Tenant"))
+	c.SetUser(claims.NewPrincipal(set))
+	next(c)
+}
@@ -0,0 +1,359 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/fgrzl/mux/internal/middleware/loadshed"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ScopeName is the instrumentation scope of the OpenTelemetry instruments.
+const ScopeName = "github.com/fgrzl/mux"
+
+// DefaultDurationBuckets are the request duration histogram boundaries, in
+// seconds, recommended by the OpenTelemetry HTTP semantic conventions.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// DefaultSizeBuckets are the body size histogram boundaries, in bytes.
+var DefaultSizeBuckets = []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// ---- Functional Options ----
+
+// MetricsOptions configures the metrics middleware behavior.
+type MetricsOptions struct {
+	// MeterProvider creates the OpenTelemetry instruments. Nil uses the
+	// global provider.
+	MeterProvider metric.MeterProvider
+	// DurationBuckets are the request duration boundaries in seconds.
+	DurationBuckets []float64
+	// SizeBuckets are the body size boundaries in bytes.
+	SizeBuckets []float64
+	// LoadShedder, when set, adds its statistics to the Prometheus output.
+	LoadShedder *loadshed.LoadShedder
+}
+
+// MetricsOption is a function type for configuring metrics options.
+type MetricsOption func(*MetricsOptions)
+
+// WithMeterProvider records the OpenTelemetry instruments with mp instead
+// of the global provider.
+func WithMeterProvider(mp metric.MeterProvider) MetricsOption {
+	return func(o *MetricsOptions) {
+		o.MeterProvider = mp
+	}
+}
+
+// WithDurationBuckets replaces the request duration histogram boundaries,
+// in seconds.
+func WithDurationBuckets(bounds ...float64) MetricsOption {
+	return func(o *MetricsOptions) {
+		if len(bounds) > 0 {
+			o.DurationBuckets = sortedBounds(bounds)
+		}
+	}
+}
+
+// WithSizeBuckets replaces the body size histogram boundaries, in bytes.
+func WithSizeBuckets(bounds ...float64) MetricsOption {
+	return func(o *MetricsOptions) {
+		if len(bounds) > 0 {
+			o.SizeBuckets = sortedBounds(bounds)
+		}
+	}
+}
+
+// WithLoadShedder adds the load shedder's limit, in-flight, waiting,
+// admitted, queued, and shed counts to the Prometheus output.
+func WithLoadShedder(s *loadshed.LoadShedder) MetricsOption {
+	return func(o *MetricsOptions) {
+		o.LoadShedder = s
+	}
+}
+
+func sortedBounds(bounds []float64) []float64 {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+	return slices.Compact(bounds)
+}
+
+// ---- Middleware ----
+
+// Metrics records HTTP server metrics following the OpenTelemetry semantic
+// conventions, labelled by route pattern so cardinality stays bounded, and
+// serves them in the Prometheus text format.
+type Metrics struct {
+	opts MetricsOptions
+
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+	rejections   metric.Int64Counter
+
+	prom             registry
+	promDuration     *histogramVec
+	promActive       *gaugeVec
+	promRequestSize  *histogramVec
+	promResponseSize *histogramVec
+	promRejections   *counterVec
+}
+
+// UseMetrics adds metrics middleware to the router.
+func UseMetrics(r *router.Router, opts ...MetricsOption) *Metrics {
+	m := NewMetrics(opts...)
+	r.Use(m)
+	return m
+}
+
+// NewMetrics constructs the metrics middleware with optional configuration.
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	o := MetricsOptions{
+		DurationBuckets: DefaultDurationBuckets,
+		SizeBuckets:     DefaultSizeBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.MeterProvider == nil {
+		o.MeterProvider = otel.GetMeterProvider()
+	}
+	m := &Metrics{opts: o}
+	m.initOTel()
+	m.initPrometheus()
+	return m
+}
+
+func (m *Metrics) initOTel() {
+	meter := m.opts.MeterProvider.Meter(ScopeName)
+	// Instrument errors only occur for invalid names and still return
+	// usable no-op instruments, so they are ignored.
+	m.duration, _ = meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithExplicitBucketBoundaries(m.opts.DurationBuckets...))
+	m.active, _ = meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Number of active HTTP server requests."))
+	m.requestSize, _ = meter.Int64Histogram("http.server.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server request bodies."),
+		metric.WithExplicitBucketBoundaries(m.opts.SizeBuckets...))
+	m.responseSize, _ = meter.Int64Histogram("http.server.response.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server response bodies."),
+		metric.WithExplicitBucketBoundaries(m.opts.SizeBuckets...))
+	m.rejections, _ = meter.Int64Counter("mux.server.rejected_requests",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Requests refused by rate limiting, load shedding, authentication, or authorization."))
+}
+
+func (m *Metrics) initPrometheus() {
+	labels := []string{"method", "route", "status"}
+	m.promDuration = &histogramVec{name: "http_server_request_duration_seconds", help: "Duration of HTTP server requests.", labels: labels, bounds: m.opts.DurationBuckets}
+	m.promActive = &gaugeVec{name: "http_server_active_requests", help: "Number of active HTTP server requests.", labels: []string{"method"}}
+	m.promRequestSize = &histogramVec{name: "http_server_request_body_size_bytes", help: "Size of HTTP server request bodies.", labels: labels, bounds: m.opts.SizeBuckets}
+	m.promResponseSize = &histogramVec{name: "http_server_response_body_size_bytes", help: "Size of HTTP server response bodies.", labels: labels, bounds: m.opts.SizeBuckets}
+	m.promRejections = &counterVec{name: "http_server_rejected_requests_total", help: "Requests refused by rate limiting, load shedding, authentication, or authorization.", labels: []string{"reason", "route"}}
+	for _, f := range []family{m.promDuration, m.promActive, m.promRequestSize, m.promResponseSize, m.promRejections} {
+		m.prom.register(f)
+	}
+	if s := m.opts.LoadShedder; s != nil {
+		stat := func(fn func(loadshed.Stats) float64) func() float64 {
+			return func() float64 { return fn(s.Stats()) }
+		}
+		for _, f := range []*funcFamily{
+			{"mux_load_shedding_limit", "Current global in-flight limit, or zero without one.", "gauge", stat(func(st loadshed.Stats) float64 { return float64(st.Limit) })},
+			{"mux_load_shedding_in_flight", "Requests being served.", "gauge", stat(func(st loadshed.Stats) float64 { return float64(st.InFlight) })},
+			{"mux_load_shedding_waiting", "Requests queued for capacity.", "gauge", stat(func(st loadshed.Stats) float64 { return float64(st.Waiting) })},
+			{"mux_load_shedding_admitted_total", "Requests admitted.", "counter", stat(func(st loadshed.Stats) float64 { return float64(st.Admitted) })},
+			{"mux_load_shedding_queued_total", "Requests that waited for capacity.", "counter", stat(func(st loadshed.Stats) float64 { return float64(st.Queued) })},
+			{"mux_load_shedding_shed_total", "Requests shed with 503.", "counter", stat(func(st loadshed.Stats) float64 { return float64(st.Shed) })},
+		} {
+			m.prom.register(f)
+		}
+	}
+}
+
+// Invoke measures the request and records it once next returns.
+func (m *Metrics) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	start := time.Now()
+	r := c.Request()
+	method := normalizeMethod(r.Method)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	activeAttrs := metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("http.request.method", method),
+		attribute.String("url.scheme", scheme),
+	))
+	ctx := r.Context()
+	m.active.Add(ctx, 1, activeAttrs)
+	m.promActive.add(1, method)
+
+	var rejection routing.Rejection
+	routing.SetRejectionRecorder(c, func(reason routing.Rejection) { rejection = reason })
+	body := &countingBody{ReadCloser: r.Body}
+	if r.Body != nil && r.Body != http.NoBody {
+		c.Request().Body = body
+	}
+	rec := &responseRecorder{ResponseWriter: c.Response()}
+	c.SetResponse(rec)
+
+	defer func() {
+		m.active.Add(ctx, -1, activeAttrs)
+		m.promActive.add(-1, method)
+		m.record(ctx, c, method, scheme, start, rec, requestSize(r, body), rejection)
+	}()
+	next(c)
+}
+
+func (m *Metrics) record(ctx context.Context, c routing.RouteContext, method, scheme string, start time.Time, rec *responseRecorder, reqSize int64, rejection routing.Rejection) {
+	status := rec.status
+	if timeout := routing.TimeoutStatus(c); timeout != 0 {
+		status = timeout
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	route := ""
+	if options := c.Options(); options != nil {
+		route = options.Pattern
+	}
+	attrs := make([]attribute.KeyValue, 0, 6)
+	attrs = append(attrs,
+		attribute.String("http.request.method", method),
+		attribute.String("url.scheme", scheme),
+		attribute.Int("http.response.status_code", status),
+	)
+	if route != "" {
+		attrs = append(attrs, attribute.String("http.route", route))
+	}
+	if version := protocolVersion(c.Request()); version != "" {
+		attrs = append(attrs, attribute.String("network.protocol.version", version))
+	}
+	if status >= 500 {
+		attrs = append(attrs, attribute.String("error.type", strconv.Itoa(status)))
+	}
+	set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+	seconds := time.Since(start).Seconds()
+	m.duration.Record(ctx, seconds, set)
+	m.requestSize.Record(ctx, reqSize, set)
+	m.responseSize.Record(ctx, rec.bytes, set)
+
+	statusLabel := strconv.Itoa(status)
+	m.promDuration.observe(seconds, method, route, statusLabel)
+	m.promRequestSize.observe(float64(reqSize), method, route, statusLabel)
+	m.promResponseSize.observe(float64(rec.bytes), method, route, statusLabel)
+
+	if rejection != "" {
+		rejectAttrs := []attribute.KeyValue{attribute.String("mux.rejection.reason", string(rejection))}
+		if route != "" {
+			rejectAttrs = append(rejectAttrs, attribute.String("http.route", route))
+		}
+		m.rejections.Add(ctx, 1, metric.WithAttributes(rejectAttrs...))
+		m.promRejections.add(1, string(rejection), route)
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	_, err := m.prom.WriteTo(w)
+	return err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format, for mounting
+// at /metrics on the router or an internal listener.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", PrometheusContentType)
+	_ = m.WritePrometheus(w)
+}
+
+// knownMethods are kept as is; anything else is recorded as _OTHER so
+// arbitrary client methods cannot grow cardinality.
+var knownMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+func normalizeMethod(method string) string {
+	if slices.Contains(knownMethods, method) {
+		return method
+	}
+	return "_OTHER"
+}
+
+func protocolVersion(r *http.Request) string {
+	switch {
+	case r.ProtoMajor == 1:
+		return "1." + strconv.Itoa(r.ProtoMinor)
+	case r.ProtoMajor >= 2:
+		return strconv.Itoa(r.ProtoMajor)
+	default:
+		return ""
+	}
+}
+
+// requestSize is the declared body size, or the bytes the handler read when
+// the size was not declared.
+func requestSize(r *http.Request, body *countingBody) int64 {
+	if r.ContentLength > 0 {
+		return r.ContentLength
+	}
+	return body.n
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// responseRecorder captures the status and body size.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status and forwards it.
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body size and forwards p.
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying writer when it supports flushing.
+func (r *responseRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/middleware/loadshed"
+	"github.com/fgrzl/mux/internal/middleware/ratelimit"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// recordedMeasurement is one value recorded through recordingMeter.
+type recordedMeasurement struct {
+	name  string
+	value float64
+	attrs attribute.Set
+}
+
+type recordingMeterProvider struct {
+	noop.MeterProvider
+	mu           sync.Mutex
+	measurements []recordedMeasurement
+}
+
+func (p *recordingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return &recordingMeter{provider: p}
+}
+
+func (p *recordingMeterProvider) record(name string, value float64, attrs attribute.Set) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.measurements = append(p.measurements, recordedMeasurement{name: name, value: value, attrs: attrs})
+}
+
+func (p *recordingMeterProvider) find(name string) []recordedMeasurement {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var found []recordedMeasurement
+	for _, m := range p.measurements {
+		if m.name == name {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+type recordingMeter struct {
+	noop.Meter
+	provider *recordingMeterProvider
+}
+
+func (m *recordingMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &float64Histogram{name: name, provider: m.provider}, nil
+}
+
+func (m *recordingMeter) Int64Histogram(name string, _ ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return &int64Histogram{name: name, provider: m.provider}, nil
+}
+
+func (m *recordingMeter) Int64UpDownCounter(name string, _ ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return &int64UpDownCounter{name: name, provider: m.provider}, nil
+}
+
+func (m *recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &int64Counter{name: name, provider: m.provider}, nil
+}
+
+type float64Histogram struct {
+	noop.Float64Histogram
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (h *float64Histogram) Record(_ context.Context, v float64, opts ...metric.RecordOption) {
+	h.provider.record(h.name, v, metric.NewRecordConfig(opts).Attributes())
+}
+
+type int64Histogram struct {
+	noop.Int64Histogram
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (h *int64Histogram) Record(_ context.Context, v int64, opts ...metric.RecordOption) {
+	h.provider.record(h.name, float64(v), metric.NewRecordConfig(opts).Attributes())
+}
+
+type int64UpDownCounter struct {
+	noop.Int64UpDownCounter
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (c *int64UpDownCounter) Add(_ context.Context, v int64, opts ...metric.AddOption) {
+	c.provider.record(c.name, float64(v), metric.NewAddConfig(opts).Attributes())
+}
+
+type int64Counter struct {
+	noop.Int64Counter
+	name     string
+	provider *recordingMeterProvider
+}
+
+func (c *int64Counter) Add(_ context.Context, v int64, opts ...metric.AddOption) {
+	c.provider.record(c.name, float64(v), metric.NewAddConfig(opts).Attributes())
+}
+
+func serve(rtr *router.Router, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func attr(set attribute.Set, key string) string {
+	v, _ := set.Value(attribute.Key(key))
+	return v.Emit()
+}
+
+func TestShouldRecordSemanticConventionMetricsGivenRequests(t *testing.T) {
+	// Arrange
+	provider := &recordingMeterProvider{}
+	rtr := router.NewRouter()
+	UseMetrics(rtr, WithMeterProvider(provider))
+	rtr.POST("/orders/{id}", func(c routing.RouteContext) {
+		c.Response().WriteHeader(http.StatusCreated)
+		_, _ = c.Response().Write([]byte("created"))
+	})
+	rtr.GET("/fail", func(c routing.RouteContext) { c.ServerError("Internal Server Error", "boom") })
+
+	// Act
+	serve(rtr, http.MethodPost, "/orders/1", `{"qty":2}`)
+	serve(rtr, http.MethodPost, "/orders/2", `{}`)
+	serve(rtr, http.MethodGet, "/fail", "")
+
+	// Assert
+	durations := provider.find("http.server.request.duration")
+	require.Len(t, durations, 3)
+	first := durations[0].attrs
+	assert.Equal(t, "POST", attr(first, "http.request.method"))
+	assert.Equal(t, "/orders/{id}", attr(first, "http.route"))
+	assert.Equal(t, "201", attr(first, "http.response.status_code"))
+	assert.Equal(t, "http", attr(first, "url.scheme"))
+	assert.Equal(t, "1.1", attr(first, "network.protocol.version"))
+	assert.False(t, first.HasValue("error.type"))
+	assert.Equal(t, "500", attr(durations[2].attrs, "error.type"))
+
+	requestSizes := provider.find("http.server.request.body.size")
+	require.Len(t, requestSizes, 3)
+	assert.Equal(t, float64(9), requestSizes[0].value)
+	responseSizes := provider.find("http.server.response.body.size")
+	assert.Equal(t, float64(7), responseSizes[0].value)
+
+	var active float64
+	for _, m := range provider.find("http.server.active_requests") {
+		active += m.value
+	}
+	assert.Zero(t, active)
+}
+
+func TestShouldNormalizeUnknownMethods(t *testing.T) {
+	assert.Equal(t, "GET", normalizeMethod(http.MethodGet))
+	assert.Equal(t, "_OTHER", normalizeMethod("PURGE"))
+}
+
+func TestShouldRenderPrometheusTextGivenRequests(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	metrics := UseMetrics(rtr, WithMeterProvider(noop.NewMeterProvider()), WithDurationBuckets(0.1, 1))
+	rtr.GET("/users/{id}", func(c routing.RouteContext) { c.OK("ok") })
+	rtr.Handle(http.MethodGet, "/metrics", metrics)
+
+	// Act
+	serve(rtr, http.MethodGet, "/users/1", "")
+	serve(rtr, http.MethodGet, "/users/2", "")
+	rec := serve(rtr, http.MethodGet, "/metrics", "")
+
+	// Assert
+	body := rec.Body.String()
+	assert.Equal(t, PrometheusContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, body, "# TYPE http_server_request_duration_seconds histogram\n")
+	assert.Contains(t, body, `http_server_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="0.1"} 2`)
+	assert.Contains(t, body, `http_server_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="+Inf"} 2`)
+	assert.Contains(t, body, `http_server_request_duration_seconds_count{method="GET",route="/users/{id}",status="200"} 2`)
+	assert.Contains(t, body, `http_server_response_body_size_bytes_sum{method="GET",route="/users/{id}",status="200"} 8`)
+	assert.Contains(t, body, `http_server_active_requests{method="GET"} 1`)
+	assert.NotContains(t, body, "/users/1")
+}
+
+func TestShouldCountRejectionsGivenRateLimiterAndLoadShedder(t *testing.T) {
+	// Arrange
+	shedder := loadshed.NewLoadShedder(loadshed.WithMaxInFlight(1), loadshed.WithQueue(0, 0))
+	rtr := router.NewRouter()
+	metrics := UseMetrics(rtr, WithMeterProvider(noop.NewMeterProvider()), WithLoadShedder(shedder))
+	rtr.Use(shedder)
+	limiter := ratelimit.NewSelectiveRateLimiter()
+	defer limiter.Stop()
+	rtr.Use(limiter)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	rtr.GET("/limited", func(c routing.RouteContext) { c.NoContent() }).WithRateLimit(1, time.Minute)
+	rtr.GET("/slow", func(c routing.RouteContext) {
+		close(started)
+		<-release
+		c.NoContent()
+	})
+
+	// Act
+	serve(rtr, http.MethodGet, "/limited", "")
+	serve(rtr, http.MethodGet, "/limited", "")
+	serve(rtr, http.MethodGet, "/limited", "")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(rtr, http.MethodGet, "/slow", "")
+	}()
+	<-started
+	serve(rtr, http.MethodGet, "/limited", "")
+	close(release)
+	<-done
+	var out strings.Builder
+	require.NoError(t, metrics.WritePrometheus(&out))
+
+	// Assert
+	body := out.String()
+	assert.Contains(t, body, `http_server_rejected_requests_total{reason="rate_limited",route="/limited"} `)
+	assert.Contains(t, body, `http_server_rejected_requests_total{reason="load_shed",route="/limited"} 1`)
+	assert.Contains(t, body, "mux_load_shedding_shed_total 1\n")
+	assert.Contains(t, body, "mux_load_shedding_admitted_total 4\n")
+}
+
+func TestShouldEscapeLabelValues(t *testing.T) {
+	// Arrange
+	var b strings.Builder
+
+	// Act
+	writeSample(&b, "m", []string{"route"}, []string{"a\"b\\c\nd"}, "", "", 1.5)
+
+	// Assert
+	assert.Equal(t, "m{route=\"a\\\"b\\\\c\\nd\"} 1.5\n", b.String())
+}
@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PrometheusContentType is the content type of the text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ---- Registry ----
+
+// registry holds metric families and renders them in the Prometheus text
+// exposition format without depending on a Prometheus client library.
+type registry struct {
+	mu       sync.RWMutex
+	families []family
+}
+
+// family is one named metric with its series.
+type family interface {
+	write(b *strings.Builder)
+}
+
+func (r *registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// WriteTo renders every family in registration order.
+func (r *registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	r.mu.RLock()
+	for _, f := range r.families {
+		f.write(&b)
+	}
+	r.mu.RUnlock()
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// series stores per-label-set values keyed by the joined label values.
+type series[T any] struct {
+	mu     sync.RWMutex
+	values map[string]*T
+	labels map[string][]string
+}
+
+func (s *series[T]) get(values []string) *T {
+	key := strings.Join(values, "\xff")
+	s.mu.RLock()
+	v, ok := s.values[key]
+	s.mu.RUnlock()
+	if ok {
+		return v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.values[key]; ok {
+		return v
+	}
+	if s.values == nil {
+		s.values = make(map[string]*T)
+		s.labels = make(map[string][]string)
+	}
+	v = new(T)
+	s.values[key] = v
+	s.labels[key] = slices.Clone(values)
+	return v
+}
+
+// each calls fn for every series in label order.
+func (s *series[T]) each(fn func(values []string, v *T)) {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	entries := make([]struct {
+		labels []string
+		value  *T
+	}, len(keys))
+	for i, key := range keys {
+		entries[i].labels, entries[i].value = s.labels[key], s.values[key]
+	}
+	s.mu.RUnlock()
+	for _, e := range entries {
+		fn(e.labels, e.value)
+	}
+}
+
+// ---- Counters and Gauges ----
+
+type counterVec struct {
+	name, help string
+	labels     []string
+	series     series[atomic.Uint64]
+}
+
+func (c *counterVec) add(n uint64, values ...string) {
+	c.series.get(values).Add(n)
+}
+
+func (c *counterVec) write(b *strings.Builder) {
+	writeHeader(b, c.name, c.help, "counter")
+	c.series.each(func(values []string, v *atomic.Uint64) {
+		writeSample(b, c.name, c.labels, values, "", "", float64(v.Load()))
+	})
+}
+
+type gaugeVec struct {
+	name, help string
+	labels     []string
+	series     series[atomic.Int64]
+}
+
+func (g *gaugeVec) add(n int64, values ...string) {
+	g.series.get(values).Add(n)
+}
+
+func (g *gaugeVec) write(b *strings.Builder) {
+	writeHeader(b, g.name, g.help, "gauge")
+	g.series.each(func(values []string, v *atomic.Int64) {
+		writeSample(b, g.name, g.labels, values, "", "", float64(v.Load()))
+	})
+}
+
+// funcFamily reports a value read at scrape time, such as load shedder
+// statistics.
+type funcFamily struct {
+	name, help, kind string
+	value            func() float64
+}
+
+func (f *funcFamily) write(b *strings.Builder) {
+	writeHeader(b, f.name, f.help, f.kind)
+	writeSample(b, f.name, nil, nil, "", "", f.value())
+}
+
+// ---- Histograms ----
+
+type histogramValue struct {
+	once    sync.Once
+	buckets []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Uint64 // float64 bits
+}
+
+type histogramVec struct {
+	name, help string
+	labels     []string
+	bounds     []float64
+	series     series[histogramValue]
+}
+
+func (h *histogramVec) observe(v float64, values ...string) {
+	s := h.series.get(values)
+	s.once.Do(func() { s.buckets = make([]atomic.Uint64, len(h.bounds)) })
+	if i, _ := slices.BinarySearch(h.bounds, v); i < len(h.bounds) {
+		s.buckets[i].Add(1)
+	}
+	s.count.Add(1)
+	for {
+		old := s.sum.Load()
+		if s.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			break
+		}
+	}
+}
+
+func (h *histogramVec) write(b *strings.Builder) {
+	writeHeader(b, h.name, h.help, "histogram")
+	h.series.each(func(values []string, s *histogramValue) {
+		s.once.Do(func() { s.buckets = make([]atomic.Uint64, len(h.bounds)) })
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += s.buckets[i].Load()
+			writeSample(b, h.name+"_bucket", h.labels, values, "le", formatFloat(bound), float64(cumulative))
+		}
+		count := s.count.Load()
+		writeSample(b, h.name+"_bucket", h.labels, values, "le", "+Inf", float64(count))
+		writeSample(b, h.name+"_sum", h.labels, values, "", "", math.Float64frombits(s.sum.Load()))
+		writeSample(b, h.name+"_count", h.labels, values, "", "", float64(count))
+	})
+}
+
+// ---- Text Format ----
+
+func writeHeader(b *strings.Builder, name, help, kind string) {
+	b.WriteString("# HELP ")
+	b.WriteString(name)
+	b.WriteByte(' ')
+	b.WriteString(strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	b.WriteString("\n# TYPE ")
+	b.WriteString(name)
+	b.WriteByte(' ')
+	b.WriteString(kind)
+	b.WriteByte('\n')
+}
+
+func writeSample(b *strings.Builder, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	b.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeLabel(b, label, values[i])
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			writeLabel(b, extraLabel, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabel(b *strings.Builder, name, value string) {
+	b.WriteString(name)
+	b.WriteString(`="`)
+	b.WriteString(labelEscaper.Replace(value))
+	b.WriteByte('"')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -5,10 +5,14 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/fgrzl/mux/internal/common"
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -16,7 +20,36 @@ import (
 
 // OpenTelemetryOptions configures the OpenTelemetry middleware behavior.
 type OpenTelemetryOptions struct {
+	// Operation names spans for requests without route metadata.
 	Operation string
+	// TracerProvider creates the spans. The default is the global provider.
+	TracerProvider trace.TracerProvider
+	// MeterProvider records the HTTP server metrics. The default is the
+	// global provider.
+	MeterProvider metric.MeterProvider
+	// Propagator extracts the parent span context from request headers. The
+	// default is the global propagator.
+	Propagator propagation.TextMapPropagator
+	// SpanNameFormatter names the span. An empty result falls back to
+	// "METHOD pattern", or Operation without route metadata.
+	SpanNameFormatter func(c routing.RouteContext) string
+	// Skip leaves requests for which any function reports true untraced,
+	// such as health probes.
+	Skip []func(c routing.RouteContext) bool
+	// PathParams adds each path parameter as a mux.path_param.<name>
+	// attribute.
+	PathParams bool
+	// Subject adds the authenticated principal's subject as enduser.id.
+	Subject bool
+	// Claims are principal claims added as mux.claim.<name> attributes.
+	Claims []string
+	// TenantClaim names the principal claim added as tenant.id.
+	TenantClaim string
+	// Attributes add custom span attributes once the handler has run.
+	Attributes []func(c routing.RouteContext) []attribute.KeyValue
+	// SpanStatus maps the response status to the span status. The default
+	// is DefaultSpanStatus.
+	SpanStatus func(c routing.RouteContext, status int) (codes.Code, string)
 }
 
 // OpenTelemetryOption is a function type for configuring OpenTelemetry options.
@@ -29,18 +62,130 @@ func WithOperation(name string) OpenTelemetryOption {
 	}
 }
 
+// WithTracerProvider creates spans with tp instead of the global provider.
+func WithTracerProvider(tp trace.TracerProvider) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.TracerProvider = tp
+	}
+}
+
+// WithMeterProvider records metrics with mp instead of the global provider.
+func WithMeterProvider(mp metric.MeterProvider) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.MeterProvider = mp
+	}
+}
+
+// WithPropagator extracts the parent span context with p instead of the
+// global propagator.
+func WithPropagator(p propagation.TextMapPropagator) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.Propagator = p
+	}
+}
+
+// WithSpanNameFormatter names spans with fn. An empty result falls back to
+// the default "METHOD pattern" name.
+func WithSpanNameFormatter(fn func(c routing.RouteContext) string) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.SpanNameFormatter = fn
+	}
+}
+
+// WithSkip leaves requests for which fn reports true untraced.
+func WithSkip(fn func(c routing.RouteContext) bool) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		if fn != nil {
+			o.Skip = append(o.Skip, fn)
+		}
+	}
+}
+
+// WithSkipPaths leaves requests to the given paths untraced, such as health
+// probes. A path ending in "*" matches every path with that prefix.
+func WithSkipPaths(paths ...string) OpenTelemetryOption {
+	return WithSkip(func(c routing.RouteContext) bool {
+		return common.MatchPath(c.Request().URL.Path, paths)
+	})
+}
+
+// WithPathParamAttributes adds each path parameter as a
+// mux.path_param.<name> span attribute.
+func WithPathParamAttributes() OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.PathParams = true
+	}
+}
+
+// WithSubjectAttribute adds the authenticated principal's subject as the
+// enduser.id span attribute.
+func WithSubjectAttribute() OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.Subject = true
+	}
+}
+
+// WithClaimAttributes adds the named principal claims as mux.claim.<name>
+// span attributes.
+func WithClaimAttributes(names ...string) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.Claims = append(o.Claims, names...)
+	}
+}
+
+// WithTenantClaim adds the named principal claim as the tenant.id span
+// attribute.
+func WithTenantClaim(name string) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.TenantClaim = name
+	}
+}
+
+// WithAttributes adds the attributes fn returns to the span once the
+// handler has run.
+func WithAttributes(fn func(c routing.RouteContext) []attribute.KeyValue) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		if fn != nil {
+			o.Attributes = append(o.Attributes, fn)
+		}
+	}
+}
+
+// WithSpanStatus maps the response status to the span status with fn instead
+// of DefaultSpanStatus. Returning codes.Unset for a 5xx response marks the
+// span Ok so the server error is not recorded as a span error. otelhttp sets
+// the error status of 5xx responses again once the handler returns, so their
+// descriptions are dropped.
+func WithSpanStatus(fn func(c routing.RouteContext, status int) (codes.Code, string)) OpenTelemetryOption {
+	return func(o *OpenTelemetryOptions) {
+		o.SpanStatus = fn
+	}
+}
+
+// DefaultSpanStatus marks server errors as span errors and leaves client
+// errors and successes unset.
+func DefaultSpanStatus(_ routing.RouteContext, status int) (codes.Code, string) {
+	if status >= http.StatusInternalServerError {
+		return codes.Error, ""
+	}
+	return codes.Unset, ""
+}
+
+// ---- Middleware ----
+
 // UseOpenTelemetry adds OpenTelemetry tracing and metrics middleware.
 func UseOpenTelemetry(rtr *router.Router, opts ...OpenTelemetryOption) {
 	options := &OpenTelemetryOptions{Operation: "http.server"}
 	for _, opt := range opts {
 		opt(options)
 	}
-	rtr.Use(newOTELMiddleware(options.Operation))
+	rtr.Use(newOTELMiddleware(options))
 }
 
 // otelMiddleware provides OpenTelemetry integration for HTTP requests.
 type otelMiddleware struct {
 	operation string
+	options   *OpenTelemetryOptions
 	handler   http.Handler
 }
 
@@ -52,8 +197,17 @@ func (m *otelMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc)
 	}
 
 	// Lazy init handler for cases where tests construct the middleware directly.
+	if m.options == nil {
+		m.options = &OpenTelemetryOptions{Operation: m.operation}
+	}
 	if m.handler == nil {
-		m.handler = buildOTELHandler(m.operation)
+		m.handler = buildOTELHandler(m.options)
+	}
+	for _, skip := range m.options.Skip {
+		if skip(c) {
+			next(c)
+			return
+		}
 	}
 	// Attach per-request data (RouteContext and next) into the request context so the
 	// prebuilt handler can retrieve them without capturing per-request closures.
@@ -75,9 +229,9 @@ func (m *otelMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc)
 }
 
 // newOTELMiddleware constructs an otelMiddleware with a pre-wired handler.
-func newOTELMiddleware(operation string) *otelMiddleware {
-	mw := &otelMiddleware{operation: operation}
-	mw.handler = buildOTELHandler(operation)
+func newOTELMiddleware(options *OpenTelemetryOptions) *otelMiddleware {
+	mw := &otelMiddleware{operation: options.Operation, options: options}
+	mw.handler = buildOTELHandler(options)
 	return mw
 }
 
@@ -96,8 +250,15 @@ type otelData struct {
 // and makes type assertions clearer and testable.
 type RequestSetter interface{ SetRequest(*http.Request) }
 
-func buildOTELHandler(operation string) http.Handler {
+func buildOTELHandler(options *OpenTelemetryOptions) http.Handler {
 	formatter := func(op string, r *http.Request) string {
+		if options.SpanNameFormatter != nil {
+			if data, ok := r.Context().Value(otelNextKey{}).(*otelData); ok && data.c != nil {
+				if name := options.SpanNameFormatter(data.c); name != "" {
+					return name
+				}
+			}
+		}
 		method, pattern := extractRouteTraceData(r.Context())
 		if method != "" && pattern != "" {
 			return method + " " + pattern
@@ -105,21 +266,93 @@ func buildOTELHandler(operation string) http.Handler {
 		return op
 	}
 
+	otelOpts := []otelhttp.Option{otelhttp.WithSpanNameFormatter(formatter)}
+	if options.TracerProvider != nil {
+		otelOpts = append(otelOpts, otelhttp.WithTracerProvider(options.TracerProvider))
+	}
+	if options.MeterProvider != nil {
+		otelOpts = append(otelOpts, otelhttp.WithMeterProvider(options.MeterProvider))
+	}
+	if options.Propagator != nil {
+		otelOpts = append(otelOpts, otelhttp.WithPropagators(options.Propagator))
+	}
+	spanStatus := options.SpanStatus
+	if spanStatus == nil {
+		spanStatus = DefaultSpanStatus
+	}
+
 	return otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		enrichSpanWithRouteAttributes(r.Context())
 		if v := r.Context().Value(otelNextKey{}); v != nil {
 			if data, ok := v.(*otelData); ok && data.c != nil && data.next != nil {
-				data.c.SetResponse(w)
+				rec := &statusRecorder{ResponseWriter: w}
+				data.c.SetResponse(rec)
 				// Use the named RequestSetter interface so callers and tests can
 				// rely on a clear, documented contract instead of an anonymous type.
 				if dc, ok2 := data.c.(RequestSetter); ok2 {
 					dc.SetRequest(r)
 				}
 				data.next(data.c)
+
+				span := trace.SpanFromContext(r.Context())
+				if attrs := requestAttributes(options, data.c); len(attrs) > 0 {
+					span.SetAttributes(attrs...)
+				}
+				status := rec.Status()
+				if timeout := routing.TimeoutStatus(data.c); timeout != 0 {
+					status = timeout
+				}
+				code, description := spanStatus(data.c, status)
+				setSpanStatus(span, status, code, description)
 				return
 			}
 		}
-	}), operation, otelhttp.WithSpanNameFormatter(formatter))
+	}), options.Operation, otelOpts...)
+}
+
+// requestAttributes collects the optional path parameter, principal, and
+// custom attributes. They are read after the handler runs so principals set
+// by authentication middleware registered later are included.
+func requestAttributes(o *OpenTelemetryOptions, c routing.RouteContext) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if o.PathParams {
+		if params := c.ParamsSlice(); params != nil {
+			for _, p := range *params {
+				attrs = append(attrs, attribute.String("mux.path_param."+p.Key, p.Value))
+			}
+		}
+	}
+	if user := c.User(); user != nil {
+		if o.Subject && user.Subject() != "" {
+			attrs = append(attrs, attribute.String("enduser.id", user.Subject()))
+		}
+		for _, name := range o.Claims {
+			if value := user.CustomClaimValue(name); value != "" {
+				attrs = append(attrs, attribute.String("mux.claim."+name, value))
+			}
+		}
+		if o.TenantClaim != "" {
+			if value := user.CustomClaimValue(o.TenantClaim); value != "" {
+				attrs = append(attrs, attribute.String("tenant.id", value))
+			}
+		}
+	}
+	for _, fn := range o.Attributes {
+		attrs = append(attrs, fn(c)...)
+	}
+	return attrs
+}
+
+// setSpanStatus applies the mapped status. otelhttp marks 5xx spans as errors
+// after the handler returns and a span status can only be raised, so an
+// unset mapping for a server error is recorded as Ok to keep it final.
+func setSpanStatus(span trace.Span, status int, code codes.Code, description string) {
+	if code == codes.Unset && status >= http.StatusInternalServerError {
+		code = codes.Ok
+	}
+	if code != codes.Unset {
+		span.SetStatus(code, description)
+	}
 }
 
 func extractRouteTraceData(ctx context.Context) (method string, pattern string) {
@@ -158,6 +391,46 @@ func enrichSpanWithRouteAttributes(ctx context.Context) {
 	}
 }
 
+// statusRecorder captures the response status for the span status mapping.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status and forwards it.
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records an implicit 200 OK and forwards p.
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Status returns the recorded status, or 200 OK when nothing was written.
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Flush forwards to the underlying writer when it supports flushing.
+func (r *statusRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func isWebSocketUpgrade(r *http.Request) bool {
 	if r == nil {
 		return false
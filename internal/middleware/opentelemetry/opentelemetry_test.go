@@ -2,8 +2,10 @@ package opentelemetry
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/fgrzl/claims"
 	"github.com/fgrzl/mux/internal/common"
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
@@ -12,6 +14,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
@@ -342,6 +345,201 @@ func TestShouldBypassTracingForWebSocketUpgradeRequests(t *testing.T) {
 	assert.Empty(t, recorder.Ended())
 }
 
+func TestShouldUseExplicitTracerProviderGivenWithTracerProvider(t *testing.T) {
+	// Arrange
+	global := installTestTracerProvider(t)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr, WithTracerProvider(tp))
+	rtr.GET("/test", func(c routing.RouteContext) { c.OK("ok") })
+
+	// Act
+	req, rec := testhelpers.NewRequestRecorder(http.MethodGet, "/test", nil)
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, recorder.Ended(), 1)
+	assert.Empty(t, global.Ended())
+}
+
+func TestShouldExtractParentGivenWithPropagator(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr, WithPropagator(propagation.TraceContext{}))
+	rtr.GET("/test", func(c routing.RouteContext) { c.OK("ok") })
+
+	// Act
+	req, rec := testhelpers.NewRequestRecorder(http.MethodGet, "/test", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	}
+}
+
+func TestShouldNameSpanGivenWithSpanNameFormatter(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr, WithSpanNameFormatter(func(c routing.RouteContext) string {
+		return "users." + strings.ToLower(c.Request().Method)
+	}))
+	rtr.GET("/users/{id}", func(c routing.RouteContext) { c.OK("ok") })
+
+	// Act
+	req, rec := testhelpers.NewRequestRecorder(http.MethodGet, "/users/1", nil)
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "users.get", spans[0].Name())
+	}
+}
+
+func TestShouldNotTraceGivenSkippedPath(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr, WithSkipPaths("/healthz", "/internal/*"))
+	handler := func(c routing.RouteContext) { c.OK("ok") }
+	rtr.GET("/healthz", handler)
+	rtr.GET("/internal/ready", handler)
+	rtr.GET("/users", handler)
+
+	// Act
+	for _, path := range []string{"/healthz", "/internal/ready", "/users"} {
+		req, rec := testhelpers.NewRequestRecorder(http.MethodGet, path, nil)
+		rtr.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /users", spans[0].Name())
+	}
+}
+
+func TestShouldAddPrincipalAndParamAttributesGivenEnrichmentOptions(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr,
+		WithPathParamAttributes(),
+		WithSubjectAttribute(),
+		WithClaimAttributes("region"),
+		WithTenantClaim("tenant"),
+		WithAttributes(func(c routing.RouteContext) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("app.feature", "orders")}
+		}),
+	)
+	rtr.Use(setUserMiddleware{})
+	rtr.GET("/orders/{orderId}", func(c routing.RouteContext) { c.OK("ok") })
+
+	// Act
+	req, rec := testhelpers.NewRequestRecorder(http.MethodGet, "/orders/42", nil)
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		attrs := attributesToMap(spans[0].Attributes())
+		assert.Equal(t, "42", attrs["mux.path_param.orderId"])
+		assert.Equal(t, "user-1", attrs["enduser.id"])
+		assert.Equal(t, "eu", attrs["mux.claim.region"])
+		assert.Equal(t, "acme", attrs["tenant.id"])
+		assert.Equal(t, "orders", attrs["app.feature"])
+	}
+}
+
+func TestShouldNotAddPrincipalAttributesGivenNoEnrichmentOptions(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr)
+	rtr.Use(setUserMiddleware{})
+	rtr.GET("/orders/{orderId}", func(c routing.RouteContext) { c.OK("ok") })
+
+	// Act
+	req, rec := testhelpers.NewRequestRecorder(http.MethodGet, "/orders/42", nil)
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		attrs := attributesToMap(spans[0].Attributes())
+		assert.NotContains(t, attrs, "enduser.id")
+		assert.NotContains(t, attrs, "tenant.id")
+		assert.NotContains(t, attrs, "mux.path_param.orderId")
+	}
+}
+
+func TestShouldMarkProblemServerErrorGivenDefaultSpanStatus(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr)
+	rtr.GET("/fail", func(c routing.RouteContext) { c.ServerError("", "boom") })
+
+	// Act
+	req, rec := testhelpers.NewRequestRecorder(http.MethodGet, "/fail", nil)
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
+
+func TestShouldFollowMappingGivenWithSpanStatus(t *testing.T) {
+	// Arrange
+	recorder := installTestTracerProvider(t)
+	rtr := router.NewRouter()
+	UseOpenTelemetry(rtr, WithSpanStatus(func(c routing.RouteContext, status int) (codes.Code, string) {
+		if status == http.StatusConflict {
+			return codes.Error, "conflict"
+		}
+		return codes.Unset, ""
+	}))
+	rtr.GET("/conflict", func(c routing.RouteContext) { c.Conflict("Exists", "already exists") })
+	rtr.GET("/unavailable", func(c routing.RouteContext) {
+		c.Response().WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// Act
+	for _, path := range []string{"/conflict", "/unavailable"} {
+		req, rec := testhelpers.NewRequestRecorder(http.MethodGet, path, nil)
+		rtr.ServeHTTP(rec, req)
+	}
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "conflict", spans[0].Status().Description)
+		assert.NotEqual(t, codes.Error, spans[1].Status().Code)
+	}
+}
+
+type setUserMiddleware struct{}
+
+func (setUserMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	set := claims.NewClaimsSet("user-1").Set("tenant", "acme").Set("region", "eu")
+	c.SetUser(claims.NewPrincipal(set))
+	next(c)
+}
+
 func installTestTracerProvider(t *testing.T) *tracetest.SpanRecorder {
 	t.Helper()
 
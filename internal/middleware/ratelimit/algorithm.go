@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// Decision is the outcome of counting one request against a policy.
+type Decision struct {
+	// Allowed reports whether the request fits within the policy.
+	Allowed bool
+	// Limit is the policy's request budget.
+	Limit int
+	// Remaining is the number of further requests allowed right now.
+	Remaining int
+	// RetryAfter is how long a rejected client should wait.
+	RetryAfter time.Duration
+	// Reset is how long until the budget is fully restored.
+	Reset time.Duration
+}
+
+// minTTL keeps stored state alive long enough for stores with one-second
+// expiry resolution.
+const minTTL = time.Second
+
+// take counts one request at now against policy given the stored state and
+// returns the state to store with its time to live.
+func take(policy routing.RateLimitPolicy, state []byte, now time.Time) (next []byte, ttl time.Duration, d Decision) {
+	switch policy.Algorithm {
+	case routing.SlidingWindowLog:
+		next, ttl, d = takeSlidingWindowLog(policy, state, now)
+	case routing.GCRA:
+		next, ttl, d = takeGCRA(policy, state, now)
+	default:
+		next, ttl, d = takeTokenBucket(policy, state, now)
+	}
+	return next, max(ttl, minTTL), d
+}
+
+func burst(policy routing.RateLimitPolicy) int {
+	if policy.Burst > 0 {
+		return policy.Burst
+	}
+	return policy.Limit
+}
+
+// takeTokenBucket stores the token count and the time it was computed.
+func takeTokenBucket(policy routing.RateLimitPolicy, state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+	capacity := float64(burst(policy))
+	perToken := float64(policy.Period) / float64(policy.Limit)
+	tokens := capacity
+	if len(state) == 16 {
+		tokens = math.Float64frombits(binary.BigEndian.Uint64(state))
+		last := time.Unix(0, int64(binary.BigEndian.Uint64(state[8:])))
+		if elapsed := now.Sub(last); elapsed > 0 {
+			tokens = min(capacity, tokens+float64(elapsed)/perToken)
+		}
+	}
+
+	d := Decision{Limit: burst(policy)}
+	if tokens >= 1 {
+		tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration(math.Ceil((1 - tokens) * perToken))
+	}
+	d.Remaining = int(tokens)
+	d.Reset = time.Duration(math.Ceil((capacity - tokens) * perToken))
+
+	next := make([]byte, 16)
+	binary.BigEndian.PutUint64(next, math.Float64bits(tokens))
+	binary.BigEndian.PutUint64(next[8:], uint64(now.UnixNano()))
+	return next, d.Reset, d
+}
+
+// takeSlidingWindowLog stores the times of the requests in the current window.
+func takeSlidingWindowLog(policy routing.RateLimitPolicy, state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+	cutoff := now.Add(-policy.Period).UnixNano()
+	times := make([]int64, 0, len(state)/8+1)
+	for i := 0; i+8 <= len(state); i += 8 {
+		if ts := int64(binary.BigEndian.Uint64(state[i:])); ts > cutoff {
+			times = append(times, ts)
+		}
+	}
+
+	d := Decision{Limit: policy.Limit}
+	if len(times) < policy.Limit {
+		times = append(times, now.UnixNano())
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration(times[len(times)-policy.Limit] - cutoff)
+	}
+	d.Remaining = max(policy.Limit-len(times), 0)
+	if len(times) > 0 {
+		d.Reset = time.Duration(times[len(times)-1] - cutoff)
+	}
+
+	next := make([]byte, 8*len(times))
+	for i, ts := range times {
+		binary.BigEndian.PutUint64(next[8*i:], uint64(ts))
+	}
+	return next, policy.Period, d
+}
+
+// takeGCRA stores the theoretical arrival time of the next request.
+func takeGCRA(policy routing.RateLimitPolicy, state []byte, now time.Time) ([]byte, time.Duration, Decision) {
+	interval := policy.Period / time.Duration(policy.Limit)
+	tolerance := interval * time.Duration(burst(policy))
+	tat := now
+	if len(state) == 8 {
+		if stored := time.Unix(0, int64(binary.BigEndian.Uint64(state))); stored.After(now) {
+			tat = stored
+		}
+	}
+
+	d := Decision{Limit: burst(policy)}
+	newTAT := tat.Add(interval)
+	if allowAt := newTAT.Add(-tolerance); now.Before(allowAt) {
+		d.RetryAfter = allowAt.Sub(now)
+		newTAT = tat
+	} else {
+		d.Allowed = true
+	}
+	d.Reset = newTAT.Sub(now)
+	if interval > 0 {
+		d.Remaining = max(int((tolerance-d.Reset)/interval), 0)
+	}
+
+	next := make([]byte, 8)
+	binary.BigEndian.PutUint64(next, uint64(newTAT.UnixNano()))
+	return next, d.Reset, d
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+// runTakes counts requests at the given offsets from a fixed start and
+// returns whether each was allowed, along with the last decision.
+func runTakes(policy routing.RateLimitPolicy, offsets ...time.Duration) ([]bool, Decision) {
+	start := time.Unix(1_700_000_000, 0)
+	var state []byte
+	var d Decision
+	allowed := make([]bool, 0, len(offsets))
+	for _, offset := range offsets {
+		var next []byte
+		next, _, d = take(policy, state, start.Add(offset))
+		if d.Allowed {
+			state = next
+		}
+		allowed = append(allowed, d.Allowed)
+	}
+	return allowed, d
+}
+
+func TestShouldEnforceLimitGivenEachAlgorithm(t *testing.T) {
+	for _, algorithm := range []routing.RateLimitAlgorithm{routing.TokenBucket, routing.SlidingWindowLog, routing.GCRA} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			// Arrange
+			policy := routing.RateLimitPolicy{Limit: 3, Period: time.Second, Algorithm: algorithm}
+
+			// Act
+			allowed, last := runTakes(policy, 0, 0, 0, 0)
+
+			// Assert
+			assert.Equal(t, []bool{true, true, true, false}, allowed)
+			assert.Equal(t, 3, last.Limit)
+			assert.Equal(t, 0, last.Remaining)
+			assert.Positive(t, last.RetryAfter)
+			assert.LessOrEqual(t, last.RetryAfter, time.Second)
+		})
+	}
+}
+
+func TestShouldRestoreCapacityGivenTimePasses(t *testing.T) {
+	cases := []struct {
+		algorithm routing.RateLimitAlgorithm
+		wait      time.Duration
+	}{
+		{routing.TokenBucket, 500 * time.Millisecond},
+		{routing.SlidingWindowLog, time.Second},
+		{routing.GCRA, 500 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		t.Run(tc.algorithm.String(), func(t *testing.T) {
+			// Arrange
+			policy := routing.RateLimitPolicy{Limit: 2, Period: time.Second, Algorithm: tc.algorithm}
+
+			// Act
+			allowed, _ := runTakes(policy, 0, 0, tc.wait-time.Millisecond, tc.wait)
+
+			// Assert
+			assert.Equal(t, []bool{true, true, false, true}, allowed)
+		})
+	}
+}
+
+func TestShouldLimitBurstGivenTokenBucketAndGCRA(t *testing.T) {
+	for _, algorithm := range []routing.RateLimitAlgorithm{routing.TokenBucket, routing.GCRA} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			// Arrange: 10 per second on average, at most 2 at once.
+			policy := routing.RateLimitPolicy{Limit: 10, Period: time.Second, Burst: 2, Algorithm: algorithm}
+
+			// Act
+			allowed, last := runTakes(policy, 0, 0, 0, 100*time.Millisecond)
+
+			// Assert
+			assert.Equal(t, []bool{true, true, false, true}, allowed)
+			assert.Equal(t, 2, last.Limit)
+		})
+	}
+}
+
+func TestShouldReportRetryAfterGivenSlidingWindowLog(t *testing.T) {
+	// Arrange
+	policy := routing.RateLimitPolicy{Limit: 2, Period: time.Minute, Algorithm: routing.SlidingWindowLog}
+
+	// Act
+	allowed, last := runTakes(policy, 0, 10*time.Second, 20*time.Second)
+
+	// Assert
+	assert.Equal(t, []bool{true, true, false}, allowed)
+	assert.Equal(t, 40*time.Second, last.RetryAfter, "the first request leaves the window at 60s")
+	assert.Equal(t, 50*time.Second, last.Reset)
+}
@@ -0,0 +1,207 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fgrzl/mux/internal/common"
+	openapi "github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// defaultLimitName names unnamed route policies in response headers, and
+// legacyLimitName names the limit set with WithRateLimit.
+const (
+	defaultLimitName = "default"
+	legacyLimitName  = "route"
+)
+
+// routeLimit is a policy to enforce on a route, with the name it is reported
+// under and the scope its budget is shared within.
+type routeLimit struct {
+	name   string
+	scope  string
+	policy routing.RateLimitPolicy
+}
+
+// appliedLimit is a policy that counted the request and its decision.
+type appliedLimit struct {
+	name     string
+	policy   routing.RateLimitPolicy
+	decision Decision
+}
+
+// routeLimits returns the windows of the first quota matching the request
+// followed by the route's policies.
+func routeLimits(c routing.RouteContext, opts *routing.RouteOptions) []routeLimit {
+	var limits []routeLimit
+	for _, quota := range opts.RateLimitQuotas {
+		if quota.Match != nil && !quota.Match(c) {
+			continue
+		}
+		for _, window := range quota.Windows {
+			name := quotaWindowName(quota, window)
+			limits = append(limits, routeLimit{name: name, scope: "quota:" + name, policy: window})
+		}
+		break
+	}
+	for _, policy := range opts.RateLimits {
+		limit := routeLimit{name: defaultLimitName, scope: opts.Method + ":" + opts.Pattern, policy: policy}
+		if policy.Name != "" {
+			limit.name = policy.Name
+			limit.scope = "name:" + policy.Name
+		}
+		limits = append(limits, limit)
+	}
+	return limits
+}
+
+// quotaWindowName names a quota window as "<quota>.<window>", where an
+// unnamed window is named for its period, such as "free.60s".
+func quotaWindowName(quota routing.RateLimitQuota, window routing.RateLimitPolicy) string {
+	name := window.Name
+	if name == "" {
+		name = strconv.FormatInt(seconds(window.Period), 10) + "s"
+	}
+	return quota.Name + "." + name
+}
+
+// setHeaders describes the applied limits with the RateLimit-Policy and
+// RateLimit fields, for example:
+//
+//	RateLimit-Policy: "free.minute";q=100;w=60, "free.day";q=10000;w=86400
+//	RateLimit: "free.minute";r=99;t=1, "free.day";r=9999;t=9
+func setHeaders(h http.Header, applied []appliedLimit) {
+	if len(applied) == 0 {
+		return
+	}
+	var policy, state strings.Builder
+	for i, limit := range applied {
+		if i > 0 {
+			policy.WriteString(", ")
+			state.WriteString(", ")
+		}
+		name := quoteString(limit.name)
+		policy.WriteString(name)
+		policy.WriteString(";q=")
+		policy.WriteString(strconv.Itoa(limit.policy.Limit))
+		policy.WriteString(";w=")
+		policy.WriteString(strconv.FormatInt(max(seconds(limit.policy.Period), 1), 10))
+		state.WriteString(name)
+		state.WriteString(";r=")
+		state.WriteString(strconv.Itoa(max(limit.decision.Remaining, 0)))
+		state.WriteString(";t=")
+		state.WriteString(strconv.FormatInt(seconds(limit.decision.Reset), 10))
+	}
+	h.Set(common.HeaderRateLimitPolicy, policy.String())
+	h.Set(common.HeaderRateLimit, state.String())
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// quoteString encodes s as a structured field string, dropping characters
+// the format cannot carry.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '"' || ch == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case ch >= 0x20 && ch < 0x7f:
+			b.WriteByte(ch)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Document adds the rate limit response headers, a 429 response, and an
+// x-ratelimit extension listing the route's limits to rate limited
+// operations. UseRateLimiter registers it with the router.
+func Document(options *routing.RouteOptions, op *openapi.Operation) {
+	if options == nil || op == nil {
+		return
+	}
+	extension := map[string]any{}
+	if len(options.RateLimits) > 0 || options.RateLimit > 0 {
+		var policies []map[string]any
+		for _, policy := range options.RateLimits {
+			name := policy.Name
+			if name == "" {
+				name = defaultLimitName
+			}
+			policies = append(policies, describePolicy(name, policy))
+		}
+		if options.RateLimit > 0 {
+			policies = append(policies, describePolicy(legacyLimitName, routing.RateLimitPolicy{Limit: options.RateLimit, Period: options.RateInterval}))
+		}
+		extension["policies"] = policies
+	}
+	if len(options.RateLimitQuotas) > 0 {
+		quotas := make([]map[string]any, 0, len(options.RateLimitQuotas))
+		for _, quota := range options.RateLimitQuotas {
+			windows := make([]map[string]any, 0, len(quota.Windows))
+			for _, window := range quota.Windows {
+				windows = append(windows, describePolicy(quotaWindowName(quota, window), window))
+			}
+			quotas = append(quotas, map[string]any{"name": quota.Name, "windows": windows})
+		}
+		extension["quotas"] = quotas
+	}
+	if len(extension) == 0 {
+		return
+	}
+
+	if op.Responses == nil {
+		op.Responses = map[string]*openapi.ResponseObject{}
+	}
+	if _, ok := op.Responses["429"]; !ok {
+		op.Responses["429"] = &openapi.ResponseObject{Description: rateLimitTitle}
+	}
+	for status, response := range op.Responses {
+		if response == nil {
+			continue
+		}
+		if response.Headers == nil {
+			response.Headers = map[string]*openapi.HeaderObject{}
+		}
+		addHeader(response.Headers, common.HeaderRateLimitPolicy, "Limits applied to this request, such as \"default\";q=100;w=60.", "string")
+		addHeader(response.Headers, common.HeaderRateLimit, "Remaining quota per limit and seconds until it resets, such as \"default\";r=99;t=1.", "string")
+		if status == "429" {
+			addHeader(response.Headers, common.HeaderRetryAfter, "Seconds to wait before retrying.", "integer")
+		}
+	}
+	if op.Extensions == nil {
+		op.Extensions = map[string]any{}
+	}
+	op.Extensions["x-ratelimit"] = extension
+}
+
+func describePolicy(name string, policy routing.RateLimitPolicy) map[string]any {
+	description := map[string]any{
+		"name":      name,
+		"limit":     policy.Limit,
+		"window":    max(seconds(policy.Period), 1),
+		"algorithm": policy.Algorithm.String(),
+	}
+	if policy.Burst > 0 {
+		description["burst"] = policy.Burst
+	}
+	return description
+}
+
+func addHeader(headers map[string]*openapi.HeaderObject, name, description, typ string) {
+	if _, ok := headers[name]; ok {
+		return
+	}
+	headers[name] = &openapi.HeaderObject{Description: description, Schema: &openapi.Schema{Type: typ}}
+}
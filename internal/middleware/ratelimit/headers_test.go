@@ -0,0 +1,179 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func freeAndProQuotas() (routing.RateLimitQuota, routing.RateLimitQuota) {
+	isPro := func(c routing.RouteContext) bool { return c.Request().Header.Get("X-Plan") == "pro" }
+	pro := routing.RateLimitQuota{
+		Name:    "pro",
+		Windows: []routing.RateLimitPolicy{{Name: "minute", Limit: 100, Period: time.Minute}},
+		Match:   isPro,
+	}
+	free := routing.RateLimitQuota{
+		Name: "free",
+		Windows: []routing.RateLimitPolicy{
+			{Name: "minute", Limit: 2, Period: time.Minute},
+			{Limit: 3, Period: 24 * time.Hour},
+		},
+	}
+	return pro, free
+}
+
+func TestShouldSetRateLimitHeadersGivenAllowedRequest(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 10, Period: time.Minute, Algorithm: routing.SlidingWindowLog})
+
+	// Act
+	rec := serve(rtr, "/a", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"default";q=10;w=60`, rec.Header().Get(common.HeaderRateLimitPolicy))
+	assert.Equal(t, `"default";r=9;t=60`, rec.Header().Get(common.HeaderRateLimit))
+}
+
+func TestShouldSetRateLimitHeadersGivenRejectedRequest(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Name: "search", Limit: 1, Period: 10 * time.Second})
+	serve(rtr, "/a", testIP1WithPort)
+
+	// Act
+	rec := serve(rtr, "/a", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, `"search";q=1;w=10`, rec.Header().Get(common.HeaderRateLimitPolicy))
+	assert.Equal(t, `"search";r=0;t=10`, rec.Header().Get(common.HeaderRateLimit))
+	assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+}
+
+func TestShouldSetHeadersAndRetryAfterGivenLegacyRouteLimit(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).WithRateLimit(1, 30*time.Second)
+
+	// Act
+	first := serve(rtr, "/a", testIP1WithPort)
+	second := serve(rtr, "/a", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, `"route";q=1;w=30`, first.Header().Get(common.HeaderRateLimitPolicy))
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+	assert.Equal(t, "30", second.Header().Get("Retry-After"))
+	assert.Equal(t, `"route";r=0;t=30`, second.Header().Get(common.HeaderRateLimit))
+}
+
+func TestShouldEnforceEveryWindowGivenQuota(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	_, free := freeAndProQuotas()
+	rtr.GET("/a", okHandler).WithRateLimitQuota(free)
+
+	// Act
+	first := serve(rtr, "/a", testIP1WithPort)
+	codes := statuses(rtr, 2, "/a", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, `"free.minute";q=2;w=60, "free.86400s";q=3;w=86400`, first.Header().Get(common.HeaderRateLimitPolicy))
+	assert.Equal(t, `"free.minute";r=1;t=30, "free.86400s";r=2;t=28800`, first.Header().Get(common.HeaderRateLimit))
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestShouldApplyFirstMatchingQuotaGivenTiers(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	pro, free := freeAndProQuotas()
+	api := rtr.NewRouteGroup("/api").WithRateLimitQuota(pro).WithRateLimitQuota(free)
+	api.GET("/a", okHandler)
+	api.GET("/b", okHandler)
+
+	// Act
+	freeCodes := []int{
+		serve(rtr, "/api/a", testIP1WithPort).Code,
+		serve(rtr, "/api/b", testIP1WithPort).Code,
+		serve(rtr, "/api/a", testIP1WithPort).Code,
+	}
+	proCodes := statuses(rtr, 3, "/api/a", testIP1WithPort, "X-Plan", "pro")
+	proHeader := serve(rtr, "/api/b", testIP1WithPort, "X-Plan", "pro").Header().Get(common.HeaderRateLimitPolicy)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, freeCodes, "routes sharing a quota share its budget")
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK}, proCodes)
+	assert.Equal(t, `"pro.minute";q=100;w=60`, proHeader)
+}
+
+func TestShouldEscapeNamesGivenStructuredFieldString(t *testing.T) {
+	// Act & Assert
+	assert.Equal(t, `"a\"b\\c"`, quoteString("a\"b\\c"))
+	assert.Equal(t, `"ab"`, quoteString("a\nb"))
+}
+
+func TestShouldDocumentRateLimitsGivenLimitedOperation(t *testing.T) {
+	// Arrange
+	_, free := freeAndProQuotas()
+	options := &routing.RouteOptions{
+		RateLimits:      []routing.RateLimitPolicy{{Name: "search", Limit: 10, Period: time.Second, Burst: 20, Algorithm: routing.GCRA}},
+		RateLimitQuotas: []routing.RateLimitQuota{free},
+	}
+	op := &openapi.Operation{Responses: map[string]*openapi.ResponseObject{"200": {Description: "OK"}}}
+
+	// Act
+	Document(options, op)
+
+	// Assert
+	require.Contains(t, op.Responses, "429")
+	assert.Contains(t, op.Responses["200"].Headers, common.HeaderRateLimitPolicy)
+	assert.Contains(t, op.Responses["200"].Headers, common.HeaderRateLimit)
+	assert.NotContains(t, op.Responses["200"].Headers, "Retry-After")
+	assert.Contains(t, op.Responses["429"].Headers, "Retry-After")
+	extension := op.Extensions["x-ratelimit"].(map[string]any)
+	assert.Equal(t, []map[string]any{{"name": "search", "limit": 10, "window": int64(1), "burst": 20, "algorithm": "gcra"}}, extension["policies"])
+	quotas := extension["quotas"].([]map[string]any)
+	require.Len(t, quotas, 1)
+	assert.Equal(t, "free", quotas[0]["name"])
+	assert.Len(t, quotas[0]["windows"], 2)
+}
+
+func TestShouldNotDocumentRateLimitsGivenUnlimitedOperation(t *testing.T) {
+	// Arrange
+	op := &openapi.Operation{Responses: map[string]*openapi.ResponseObject{"200": {Description: "OK"}}}
+
+	// Act
+	Document(&routing.RouteOptions{}, op)
+
+	// Assert
+	assert.NotContains(t, op.Responses, "429")
+	assert.Nil(t, op.Extensions)
+}
+
+func TestShouldDocumentRateLimitsGivenRouterSpec(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseRateLimiter(rtr)
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 5, Period: time.Minute})
+	rtr.GET("/b", okHandler)
+
+	// Act
+	routes, err := rtr.Routes()
+
+	// Assert
+	require.NoError(t, err)
+	for _, route := range routes {
+		_, limited := route.Options.Extensions["x-ratelimit"]
+		assert.Equal(t, route.Path == "/a", limited, route.Path)
+	}
+}
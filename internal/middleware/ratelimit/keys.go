@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"net"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// KeyByClientIP identifies clients by IP address. It reads the request's
+// RemoteAddr, which the forwarded headers middleware rewrites to the client
+// IP reported by trusted proxies, so register that middleware first when
+// running behind a proxy.
+func KeyByClientIP() routing.RateLimitKeyFunc {
+	return func(c routing.RouteContext) (string, bool) {
+		ip := clientIP(c)
+		return "ip:" + ip, ip != ""
+	}
+}
+
+// KeyBySubject identifies clients by the authenticated principal's subject.
+func KeyBySubject() routing.RateLimitKeyFunc {
+	return func(c routing.RouteContext) (string, bool) {
+		user := c.User()
+		if user == nil || user.Subject() == "" {
+			return "", false
+		}
+		return "sub:" + user.Subject(), true
+	}
+}
+
+// KeyByHeader identifies clients by a request header such as an API key.
+func KeyByHeader(name string) routing.RateLimitKeyFunc {
+	return func(c routing.RouteContext) (string, bool) {
+		value := strings.TrimSpace(c.Request().Header.Get(name))
+		return "header:" + strings.ToLower(name) + ":" + value, value != ""
+	}
+}
+
+// KeyByClaim identifies clients by a claim of the authenticated principal,
+// such as a tenant or organization identifier.
+func KeyByClaim(name string) routing.RateLimitKeyFunc {
+	return func(c routing.RouteContext) (string, bool) {
+		user := c.User()
+		if user == nil {
+			return "", false
+		}
+		value := user.CustomClaimValue(name)
+		return "claim:" + name + ":" + value, value != ""
+	}
+}
+
+// KeyByPathParams identifies clients by path parameter values, such as a
+// tenant ID in the path. Every parameter must be present.
+func KeyByPathParams(names ...string) routing.RateLimitKeyFunc {
+	return func(c routing.RouteContext) (string, bool) {
+		if len(names) == 0 {
+			return "", false
+		}
+		var b strings.Builder
+		b.WriteString("params")
+		for _, name := range names {
+			value, ok := c.Param(name)
+			if !ok || value == "" {
+				return "", false
+			}
+			b.WriteString(":")
+			b.WriteString(name)
+			b.WriteString("=")
+			b.WriteString(value)
+		}
+		return b.String(), true
+	}
+}
+
+func clientIP(c routing.RouteContext) string {
+	addr := c.Request().RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
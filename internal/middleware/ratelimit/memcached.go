@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMemcachedTTL is the longest relative expiry memcached accepts; larger
+// values are read as absolute Unix times.
+const maxMemcachedTTL = 30 * 24 * time.Hour
+
+// memcachedPoolSize bounds the idle connections kept per store.
+const memcachedPoolSize = 8
+
+// MemcachedStore is a reference Store backed by a memcached server, using
+// the text protocol's gets and cas commands for atomic updates.
+type MemcachedStore struct {
+	addr    string
+	timeout time.Duration
+	dialer  net.Dialer
+	idle    chan *memcachedConn
+}
+
+type memcachedConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// NewMemcachedStore returns a store that talks to the memcached server at
+// addr. timeout bounds each command; zero means one second.
+func NewMemcachedStore(addr string, timeout time.Duration) *MemcachedStore {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	return &MemcachedStore{addr: addr, timeout: timeout, idle: make(chan *memcachedConn, memcachedPoolSize)}
+}
+
+// Close closes idle connections.
+func (s *MemcachedStore) Close() error {
+	var errs []error
+	for {
+		select {
+		case mc := <-s.idle:
+			errs = append(errs, mc.conn.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// Get implements Store.
+func (s *MemcachedStore) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	var value []byte
+	var version uint64
+	err := s.do(ctx, func(mc *memcachedConn) error {
+		if _, err := fmt.Fprintf(mc.rw, "gets %s\r\n", memcachedKey(key)); err != nil {
+			return err
+		}
+		if err := mc.rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readLine(mc.rw)
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return nil
+		}
+		// VALUE <key> <flags> <bytes> <cas unique>
+		fields := strings.Fields(line)
+		if len(fields) != 5 || fields[0] != "VALUE" {
+			return fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil || size < 0 {
+			return fmt.Errorf("memcached: bad value length %q", fields[3])
+		}
+		if version, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+			return fmt.Errorf("memcached: bad cas unique %q", fields[4])
+		}
+		value = make([]byte, size+2)
+		if _, err := io.ReadFull(mc.rw, value); err != nil {
+			return err
+		}
+		value = value[:size]
+		if line, err = readLine(mc.rw); err != nil {
+			return err
+		}
+		if line != "END" {
+			return fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		return nil
+	})
+	return value, version, err
+}
+
+// CompareAndSwap implements Store. Version 0 uses add, which stores only
+// when the key is absent; other versions use cas.
+func (s *MemcachedStore) CompareAndSwap(ctx context.Context, key string, version uint64, value []byte, ttl time.Duration) (bool, error) {
+	seconds := int64(min(max(ttl, time.Second), maxMemcachedTTL) / time.Second)
+	var stored bool
+	err := s.do(ctx, func(mc *memcachedConn) error {
+		var err error
+		if version == 0 {
+			_, err = fmt.Fprintf(mc.rw, "add %s 0 %d %d\r\n", memcachedKey(key), seconds, len(value))
+		} else {
+			_, err = fmt.Fprintf(mc.rw, "cas %s 0 %d %d %d\r\n", memcachedKey(key), seconds, len(value), version)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := mc.rw.Write(value); err != nil {
+			return err
+		}
+		if _, err := mc.rw.WriteString("\r\n"); err != nil {
+			return err
+		}
+		if err := mc.rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readLine(mc.rw)
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "STORED":
+			stored = true
+		case "NOT_STORED", "EXISTS", "NOT_FOUND":
+		default:
+			return fmt.Errorf("memcached: unexpected reply %q", line)
+		}
+		return nil
+	})
+	return stored, err
+}
+
+// do runs fn on a pooled connection. Connections that fail are closed
+// rather than returned to the pool.
+func (s *MemcachedStore) do(ctx context.Context, fn func(*memcachedConn) error) error {
+	mc, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := mc.conn.SetDeadline(deadline); err != nil {
+		_ = mc.conn.Close()
+		return err
+	}
+	if err := fn(mc); err != nil {
+		_ = mc.conn.Close()
+		return err
+	}
+	select {
+	case s.idle <- mc:
+	default:
+		_ = mc.conn.Close()
+	}
+	return nil
+}
+
+func (s *MemcachedStore) conn(ctx context.Context) (*memcachedConn, error) {
+	select {
+	case mc := <-s.idle:
+		return mc, nil
+	default:
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &memcachedConn{conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}, nil
+}
+
+func readLine(r *bufio.ReadWriter) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "ERROR") || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+		return "", fmt.Errorf("memcached: %s", line)
+	}
+	return line, nil
+}
+
+// memcachedKey returns key if memcached accepts it as is, and otherwise a
+// hash of it. Keys may not exceed 250 bytes or contain spaces or control
+// characters.
+func memcachedKey(key string) string {
+	valid := len(key) > 0 && len(key) <= 250
+	for i := 0; valid && i < len(key); i++ {
+		valid = key[i] > ' ' && key[i] != 0x7f
+	}
+	if valid {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "ratelimit:sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,274 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/claims"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+type setUserMiddleware struct{}
+
+func (setUserMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	if subject := c.Request().Header.Get("X-Subject"); subject != "" {
+		set := claims.NewClaimsSet(subject).Set("tenant", c.Request().Header.Get("X-Tenant"))
+		c.SetUser(claims.NewPrincipal(set))
+	}
+	next(c)
+}
+
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, uint64, error) {
+	return nil, 0, errors.New("store unavailable")
+}
+
+func (failingStore) CompareAndSwap(context.Context, string, uint64, []byte, time.Duration) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func newPolicyRouter(opts ...RateLimiterOption) *router.Router {
+	rtr := router.NewRouter()
+	rtr.Use(setUserMiddleware{})
+	limiter := NewSelectiveRateLimiter(opts...)
+	rtr.Use(limiter)
+	return rtr
+}
+
+func okHandler(c routing.RouteContext) { c.OK("ok") }
+
+// serve sends a GET for path from remoteAddr with the given headers and
+// returns the recorder.
+func serve(rtr *router.Router, path, remoteAddr string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func statuses(rtr *router.Router, n int, path, remoteAddr string, headers ...string) []int {
+	codes := make([]int, n)
+	for i := range codes {
+		codes[i] = serve(rtr, path, remoteAddr, headers...).Code
+	}
+	return codes
+}
+
+func TestShouldRejectWithRetryAfterGivenRoutePolicyExceeded(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 2, Period: time.Minute})
+
+	// Act
+	codes := statuses(rtr, 2, "/a", testIP1WithPort)
+	rec := serve(rtr, "/a", testIP1WithPort)
+	other := serve(rtr, "/a", testIP2WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), rateLimitTitle)
+	assert.Equal(t, http.StatusOK, other.Code, "each client IP has its own budget")
+}
+
+func TestShouldInheritGroupPolicyGivenRoutesInGroup(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	api := rtr.NewRouteGroup("/api").WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 1, Period: time.Minute})
+	api.GET("/a", okHandler)
+	api.GET("/b", okHandler)
+
+	// Act
+	a := statuses(rtr, 2, "/api/a", testIP1WithPort)
+	b := statuses(rtr, 1, "/api/b", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, a)
+	assert.Equal(t, []int{http.StatusOK}, b, "unnamed policies give each route its own budget")
+}
+
+func TestShouldShareBudgetGivenPoliciesWithSameName(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	api := rtr.NewRouteGroup("/api").WithRateLimitPolicy(routing.RateLimitPolicy{Name: "api", Limit: 2, Period: time.Minute})
+	api.GET("/a", okHandler)
+	api.GET("/b", okHandler)
+
+	// Act
+	codes := []int{
+		serve(rtr, "/api/a", testIP1WithPort).Code,
+		serve(rtr, "/api/b", testIP1WithPort).Code,
+		serve(rtr, "/api/a", testIP1WithPort).Code,
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestShouldOverrideGroupPolicyGivenRoutePolicyWithSameName(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	api := rtr.NewRouteGroup("/api").WithRateLimitPolicy(routing.RateLimitPolicy{Name: "api", Limit: 1, Period: time.Minute})
+	api.GET("/bulk", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Name: "api", Limit: 3, Period: time.Minute})
+
+	// Act
+	codes := statuses(rtr, 4, "/api/bulk", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestShouldApplyEveryPolicyGivenRouteWithSeveral(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).
+		WithRateLimitPolicy(routing.RateLimitPolicy{Name: "burst", Limit: 5, Period: time.Second}).
+		WithRateLimitPolicy(routing.RateLimitPolicy{Name: "daily", Limit: 2, Period: 24 * time.Hour})
+
+	// Act
+	codes := statuses(rtr, 3, "/a", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestShouldNotChargeOtherPoliciesGivenRequestRejectedByOne(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	shared := routing.RateLimitPolicy{Name: "shared", Limit: 3, Period: time.Hour}
+	rtr.GET("/a", okHandler).
+		WithRateLimitPolicy(shared).
+		WithRateLimitPolicy(routing.RateLimitPolicy{Name: "strict", Limit: 1, Period: time.Hour})
+	rtr.GET("/b", okHandler).WithRateLimitPolicy(shared)
+
+	// Act
+	a := statuses(rtr, 3, "/a", testIP1WithPort)
+	b := statuses(rtr, 3, "/b", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}, a)
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, b, "rejected requests leave the shared budget untouched")
+}
+
+func TestShouldNotChargePoliciesGivenRequestRejectedByRouteRateLimit(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	shared := routing.RateLimitPolicy{Name: "shared", Limit: 3, Period: time.Hour}
+	// WithRateLimit(2, ...) lets one request through per interval.
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(shared).WithRateLimit(2, time.Hour)
+	rtr.GET("/b", okHandler).WithRateLimitPolicy(shared)
+
+	// Act
+	a := statuses(rtr, 3, "/a", testIP1WithPort)
+	b := statuses(rtr, 3, "/b", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}, a)
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, b)
+}
+
+func TestShouldKeyByHeaderGivenHeaderKeyFunc(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 1, Period: time.Minute, Key: KeyByHeader("X-API-Key")})
+
+	// Act
+	first := serve(rtr, "/a", testIP1WithPort, "X-API-Key", "one").Code
+	sameKeyOtherIP := serve(rtr, "/a", testIP2WithPort, "X-API-Key", "one").Code
+	otherKey := serve(rtr, "/a", testIP1WithPort, "X-API-Key", "two").Code
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first)
+	assert.Equal(t, http.StatusTooManyRequests, sameKeyOtherIP)
+	assert.Equal(t, http.StatusOK, otherKey)
+}
+
+func TestShouldFallBackToClientIPGivenKeyMissing(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 1, Period: time.Minute, Key: KeyBySubject()})
+
+	// Act
+	anonymous := statuses(rtr, 2, "/a", testIP1WithPort)
+	alice := serve(rtr, "/a", testIP1WithPort, "X-Subject", "alice").Code
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, anonymous)
+	assert.Equal(t, http.StatusOK, alice)
+}
+
+func TestShouldKeyByClaimGivenDefaultKey(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter(WithDefaultKey(KeyByClaim("tenant")))
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 1, Period: time.Minute})
+
+	// Act
+	alice := serve(rtr, "/a", testIP1WithPort, "X-Subject", "alice", "X-Tenant", "acme").Code
+	bob := serve(rtr, "/a", testIP2WithPort, "X-Subject", "bob", "X-Tenant", "acme").Code
+	carol := serve(rtr, "/a", testIP2WithPort, "X-Subject", "carol", "X-Tenant", "globex").Code
+
+	// Assert
+	assert.Equal(t, http.StatusOK, alice)
+	assert.Equal(t, http.StatusTooManyRequests, bob, "users of one tenant share its budget")
+	assert.Equal(t, http.StatusOK, carol)
+}
+
+func TestShouldKeyByPathParamsGivenPathParamsKeyFunc(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter()
+	rtr.GET("/tenants/{id}", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 1, Period: time.Minute, Key: KeyByPathParams("id")})
+
+	// Act
+	codes := []int{
+		serve(rtr, "/tenants/1", testIP1WithPort).Code,
+		serve(rtr, "/tenants/1", testIP2WithPort).Code,
+		serve(rtr, "/tenants/2", testIP1WithPort).Code,
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK}, codes)
+}
+
+func TestShouldAllowRequestGivenStoreFails(t *testing.T) {
+	// Arrange
+	rtr := newPolicyRouter(WithStore(failingStore{}))
+	rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 1, Period: time.Minute})
+
+	// Act
+	codes := statuses(rtr, 2, "/a", testIP1WithPort)
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+}
+
+func TestShouldShareBudgetGivenLimitersUsingOneStore(t *testing.T) {
+	// Arrange
+	store := NewMemcachedStore(startFakeMemcached(t), time.Second)
+	t.Cleanup(func() { _ = store.Close() })
+	replicas := []*router.Router{newPolicyRouter(WithStore(store)), newPolicyRouter(WithStore(store))}
+	for _, rtr := range replicas {
+		rtr.GET("/a", okHandler).WithRateLimitPolicy(routing.RateLimitPolicy{Limit: 2, Period: time.Minute})
+	}
+
+	// Act
+	codes := []int{
+		serve(replicas[0], "/a", testIP1WithPort).Code,
+		serve(replicas[1], "/a", testIP1WithPort).Code,
+		serve(replicas[0], "/a", testIP1WithPort).Code,
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
@@ -2,22 +2,34 @@ package ratelimit
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/fgrzl/mux/internal/common"
+
 	"github.com/fgrzl/mux/internal/router"
 	"github.com/fgrzl/mux/internal/routing"
 )
 
-// SelectiveRateLimiter implements a simple token-bucket rate limiter keyed by client IP and route pattern.
+// SelectiveRateLimiter enforces the rate limit policies attached to routes
+// and groups against a Store, and the legacy per-route WithRateLimit limit
+// with an in-memory token bucket keyed by client IP and route pattern.
 type SelectiveRateLimiter struct {
 	mu            sync.Mutex
 	visitors      map[string]*visitor
 	cleanupTicker time.Duration
 	stopCleanup   chan struct{}
 	cleanupDone   chan struct{}
+
+	store      Store
+	defaultKey routing.RateLimitKeyFunc
+	now        func() time.Time
 }
 
 type visitor struct {
@@ -30,6 +42,11 @@ type visitor struct {
 // RateLimiterOptions configures the rate limiter behavior.
 type RateLimiterOptions struct {
 	CleanupInterval time.Duration
+	// Store holds policy state. Nil uses a new MemoryStore.
+	Store Store
+	// DefaultKey identifies clients for policies without a Key. Nil uses
+	// KeyByClientIP.
+	DefaultKey routing.RateLimitKeyFunc
 }
 
 // RateLimiterOption applies a configuration to RateLimiterOptions.
@@ -42,10 +59,26 @@ func WithCleanupInterval(d time.Duration) RateLimiterOption {
 	}
 }
 
+// WithStore sets the Store that holds policy state, such as a
+// MemcachedStore shared by several replicas.
+func WithStore(store Store) RateLimiterOption {
+	return func(o *RateLimiterOptions) {
+		o.Store = store
+	}
+}
+
+// WithDefaultKey sets how clients are identified for policies without a Key.
+func WithDefaultKey(key routing.RateLimitKeyFunc) RateLimiterOption {
+	return func(o *RateLimiterOptions) {
+		o.DefaultKey = key
+	}
+}
+
 // UseRateLimiter adds rate limiting middleware to the router with the given options.
 func UseRateLimiter(r *router.Router, opts ...RateLimiterOption) {
 	limiter := NewSelectiveRateLimiter(opts...)
 	r.Use(limiter)
+	r.DocumentOperations(Document)
 }
 
 // NewSelectiveRateLimiter constructs a SelectiveRateLimiter with optional configuration.
@@ -62,6 +95,15 @@ func NewSelectiveRateLimiter(opts ...RateLimiterOption) *SelectiveRateLimiter {
 		cleanupTicker: config.CleanupInterval,
 		stopCleanup:   make(chan struct{}),
 		cleanupDone:   make(chan struct{}),
+		store:         config.Store,
+		defaultKey:    config.DefaultKey,
+		now:           time.Now,
+	}
+	if rl.store == nil {
+		rl.store = NewMemoryStore()
+	}
+	if rl.defaultKey == nil {
+		rl.defaultKey = KeyByClientIP()
 	}
 	go rl.cleanupExpiredVisitors()
 	return rl
@@ -96,43 +138,210 @@ func (m *SelectiveRateLimiter) Stop() {
 const rateLimitTitle = "Rate limit exceeded"
 const rateLimitDetail = "You have exceeded the allowed number of requests. Please try again later."
 
+// maxSwapAttempts bounds the compare-and-swap retries per policy when
+// concurrent requests update the same key.
+const maxSwapAttempts = 8
+
+var errContention = errors.New("rate limit state changed concurrently too many times")
+
+// Invoke checks every limit before charging any of them, so a request
+// rejected by one policy does not spend the others' budgets.
 func (m *SelectiveRateLimiter) Invoke(c routing.RouteContext, next router.HandlerFunc) {
 	opts := c.Options()
-	if opts == nil || opts.RateLimit <= 0 {
+	if opts == nil {
 		next(c)
 		return
 	}
+	var pending []pendingLimit
+	var applied []appliedLimit
+	for _, limit := range routeLimits(c, opts) {
+		key, ok := m.key(c, limit.scope, limit.policy)
+		if !ok {
+			continue
+		}
+		d, err := m.peek(c, key, limit.policy)
+		if err != nil {
+			slog.WarnContext(c, "rate limit store failed; allowing request", "key", key, "error", err)
+			continue
+		}
+		pending = append(pending, pendingLimit{routeLimit: limit, key: key})
+		applied = append(applied, appliedLimit{name: limit.name, policy: limit.policy, decision: d})
+		if !d.Allowed {
+			setHeaders(c.Response().Header(), applied)
+			m.reject(c, d.RetryAfter)
+			return
+		}
+	}
+
+	var legacy *appliedLimit
+	var ip string
+	if opts.RateLimit > 0 {
+		var err error
+		ip, _, err = net.SplitHostPort(c.Request().RemoteAddr)
+		if err != nil {
+			// If we can't parse the host:port, use the entire RemoteAddr as IP
+			ip = c.Request().RemoteAddr
+		}
+		legacy = &appliedLimit{
+			name:   legacyLimitName,
+			policy: routing.RateLimitPolicy{Limit: opts.RateLimit, Period: opts.RateInterval},
+			// A token is restored every RateInterval.
+			decision: Decision{Limit: opts.RateLimit, RetryAfter: opts.RateInterval, Reset: opts.RateInterval},
+		}
+		// Use the configured route pattern (from options) as the visitor key part.
+		// Reading pattern from the RouteOptions ensures we use the canonical route
+		// identifier registered at startup rather than any request-derived value.
+		remaining, ok := m.takeVisitor(ip, opts.Pattern, opts.RateLimit, opts.RateInterval)
+		if !ok {
+			setHeaders(c.Response().Header(), append(applied, *legacy))
+			m.reject(c, opts.RateInterval)
+			return
+		}
+		legacy.decision.Allowed = true
+		legacy.decision.Remaining = remaining
+	}
 
-	ip, _, err := net.SplitHostPort(c.Request().RemoteAddr)
+	// Every limit allowed the request; charge them. A concurrent request can
+	// still exhaust a policy in between, in which case the legacy token is
+	// returned, though policies already charged keep the charge.
+	applied = applied[:0]
+	for _, limit := range pending {
+		d, err := m.consume(c, limit.key, limit.policy)
+		if err != nil {
+			slog.WarnContext(c, "rate limit store failed; allowing request", "key", limit.key, "error", err)
+			continue
+		}
+		applied = append(applied, appliedLimit{name: limit.name, policy: limit.policy, decision: d})
+		if !d.Allowed {
+			if legacy != nil {
+				m.returnVisitorToken(ip, opts.Pattern, opts.RateLimit)
+			}
+			setHeaders(c.Response().Header(), applied)
+			m.reject(c, d.RetryAfter)
+			return
+		}
+	}
+	if legacy != nil {
+		applied = append(applied, *legacy)
+	}
+	setHeaders(c.Response().Header(), applied)
+	next(c)
+}
+
+// pendingLimit is a limit that allowed the request and is charged once every
+// limit has.
+type pendingLimit struct {
+	routeLimit
+	key string
+}
+
+// reject writes the 429 problem, with Retry-After when the wait is known.
+func (m *SelectiveRateLimiter) reject(c routing.RouteContext, retryAfter time.Duration) {
+	routing.ReportRejection(c, routing.RejectionRateLimited)
+	if retryAfter > 0 {
+		seconds := int64(math.Ceil(retryAfter.Seconds()))
+		c.Response().Header().Set(common.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+	}
+	instance := c.Request().RequestURI
+	c.Problem(&routing.ProblemDetails{
+		Title:    rateLimitTitle,
+		Detail:   rateLimitDetail,
+		Status:   http.StatusTooManyRequests,
+		Type:     "https://httpstatuses.com/429",
+		Instance: &instance,
+	})
+}
+
+// key returns the store key counting the request against policy within
+// scope. ok is false when the policy is not applied: it is invalid or no
+// client key is available.
+func (m *SelectiveRateLimiter) key(c routing.RouteContext, scope string, policy routing.RateLimitPolicy) (string, bool) {
+	if policy.Limit <= 0 || policy.Period <= 0 {
+		return "", false
+	}
+	keyFunc := policy.Key
+	if keyFunc == nil {
+		keyFunc = m.defaultKey
+	}
+	key, ok := keyFunc(c)
+	if !ok {
+		// Requests without the configured identity share their IP's budget.
+		if key, ok = KeyByClientIP()(c); !ok {
+			return "", false
+		}
+	}
+	return "ratelimit:" + scope + ":" + key, true
+}
+
+// peek reports whether the policy would allow the request without charging
+// it. A store failure is returned so the caller can allow the request and
+// log it.
+func (m *SelectiveRateLimiter) peek(ctx context.Context, key string, policy routing.RateLimitPolicy) (Decision, error) {
+	state, _, err := m.store.Get(ctx, key)
 	if err != nil {
-		// If we can't parse the host:port, use the entire RemoteAddr as IP
-		ip = c.Request().RemoteAddr
+		return Decision{}, err
 	}
-	// Use the configured route pattern (from options) as the visitor key part.
-	// Reading pattern from the RouteOptions ensures we use the canonical route
-	// identifier registered at startup rather than any request-derived value.
-	v := m.getVisitor(ip, opts.Pattern, opts.RateLimit, opts.RateInterval)
+	_, _, d := take(policy, state, m.now())
+	return d, nil
+}
 
-	if v.tokens <= 0 {
-		instance := c.Request().RequestURI
-		c.Problem(&routing.ProblemDetails{
-			Title:    rateLimitTitle,
-			Detail:   rateLimitDetail,
-			Status:   http.StatusTooManyRequests,
-			Type:     "https://httpstatuses.com/429",
-			Instance: &instance,
-		})
-		return
+// consume applies the policy's algorithm to the stored state, retrying
+// when another request updates the key concurrently. Rejections leave the
+// state unchanged and are not written back.
+func (m *SelectiveRateLimiter) consume(ctx context.Context, key string, policy routing.RateLimitPolicy) (Decision, error) {
+	for range maxSwapAttempts {
+		state, version, err := m.store.Get(ctx, key)
+		if err != nil {
+			return Decision{}, err
+		}
+		next, ttl, d := take(policy, state, m.now())
+		if !d.Allowed {
+			return d, nil
+		}
+		stored, err := m.store.CompareAndSwap(ctx, key, version, next, ttl)
+		if err != nil {
+			return Decision{}, err
+		}
+		if stored {
+			return d, nil
+		}
 	}
+	return Decision{}, errContention
+}
 
+// takeVisitor takes a token from the visitor's bucket and reports the
+// tokens remaining, or false when the bucket is empty. The check and the
+// decrement happen under mu.
+func (m *SelectiveRateLimiter) takeVisitor(ip, key string, limit int, interval time.Duration) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := m.visitorLocked(ip, key, limit, interval)
+	if v.tokens <= 0 {
+		return 0, false
+	}
 	v.tokens--
-	next(c)
+	return max(v.tokens, 0), true
+}
+
+// returnVisitorToken gives back a token taken by takeVisitor for a request
+// that was rejected afterwards.
+func (m *SelectiveRateLimiter) returnVisitorToken(ip, key string, limit int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.visitors[ip+":"+key]; ok && v.tokens < limit {
+		v.tokens++
+	}
 }
 
 func (m *SelectiveRateLimiter) getVisitor(ip, key string, limit int, interval time.Duration) *visitor {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.visitorLocked(ip, key, limit, interval)
+}
 
+// visitorLocked returns the visitor's bucket refilled for the time since its
+// last access. The caller holds mu.
+func (m *SelectiveRateLimiter) visitorLocked(ip, key string, limit int, interval time.Duration) *visitor {
 	id := ip + ":" + key
 	v, ok := m.visitors[id]
 	now := time.Now()
@@ -2,6 +2,8 @@ package ratelimit
 
 import (
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -365,6 +367,27 @@ func TestShouldDecrementTokensOnEachRequest(t *testing.T) {
 	assert.Equal(t, 3, callCount)
 }
 
+func TestShouldAllowLimitGivenConcurrentRequestsFromOneVisitor(t *testing.T) {
+	// Arrange
+	limiter := NewSelectiveRateLimiter()
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+
+	// Act
+	for range 20 {
+		ctx, _ := testhelpers.NewRouteContext(http.MethodGet, testPath, nil)
+		ctx.Request().RemoteAddr = testIP1WithPort
+		ctx.SetOptions(&routing.RouteOptions{RateLimit: 6, RateInterval: time.Hour})
+		wg.Go(func() {
+			limiter.Invoke(ctx, func(routing.RouteContext) { allowed.Add(1) })
+		})
+	}
+	wg.Wait()
+
+	// Assert - the first visitor starts with 5 tokens (6-1)
+	assert.Equal(t, int32(5), allowed.Load())
+}
+
 func TestShouldAddMiddlewareToRouterGivenUseRateLimiter(t *testing.T) {
 	// Arrange
 	rtr := router.NewRouter()
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store persists rate limit state so that replicas sharing a store share
+// budgets. State is opaque to the store. Implementations must make
+// CompareAndSwap atomic: of two callers swapping the same version, at most
+// one succeeds.
+type Store interface {
+	// Get returns the state stored under key and its version. A missing or
+	// expired key returns a nil value and version 0.
+	Get(ctx context.Context, key string) (value []byte, version uint64, err error)
+	// CompareAndSwap stores value under key if the key is still at version,
+	// where version 0 means the key must not exist. The entry expires after
+	// ttl. It reports whether the value was stored.
+	CompareAndSwap(ctx context.Context, key string, version uint64, value []byte, ttl time.Duration) (bool, error)
+}
+
+// sweepEvery is the number of writes between sweeps of expired entries.
+const sweepEvery = 1024
+
+// MemoryStore is an in-process Store. It is the default when no store is
+// configured and does not share state across processes.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	version uint64
+	writes  int
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	version uint64
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}, now: time.Now}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || !s.now().Before(entry.expires) {
+		return nil, 0, nil
+	}
+	return append([]byte(nil), entry.value...), entry.version, nil
+}
+
+// CompareAndSwap implements Store.
+func (s *MemoryStore) CompareAndSwap(_ context.Context, key string, version uint64, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	current := uint64(0)
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		current = entry.version
+	}
+	if current != version {
+		return false, nil
+	}
+	s.version++
+	s.entries[key] = &memoryEntry{value: append([]byte(nil), value...), version: s.version, expires: now.Add(ttl)}
+	if s.writes++; s.writes >= sweepEvery {
+		s.writes = 0
+		for k, entry := range s.entries {
+			if !now.Before(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	return true, nil
+}
+
+// Len reports the number of stored entries, including expired entries not
+// yet swept.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
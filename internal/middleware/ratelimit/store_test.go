@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSwapOnlyCurrentVersionGivenMemoryStore(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	// Act
+	added, _ := store.CompareAndSwap(ctx, "k", 0, []byte("a"), time.Minute)
+	addedAgain, _ := store.CompareAndSwap(ctx, "k", 0, []byte("b"), time.Minute)
+	value, version, _ := store.Get(ctx, "k")
+	swapped, _ := store.CompareAndSwap(ctx, "k", version, []byte("c"), time.Minute)
+	stale, _ := store.CompareAndSwap(ctx, "k", version, []byte("d"), time.Minute)
+	final, _, _ := store.Get(ctx, "k")
+
+	// Assert
+	assert.True(t, added)
+	assert.False(t, addedAgain)
+	assert.Equal(t, []byte("a"), value)
+	assert.True(t, swapped)
+	assert.False(t, stale)
+	assert.Equal(t, []byte("c"), final)
+}
+
+func TestShouldExpireEntriesGivenMemoryStore(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	_, _ = store.CompareAndSwap(ctx, "k", 0, []byte("a"), time.Second)
+
+	// Act
+	now = now.Add(time.Second)
+	value, version, err := store.Get(ctx, "k")
+	added, _ := store.CompareAndSwap(ctx, "k", 0, []byte("b"), time.Second)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	assert.Zero(t, version)
+	assert.True(t, added, "an expired key counts as absent")
+}
+
+func TestShouldSwapOnlyCurrentVersionGivenMemcachedStore(t *testing.T) {
+	// Arrange
+	store := NewMemcachedStore(startFakeMemcached(t), time.Second)
+	t.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+
+	// Act
+	missing, missingVersion, missingErr := store.Get(ctx, "k")
+	added, _ := store.CompareAndSwap(ctx, "k", 0, []byte("a\r\nb"), time.Minute)
+	addedAgain, _ := store.CompareAndSwap(ctx, "k", 0, []byte("x"), time.Minute)
+	value, version, _ := store.Get(ctx, "k")
+	swapped, _ := store.CompareAndSwap(ctx, "k", version, []byte("c"), time.Minute)
+	stale, _ := store.CompareAndSwap(ctx, "k", version, []byte("d"), time.Minute)
+	final, _, err := store.Get(ctx, "k")
+
+	// Assert
+	require.NoError(t, missingErr)
+	assert.Nil(t, missing)
+	assert.Zero(t, missingVersion)
+	assert.True(t, added)
+	assert.False(t, addedAgain)
+	assert.Equal(t, []byte("a\r\nb"), value, "values are length-prefixed, not line-delimited")
+	assert.True(t, swapped)
+	assert.False(t, stale)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("c"), final)
+}
+
+func TestShouldHashKeysGivenMemcachedRejectsThem(t *testing.T) {
+	// Arrange
+	long := strings.Repeat("k", 251)
+
+	// Act & Assert
+	assert.Equal(t, "ratelimit:GET:/a:ip:1.2.3.4", memcachedKey("ratelimit:GET:/a:ip:1.2.3.4"))
+	assert.True(t, strings.HasPrefix(memcachedKey("header:x-api-key:a b"), "ratelimit:sha256:"))
+	assert.True(t, strings.HasPrefix(memcachedKey(long), "ratelimit:sha256:"))
+	assert.NotEqual(t, memcachedKey("a b"), memcachedKey("a  b"))
+}
+
+func TestShouldReturnErrorGivenMemcachedUnreachable(t *testing.T) {
+	// Arrange
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	store := NewMemcachedStore(addr, 100*time.Millisecond)
+
+	// Act
+	_, _, err = store.Get(context.Background(), "k")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+// startFakeMemcached serves the subset of the memcached text protocol used
+// by MemcachedStore: gets, add, and cas.
+func startFakeMemcached(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	var mu sync.Mutex
+	type item struct {
+		value []byte
+		cas   uint64
+	}
+	items := map[string]item{}
+	var nextCAS uint64
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				return
+			}
+			mu.Lock()
+			switch fields[0] {
+			case "gets":
+				if it, ok := items[fields[1]]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d %d\r\n", fields[1], len(it.value), it.cas)
+					rw.Write(it.value)
+					rw.WriteString("\r\n")
+				}
+				rw.WriteString("END\r\n")
+			case "add", "cas":
+				size, _ := strconv.Atoi(fields[4])
+				value := make([]byte, size+2)
+				if _, err := io.ReadFull(rw, value); err != nil {
+					mu.Unlock()
+					return
+				}
+				it, exists := items[fields[1]]
+				reply := "STORED"
+				switch {
+				case fields[0] == "add" && exists:
+					reply = "NOT_STORED"
+				case fields[0] == "cas" && !exists:
+					reply = "NOT_FOUND"
+				case fields[0] == "cas" && strconv.FormatUint(it.cas, 10) != fields[5]:
+					reply = "EXISTS"
+				default:
+					nextCAS++
+					items[fields[1]] = item{value: value[:size], cas: nextCAS}
+				}
+				rw.WriteString(reply + "\r\n")
+			default:
+				rw.WriteString("ERROR\r\n")
+			}
+			mu.Unlock()
+			if err := rw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// LogAttrKey is the attribute the log handler adds the request ID under.
+const LogAttrKey = "request_id"
+
+// NewLogHandler wraps inner so every record logged with a request's context
+// carries the request ID:
+//
+//	slog.SetDefault(slog.New(requestid.NewLogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+func NewLogHandler(inner slog.Handler) slog.Handler {
+	if h, ok := inner.(*logHandler); ok {
+		return h
+	}
+	return &logHandler{inner: inner}
+}
+
+type logHandler struct {
+	inner slog.Handler
+}
+
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *logHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := routing.RequestIDFromContext(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String(LogAttrKey, id))
+	}
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{inner: h.inner.WithGroup(name)}
+}
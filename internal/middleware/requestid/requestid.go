@@ -0,0 +1,128 @@
+package requestid
+
+import (
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/google/uuid"
+)
+
+// MaxLength is the longest incoming request ID the default validator accepts.
+const MaxLength = 128
+
+// ---- Functional Options ----
+
+// RequestIDOptions configures the request ID middleware behavior.
+type RequestIDOptions struct {
+	// Header is read for an incoming ID and set on the response.
+	Header string
+	// Generate creates an ID when the request has no valid one.
+	Generate func() string
+	// Validate reports whether an incoming ID may be used.
+	Validate func(id string) bool
+	// TrustIncoming uses valid incoming IDs. When false every request gets a
+	// new ID, for services reachable directly by untrusted clients.
+	TrustIncoming bool
+}
+
+// RequestIDOption is a function type for configuring request ID options.
+type RequestIDOption func(*RequestIDOptions)
+
+// WithHeader sets the header carrying the ID, such as X-Correlation-Id.
+func WithHeader(name string) RequestIDOption {
+	return func(o *RequestIDOptions) {
+		if name != "" {
+			o.Header = name
+		}
+	}
+}
+
+// WithGenerator sets the function creating new IDs. The default generates
+// random UUIDs.
+func WithGenerator(fn func() string) RequestIDOption {
+	return func(o *RequestIDOptions) {
+		if fn != nil {
+			o.Generate = fn
+		}
+	}
+}
+
+// WithValidator sets the check incoming IDs must pass. The default accepts
+// up to MaxLength letters, digits, and -._:/+= characters.
+func WithValidator(fn func(id string) bool) RequestIDOption {
+	return func(o *RequestIDOptions) {
+		if fn != nil {
+			o.Validate = fn
+		}
+	}
+}
+
+// WithTrustIncoming sets whether valid incoming IDs are used.
+func WithTrustIncoming(trust bool) RequestIDOption {
+	return func(o *RequestIDOptions) {
+		o.TrustIncoming = trust
+	}
+}
+
+// ---- Middleware ----
+
+// UseRequestID adds request ID middleware to the router.
+func UseRequestID(r *router.Router, opts ...RequestIDOption) {
+	r.Use(NewRequestIDMiddleware(opts...))
+}
+
+// NewRequestIDMiddleware constructs the request ID middleware with optional
+// configuration.
+func NewRequestIDMiddleware(opts ...RequestIDOption) routing.Middleware {
+	o := RequestIDOptions{
+		Header:        common.HeaderXRequestID,
+		Generate:      uuid.NewString,
+		Validate:      Valid,
+		TrustIncoming: true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &requestIDMiddleware{options: o}
+}
+
+type requestIDMiddleware struct {
+	options RequestIDOptions
+}
+
+// Invoke assigns the request ID, echoes it on the response, and calls next.
+func (m *requestIDMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	id := ""
+	if m.options.TrustIncoming {
+		if incoming := c.Request().Header.Get(m.options.Header); m.options.Validate(incoming) {
+			id = incoming
+		}
+	}
+	if id == "" {
+		id = m.options.Generate()
+	}
+	c.SetRequestID(id)
+	c.Response().Header().Set(m.options.Header, id)
+	// Responses written by the framework itself, such as route timeouts,
+	// echo the header too.
+	routing.RecordRequestID(c.Response(), m.options.Header, id)
+	next(c)
+}
+
+// Valid reports whether id is a non-empty request ID of at most MaxLength
+// letters, digits, and -._:/+= characters. Rejecting anything else keeps
+// IDs safe to log and echo.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch ch := id[i]; {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '.', ch == '_', ch == ':', ch == '/', ch == '+', ch == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
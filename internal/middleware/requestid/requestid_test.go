@@ -0,0 +1,173 @@
+package requestid
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(rtr *router.Router, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldEchoIncomingIDGivenValidHeader(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseRequestID(rtr)
+	var seen string
+	rtr.GET("/", func(c routing.RouteContext) {
+		seen = c.RequestID()
+		c.OK(routing.RequestIDFromContext(c.Request().Context()))
+	})
+
+	// Act
+	rec := serve(rtr, "/", "X-Request-ID", "abc-123")
+
+	// Assert
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", rec.Header().Get("X-Request-ID"))
+	assert.JSONEq(t, `"abc-123"`, rec.Body.String())
+}
+
+func TestShouldGenerateIDGivenMissingOrInvalidHeader(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseRequestID(rtr)
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	missing := serve(rtr, "/")
+	invalid := serve(rtr, "/", "X-Request-ID", "<script>")
+
+	// Assert
+	assert.Len(t, missing.Header().Get("X-Request-ID"), 36)
+	assert.Len(t, invalid.Header().Get("X-Request-ID"), 36)
+	assert.NotEqual(t, missing.Header().Get("X-Request-ID"), invalid.Header().Get("X-Request-ID"))
+}
+
+func TestShouldUseConfiguredHeaderAndGeneratorGivenOptions(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseRequestID(rtr,
+		WithHeader("X-Correlation-Id"),
+		WithGenerator(func() string { return "generated" }),
+		WithTrustIncoming(false),
+	)
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := serve(rtr, "/", "X-Correlation-Id", "from-client")
+
+	// Assert
+	assert.Equal(t, "generated", rec.Header().Get("X-Correlation-Id"))
+	assert.Empty(t, rec.Header().Get("X-Request-ID"))
+}
+
+func TestShouldAddRequestIDToProblemsGivenRequestID(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseRequestID(rtr)
+	rtr.GET("/missing", func(c routing.RouteContext) { c.BadRequest("Bad Request", "nope") })
+	rtr.GET("/slow", func(c routing.RouteContext) { <-c.Done() }).WithTimeout(10 * time.Millisecond)
+	rtr.GET("/own", func(c routing.RouteContext) {
+		c.Problem(&routing.ProblemDetails{Status: http.StatusConflict, Extensions: map[string]any{"requestId": "kept"}})
+	})
+
+	// Act
+	results := map[string]*httptest.ResponseRecorder{
+		"abc":  serve(rtr, "/missing", "X-Request-ID", "abc"),
+		"slow": serve(rtr, "/slow", "X-Request-ID", "slow"),
+		"kept": serve(rtr, "/own", "X-Request-ID", "ignored"),
+	}
+
+	// Assert
+	for expected, rec := range results {
+		var problem map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, expected, problem["requestId"])
+	}
+}
+
+func TestShouldAddRequestIDToLogRecordsGivenLogHandler(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+	rtr := router.NewRouter()
+	UseRequestID(rtr)
+	rtr.GET("/", func(c routing.RouteContext) {
+		logger.InfoContext(c, "handling")
+		c.NoContent()
+	})
+
+	// Act
+	serve(rtr, "/", "X-Request-ID", "log-1")
+	logger.Info("outside a request")
+
+	// Assert
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var inside, outside map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &inside))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &outside))
+	assert.Equal(t, "log-1", inside[LogAttrKey])
+	assert.Equal(t, "test", inside["component"])
+	assert.NotContains(t, outside, LogAttrKey)
+}
+
+func TestShouldKeepRequestIDGivenBackgroundTask(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseRequestID(rtr)
+	done := make(chan string, 1)
+	rtr.GET("/", func(c routing.RouteContext) {
+		c.Go(func(task routing.RouteContext) {
+			done <- task.RequestID() + "|" + routing.RequestIDFromContext(task)
+		})
+		c.NoContent()
+	})
+
+	// Act
+	serve(rtr, "/", "X-Request-ID", "task-1")
+
+	// Assert
+	select {
+	case got := <-done:
+		assert.Equal(t, "task-1|task-1", got)
+	case <-time.After(time.Second):
+		t.Fatal("background task did not run")
+	}
+}
+
+func TestShouldValidateRequestIDs(t *testing.T) {
+	// Arrange
+	cases := map[string]bool{
+		"":        false,
+		"abc-123": true,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01": true,
+		"has space":                      false,
+		"line\nbreak":                    false,
+		strings.Repeat("a", MaxLength):   true,
+		strings.Repeat("a", MaxLength+1): false,
+	}
+
+	// Act & Assert
+	for id, expected := range cases {
+		assert.Equal(t, expected, Valid(id), id)
+	}
+}
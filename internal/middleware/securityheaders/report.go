@@ -0,0 +1,208 @@
+package securityheaders
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// MaxReportBytes bounds the body NewReportHandler reads.
+const MaxReportBytes = 64 << 10
+
+// Content types browsers send violation reports with.
+const (
+	// MimeCSPReport is the legacy report-uri format.
+	MimeCSPReport = "application/csp-report"
+	// MimeReports is the Reporting API format used by report-to.
+	MimeReports = "application/reports+json"
+)
+
+// ErrUnsupportedReport reports a body that is neither a csp-report nor a
+// Reporting API payload.
+var ErrUnsupportedReport = errors.New("unsupported violation report")
+
+// ---- Reports ----
+
+// CSPReport is a Content-Security-Policy violation, normalized from either
+// report format.
+type CSPReport struct {
+	DocumentURI        string `json:"documentURI"`
+	Referrer           string `json:"referrer,omitempty"`
+	BlockedURI         string `json:"blockedURI,omitempty"`
+	EffectiveDirective string `json:"effectiveDirective,omitempty"`
+	ViolatedDirective  string `json:"violatedDirective,omitempty"`
+	OriginalPolicy     string `json:"originalPolicy,omitempty"`
+	// Disposition is "enforce" or "report".
+	Disposition string `json:"disposition,omitempty"`
+	StatusCode  int    `json:"statusCode,omitempty"`
+	SourceFile  string `json:"sourceFile,omitempty"`
+	Line        int    `json:"lineNumber,omitempty"`
+	Column      int    `json:"columnNumber,omitempty"`
+	Sample      string `json:"sample,omitempty"`
+	// UserAgent is only present in Reporting API payloads.
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// legacyReport is the body of an application/csp-report request.
+type legacyReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		EffectiveDirective string `json:"effective-directive"`
+		ViolatedDirective  string `json:"violated-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		StatusCode         int    `json:"status-code"`
+		SourceFile         string `json:"source-file"`
+		Line               int    `json:"line-number"`
+		Column             int    `json:"column-number"`
+		Sample             string `json:"script-sample"`
+	} `json:"csp-report"`
+}
+
+// reportingEntry is one report of an application/reports+json request.
+type reportingEntry struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	UserAgent string `json:"user_agent"`
+	Body      struct {
+		DocumentURL        string `json:"documentURL"`
+		Referrer           string `json:"referrer"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		OriginalPolicy     string `json:"originalPolicy"`
+		Disposition        string `json:"disposition"`
+		StatusCode         int    `json:"statusCode"`
+		SourceFile         string `json:"sourceFile"`
+		Line               int    `json:"lineNumber"`
+		Column             int    `json:"columnNumber"`
+		Sample             string `json:"sample"`
+	} `json:"body"`
+}
+
+// ParseReports decodes a csp-report or Reporting API body. Reporting API
+// entries other than csp-violation are skipped. A plain application/json
+// body is accepted in either format.
+func ParseReports(contentType string, body []byte) ([]CSPReport, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case MimeCSPReport:
+		return parseLegacy(body)
+	case MimeReports:
+		return parseReporting(body)
+	case "application/json", "":
+		if reports, err := parseReporting(body); err == nil {
+			return reports, nil
+		}
+		return parseLegacy(body)
+	}
+	return nil, ErrUnsupportedReport
+}
+
+func parseLegacy(body []byte) ([]CSPReport, error) {
+	var legacy legacyReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	r := legacy.Report
+	if r.DocumentURI == "" && r.EffectiveDirective == "" && r.ViolatedDirective == "" {
+		return nil, ErrUnsupportedReport
+	}
+	effective := r.EffectiveDirective
+	if effective == "" {
+		effective = r.ViolatedDirective
+	}
+	return []CSPReport{{
+		DocumentURI:        r.DocumentURI,
+		Referrer:           r.Referrer,
+		BlockedURI:         r.BlockedURI,
+		EffectiveDirective: effective,
+		ViolatedDirective:  r.ViolatedDirective,
+		OriginalPolicy:     r.OriginalPolicy,
+		Disposition:        r.Disposition,
+		StatusCode:         r.StatusCode,
+		SourceFile:         r.SourceFile,
+		Line:               r.Line,
+		Column:             r.Column,
+		Sample:             r.Sample,
+	}}, nil
+}
+
+func parseReporting(body []byte) ([]CSPReport, error) {
+	var entries []reportingEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, err
+	}
+	reports := make([]CSPReport, 0, len(entries))
+	for _, e := range entries {
+		if e.Type != "csp-violation" {
+			continue
+		}
+		documentURI := e.Body.DocumentURL
+		if documentURI == "" {
+			documentURI = e.URL
+		}
+		reports = append(reports, CSPReport{
+			DocumentURI:        documentURI,
+			Referrer:           e.Body.Referrer,
+			BlockedURI:         e.Body.BlockedURL,
+			EffectiveDirective: e.Body.EffectiveDirective,
+			ViolatedDirective:  e.Body.EffectiveDirective,
+			OriginalPolicy:     e.Body.OriginalPolicy,
+			Disposition:        e.Body.Disposition,
+			StatusCode:         e.Body.StatusCode,
+			SourceFile:         e.Body.SourceFile,
+			Line:               e.Body.Line,
+			Column:             e.Body.Column,
+			Sample:             e.Body.Sample,
+			UserAgent:          e.UserAgent,
+		})
+	}
+	return reports, nil
+}
+
+// ---- Report Endpoint ----
+
+// NewReportHandler returns a route handler accepting violation reports,
+// such as the endpoint named by WithCSPReportURI. It calls fn for each
+// report and answers 204 No Content, or 400 for an unreadable body. A nil
+// fn logs each report at warning level.
+func NewReportHandler(fn func(c routing.RouteContext, report CSPReport)) routing.HandlerFunc {
+	if fn == nil {
+		fn = logReport
+	}
+	return func(c routing.RouteContext) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, MaxReportBytes))
+		if err != nil {
+			c.BadRequest("Invalid Violation Report", "The report body could not be read.")
+			return
+		}
+		reports, err := ParseReports(c.Request().Header.Get(common.HeaderContentType), body)
+		if err != nil {
+			c.BadRequest("Invalid Violation Report", "The body is not a Content-Security-Policy violation report.")
+			return
+		}
+		for _, report := range reports {
+			fn(c, report)
+		}
+		c.NoContent()
+	}
+}
+
+func logReport(c routing.RouteContext, r CSPReport) {
+	slog.WarnContext(c, "content security policy violation",
+		"document_uri", r.DocumentURI,
+		"blocked_uri", r.BlockedURI,
+		"directive", r.EffectiveDirective,
+		"disposition", r.Disposition,
+		"source_file", r.SourceFile,
+		"line", r.Line,
+	)
+}
@@ -0,0 +1,103 @@
+package securityheaders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyBody = `{"csp-report":{"document-uri":"https://example.com/page","referrer":"","violated-directive":"script-src-elem","effective-directive":"script-src-elem","original-policy":"script-src 'self'","disposition":"report","blocked-uri":"https://evil.example/x.js","line-number":12,"column-number":4,"source-file":"https://example.com/app.js","status-code":200,"script-sample":""}}`
+
+const reportingBody = `[{"type":"csp-violation","age":10,"url":"https://example.com/page","user_agent":"Mozilla/5.0","body":{"documentURL":"https://example.com/page","blockedURL":"inline","effectiveDirective":"script-src-elem","originalPolicy":"script-src 'self'","disposition":"enforce","statusCode":200,"lineNumber":3,"columnNumber":9,"sample":"alert(1)"}},{"type":"deprecation","url":"https://example.com/page","body":{}}]`
+
+func TestShouldParseLegacyCSPReport(t *testing.T) {
+	// Act
+	reports, err := ParseReports("application/csp-report", []byte(legacyBody))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "https://example.com/page", reports[0].DocumentURI)
+	assert.Equal(t, "https://evil.example/x.js", reports[0].BlockedURI)
+	assert.Equal(t, "script-src-elem", reports[0].EffectiveDirective)
+	assert.Equal(t, "report", reports[0].Disposition)
+	assert.Equal(t, 12, reports[0].Line)
+}
+
+func TestShouldParseReportingAPIPayloadSkippingOtherTypes(t *testing.T) {
+	// Act
+	reports, err := ParseReports("application/reports+json", []byte(reportingBody))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "inline", reports[0].BlockedURI)
+	assert.Equal(t, "enforce", reports[0].Disposition)
+	assert.Equal(t, "alert(1)", reports[0].Sample)
+	assert.Equal(t, "Mozilla/5.0", reports[0].UserAgent)
+}
+
+func TestShouldDetectFormatGivenPlainJSON(t *testing.T) {
+	for _, body := range []string{legacyBody, reportingBody} {
+		// Act
+		reports, err := ParseReports("application/json", []byte(body))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, reports, 1)
+	}
+}
+
+func TestShouldRejectUnsupportedReport(t *testing.T) {
+	// Act
+	_, textErr := ParseReports("text/plain", []byte(legacyBody))
+	_, emptyErr := ParseReports("application/csp-report", []byte(`{}`))
+
+	// Assert
+	assert.ErrorIs(t, textErr, ErrUnsupportedReport)
+	assert.ErrorIs(t, emptyErr, ErrUnsupportedReport)
+}
+
+func TestShouldPassReportsToCallbackGivenReportHandler(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	var received []CSPReport
+	rtr.POST("/csp-reports", NewReportHandler(func(c routing.RouteContext, report CSPReport) {
+		received = append(received, report)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/csp-reports", strings.NewReader(reportingBody))
+	req.Header.Set("Content-Type", "application/reports+json")
+	rec := httptest.NewRecorder()
+
+	// Act
+	rtr.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	require.Len(t, received, 1)
+	assert.Equal(t, "https://example.com/page", received[0].DocumentURI)
+}
+
+func TestShouldAnswerBadRequestGivenOversizedOrInvalidReport(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	rtr.POST("/csp-reports", NewReportHandler(nil))
+
+	for _, body := range []string{"not json", `{"csp-report":{"document-uri":"` + strings.Repeat("a", MaxReportBytes) + `"}}`} {
+		req := httptest.NewRequest(http.MethodPost, "/csp-reports", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/csp-report")
+		rec := httptest.NewRecorder()
+
+		// Act
+		rtr.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
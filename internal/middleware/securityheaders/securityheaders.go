@@ -0,0 +1,302 @@
+package securityheaders
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// ReportGroup is the Reporting-Endpoints group named by the report-to
+// directive WithCSPReportURI adds.
+const ReportGroup = "csp-endpoint"
+
+// DefaultHeaders returns the headers UseSecurityHeaders sets unless options
+// replace them. Strict-Transport-Security is only sent over HTTPS.
+func DefaultHeaders() map[string]string {
+	return map[string]string{
+		common.HeaderStrictTransportSecurity:   "max-age=31536000; includeSubDomains",
+		common.HeaderContentSecurityPolicy:     "default-src 'self'; base-uri 'self'; object-src 'none'; frame-ancestors 'none'",
+		common.HeaderXContentTypeOptions:       "nosniff",
+		common.HeaderXFrameOptions:             "DENY",
+		common.HeaderReferrerPolicy:            "strict-origin-when-cross-origin",
+		common.HeaderPermissionsPolicy:         "camera=(), geolocation=(), microphone=()",
+		common.HeaderCrossOriginOpenerPolicy:   "same-origin",
+		common.HeaderCrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// ---- Functional Options ----
+
+// SecurityHeadersOptions configures the security headers middleware.
+type SecurityHeadersOptions struct {
+	// Headers maps canonical header names to values. An empty value omits
+	// the header.
+	Headers map[string]string
+	// Nonce generates a nonce per request, adds it to the script-src and
+	// style-src directives of both policies, and exposes it as
+	// RouteContext.CSPNonce.
+	Nonce bool
+	// ReportURI receives violation reports of both policies through the
+	// report-uri and report-to directives.
+	ReportURI string
+}
+
+// SecurityHeadersOption is a function type for configuring security header
+// options.
+type SecurityHeadersOption func(*SecurityHeadersOptions)
+
+// WithHeader sets a response header. An empty value omits the header.
+func WithHeader(name, value string) SecurityHeadersOption {
+	return func(o *SecurityHeadersOptions) {
+		if o.Headers == nil {
+			o.Headers = make(map[string]string)
+		}
+		o.Headers[http.CanonicalHeaderKey(name)] = value
+	}
+}
+
+// WithHSTS sets Strict-Transport-Security. A maxAge <= 0 omits the header.
+func WithHSTS(maxAge time.Duration, includeSubDomains, preload bool) SecurityHeadersOption {
+	if maxAge <= 0 {
+		return WithHeader(common.HeaderStrictTransportSecurity, "")
+	}
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if includeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if preload {
+		value += "; preload"
+	}
+	return WithHeader(common.HeaderStrictTransportSecurity, value)
+}
+
+// WithContentSecurityPolicy sets the enforced Content-Security-Policy.
+func WithContentSecurityPolicy(policy string) SecurityHeadersOption {
+	return WithHeader(common.HeaderContentSecurityPolicy, policy)
+}
+
+// WithContentSecurityPolicyReportOnly sets a policy that browsers report
+// violations of without enforcing it, to trial a policy before enforcing it.
+func WithContentSecurityPolicyReportOnly(policy string) SecurityHeadersOption {
+	return WithHeader(common.HeaderContentSecurityPolicyReportOnly, policy)
+}
+
+// WithCSPNonce generates a nonce per request for inline scripts and styles.
+func WithCSPNonce() SecurityHeadersOption {
+	return func(o *SecurityHeadersOptions) {
+		o.Nonce = true
+	}
+}
+
+// WithCSPReportURI sends violation reports of both policies to uri, such as
+// a route served by NewReportHandler.
+func WithCSPReportURI(uri string) SecurityHeadersOption {
+	return func(o *SecurityHeadersOptions) {
+		o.ReportURI = uri
+	}
+}
+
+// WithFrameOptions sets X-Frame-Options.
+func WithFrameOptions(value string) SecurityHeadersOption {
+	return WithHeader(common.HeaderXFrameOptions, value)
+}
+
+// WithReferrerPolicy sets Referrer-Policy.
+func WithReferrerPolicy(value string) SecurityHeadersOption {
+	return WithHeader(common.HeaderReferrerPolicy, value)
+}
+
+// WithPermissionsPolicy sets Permissions-Policy.
+func WithPermissionsPolicy(value string) SecurityHeadersOption {
+	return WithHeader(common.HeaderPermissionsPolicy, value)
+}
+
+// WithCrossOriginOpenerPolicy sets Cross-Origin-Opener-Policy.
+func WithCrossOriginOpenerPolicy(value string) SecurityHeadersOption {
+	return WithHeader(common.HeaderCrossOriginOpenerPolicy, value)
+}
+
+// WithCrossOriginEmbedderPolicy sets Cross-Origin-Embedder-Policy, such as
+// "require-corp" for cross-origin isolation. It is not sent by default.
+func WithCrossOriginEmbedderPolicy(value string) SecurityHeadersOption {
+	return WithHeader(common.HeaderCrossOriginEmbedderPolicy, value)
+}
+
+// WithCrossOriginResourcePolicy sets Cross-Origin-Resource-Policy.
+func WithCrossOriginResourcePolicy(value string) SecurityHeadersOption {
+	return WithHeader(common.HeaderCrossOriginResourcePolicy, value)
+}
+
+// Overrides returns the headers set by opts, for route and group overrides.
+// Nonce and report options only apply to UseSecurityHeaders.
+func Overrides(opts ...SecurityHeadersOption) map[string]string {
+	var o SecurityHeadersOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.Headers
+}
+
+// ---- Middleware ----
+
+// UseSecurityHeaders adds middleware setting security response headers.
+func UseSecurityHeaders(rtr *router.Router, opts ...SecurityHeadersOption) {
+	rtr.Use(NewSecurityHeadersMiddleware(opts...))
+}
+
+// NewSecurityHeadersMiddleware builds the middleware with DefaultHeaders
+// updated by opts.
+func NewSecurityHeadersMiddleware(opts ...SecurityHeadersOption) routing.Middleware {
+	o := SecurityHeadersOptions{Headers: DefaultHeaders()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ReportURI != "" {
+		if _, ok := o.Headers[common.HeaderReportingEndpoints]; !ok {
+			o.Headers[common.HeaderReportingEndpoints] = ReportGroup + `="` + o.ReportURI + `"`
+		}
+	}
+	names := slices.Sorted(maps.Keys(o.Headers))
+	return &securityHeadersMiddleware{options: o, names: names}
+}
+
+type securityHeadersMiddleware struct {
+	options SecurityHeadersOptions
+	// names orders the configured headers for deterministic output.
+	names []string
+}
+
+// Invoke sets the headers before calling next, so handlers can still
+// replace them.
+func (m *securityHeadersMiddleware) Invoke(c routing.RouteContext, next router.HandlerFunc) {
+	var overrides map[string]string
+	if opts := c.Options(); opts != nil {
+		overrides = opts.SecurityHeaders
+	}
+	w := &headerWriter{m: m, c: c, header: c.Response().Header(), secure: c.Request().TLS != nil}
+	for _, name := range m.names {
+		value, ok := overrides[name]
+		if !ok {
+			value = m.options.Headers[name]
+		}
+		w.set(name, value)
+	}
+	for name, value := range overrides {
+		if _, ok := m.options.Headers[name]; !ok {
+			w.set(name, value)
+		}
+	}
+	next(c)
+}
+
+// headerWriter applies one request's headers, generating the nonce the
+// first time a policy needs it.
+type headerWriter struct {
+	m      *securityHeadersMiddleware
+	c      routing.RouteContext
+	header http.Header
+	secure bool
+	nonce  string
+}
+
+func (w *headerWriter) set(name, value string) {
+	if value == "" {
+		return
+	}
+	switch name {
+	case common.HeaderStrictTransportSecurity:
+		// Browsers ignore HSTS over plain HTTP.
+		if !w.secure {
+			return
+		}
+	case common.HeaderContentSecurityPolicy, common.HeaderContentSecurityPolicyReportOnly:
+		value = w.policy(value)
+	}
+	w.header.Set(name, value)
+}
+
+func (w *headerWriter) policy(policy string) string {
+	if w.m.options.Nonce {
+		if w.nonce == "" {
+			w.nonce = newNonce()
+			w.c.SetCSPNonce(w.nonce)
+		}
+		policy = addNonce(policy, w.nonce)
+	}
+	if uri := w.m.options.ReportURI; uri != "" && !hasDirective(policy, "report-uri") && !hasDirective(policy, "report-to") {
+		policy += "; report-uri " + uri + "; report-to " + ReportGroup
+	}
+	return policy
+}
+
+// newNonce returns 128 random bits in base64, as CSP recommends.
+func newNonce() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return base64.StdEncoding.EncodeToString(b[:])
+}
+
+// addNonce adds the nonce source to the script-src and style-src directives.
+// A missing directive is added with the default-src sources, or 'self', so
+// the policy does not become stricter than intended for other sources.
+func addNonce(policy, nonce string) string {
+	source := "'nonce-" + nonce + "'"
+	directives := splitDirectives(policy)
+	fallback := "'self'"
+	for _, d := range directives {
+		if name, sources, _ := strings.Cut(d, " "); strings.EqualFold(name, "default-src") && strings.TrimSpace(sources) != "" {
+			fallback = strings.TrimSpace(sources)
+		}
+	}
+	for _, want := range []string{"script-src", "style-src"} {
+		found := false
+		for i, d := range directives {
+			if name, sources, _ := strings.Cut(d, " "); strings.EqualFold(name, want) {
+				directives[i] = withSource(name, sources, source)
+				found = true
+			}
+		}
+		if !found {
+			directives = append(directives, withSource(want, fallback, source))
+		}
+	}
+	return strings.Join(directives, "; ")
+}
+
+// withSource appends source to a directive, replacing 'none', which must be
+// a directive's only source.
+func withSource(name, sources, source string) string {
+	sources = strings.TrimSpace(sources)
+	if sources == "" || strings.EqualFold(sources, "'none'") {
+		return name + " " + source
+	}
+	return name + " " + sources + " " + source
+}
+
+func hasDirective(policy, name string) bool {
+	for _, d := range splitDirectives(policy) {
+		if directive, _, _ := strings.Cut(d, " "); strings.EqualFold(directive, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitDirectives(policy string) []string {
+	var directives []string
+	for _, d := range strings.Split(policy, ";") {
+		if d = strings.TrimSpace(d); d != "" {
+			directives = append(directives, d)
+		}
+	}
+	return directives
+}
@@ -0,0 +1,189 @@
+package securityheaders
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/router"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(rtr *router.Router, path string, secure bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if secure {
+		req.TLS = &tls.ConnectionState{}
+	}
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldSetDefaultHeadersGivenUseSecurityHeaders(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr)
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := serve(rtr, "/", true)
+
+	// Assert
+	for name, value := range DefaultHeaders() {
+		assert.Equal(t, value, rec.Header().Get(name), name)
+	}
+	assert.Empty(t, rec.Header().Get("Cross-Origin-Embedder-Policy"))
+}
+
+func TestShouldOmitHSTSGivenPlainHTTP(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr, WithHSTS(2*365*24*time.Hour, true, true))
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	plain := serve(rtr, "/", false)
+	secure := serve(rtr, "/", true)
+
+	// Assert
+	assert.Empty(t, plain.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "max-age=63072000; includeSubDomains; preload", secure.Header().Get("Strict-Transport-Security"))
+}
+
+func TestShouldReplaceAndOmitHeadersGivenOptions(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr,
+		WithFrameOptions("SAMEORIGIN"),
+		WithPermissionsPolicy(""),
+		WithCrossOriginEmbedderPolicy("require-corp"),
+		WithHeader("x-permitted-cross-domain-policies", "none"),
+	)
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := serve(rtr, "/", false)
+
+	// Assert
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+	assert.Empty(t, rec.Header().Values("Permissions-Policy"))
+	assert.Equal(t, "require-corp", rec.Header().Get("Cross-Origin-Embedder-Policy"))
+	assert.Equal(t, "none", rec.Header().Get("X-Permitted-Cross-Domain-Policies"))
+}
+
+func TestShouldApplyGroupAndRouteOverrides(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr)
+	docs := rtr.NewRouteGroup("/docs").WithSecurityHeaders(Overrides(
+		WithContentSecurityPolicy("default-src 'self' https://cdn.example.com"),
+		WithFrameOptions(""),
+	))
+	docs.GET("/", func(c routing.RouteContext) { c.NoContent() })
+	docs.GET("/embed", func(c routing.RouteContext) { c.NoContent() }).
+		WithSecurityHeaders(Overrides(WithCrossOriginResourcePolicy("cross-origin")))
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	group := serve(rtr, "/docs/", false)
+	route := serve(rtr, "/docs/embed", false)
+	root := serve(rtr, "/", false)
+
+	// Assert
+	assert.Equal(t, "default-src 'self' https://cdn.example.com", group.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, group.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "same-origin", group.Header().Get("Cross-Origin-Resource-Policy"))
+	assert.Equal(t, "cross-origin", route.Header().Get("Cross-Origin-Resource-Policy"))
+	assert.Empty(t, route.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "DENY", root.Header().Get("X-Frame-Options"))
+	assert.Equal(t, DefaultHeaders()["Content-Security-Policy"], root.Header().Get("Content-Security-Policy"))
+}
+
+func TestShouldExposeNonceGivenWithCSPNonce(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr, WithCSPNonce())
+	var nonces []string
+	rtr.GET("/", func(c routing.RouteContext) {
+		nonces = append(nonces, c.CSPNonce())
+		c.HTML(http.StatusOK, `<script nonce="`+c.CSPNonce()+`">ok()</script>`)
+	})
+
+	// Act
+	first := serve(rtr, "/", false)
+	second := serve(rtr, "/", false)
+
+	// Assert
+	if assert.Len(t, nonces, 2) {
+		assert.NotEmpty(t, nonces[0])
+		assert.NotEqual(t, nonces[0], nonces[1])
+		policy := first.Header().Get("Content-Security-Policy")
+		assert.Contains(t, policy, "script-src 'self' 'nonce-"+nonces[0]+"'")
+		assert.Contains(t, policy, "style-src 'self' 'nonce-"+nonces[0]+"'")
+		assert.Contains(t, second.Header().Get("Content-Security-Policy"), nonces[1])
+		assert.Contains(t, first.Body.String(), nonces[0])
+	}
+}
+
+func TestShouldNotGenerateNonceGivenNoOption(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr)
+	nonce := "unset"
+	rtr.GET("/", func(c routing.RouteContext) {
+		nonce = c.CSPNonce()
+		c.NoContent()
+	})
+
+	// Act
+	serve(rtr, "/", false)
+
+	// Assert
+	assert.Empty(t, nonce)
+}
+
+func TestShouldAddNonceToExistingDirectives(t *testing.T) {
+	// Act
+	policy := addNonce("default-src 'none'; script-src 'self' https://cdn.example.com; style-src 'none'", "abc")
+
+	// Assert
+	assert.Equal(t, "default-src 'none'; script-src 'self' https://cdn.example.com 'nonce-abc'; style-src 'nonce-abc'", policy)
+}
+
+func TestShouldAddReportDirectivesGivenWithCSPReportURI(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr,
+		WithContentSecurityPolicyReportOnly("default-src 'self'; script-src 'self'"),
+		WithCSPReportURI("/csp-reports"),
+	)
+	rtr.GET("/", func(c routing.RouteContext) { c.NoContent() })
+
+	// Act
+	rec := serve(rtr, "/", false)
+
+	// Assert
+	assert.True(t, strings.HasSuffix(rec.Header().Get("Content-Security-Policy-Report-Only"), "; report-uri /csp-reports; report-to csp-endpoint"))
+	assert.True(t, strings.HasSuffix(rec.Header().Get("Content-Security-Policy"), "; report-uri /csp-reports; report-to csp-endpoint"))
+	assert.Equal(t, `csp-endpoint="/csp-reports"`, rec.Header().Get("Reporting-Endpoints"))
+}
+
+func TestShouldLetHandlerReplaceHeader(t *testing.T) {
+	// Arrange
+	rtr := router.NewRouter()
+	UseSecurityHeaders(rtr)
+	rtr.GET("/", func(c routing.RouteContext) {
+		c.Response().Header().Set("X-Frame-Options", "SAMEORIGIN")
+		c.NoContent()
+	})
+
+	// Act
+	rec := serve(rtr, "/", false)
+
+	// Assert
+	assert.Equal(t, "SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+}
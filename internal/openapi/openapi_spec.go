@@ -1,10 +1,12 @@
 package openapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -174,6 +176,66 @@ type Operation struct {
 	Extensions   map[string]any             `json:"-" yaml:"-,inline"`
 }
 
+// MarshalJSON writes the operation with its specification extensions inlined
+// as "x-" members, matching the YAML encoding.
+func (op Operation) MarshalJSON() ([]byte, error) {
+	type plain Operation
+	data, err := json.Marshal(plain(op))
+	if err != nil || len(op.Extensions) == 0 {
+		return data, err
+	}
+	keys := make([]string, 0, len(op.Extensions))
+	for key := range op.Extensions {
+		if strings.HasPrefix(key, "x-") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for i, key := range keys {
+		value, err := json.Marshal(op.Extensions[key])
+		if err != nil {
+			return nil, fmt.Errorf("marshaling extension %q: %w", key, err)
+		}
+		name, _ := json.Marshal(key)
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads the operation and collects "x-" members into
+// Extensions.
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	type plain Operation
+	if err := json.Unmarshal(data, (*plain)(op)); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for key, raw := range members {
+		if !strings.HasPrefix(key, "x-") {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fmt.Errorf("unmarshaling extension %q: %w", key, err)
+		}
+		if op.Extensions == nil {
+			op.Extensions = map[string]any{}
+		}
+		op.Extensions[key] = value
+	}
+	return nil
+}
+
 // ParameterObject defines a parameter for an operation or path.
 type ParameterObject struct {
 	Name            string                    `json:"name" yaml:"name"`
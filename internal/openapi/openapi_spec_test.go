@@ -536,6 +536,26 @@ func TestShouldSupportPropertyLevelDescriptions(t *testing.T) {
 	assert.Equal(t, "The unique identifier for the user", yamlIDProp["description"])
 }
 
+func TestShouldInlineOperationExtensionsGivenJSON(t *testing.T) {
+	// Arrange
+	op := Operation{
+		OperationID: "listOrders",
+		Responses:   map[string]*ResponseObject{"200": {Description: "OK"}},
+		Extensions:  map[string]any{"x-ratelimit": map[string]any{"limit": 10}},
+	}
+
+	// Act
+	data, err := json.Marshal(op)
+	require.NoError(t, err)
+	var decoded Operation
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	// Assert
+	assert.JSONEq(t, `{"operationId":"listOrders","responses":{"200":{"description":"OK"}},"x-ratelimit":{"limit":10}}`, string(data))
+	assert.Equal(t, "listOrders", decoded.OperationID)
+	assert.Equal(t, map[string]any{"x-ratelimit": map[string]any{"limit": float64(10)}}, decoded.Extensions)
+}
+
 func ptrFloat64(v float64) *float64 {
 	return &v
 }
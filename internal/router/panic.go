@@ -0,0 +1,198 @@
+package router
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// PanicHandler responds to a panic recovered while serving a request.
+// recovered is the value passed to panic and stack the goroutine stack at
+// the point of the panic. A handler that panics itself, for example to
+// re-raise the panic in tests, is not recovered.
+type PanicHandler func(c routing.RouteContext, recovered any, stack []byte)
+
+// DefaultPanicHandler logs the panic with its stack and writes a generic 500
+// problem. The response never includes the panic value or stack.
+func DefaultPanicHandler(c routing.RouteContext, recovered any, stack []byte) {
+	logPanic(c, recovered, stack)
+	c.ServerError("Internal Server Error", "An unexpected error occurred")
+}
+
+// recoverPanic recovers a panic raised while serving r and hands it to the
+// configured PanicHandler. It must be deferred directly.
+func (rtr *Router) recoverPanic(c *routing.DefaultRouteContext, w http.ResponseWriter, r *http.Request) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	stack := debug.Stack()
+	if c == nil {
+		slog.Error("panic recovered in ServeHTTP", "error", rec, "path", safeURLPath(r), "method", safeMethod(r), "stack", string(stack))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	c.MarkFailed()
+	rtr.panicHandler()(c, rec, stack)
+}
+
+func (rtr *Router) panicHandler() PanicHandler {
+	switch {
+	case rtr.options == nil:
+		return DefaultPanicHandler
+	case rtr.options.PanicHandler != nil:
+		return rtr.options.PanicHandler
+	case rtr.options.DevelopmentErrors:
+		return developmentPanicHandler
+	default:
+		return DefaultPanicHandler
+	}
+}
+
+func logPanic(c routing.RouteContext, recovered any, stack []byte) {
+	r := c.Request()
+	slog.ErrorContext(c, "panic recovered in ServeHTTP", "error", recovered, "path", safeURLPath(r), "method", safeMethod(r), "stack", string(stack))
+}
+
+// developmentPanicHandler logs the panic and renders it with its stack and
+// a dump of the request: an HTML page for browsers and a problem with
+// "panic", "stack", and "request" members for everything else. It is only
+// installed by WithDevelopmentErrors.
+func developmentPanicHandler(c routing.RouteContext, recovered any, stack []byte) {
+	logPanic(c, recovered, stack)
+	r := c.Request()
+	dump := dumpRequest(r)
+	if prefersHTML(r.Header.Get(common.HeaderAccept)) {
+		var b strings.Builder
+		err := developmentErrorPage.Execute(&b, map[string]any{
+			"Panic":   fmt.Sprint(recovered),
+			"Method":  r.Method,
+			"Path":    r.URL.Path,
+			"Stack":   string(stack),
+			"Request": dump,
+		})
+		if err == nil {
+			c.HTML(http.StatusInternalServerError, b.String())
+			return
+		}
+	}
+	instance := r.RequestURI
+	c.Problem(&routing.ProblemDetails{
+		Title:    "Internal Server Error",
+		Detail:   fmt.Sprintf("panic: %v", recovered),
+		Status:   http.StatusInternalServerError,
+		Type:     routing.ProblemTypeAboutBlank,
+		Instance: &instance,
+		Extensions: map[string]any{
+			"panic":   fmt.Sprint(recovered),
+			"stack":   strings.Split(strings.TrimSpace(string(stack)), "\n"),
+			"request": dump,
+		},
+	})
+}
+
+// redactedHeaders are replaced in request dumps because they carry
+// credentials.
+var redactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"X-Csrf-Token",
+}
+
+// requestDump is the part of a request shown on development error pages.
+type requestDump struct {
+	Method     string              `json:"method"`
+	URL        string              `json:"url"`
+	Proto      string              `json:"proto"`
+	Host       string              `json:"host"`
+	RemoteAddr string              `json:"remoteAddr"`
+	Headers    map[string][]string `json:"headers"`
+}
+
+func dumpRequest(r *http.Request) requestDump {
+	headers := make(map[string][]string, len(r.Header))
+	for name, values := range r.Header {
+		if slices.Contains(redactedHeaders, http.CanonicalHeaderKey(name)) {
+			headers[name] = []string{"[redacted]"}
+			continue
+		}
+		headers[name] = slices.Clone(values)
+	}
+	return requestDump{
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		Proto:      r.Proto,
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		Headers:    headers,
+	}
+}
+
+// prefersHTML reports whether an Accept header ranks text/html above JSON.
+// Ties go to JSON so clients sending */* get a problem.
+func prefersHTML(accept string) bool {
+	html, json := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case common.MimeTextHTML, "application/xhtml+xml":
+			html = max(html, q)
+		case common.MimeJSON, common.MimeProblemJSON:
+			json = max(json, q)
+		case "*/*", "application/*":
+			json = max(json, q)
+		}
+	}
+	return html > 0 && html > json
+}
+
+var developmentErrorPage = template.Must(template.New("panic").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>500 Internal Server Error</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+h1 { color: #b00020; }
+pre { background: #f6f6f6; padding: 1rem; overflow-x: auto; }
+th { text-align: left; padding-right: 1rem; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>panic: {{.Panic}}</h1>
+<p>{{.Method}} {{.Path}}</p>
+<h2>Stack</h2>
+<pre>{{.Stack}}</pre>
+<h2>Request</h2>
+<table>
+<tr><th>URL</th><td>{{.Request.URL}}</td></tr>
+<tr><th>Protocol</th><td>{{.Request.Proto}}</td></tr>
+<tr><th>Host</th><td>{{.Request.Host}}</td></tr>
+<tr><th>Remote address</th><td>{{.Request.RemoteAddr}}</td></tr>
+{{range $name, $values := .Request.Headers}}<tr><th>{{$name}}</th><td>{{range $values}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+<p>This page is shown because the router was created with WithDevelopmentErrors. Do not enable it in production.</p>
+</body>
+</html>
+`))
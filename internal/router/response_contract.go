@@ -0,0 +1,391 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fgrzl/json/jsonschema"
+	"github.com/fgrzl/mux/internal/common"
+	openapi "github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+// ResponseContractMode controls how the router reacts to responses that do not
+// match the responses declared on the route.
+type ResponseContractMode int
+
+const (
+	// ResponseContractOff disables response verification.
+	ResponseContractOff ResponseContractMode = iota
+	// ResponseContractLog passes the original response through as it is
+	// written and reports violations via slog and the configured violation
+	// handler.
+	ResponseContractLog
+	// ResponseContractStrict replaces violating responses with a 500 problem,
+	// dropping the headers the handler set.
+	ResponseContractStrict
+)
+
+// ResponseContractViolation describes a response that did not match the
+// route's declared responses.
+type ResponseContractViolation struct {
+	Method      string
+	Route       string
+	OperationID string
+	Status      int
+	ContentType string
+	Problems    []string
+}
+
+// responseContractVerifier checks handler output against the route's
+// declared OpenAPI responses. In strict mode the output is buffered until it
+// has been checked.
+type responseContractVerifier struct {
+	mode        ResponseContractMode
+	onViolation func(ResponseContractViolation)
+	schemas     sync.Map // contractSchemaKey -> map[string]any
+}
+
+type contractSchemaKey struct {
+	options   *routing.RouteOptions
+	status    string
+	mediaType string
+}
+
+func newResponseContractVerifier(options *RouterOptions) *responseContractVerifier {
+	if options == nil || options.ResponseContract == ResponseContractOff {
+		return nil
+	}
+	return &responseContractVerifier{
+		mode:        options.ResponseContract,
+		onViolation: options.ResponseContractHandler,
+	}
+}
+
+// maxContractBody bounds the body copy kept for verification in log mode.
+// Longer bodies are checked for status and content type only.
+const maxContractBody = 1 << 20
+
+// contractRecorder records the status and body written by the handler.
+// Headers are shared with the wrapped writer so middleware-set headers are
+// preserved. Unless passthrough is set, the response is buffered until it is
+// verified; otherwise it is written straight through and a copy of the body
+// is kept. A handler that flushes is streaming, so the buffered response is
+// released and the response is not verified.
+type contractRecorder struct {
+	http.ResponseWriter
+	passthrough bool
+	status      int
+	body        bytes.Buffer
+	truncated   bool
+	flushed     bool
+}
+
+func (r *contractRecorder) WriteHeader(code int) {
+	if r.status != 0 {
+		return
+	}
+	r.status = code
+	if r.passthrough {
+		r.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (r *contractRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.passthrough {
+		return r.body.Write(p)
+	}
+	if !r.flushed && !r.truncated {
+		if r.body.Len()+len(p) > maxContractBody {
+			r.truncated = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *contractRecorder) Flush() {
+	if !r.passthrough {
+		r.passthrough = true
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		flushContractRecorder(r.ResponseWriter, r.status, r.body.Bytes())
+	}
+	r.flushed = true
+	r.body = bytes.Buffer{}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (r *contractRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (v *responseContractVerifier) invoke(c routing.RouteContext, next HandlerFunc) {
+	options := c.Options()
+	if options == nil || len(options.Responses) == 0 || !contractVerifiable(c.Request()) {
+		next(c)
+		return
+	}
+
+	original := c.Response()
+	before := original.Header().Clone()
+	rec := &contractRecorder{ResponseWriter: original, passthrough: v.mode == ResponseContractLog}
+	c.SetResponse(rec)
+	defer c.SetResponse(original)
+
+	next(c)
+
+	c.SetResponse(original)
+	if rec.flushed {
+		// Streamed responses are not verified.
+		return
+	}
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	problems := v.verify(options, status, original.Header().Get(common.HeaderContentType), rec.body.Bytes(), rec.truncated)
+	if len(problems) == 0 {
+		if !rec.passthrough {
+			flushContractRecorder(original, status, rec.body.Bytes())
+		}
+		return
+	}
+
+	violation := ResponseContractViolation{
+		Method:      options.Method,
+		Route:       options.Pattern,
+		OperationID: options.OperationID,
+		Status:      status,
+		ContentType: original.Header().Get(common.HeaderContentType),
+		Problems:    problems,
+	}
+	v.report(c, violation)
+
+	if rec.passthrough {
+		return
+	}
+
+	// Drop the headers the handler set, such as ETag or Location, so they do
+	// not describe the problem. Headers set before the handler ran are kept.
+	header := original.Header()
+	clear(header)
+	maps.Copy(header, before)
+	header.Del(common.HeaderContentType)
+	header.Del(common.HeaderContentLength)
+	header.Del(common.HeaderContentEncoding)
+	c.ServerError("Response Contract Violation", strings.Join(problems, "; "))
+}
+
+func (v *responseContractVerifier) report(c routing.RouteContext, violation ResponseContractViolation) {
+	level := slog.LevelWarn
+	if v.mode == ResponseContractStrict {
+		level = slog.LevelError
+	}
+	slog.Log(c, level, "response contract violation",
+		"method", violation.Method,
+		"route", violation.Route,
+		"operationId", violation.OperationID,
+		"status", violation.Status,
+		"contentType", violation.ContentType,
+		"violations", violation.Problems,
+	)
+	if v.onViolation != nil {
+		v.onViolation(violation)
+	}
+}
+
+// verify checks the response against the declared responses. truncated
+// reports that body is unavailable because it was too long to keep, in which
+// case only the status and content type are checked.
+func (v *responseContractVerifier) verify(options *routing.RouteOptions, status int, contentType string, body []byte, truncated bool) []string {
+	key, declared := matchDeclaredResponse(options.Responses, status)
+	if declared == nil {
+		return []string{fmt.Sprintf("status %d is not declared", status)}
+	}
+	if len(declared.Content) == 0 {
+		return nil
+	}
+	if len(body) == 0 && !truncated {
+		if status == http.StatusNoContent || status == http.StatusNotModified {
+			return nil
+		}
+		return []string{fmt.Sprintf("status %d declares content but the body is empty", status)}
+	}
+
+	actual, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return []string{fmt.Sprintf("content type %q is not a valid media type", contentType)}
+	}
+	declaredType, media := matchDeclaredMediaType(declared.Content, actual)
+	if media == nil {
+		return []string{fmt.Sprintf("content type %q is not declared for status %s", actual, key)}
+	}
+	if !isJSONMediaType(actual) || truncated {
+		return nil
+	}
+
+	schema := v.schemaFor(contractSchemaKey{options: options, status: key, mediaType: declaredType}, media)
+	if schema == nil {
+		return nil
+	}
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return []string{"body is not valid JSON: " + err.Error()}
+	}
+	err = jsonschema.Validate(schema, data)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ErrValidation)
+	if !ok {
+		return []string{err.Error()}
+	}
+	problems := make([]string, 0, len(verr.Errs))
+	for _, e := range verr.Errs {
+		path := e.Path
+		if path == "" {
+			path = "/"
+		}
+		problems = append(problems, path+": "+e.Message)
+	}
+	return problems
+}
+
+// schemaFor resolves a self-contained JSON schema for the declared media type.
+// Named types are documented as component references, so the schema is
+// regenerated from the example's Go type together with its components.
+func (v *responseContractVerifier) schemaFor(key contractSchemaKey, media *openapi.MediaType) map[string]any {
+	if cached, ok := v.schemas.Load(key); ok {
+		schema, _ := cached.(map[string]any)
+		return schema
+	}
+	schema := buildContractSchema(media)
+	v.schemas.Store(key, schema)
+	return schema
+}
+
+func buildContractSchema(media *openapi.MediaType) map[string]any {
+	if media.Example != nil {
+		root, components := jsonschema.GenerateSchemaWithComponents(reflect.TypeOf(media.Example))
+		schema := make(map[string]any, len(root)+1)
+		for k, val := range root {
+			schema[k] = val
+		}
+		if len(components) > 0 {
+			schema["components"] = map[string]any{"schemas": components}
+		}
+		return schema
+	}
+	if media.Schema == nil || media.Schema.Ref != "" {
+		return nil
+	}
+	raw, err := json.Marshal(media.Schema)
+	if err != nil {
+		return nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil
+	}
+	return schema
+}
+
+// matchDeclaredResponse finds the declared response for status, honoring exact
+// codes, NXX ranges, and the default response in that order.
+func matchDeclaredResponse(responses map[string]*openapi.ResponseObject, status int) (string, *openapi.ResponseObject) {
+	code := strconv.Itoa(status)
+	if resp, ok := responses[code]; ok {
+		return code, responseOrEmpty(resp)
+	}
+	for _, rangeKey := range []string{code[:1] + "XX", code[:1] + "xx"} {
+		if resp, ok := responses[rangeKey]; ok {
+			return rangeKey, responseOrEmpty(resp)
+		}
+	}
+	if resp, ok := responses["default"]; ok {
+		return "default", responseOrEmpty(resp)
+	}
+	return "", nil
+}
+
+func responseOrEmpty(resp *openapi.ResponseObject) *openapi.ResponseObject {
+	if resp == nil {
+		return &openapi.ResponseObject{}
+	}
+	return resp
+}
+
+// matchDeclaredMediaType finds the declared media type compatible with actual.
+// Structured-syntax JSON types such as application/problem+json satisfy a
+// declared application/json.
+func matchDeclaredMediaType(content map[string]*openapi.MediaType, actual string) (string, *openapi.MediaType) {
+	if media, ok := content[actual]; ok {
+		return actual, mediaOrEmpty(media)
+	}
+	if isJSONMediaType(actual) {
+		if media, ok := content[common.MimeJSON]; ok {
+			return common.MimeJSON, mediaOrEmpty(media)
+		}
+	}
+	if i := strings.IndexByte(actual, '/'); i > 0 {
+		wildcard := actual[:i] + "/*"
+		if media, ok := content[wildcard]; ok {
+			return wildcard, mediaOrEmpty(media)
+		}
+	}
+	if media, ok := content["*/*"]; ok {
+		return "*/*", mediaOrEmpty(media)
+	}
+	return "", nil
+}
+
+func mediaOrEmpty(media *openapi.MediaType) *openapi.MediaType {
+	if media == nil {
+		return &openapi.MediaType{}
+	}
+	return media
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == common.MimeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// contractVerifiable reports whether the request can be safely buffered.
+// Upgrades and event streams need direct access to the connection; other
+// streaming handlers are detected when they flush.
+func contractVerifiable(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	if r.Header.Get(common.HeaderUpgrade) != "" {
+		return false
+	}
+	return !strings.Contains(r.Header.Get(common.HeaderAccept), "text/event-stream")
+}
+
+func flushContractRecorder(w http.ResponseWriter, status int, body []byte) {
+	w.WriteHeader(status)
+	if len(body) > 0 {
+		_, _ = w.Write(body)
+	}
+}
@@ -0,0 +1,228 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contractWidget struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func serveContract(t *testing.T, rtr *Router, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldPassThroughResponseGivenDeclaredContractWhenVerifying(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractStrict),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/widgets/{id}", func(c routing.RouteContext) {
+		c.OK(contractWidget{ID: 1, Name: "gear"})
+	}).WithOKResponse(contractWidget{}).WithNotFoundResponse()
+
+	// Act
+	rec := serveContract(t, rtr, "/widgets/1")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"name":"gear"}`, rec.Body.String())
+	assert.Empty(t, violations)
+}
+
+func TestShouldFailRequestGivenUndeclaredStatusWhenStrict(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractStrict),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/widgets", func(c routing.RouteContext) {
+		c.Conflict("conflict", "already exists")
+	}).WithOperationID("listWidgets").WithOKResponse([]contractWidget{})
+
+	// Act
+	rec := serveContract(t, rtr, "/widgets")
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, common.MimeProblemJSON, rec.Header().Get(common.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), "status 409 is not declared")
+	require.Len(t, violations, 1)
+	assert.Equal(t, "/widgets", violations[0].Route)
+	assert.Equal(t, "listWidgets", violations[0].OperationID)
+	assert.Equal(t, http.StatusConflict, violations[0].Status)
+}
+
+func TestShouldDropHandlerHeadersGivenStrictViolation(t *testing.T) {
+	// Arrange
+	rtr := NewRouter(WithResponseContract(ResponseContractStrict))
+	rtr.Use(&testMiddleware{invoke: func(c routing.RouteContext, next HandlerFunc) {
+		c.Response().Header().Set("X-Served-By", "edge")
+		next(c)
+	}})
+	rtr.GET("/widgets/{id}", func(c routing.RouteContext) {
+		header := c.Response().Header()
+		header.Set("ETag", `"v1"`)
+		header.Set("Location", "/widgets/1")
+		header.Set("Content-Disposition", `attachment; filename="widget.json"`)
+		c.Conflict("conflict", "already exists")
+	}).WithOKResponse(contractWidget{})
+
+	// Act
+	rec := serveContract(t, rtr, "/widgets/1")
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, common.MimeProblemJSON, rec.Header().Get(common.HeaderContentType))
+	assert.Equal(t, "edge", rec.Header().Get("X-Served-By"))
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Empty(t, rec.Header().Get("Location"))
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+}
+
+func TestShouldReportBodyShapeViolationGivenLogModeWhenBodyDoesNotMatchSchema(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractLog),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/widgets/{id}", func(c routing.RouteContext) {
+		c.OK(map[string]any{"id": "one", "name": "gear"})
+	}).WithOKResponse(contractWidget{})
+
+	// Act
+	rec := serveContract(t, rtr, "/widgets/1")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":"one","name":"gear"}`, rec.Body.String())
+	require.Len(t, violations, 1)
+	assert.Equal(t, "/widgets/{id}", violations[0].Route)
+	require.NotEmpty(t, violations[0].Problems)
+	assert.Contains(t, violations[0].Problems[0], "/id")
+}
+
+func TestShouldReportContentTypeViolationGivenUndeclaredMediaTypeWhenVerifying(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractLog),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/widgets", func(c routing.RouteContext) {
+		c.Plain(http.StatusOK, []byte("gear"))
+	}).WithOKResponse(contractWidget{})
+
+	// Act
+	rec := serveContract(t, rtr, "/widgets")
+
+	// Assert
+	assert.Equal(t, "gear", rec.Body.String())
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0].Problems[0], "text/plain")
+}
+
+func TestShouldAcceptProblemJSONGivenDeclaredJSONProblemResponseWhenVerifying(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractStrict),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/widgets", func(c routing.RouteContext) {
+		c.BadRequest("invalid", "bad filter")
+	}).WithOKResponse(contractWidget{}).WithBadRequestResponse()
+
+	// Act
+	rec := serveContract(t, rtr, "/widgets")
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, violations)
+}
+
+func TestShouldSkipVerificationGivenRouteWithoutDeclaredResponses(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractStrict),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/raw", func(c routing.RouteContext) {
+		c.Plain(http.StatusTeapot, []byte("tea"))
+	})
+
+	// Act
+	rec := serveContract(t, rtr, "/raw")
+
+	// Assert
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, violations)
+}
+
+func TestShouldStreamWithoutVerifyingGivenHandlerFlushesWhenStrict(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractStrict),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rtr.GET("/events", func(c routing.RouteContext) {
+		c.Response().Header().Set(common.HeaderContentType, "application/x-ndjson")
+		_, _ = c.Response().Write([]byte("{\"n\":1}\n"))
+		require.NoError(t, http.NewResponseController(c.Response()).Flush())
+		_, _ = c.Response().Write([]byte("{\"n\":2}\n"))
+	}).WithOKResponse(contractWidget{})
+
+	// Act
+	rec := serveContract(t, rtr, "/events")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", rec.Body.String())
+	assert.Empty(t, violations)
+}
+
+func TestShouldWriteThroughBeforeHandlerReturnsGivenLogMode(t *testing.T) {
+	// Arrange
+	var violations []ResponseContractViolation
+	rtr := NewRouter(
+		WithResponseContract(ResponseContractLog),
+		WithResponseContractHandler(func(v ResponseContractViolation) { violations = append(violations, v) }),
+	)
+	rec := httptest.NewRecorder()
+	var written int
+	rtr.GET("/widgets/{id}", func(c routing.RouteContext) {
+		c.Response().Header().Set(common.HeaderContentType, common.MimeJSON)
+		c.Response().WriteHeader(http.StatusTeapot)
+		_, _ = c.Response().Write([]byte(`{"id":1}`))
+		written = rec.Body.Len()
+	}).WithOKResponse(contractWidget{})
+
+	// Act
+	rtr.ServeHTTP(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/widgets/1", nil))
+
+	// Assert
+	assert.Equal(t, len(`{"id":1}`), written)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	require.Len(t, violations, 1)
+	assert.Equal(t, []string{"status 418 is not declared"}, violations[0].Problems)
+}
@@ -11,8 +11,9 @@ import (
 )
 
 // collectRoutesFromNode traverses the router's internal routing.RouteNode tree and
-// produces a slice of openapi.RouteData.
-func collectRoutesFromNode(node *routing.RouteNode) ([]openapi.RouteData, error) {
+// produces a slice of openapi.RouteData, applying documenters to each
+// operation.
+func collectRoutesFromNode(node *routing.RouteNode, documenters ...OperationDocumenter) ([]openapi.RouteData, error) {
 	if node == nil {
 		return nil, fmt.Errorf("route node is nil")
 	}
@@ -23,10 +24,14 @@ func collectRoutesFromNode(node *routing.RouteNode) ([]openapi.RouteData, error)
 			if method == "" {
 				return fmt.Errorf("empty method in route options at path %q", prefix)
 			}
+			op := openapi.CloneOperation(&opt.Operation)
+			for _, document := range documenters {
+				document(opt, op)
+			}
 			routes = append(routes, openapi.RouteData{
 				Path:    cleanPath(prefix),
 				Method:  strings.ToUpper(method),
-				Options: openapi.CloneOperation(&opt.Operation),
+				Options: op,
 			})
 		}
 		for seg, child := range n.Children {
@@ -2,12 +2,14 @@ package router
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/fgrzl/mux/internal/binder"
 	"github.com/fgrzl/mux/internal/builder"
@@ -39,18 +41,26 @@ type RouteGroup struct {
 	validation    *routing.ValidationState
 
 	// Group-level defaults:
-	defaultMiddleware  []Middleware
-	defaultServices    map[routing.ServiceKey]any
-	defaultParams      []*openapi.ParameterObject
-	defaultRoles       []string
-	defaultScopes      []string
-	defaultPermissions []string
-	defaultTags        []string
-	defaultSummary     string
-	defaultDescription string
-	defaultSecurity    []*openapi.SecurityRequirement
-	defaultAllowAnon   bool
-	defaultDeprecated  bool
+	defaultMiddleware      []Middleware
+	defaultServices        map[routing.ServiceKey]any
+	defaultParams          []*openapi.ParameterObject
+	defaultRoles           []string
+	defaultScopes          []string
+	defaultPermissions     []string
+	defaultTags            []string
+	defaultSummary         string
+	defaultDescription     string
+	defaultSecurity        []*openapi.SecurityRequirement
+	defaultAllowAnon       bool
+	defaultDeprecated      bool
+	defaultRateLimits      []routing.RateLimitPolicy
+	defaultRateLimitQuotas []routing.RateLimitQuota
+	defaultPriority        routing.Priority
+	defaultInFlight        *routing.InFlightLimit
+	defaultTimeout         time.Duration
+	defaultSecurityHeaders map[string]string
+	defaultDisableCSRF     bool
+	defaultDisableHTTPS    bool
 }
 
 func (rg *RouteGroup) RouteRegistry() *registry.RouteRegistry {
@@ -349,6 +359,63 @@ func (rg *RouteGroup) Deprecated() *RouteGroup {
 	return rg
 }
 
+// WithRateLimitPolicy adds a rate limit policy to the group defaults,
+// replacing an inherited policy with the same name.
+func (rg *RouteGroup) WithRateLimitPolicy(policy routing.RateLimitPolicy) *RouteGroup {
+	rg.defaultRateLimits = routing.AddRateLimitPolicy(rg.defaultRateLimits, policy)
+	return rg
+}
+
+// WithRateLimitQuota adds a quota tier to the group defaults, replacing an
+// inherited quota with the same name.
+func (rg *RouteGroup) WithRateLimitQuota(quota routing.RateLimitQuota) *RouteGroup {
+	rg.defaultRateLimitQuotas = routing.AddRateLimitQuota(rg.defaultRateLimitQuotas, quota)
+	return rg
+}
+
+// WithPriority sets the load shedding priority of the group's routes.
+func (rg *RouteGroup) WithPriority(priority routing.Priority) *RouteGroup {
+	rg.defaultPriority = priority
+	return rg
+}
+
+// WithMaxInFlight bounds the requests served at once across the group's
+// routes, including routes of nested groups created afterwards. A value <= 0
+// removes the group limit.
+func (rg *RouteGroup) WithMaxInFlight(n int) *RouteGroup {
+	rg.defaultInFlight = nil
+	if n > 0 {
+		rg.defaultInFlight = &routing.InFlightLimit{Max: n}
+	}
+	return rg
+}
+
+// WithTimeout sets the request timeout of the group's routes. A value <= 0
+// removes the group timeout.
+func (rg *RouteGroup) WithTimeout(d time.Duration) *RouteGroup {
+	rg.defaultTimeout = max(d, 0)
+	return rg
+}
+
+// WithSecurityHeaders overrides the headers the security headers middleware
+// sets on the group's routes. An empty value omits the header.
+func (rg *RouteGroup) WithSecurityHeaders(headers map[string]string) *RouteGroup {
+	rg.defaultSecurityHeaders = routing.MergeSecurityHeaders(rg.defaultSecurityHeaders, headers)
+	return rg
+}
+
+// WithoutCSRF exempts the group's routes from the CSRF middleware.
+func (rg *RouteGroup) WithoutCSRF() *RouteGroup {
+	rg.defaultDisableCSRF = true
+	return rg
+}
+
+// WithoutHTTPSEnforcement lets the group's routes answer plain HTTP requests.
+func (rg *RouteGroup) WithoutHTTPSEnforcement() *RouteGroup {
+	rg.defaultDisableHTTPS = true
+	return rg
+}
+
 // ---- Nested Group Creation ----
 
 // copyDefaults copies all default settings from source to this RouteGroup.
@@ -378,6 +445,14 @@ func (rg *RouteGroup) copyDefaults(source *RouteGroup) {
 	rg.defaultDescription = source.defaultDescription
 	rg.defaultAllowAnon = source.defaultAllowAnon
 	rg.defaultDeprecated = source.defaultDeprecated
+	rg.defaultRateLimits = slices.Clone(source.defaultRateLimits)
+	rg.defaultRateLimitQuotas = slices.Clone(source.defaultRateLimitQuotas)
+	rg.defaultPriority = source.defaultPriority
+	rg.defaultInFlight = source.defaultInFlight
+	rg.defaultTimeout = source.defaultTimeout
+	rg.defaultSecurityHeaders = maps.Clone(source.defaultSecurityHeaders)
+	rg.defaultDisableCSRF = source.defaultDisableCSRF
+	rg.defaultDisableHTTPS = source.defaultDisableHTTPS
 }
 
 func cloneGroupServices(services map[routing.ServiceKey]any) map[routing.ServiceKey]any {
@@ -425,7 +500,19 @@ func cloneDetachedRouteOptions(source *routing.RouteOptions) *routing.RouteOptio
 		RateLimit:      source.RateLimit,
 		RateInterval:   source.RateInterval,
 		MaxBodyBytes:   source.MaxBodyBytes,
+		Uploads:        source.Uploads,
+		RateLimits:     slices.Clone(source.RateLimits),
 		Operation:      *operation,
+
+		RateLimitQuotas: slices.Clone(source.RateLimitQuotas),
+
+		DisableCompression:      source.DisableCompression,
+		DisableCSRF:             source.DisableCSRF,
+		DisableHTTPSEnforcement: source.DisableHTTPSEnforcement,
+		Priority:                source.Priority,
+		InFlight:                source.InFlight,
+		Timeout:                 source.Timeout,
+		SecurityHeaders:         maps.Clone(source.SecurityHeaders),
 	}
 	cloned.SetMiddleware(slices.Clone(source.Middleware))
 	cloned.SetServices(source.Services)
@@ -454,6 +541,28 @@ func mergeRouteOptions(target, source *routing.RouteOptions) {
 	if source.MaxBodyBytes > 0 {
 		target.MaxBodyBytes = source.MaxBodyBytes
 	}
+	if source.Uploads != nil {
+		target.Uploads = source.Uploads
+	}
+	for _, policy := range source.RateLimits {
+		target.RateLimits = routing.AddRateLimitPolicy(target.RateLimits, policy)
+	}
+	for _, quota := range source.RateLimitQuotas {
+		target.RateLimitQuotas = routing.AddRateLimitQuota(target.RateLimitQuotas, quota)
+	}
+	target.DisableCompression = target.DisableCompression || source.DisableCompression
+	target.DisableCSRF = target.DisableCSRF || source.DisableCSRF
+	target.DisableHTTPSEnforcement = target.DisableHTTPSEnforcement || source.DisableHTTPSEnforcement
+	if source.Priority != routing.PriorityNormal {
+		target.Priority = source.Priority
+	}
+	if source.InFlight != nil {
+		target.InFlight = source.InFlight
+	}
+	if source.Timeout > 0 {
+		target.Timeout = source.Timeout
+	}
+	target.SecurityHeaders = routing.MergeSecurityHeaders(target.SecurityHeaders, source.SecurityHeaders)
 	target.AppendMiddleware(slices.Clone(source.Middleware)...)
 	for key, service := range source.Services {
 		target.SetService(key, service)
@@ -557,7 +666,16 @@ func (rg *RouteGroup) newRouteOptions(method, pattern string, handler routing.Ha
 		RateLimit:      0,
 		RateInterval:   0,
 		MaxBodyBytes:   0,
+		RateLimits:     slices.Clone(rg.defaultRateLimits),
 		Operation:      op,
+
+		RateLimitQuotas:         slices.Clone(rg.defaultRateLimitQuotas),
+		Priority:                rg.defaultPriority,
+		InFlight:                rg.defaultInFlight,
+		Timeout:                 rg.defaultTimeout,
+		SecurityHeaders:         maps.Clone(rg.defaultSecurityHeaders),
+		DisableCSRF:             rg.defaultDisableCSRF,
+		DisableHTTPSEnforcement: rg.defaultDisableHTTPS,
 	}
 	if len(op.Parameters) > 0 {
 		options.ParamIndex = routing.BuildParamIndex(op.Parameters)
@@ -741,7 +859,7 @@ func (rg *RouteGroup) HealthzWithReady(isReady func(c routing.RouteContext) bool
 			return
 		}
 		c.Plain(http.StatusServiceUnavailable, []byte("not ready"))
-	}).AllowAnonymous()
+	}).AllowAnonymous().WithPriority(routing.PriorityCritical)
 }
 
 // Livez registers a /livez endpoint that always returns live.
@@ -757,7 +875,7 @@ func (rg *RouteGroup) LivezWithCheck(isLive func(c routing.RouteContext) bool) *
 			return
 		}
 		c.Plain(http.StatusServiceUnavailable, []byte("not live"))
-	}).AllowAnonymous()
+	}).AllowAnonymous().WithPriority(routing.PriorityCritical)
 }
 
 // Readyz registers a /readyz endpoint that always returns ready.
@@ -773,7 +891,7 @@ func (rg *RouteGroup) ReadyzWithCheck(isReady func(c routing.RouteContext) bool)
 			return
 		}
 		c.Plain(http.StatusServiceUnavailable, []byte("not ready"))
-	}).AllowAnonymous()
+	}).AllowAnonymous().WithPriority(routing.PriorityCritical)
 }
 
 // Startupz registers a /startupz endpoint that always returns started.
@@ -789,7 +907,7 @@ func (rg *RouteGroup) StartupzWithCheck(hasStarted func(c routing.RouteContext)
 			return
 		}
 		c.Plain(http.StatusServiceUnavailable, []byte("not started"))
-	}).AllowAnonymous()
+	}).AllowAnonymous().WithPriority(routing.PriorityCritical)
 }
 
 // StaticFallback serves static files with a fallback for SPA routing, with directory safety checks.
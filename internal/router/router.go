@@ -4,16 +4,16 @@ package router
 
 import (
 	"context"
-	"log/slog"
 	"net/http"
-	"runtime/debug"
 	"strings"
 	"sync/atomic"
 
 	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/di"
 	openapi "github.com/fgrzl/mux/internal/openapi"
 	"github.com/fgrzl/mux/internal/registry"
 	"github.com/fgrzl/mux/internal/routing"
+	"github.com/fgrzl/mux/internal/tasks"
 )
 
 // RouteContext is an alias for routing.RouteContext exposed for callers.
@@ -32,14 +32,16 @@ func NewRouter(opts ...RouterOption) *Router {
 			routeRegistry: registry.NewRouteRegistry(),
 			validation:    routing.NewValidationState(),
 		},
-		options: options,
+		options:  options,
+		contract: newResponseContractVerifier(options),
+		tasks:    tasks.New(options.MaxBackgroundTasks, options.MaxQueuedBackgroundTasks),
 	}
 	// initialize pipeline with a default final handler to avoid storing nil
 	// into atomic.Value (which panics). The handler will call the route's
 	// configured handler when executed. We also store the current middleware
 	// count to detect changes and rebuild lazily in ServeHTTP.
 	defaultHandler := func(c routing.RouteContext) {
-		invokeRouteHandler(c)
+		r.invokeRoute(c)
 	}
 	r.pipeline.Store(pipelineCache{h: defaultHandler, mwCount: 0})
 	return r
@@ -87,6 +89,27 @@ type Router struct {
 	// rebuilt when middleware are added via Use. Stored with atomic.Value
 	// to avoid per-request locking and allocations.
 	pipeline atomic.Value // holds pipelineCache
+	// contract verifies responses against declared route responses when enabled.
+	contract *responseContractVerifier
+	// container holds typed service providers resolved through request scopes.
+	container *di.Container
+	// tasks runs background work started with RouteContext.Go.
+	tasks *tasks.Group
+	// documenters let middleware describe their effect on each operation in
+	// the generated OpenAPI document.
+	documenters []OperationDocumenter
+}
+
+// OperationDocumenter adjusts the OpenAPI operation generated for a route.
+// It receives a copy of the operation, so changes affect only the document.
+type OperationDocumenter func(options *routing.RouteOptions, op *openapi.Operation)
+
+// DocumentOperations registers fn to adjust every operation returned by
+// Routes. Middleware use it to document headers and responses they add.
+func (rtr *Router) DocumentOperations(fn OperationDocumenter) {
+	if fn != nil {
+		rtr.documenters = append(rtr.documenters, fn)
+	}
 }
 
 // Safe switches the router's configuration tree into non-panicking validation
@@ -114,6 +137,7 @@ func (rtr *Router) Configure(configure func(*Router)) error {
 	}()
 
 	configure(rtr)
+	configured.Handle(rtr.container.Validate())
 	return configured.Err()
 }
 
@@ -140,6 +164,26 @@ func (rtr *Router) WithService(key routing.ServiceKey, svc any) *Router {
 	return rtr
 }
 
+// Provide registers a typed service factory with the given lifetime. The
+// factory's parameters are resolved from the container when the service is
+// first requested; Configure reports missing dependencies and cycles.
+func (rtr *Router) Provide(lifetime di.Lifetime, factory any) *Router {
+	if rtr.container == nil {
+		rtr.container = di.New()
+	}
+	rtr.validationState().Handle(rtr.container.Provide(lifetime, factory))
+	return rtr
+}
+
+// WaitTasks stops accepting background tasks and waits until the running ones
+// finish or ctx is done, in which case their context is canceled.
+func (rtr *Router) WaitTasks(ctx context.Context) error {
+	if rtr.tasks == nil {
+		return nil
+	}
+	return rtr.tasks.Wait(ctx)
+}
+
 // pipelineCache stores the composed handler and the middleware count used to build it.
 type pipelineCache struct {
 	h       HandlerFunc
@@ -195,7 +239,7 @@ func (rtr *Router) Use(m Middleware) {
 	// doesn't pay for pipeline construction.
 	mw := rtr.middleware
 	var final = func(c routing.RouteContext) {
-		invokeRouteHandler(c)
+		rtr.invokeRoute(c)
 	}
 	for i := len(mw) - 1; i >= 0; i-- {
 		m := mw[i]
@@ -247,6 +291,11 @@ func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Manual release instead of defer for ~5-10ns improvement
 		rtr.configureContext(c, w, routeResolution{options: opt})
 
+		if opt != nil && opt.Timeout > 0 {
+			rtr.executeWithTimeout(c, w, r, opt.Timeout)
+			return
+		}
+
 		// Skip middleware pipeline if no middleware configured (~20-30ns faster)
 		if len(rtr.middleware) == 0 {
 			rtr.executeHandlerWithRecover(c, w, r)
@@ -285,6 +334,11 @@ func (rtr *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	rtr.configureContext(c, w, res)
 
+	if res.options != nil && res.options.Timeout > 0 {
+		rtr.executeWithTimeout(c, w, r, res.options.Timeout)
+		return
+	}
+
 	// Skip middleware pipeline if no middleware configured (~20-30ns faster)
 	if len(rtr.middleware) == 0 {
 		rtr.executeHandlerWithRecover(c, w, r)
@@ -302,51 +356,31 @@ func (rtr *Router) acquireRouteContext(w http.ResponseWriter, r *http.Request) *
 	return routing.NewRouteContext(w, r)
 }
 
-// releaseContext returns the context to the pool if pooling is enabled
+// releaseContext disposes the request's service scope, then returns the
+// context to the pool if pooling is enabled and otherwise removes any uploads
+// the request left on disk.
 func (rtr *Router) releaseContext(c *routing.DefaultRouteContext) {
-	if rtr.options != nil && rtr.options.ContextPooling && c != nil {
+	if c == nil {
+		return
+	}
+	c.CloseScope()
+	if rtr.options != nil && rtr.options.ContextPooling {
 		routing.ReleaseContext(c)
+		return
 	}
+	c.ReleaseUploads()
 }
 
 // executeHandlerWithRecover executes the resolved route handler directly with panic recovery (no middleware).
 func (rtr *Router) executeHandlerWithRecover(c *routing.DefaultRouteContext, w http.ResponseWriter, r *http.Request) {
-	defer func() {
-		if rec := recover(); rec != nil {
-			logCtx := context.Background()
-			if r != nil && r.Context() != nil {
-				logCtx = r.Context()
-			}
-			stack := debug.Stack()
-			slog.ErrorContext(logCtx, "panic recovered in ServeHTTP", "error", rec, "path", safeURLPath(r), "method", safeMethod(r), "stack", string(stack))
-			if c != nil {
-				c.ServerError("Internal Server Error", "An unexpected error occurred")
-			} else {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			}
-		}
-	}()
-	invokeRouteHandler(c)
+	defer rtr.recoverPanic(c, w, r)
+	rtr.invokeRoute(c)
 }
 
 // executePipelineWithRecover executes the pipeline with panic recovery
 // This is separate from executePipeline to allow non-panic paths to skip defer overhead
 func (rtr *Router) executePipelineWithRecover(c *routing.DefaultRouteContext, w http.ResponseWriter, r *http.Request) {
-	defer func() {
-		if rec := recover(); rec != nil {
-			logCtx := context.Background()
-			if r != nil && r.Context() != nil {
-				logCtx = r.Context()
-			}
-			stack := debug.Stack()
-			slog.ErrorContext(logCtx, "panic recovered in ServeHTTP", "error", rec, "path", safeURLPath(r), "method", safeMethod(r), "stack", string(stack))
-			if c != nil {
-				c.ServerError("Internal Server Error", "An unexpected error occurred")
-			} else {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			}
-		}
-	}()
+	defer rtr.recoverPanic(c, w, r)
 	rtr.executePipeline(c)
 }
 
@@ -394,6 +428,16 @@ func allowHeaderHasMethod(allowHeader, method string) bool {
 	return false
 }
 
+// invokeRoute runs the route handler, verifying the response contract when
+// response verification is enabled.
+func (rtr *Router) invokeRoute(c routing.RouteContext) {
+	if rtr.contract != nil {
+		rtr.contract.invoke(c, invokeRouteHandler)
+		return
+	}
+	invokeRouteHandler(c)
+}
+
 func invokeRouteHandler(c routing.RouteContext) {
 	if c == nil {
 		panic("router: invokeRouteHandler called with nil route context")
@@ -407,6 +451,8 @@ func invokeRouteHandler(c routing.RouteContext) {
 		panic("router: invokeRouteHandler called with nil effective handler")
 	}
 	handler(c)
+	// Reject requests whose handler recorded parameter errors but wrote nothing.
+	c.WriteParamErrors()
 }
 
 func (rtr *Router) resolveRoute(r *http.Request, c *routing.DefaultRouteContext) (routeResolution, routeOutcome) {
@@ -520,9 +566,14 @@ func (rtr *Router) configureContext(c *routing.DefaultRouteContext, w http.Respo
 		c.SetMaxBodyBytes(res.options.MaxBodyBytes)
 	}
 
+	c.SetTaskGroup(rtr.tasks)
+
 	if res.suppressBody {
 		c.SetResponse(headWriter{ResponseWriter: w})
 	}
+	if rtr.container.Len() > 0 {
+		c.SetContainer(rtr.container)
+	}
 }
 
 func (rtr *Router) executePipeline(c routing.RouteContext) {
@@ -541,7 +592,7 @@ func (rtr *Router) executePipeline(c routing.RouteContext) {
 
 func (rtr *Router) buildPipeline(mw []Middleware) HandlerFunc {
 	final := func(c routing.RouteContext) {
-		invokeRouteHandler(c)
+		rtr.invokeRoute(c)
 	}
 	for i := len(mw) - 1; i >= 0; i-- {
 		middleware := mw[i]
@@ -563,5 +614,6 @@ func (rtr *Router) InfoObject() (*openapi.InfoObject, error) {
 // Routes returns a list of OpenAPI route metadata collected from the registry.
 func (rtr *Router) Routes() ([]openapi.RouteData, error) {
 	root := rtr.routeRegistry.Root()
-	return collectRoutesFromNode(root)
+	documenters := append([]OperationDocumenter{rtr.documentTimeout}, rtr.documenters...)
+	return collectRoutesFromNode(root, documenters...)
 }
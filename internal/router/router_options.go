@@ -2,6 +2,7 @@ package router
 
 import (
 	"log/slog"
+	"net/http"
 	"net/url"
 
 	openapi "github.com/fgrzl/mux/internal/openapi"
@@ -19,6 +20,27 @@ type RouterOptions struct {
 	// ContextPooling enables sync.Pool reuse of RouteContext instances to
 	// reduce allocations on the hot path.
 	ContextPooling bool
+	// ResponseContract enables verification of handler responses against the
+	// responses declared on each route.
+	ResponseContract ResponseContractMode
+	// ResponseContractHandler receives every detected contract violation.
+	ResponseContractHandler func(ResponseContractViolation)
+	// MaxBackgroundTasks bounds how many RouteContext.Go tasks run at once.
+	// Zero or negative uses tasks.DefaultLimit.
+	MaxBackgroundTasks int
+	// MaxQueuedBackgroundTasks bounds how many RouteContext.Go tasks wait for
+	// a free slot. Zero or negative uses tasks.DefaultQueueLimit.
+	MaxQueuedBackgroundTasks int
+	// TimeoutStatus is the status answered when a route timeout passes
+	// before the handler commits a response: 503 (the default) or 504.
+	TimeoutStatus int
+	// PanicHandler responds to panics recovered while serving requests. Nil
+	// uses DefaultPanicHandler, or the development renderer when
+	// DevelopmentErrors is set.
+	PanicHandler PanicHandler
+	// DevelopmentErrors renders recovered panics with their stack and a
+	// request dump. It must not be enabled in production.
+	DevelopmentErrors bool
 }
 
 func (o *RouterOptions) SetClientURL(clientURL *url.URL) {
@@ -119,6 +141,67 @@ func WithContextPooling() RouterOption {
 	}
 }
 
+// WithMaxBackgroundTasks bounds how many background tasks started with
+// RouteContext.Go run concurrently; further tasks wait for a free slot, up
+// to the limit set with WithMaxQueuedBackgroundTasks.
+func WithMaxBackgroundTasks(n int) RouterOption {
+	return func(o *RouterOptions) {
+		o.MaxBackgroundTasks = n
+	}
+}
+
+// WithMaxQueuedBackgroundTasks bounds how many background tasks wait for a
+// free slot; RouteContext.Go rejects further tasks with tasks.ErrQueueFull.
+func WithMaxQueuedBackgroundTasks(n int) RouterOption {
+	return func(o *RouterOptions) {
+		o.MaxQueuedBackgroundTasks = n
+	}
+}
+
+// WithTimeoutStatus sets the status answered when a route timeout passes,
+// http.StatusServiceUnavailable (the default) or http.StatusGatewayTimeout.
+// Other values are ignored.
+func WithTimeoutStatus(status int) RouterOption {
+	return func(o *RouterOptions) {
+		if status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout {
+			o.TimeoutStatus = status
+		}
+	}
+}
+
+// WithPanicHandler replaces the response to panics recovered while serving
+// requests, for example to report them to an error tracker.
+func WithPanicHandler(handler PanicHandler) RouterOption {
+	return func(o *RouterOptions) {
+		o.PanicHandler = handler
+	}
+}
+
+// WithDevelopmentErrors renders recovered panics with their stack and a dump
+// of the request, as HTML for browsers and problem+json otherwise. Without
+// it, panic responses never include stacks.
+func WithDevelopmentErrors() RouterOption {
+	return func(o *RouterOptions) {
+		o.DevelopmentErrors = true
+	}
+}
+
+// WithResponseContract verifies handler responses against the declared route
+// responses using the given mode.
+func WithResponseContract(mode ResponseContractMode) RouterOption {
+	return func(o *RouterOptions) {
+		o.ResponseContract = mode
+	}
+}
+
+// WithResponseContractHandler registers a callback invoked for every response
+// contract violation.
+func WithResponseContractHandler(handler func(ResponseContractViolation)) RouterOption {
+	return func(o *RouterOptions) {
+		o.ResponseContractHandler = handler
+	}
+}
+
 // helper
 func initInfo(o *RouterOptions) {
 	if o.openapi == nil {
@@ -42,3 +42,120 @@ func TestShouldRecoverFromPanicInHandler(t *testing.T) {
 
 // Helper middleware that panics for testing
 // No unused middleware types kept in this test file.
+
+func servePanic(rtr *Router, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/panic?id=7", nil)
+	req.Header.Set(common.HeaderAccept, accept)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	recorder := httptest.NewRecorder()
+	rtr.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestShouldCallPanicHandlerGivenWithPanicHandler(t *testing.T) {
+	// Arrange
+	var recovered any
+	var stack []byte
+	rtr := NewRouter(WithPanicHandler(func(c routing.RouteContext, rec any, s []byte) {
+		recovered, stack = rec, s
+		c.Problem(&routing.ProblemDetails{
+			Title:      "Internal Server Error",
+			Status:     http.StatusInternalServerError,
+			Extensions: map[string]any{"traceId": "abc123"},
+		})
+	}))
+	rtr.Use(&testMiddleware{invoke: func(c routing.RouteContext, next HandlerFunc) { next(c) }})
+	rtr.GET("/panic", func(c routing.RouteContext) { panic("boom") })
+
+	// Act
+	recorder := servePanic(rtr, common.MimeJSON)
+
+	// Assert
+	assert.Equal(t, "boom", recovered)
+	assert.Contains(t, string(stack), "router_panic_test.go")
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"traceId":"abc123"`)
+}
+
+func TestShouldRepanicGivenPanicHandlerThatPanics(t *testing.T) {
+	// Arrange
+	rtr := NewRouter(WithPanicHandler(func(c routing.RouteContext, rec any, _ []byte) { panic(rec) }))
+	rtr.GET("/panic", func(c routing.RouteContext) { panic("boom") })
+
+	// Act & Assert
+	assert.PanicsWithValue(t, "boom", func() { servePanic(rtr, common.MimeJSON) })
+}
+
+func TestShouldHideStackGivenProductionMode(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	rtr.GET("/panic", func(c routing.RouteContext) { panic("secret detail") })
+
+	// Act
+	recorder := servePanic(rtr, "text/html")
+
+	// Assert
+	assert.Equal(t, common.MimeProblemJSON, recorder.Header().Get(common.HeaderContentType))
+	assert.NotContains(t, recorder.Body.String(), "secret detail")
+	assert.NotContains(t, recorder.Body.String(), "goroutine")
+}
+
+func TestShouldRenderHTMLGivenDevelopmentErrorsAndBrowser(t *testing.T) {
+	// Arrange
+	rtr := NewRouter(WithDevelopmentErrors())
+	rtr.GET("/panic", func(c routing.RouteContext) { panic("<b>boom</b>") })
+
+	// Act
+	recorder := servePanic(rtr, "text/html,application/xhtml+xml,*/*;q=0.8")
+
+	// Assert
+	body := recorder.Body.String()
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, common.MimeTextHTML, recorder.Header().Get(common.HeaderContentType))
+	assert.Contains(t, body, "panic: &lt;b&gt;boom&lt;/b&gt;")
+	assert.Contains(t, body, "goroutine")
+	assert.Contains(t, body, "/panic?id=7")
+	assert.Contains(t, body, "[redacted]")
+	assert.NotContains(t, body, "secret-token")
+}
+
+func TestShouldRenderProblemGivenDevelopmentErrorsAndAPIClient(t *testing.T) {
+	// Arrange
+	rtr := NewRouter(WithDevelopmentErrors())
+	rtr.GET("/panic", func(c routing.RouteContext) { panic("boom") })
+
+	// Act
+	recorder := servePanic(rtr, "*/*")
+
+	// Assert
+	require.Equal(t, common.MimeProblemJSON, recorder.Header().Get(common.HeaderContentType))
+	var problem struct {
+		Detail  string         `json:"detail"`
+		Panic   string         `json:"panic"`
+		Stack   []string       `json:"stack"`
+		Request map[string]any `json:"request"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, "panic: boom", problem.Detail)
+	assert.Equal(t, "boom", problem.Panic)
+	assert.NotEmpty(t, problem.Stack)
+	assert.Equal(t, "/panic?id=7", problem.Request["url"])
+	assert.NotContains(t, recorder.Body.String(), "secret-token")
+}
+
+func TestShouldPreferHTMLOnlyGivenHigherQuality(t *testing.T) {
+	// Arrange
+	cases := map[string]bool{
+		"":                                  false,
+		"*/*":                               false,
+		"application/json":                  false,
+		"text/html":                         true,
+		"text/html;q=0.5, */*":              false,
+		"application/json;q=0.5, text/*":    false,
+		"text/html, application/json;q=0.9": true,
+	}
+	// Act & Assert
+	for accept, expected := range cases {
+		assert.Equal(t, expected, prefersHTML(accept), accept)
+	}
+}
@@ -0,0 +1,263 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fgrzl/mux/internal/common"
+	openapi "github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/routing"
+)
+
+const timeoutDetail = "The server did not finish handling the request in time."
+
+// executeWithTimeout runs the request with a context deadline of timeout
+// and releases c. Like http.TimeoutHandler, it runs the pipeline on its own
+// goroutine and returns at the deadline when the handler has not committed a
+// response, so the client gets a timeout problem on time and later handler
+// writes fail with http.ErrHandlerTimeout. A handler that ignores ctx keeps
+// running, and its scope, uploads, and context are released only when it
+// returns, so handlers should honor ctx.
+func (rtr *Router) executeWithTimeout(c *routing.DefaultRouteContext, w http.ResponseWriter, r *http.Request, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	tw := &timeoutWriter{
+		w:        c.Response(),
+		h:        make(http.Header),
+		status:   rtr.timeoutStatus(),
+		instance: r.RequestURI,
+		expiredC: make(chan struct{}),
+	}
+	ctx = routing.ContextWithTimeoutStatus(ctx, &tw.timeoutStatus)
+	tw.ctx = ctx
+	c.SetRequest(c.Request().WithContext(ctx))
+	c.SetResponse(tw)
+	stop := context.AfterFunc(ctx, tw.expire)
+
+	done := make(chan any, 1)
+	go func() {
+		var panicked any
+		defer func() {
+			stop()
+			if tw.finish() {
+				c.MarkFailed()
+			}
+			cancel()
+			rtr.releaseContext(c)
+			if panicked != nil && tw.timeoutStatus.Load() != 0 {
+				// The client already has the timeout response and ServeHTTP
+				// may have returned, so the panic can only be logged.
+				slog.Error("panic after route timeout", "error", panicked, "path", safeURLPath(r), "method", safeMethod(r))
+			}
+			done <- panicked
+		}()
+		// A panic handler that panics itself is re-raised on the serving
+		// goroutine, as it would be without a timeout.
+		defer func() { panicked = recover() }()
+		if len(rtr.middleware) == 0 {
+			rtr.executeHandlerWithRecover(c, w, r)
+		} else {
+			rtr.executePipelineWithRecover(c, w, r)
+		}
+	}()
+
+	select {
+	case p := <-done:
+		if p != nil {
+			panic(p)
+		}
+	case <-tw.expiredC:
+	}
+}
+
+func (rtr *Router) timeoutStatus() int {
+	if rtr.options != nil && rtr.options.TimeoutStatus != 0 {
+		return rtr.options.TimeoutStatus
+	}
+	return http.StatusServiceUnavailable
+}
+
+// timeoutWriter guards a ResponseWriter shared by the handler and the
+// deadline callback. The handler's headers stay private until it commits a
+// response so the timeout response never races a half-built one.
+type timeoutWriter struct {
+	ctx      context.Context
+	w        http.ResponseWriter
+	h        http.Header
+	status   int
+	instance string
+	// expiredC is closed once the timeout problem has been written.
+	expiredC chan struct{}
+	// timeoutStatus is read by routing.TimeoutStatus and set to status once
+	// the timeout problem has been written.
+	timeoutStatus atomic.Int32
+
+	mu              sync.Mutex
+	requestID       string
+	requestIDHeader string
+	committed       bool
+	timedOut        bool
+	done            bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+// RecordRequestID implements routing.RequestIDRecorder so the timeout
+// response carries the ID assigned by request ID middleware, in its problem
+// and in the header the middleware echoes it in.
+func (tw *timeoutWriter) RecordRequestID(header, id string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.requestID = id
+	if header != "" {
+		tw.requestIDHeader = header
+	}
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.committed || tw.expired() {
+		return
+	}
+	tw.commit(status)
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.committed {
+		if tw.expired() {
+			return 0, http.ErrHandlerTimeout
+		}
+		tw.commit(http.StatusOK)
+	}
+	return tw.w.Write(p)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.committed {
+		if tw.expired() {
+			return
+		}
+		tw.commit(http.StatusOK)
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// commit copies the handler's headers and writes status. The caller holds
+// mu.
+func (tw *timeoutWriter) commit(status int) {
+	dst := tw.w.Header()
+	for key, values := range tw.h {
+		dst[key] = values
+	}
+	tw.w.WriteHeader(status)
+	tw.committed = true
+}
+
+// expire answers with the timeout problem when the deadline passes before
+// the handler commits a response or returns.
+func (tw *timeoutWriter) expire() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.done && !tw.committed {
+		tw.expired()
+	}
+}
+
+// expired reports whether the deadline passed without a committed response,
+// writing the timeout problem the first time it does. The caller holds mu.
+func (tw *timeoutWriter) expired() bool {
+	if tw.timedOut {
+		return true
+	}
+	if !errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		return false
+	}
+	tw.timedOut = true
+	if tw.requestIDHeader != "" && tw.requestID != "" {
+		tw.w.Header().Set(tw.requestIDHeader, tw.requestID)
+	}
+	problem := &routing.ProblemDetails{
+		Title:    http.StatusText(tw.status),
+		Detail:   timeoutDetail,
+		Status:   tw.status,
+		Type:     routing.ProblemTypeAboutBlank,
+		Instance: &tw.instance,
+	}
+	if tw.requestID != "" {
+		problem.Extensions = map[string]any{routing.ProblemRequestIDMember: tw.requestID}
+	}
+	b, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(tw.w, http.StatusText(tw.status), tw.status)
+		tw.answered()
+		return true
+	}
+	tw.w.Header().Set(common.HeaderContentType, common.MimeProblemJSON)
+	tw.w.WriteHeader(tw.status)
+	_, _ = tw.w.Write(b)
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	tw.answered()
+	return true
+}
+
+// answered records the timeout status and releases ServeHTTP. The caller
+// holds mu.
+func (tw *timeoutWriter) answered() {
+	tw.timeoutStatus.Store(int32(tw.status))
+	close(tw.expiredC)
+}
+
+// finish stops the deadline callback from writing once the handler returns
+// and reports whether the request timed out. A handler that gave up at the
+// deadline without responding still gets the timeout response; otherwise
+// headers set by a handler that wrote no body are passed on.
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.done = true
+	if tw.committed {
+		return false
+	}
+	if tw.expired() {
+		return true
+	}
+	dst := tw.w.Header()
+	for key, values := range tw.h {
+		dst[key] = values
+	}
+	return false
+}
+
+// documentTimeout describes a route timeout with an x-timeout extension and
+// the timeout response.
+func (rtr *Router) documentTimeout(options *routing.RouteOptions, op *openapi.Operation) {
+	if options == nil || op == nil || options.Timeout <= 0 {
+		return
+	}
+	status := rtr.timeoutStatus()
+	if op.Responses == nil {
+		op.Responses = map[string]*openapi.ResponseObject{}
+	}
+	if _, ok := op.Responses[strconv.Itoa(status)]; !ok {
+		op.Responses[strconv.Itoa(status)] = &openapi.ResponseObject{Description: "Request timed out"}
+	}
+	if op.Extensions == nil {
+		op.Extensions = map[string]any{}
+	}
+	op.Extensions["x-timeout"] = options.Timeout.String()
+}
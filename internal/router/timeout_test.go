@@ -0,0 +1,188 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveTimeoutRequest(rtr *Router, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestShouldAnswerTimeoutProblemGivenHandlerMissesDeadline(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	lateWrite := make(chan error, 1)
+	hasDeadline := make(chan bool, 1)
+	rtr.GET("/report", func(c routing.RouteContext) {
+		_, ok := c.Deadline()
+		hasDeadline <- ok
+		<-c.Done()
+		c.Response().Header().Set("X-Partial", "true")
+		_, err := c.Response().Write([]byte("late"))
+		lateWrite <- err
+	}).WithTimeout(10 * time.Millisecond)
+
+	// Act
+	rec := serveTimeoutRequest(rtr, "/report")
+
+	// Assert
+	assert.True(t, <-hasDeadline)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), timeoutDetail)
+	assert.Empty(t, rec.Header().Get("X-Partial"))
+	assert.ErrorIs(t, <-lateWrite, http.ErrHandlerTimeout)
+}
+
+func TestShouldKeepResponseGivenHandlerCommittedBeforeDeadline(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	rtr.GET("/stream", func(c routing.RouteContext) {
+		c.Response().WriteHeader(http.StatusAccepted)
+		<-c.Done()
+		_, _ = c.Response().Write([]byte("done"))
+	}).WithTimeout(10 * time.Millisecond)
+
+	// Act
+	rec := serveTimeoutRequest(rtr, "/stream")
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "done", rec.Body.String())
+}
+
+func TestShouldPassHeadersGivenFastHandlerWithoutBody(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	rtr.GET("/fast", func(c routing.RouteContext) {
+		c.Response().Header().Set("X-Handled", "yes")
+	}).WithTimeout(time.Second)
+
+	// Act
+	rec := serveTimeoutRequest(rtr, "/fast")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "yes", rec.Header().Get("X-Handled"))
+}
+
+func TestShouldApplyGroupTimeoutGivenNoRouteOverride(t *testing.T) {
+	// Arrange
+	rtr := NewRouter(WithTimeoutStatus(http.StatusGatewayTimeout))
+	api := rtr.NewRouteGroup("/api").WithTimeout(10 * time.Millisecond)
+	slow := func(c routing.RouteContext) {
+		select {
+		case <-c.Done():
+		case <-time.After(50 * time.Millisecond):
+			c.OK("slow")
+		}
+	}
+	api.GET("/lookup", slow)
+	api.GET("/report", slow).WithTimeout(time.Second)
+
+	// Act
+	lookup := serveTimeoutRequest(rtr, "/api/lookup")
+	report := serveTimeoutRequest(rtr, "/api/report")
+
+	// Assert
+	assert.Equal(t, http.StatusGatewayTimeout, lookup.Code)
+	assert.Equal(t, http.StatusOK, report.Code)
+}
+
+func TestShouldAnswerTimeoutGivenMiddlewarePipeline(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	rtr.Use(&testMiddleware{invoke: func(c routing.RouteContext, next HandlerFunc) { next(c) }})
+	rtr.GET("/slow", func(c routing.RouteContext) { <-c.Done() }).WithTimeout(10 * time.Millisecond)
+
+	// Act
+	rec := serveTimeoutRequest(rtr, "/slow")
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestShouldBoundMiddlewareGivenRouteTimeout(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	hasDeadline := make(chan bool, 1)
+	rtr.Use(&testMiddleware{invoke: func(c routing.RouteContext, next HandlerFunc) {
+		_, ok := c.Deadline()
+		hasDeadline <- ok
+		<-c.Done()
+		next(c)
+	}})
+	rtr.GET("/slow", func(c routing.RouteContext) { c.OK("late") }).WithTimeout(10 * time.Millisecond)
+
+	// Act
+	rec := serveTimeoutRequest(rtr, "/slow")
+
+	// Assert
+	assert.True(t, <-hasDeadline)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestShouldAnswerAtDeadlineGivenHandlerIgnoresContext(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	release := make(chan struct{})
+	statuses := make(chan int, 1)
+	rtr.Use(&testMiddleware{invoke: func(c routing.RouteContext, next HandlerFunc) {
+		next(c)
+		statuses <- routing.TimeoutStatus(c)
+	}})
+	rtr.GET("/stuck", func(c routing.RouteContext) { <-release }).WithTimeout(10 * time.Millisecond)
+	time.AfterFunc(time.Second, func() { close(release) })
+
+	// Act
+	start := time.Now()
+	rec := serveTimeoutRequest(rtr, "/stuck")
+	elapsed := time.Since(start)
+
+	// Assert
+	assert.Less(t, elapsed, time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, <-statuses)
+}
+
+func TestShouldDocumentTimeoutGivenRouteTimeout(t *testing.T) {
+	// Arrange
+	rtr := NewRouter(WithTimeoutStatus(http.StatusGatewayTimeout))
+	rtr.GET("/report", func(c routing.RouteContext) {}).WithTimeout(1500 * time.Millisecond)
+	rtr.GET("/lookup", func(c routing.RouteContext) {})
+
+	// Act
+	routes, err := rtr.Routes()
+
+	// Assert
+	require.NoError(t, err)
+	for _, route := range routes {
+		switch route.Path {
+		case "/report":
+			assert.Equal(t, "1.5s", route.Options.Extensions["x-timeout"])
+			assert.Contains(t, route.Options.Responses, "504")
+		case "/lookup":
+			assert.NotContains(t, route.Options.Extensions, "x-timeout")
+		}
+	}
+}
+
+func TestShouldIgnoreTimeoutStatusGivenUnsupportedStatus(t *testing.T) {
+	// Arrange
+	options := &RouterOptions{}
+
+	// Act
+	WithTimeoutStatus(http.StatusTeapot)(options)
+
+	// Assert
+	assert.Zero(t, options.TimeoutStatus)
+}
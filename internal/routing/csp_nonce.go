@@ -0,0 +1,13 @@
+package routing
+
+// CSPNonce returns the Content-Security-Policy nonce generated for the
+// request, or "" if none was.
+func (c *DefaultRouteContext) CSPNonce() string {
+	return c.cspNonce
+}
+
+// SetCSPNonce records the Content-Security-Policy nonce sent with the
+// response so handlers can add it to inline scripts and styles.
+func (c *DefaultRouteContext) SetCSPNonce(nonce string) {
+	c.cspNonce = nonce
+}
@@ -0,0 +1,33 @@
+package routing
+
+import "context"
+
+type forwardedHeadersKey struct{}
+
+// MarkForwardedHeaders records that forwarded headers middleware has applied
+// or, for an untrusted sender, ignored the request's forwarded headers.
+// Later middleware then rely on the request's scheme and TLS state instead
+// of reading the headers themselves.
+func MarkForwardedHeaders(c RouteContext, applied bool) {
+	c.SetContextValue(forwardedHeadersKey{}, applied)
+}
+
+// ForwardedHeadersHandled reports whether MarkForwardedHeaders was called for
+// the request.
+func ForwardedHeadersHandled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	_, handled := ctx.Value(forwardedHeadersKey{}).(bool)
+	return handled
+}
+
+// ForwardedHeadersIgnored reports whether forwarded headers middleware
+// ignored the request's forwarded headers because the sender is untrusted.
+func ForwardedHeadersIgnored(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	applied, handled := ctx.Value(forwardedHeadersKey{}).(bool)
+	return handled && !applied
+}
@@ -0,0 +1,40 @@
+package routing
+
+// Priority orders requests waiting for capacity under load shedding. Higher
+// priorities are admitted first and displace lower priorities from a full
+// queue.
+type Priority int
+
+const (
+	// PriorityLow suits batch and background work that can wait.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the default.
+	PriorityNormal
+	// PriorityHigh suits administrative and control-plane routes.
+	PriorityHigh
+	// PriorityCritical requests are never queued or shed. Health probes use
+	// it so orchestrators see the process as live while it sheds load.
+	PriorityCritical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// InFlightLimit bounds the requests served at once by the routes sharing it.
+// A RouteGroup's limit is shared by every route registered in it.
+type InFlightLimit struct {
+	// Max is the most requests served at once.
+	Max int
+}
@@ -0,0 +1,96 @@
+package routing
+
+import "time"
+
+// RateLimitAlgorithm selects how a RateLimitPolicy counts requests.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket refills Limit tokens per Period into a bucket holding at
+	// most Burst tokens. It is the default.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindowLog records each request time and allows Limit requests
+	// in any Period. State grows with Limit, so it suits small limits.
+	SlidingWindowLog
+	// GCRA is the generic cell rate algorithm: requests are spaced
+	// Period/Limit apart with up to Burst requests allowed at once. It stores
+	// a single timestamp per key.
+	GCRA
+)
+
+func (a RateLimitAlgorithm) String() string {
+	switch a {
+	case TokenBucket:
+		return "token-bucket"
+	case SlidingWindowLog:
+		return "sliding-window-log"
+	case GCRA:
+		return "gcra"
+	default:
+		return "unknown"
+	}
+}
+
+// RateLimitKeyFunc identifies the client a request is counted against. ok
+// is false when the request carries no such identity.
+type RateLimitKeyFunc func(c RouteContext) (key string, ok bool)
+
+// RateLimitPolicy describes one limit enforced by the rate limiting
+// middleware.
+type RateLimitPolicy struct {
+	// Name makes routes with the same name share one budget. Empty gives
+	// each route its own budget. A route policy replaces inherited group
+	// policies with the same name.
+	Name string
+	// Limit is the number of requests allowed per Period.
+	Limit int
+	// Period is the window the Limit applies to.
+	Period time.Duration
+	// Burst is the most requests allowed at once by TokenBucket and GCRA.
+	// Zero means Limit.
+	Burst int
+	// Algorithm selects the counting algorithm.
+	Algorithm RateLimitAlgorithm
+	// Key identifies the client. Nil uses the limiter's default key, which
+	// is the client IP unless configured otherwise.
+	Key RateLimitKeyFunc
+}
+
+// AddRateLimitPolicy appends policy to policies, replacing any policy with
+// the same name.
+func AddRateLimitPolicy(policies []RateLimitPolicy, policy RateLimitPolicy) []RateLimitPolicy {
+	out := make([]RateLimitPolicy, 0, len(policies)+1)
+	for _, existing := range policies {
+		if existing.Name != policy.Name {
+			out = append(out, existing)
+		}
+	}
+	return append(out, policy)
+}
+
+// RateLimitQuota is a named tier of limits enforced together, such as a free
+// plan allowing 100 requests per minute and 10,000 per day. Routes carrying
+// the same quota share its budgets.
+type RateLimitQuota struct {
+	// Name identifies the quota in budgets and response headers.
+	Name string
+	// Windows are the limits enforced together. Each window's Name tells it
+	// apart in response headers and defaults to its period in seconds.
+	Windows []RateLimitPolicy
+	// Match reports whether the quota applies to a request, such as a
+	// request from a client on the plan. Nil matches every request. The
+	// first matching quota on a route applies.
+	Match func(c RouteContext) bool
+}
+
+// AddRateLimitQuota appends quota to quotas, replacing any quota with the
+// same name.
+func AddRateLimitQuota(quotas []RateLimitQuota, quota RateLimitQuota) []RateLimitQuota {
+	out := make([]RateLimitQuota, 0, len(quotas)+1)
+	for _, existing := range quotas {
+		if existing.Name != quota.Name {
+			out = append(out, existing)
+		}
+	}
+	return append(out, quota)
+}
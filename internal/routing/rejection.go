@@ -0,0 +1,37 @@
+package routing
+
+import "context"
+
+// Rejection names why middleware refused a request.
+type Rejection string
+
+const (
+	// RejectionRateLimited is a request refused by a rate limit.
+	RejectionRateLimited Rejection = "rate_limited"
+	// RejectionLoadShed is a request shed by the load shedder.
+	RejectionLoadShed Rejection = "load_shed"
+	// RejectionUnauthenticated is a request without valid credentials.
+	RejectionUnauthenticated Rejection = "unauthenticated"
+	// RejectionForbidden is an authenticated request lacking the required
+	// roles, scopes, or permissions, or failing a CSRF check.
+	RejectionForbidden Rejection = "forbidden"
+)
+
+type rejectionRecorderKey struct{}
+
+// SetRejectionRecorder makes fn receive the rejections middleware reports
+// while serving the request, such as to count them.
+func SetRejectionRecorder(c RouteContext, fn func(reason Rejection)) {
+	c.SetContextValue(rejectionRecorderKey{}, fn)
+}
+
+// ReportRejection reports that middleware refused the request for reason. It
+// does nothing unless a recorder was set for the request.
+func ReportRejection(ctx context.Context, reason Rejection) {
+	if ctx == nil {
+		return
+	}
+	if fn, ok := ctx.Value(rejectionRecorderKey{}).(func(Rejection)); ok {
+		fn(reason)
+	}
+}
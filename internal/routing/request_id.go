@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"context"
+	"maps"
+	"net/http"
+)
+
+// ProblemRequestIDMember is the problem extension member carrying the
+// request ID.
+const ProblemRequestIDMember = "requestId"
+
+type requestIDKey struct{}
+
+// RequestIDRecorder is implemented by response writers that write responses
+// of their own, such as the route timeout writer, so those responses can
+// carry the request ID. header names the response header echoing the ID, or
+// is empty when the ID is not echoed.
+type RequestIDRecorder interface {
+	RecordRequestID(header, id string)
+}
+
+// RecordRequestID passes the request ID and the response header echoing it
+// to the first RequestIDRecorder w wraps, if any.
+func RecordRequestID(w http.ResponseWriter, header, id string) {
+	for w != nil {
+		if recorder, ok := w.(RequestIDRecorder); ok {
+			recorder.RecordRequestID(header, id)
+			return
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" if it
+// carries none.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestID returns the ID assigned to the request, or "" if none was.
+func (c *DefaultRouteContext) RequestID() string {
+	return c.requestID
+}
+
+// SetRequestID assigns the request ID and adds it to the request context so
+// loggers and outgoing calls made with the context can read it.
+func (c *DefaultRouteContext) SetRequestID(id string) {
+	c.requestID = id
+	c.SetContextValue(requestIDKey{}, id)
+	RecordRequestID(c.Response(), "", id)
+}
+
+// withRequestID returns problem with the request ID member added, copying it
+// rather than modifying the caller's value.
+func withRequestID(problem *ProblemDetails, id string) *ProblemDetails {
+	if id == "" {
+		return problem
+	}
+	if _, ok := problem.Extensions[ProblemRequestIDMember]; ok {
+		return problem
+	}
+	copied := *problem
+	copied.Extensions = maps.Clone(problem.Extensions)
+	if copied.Extensions == nil {
+		copied.Extensions = make(map[string]any, 1)
+	}
+	copied.Extensions[ProblemRequestIDMember] = id
+	return &copied
+}
@@ -17,7 +17,9 @@ import (
 	"github.com/fgrzl/mux/internal/binder"
 	"github.com/fgrzl/mux/internal/common"
 	"github.com/fgrzl/mux/internal/cookiekit"
+	"github.com/fgrzl/mux/internal/di"
 	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/fgrzl/mux/internal/tasks"
 	"github.com/google/uuid"
 )
 
@@ -33,15 +35,15 @@ type ServiceKey = common.ServiceKey
 // THREAD SAFETY: RouteContext instances are NOT safe for concurrent use by multiple goroutines.
 // Each RouteContext is bound to a single request lifecycle and may be pooled for reuse.
 // If you need to use the context in a goroutine that may outlive the request handler,
-// use the Detach() function to create a safe background copy:
+// use Go, which runs the work on a safe background copy:
 //
-//	go func(ctx *routing.DefaultRouteContext) {
+//	c.Go(func(ctx RouteContext) {
 //	    // Safe to read request metadata, params, services, and user here.
 //	    // Response writes are discarded on detached contexts.
-//	}(routing.Detach(c))
+//	})
 //
 // WARNING: After the request handler returns, the original RouteContext may be recycled.
-// Accessing it after this point leads to undefined behavior. Always use Detach() for
+// Accessing it after this point leads to undefined behavior. Always use Go or Detach() for
 // background work.
 //
 // Typical usage includes extracting parameters, binding request data, managing authentication, and sending responses.
@@ -61,12 +63,23 @@ type RouteContext interface {
 	SetRequest(*http.Request)
 	// Options returns the RouteOptions in effect for this request.
 	Options() *RouteOptions
+	// MaxBodyBytes returns the request body limit enforced by Bind.
+	MaxBodyBytes() int64
 
 	// Core context methods
 	// User returns the authenticated user principal, or nil if unauthenticated.
 	User() claims.Principal
 	// SetUser sets the authenticated user principal on the context.
 	SetUser(user claims.Principal)
+	// RequestID returns the ID assigned to the request, or "" if none was.
+	RequestID() string
+	// SetRequestID assigns the request ID and adds it to the request context.
+	SetRequestID(id string)
+	// CSPNonce returns the Content-Security-Policy nonce for the request, or
+	// "" if none was generated.
+	CSPNonce() string
+	// SetCSPNonce records the Content-Security-Policy nonce for the request.
+	SetCSPNonce(nonce string)
 	// SetContextValue adds a key-value pair to the request context.
 	// This properly updates both the embedded context and the underlying request
 	// to ensure consistency when accessing values via either c or c.Request().Context().
@@ -129,6 +142,26 @@ type RouteContext interface {
 	// Request binding
 	// Bind aggregates query, form/body, headers, and route params into the target struct.
 	Bind(target any) error
+	// Files reads a multipart upload within the route's limits, writing a
+	// problem response when the upload is rejected.
+	Files() (*Uploads, error)
+	// MultipartReader streams multipart parts within the route's limits.
+	MultipartReader() (*MultipartReader, error)
+	// BindPatch applies a JSON Merge Patch or JSON Patch request body to
+	// current, writing a problem response when the patch is rejected.
+	BindPatch(current any) error
+
+	// Resolve returns the registered service of type t from the request scope.
+	Resolve(t reflect.Type) (any, error)
+	// Go runs fn in the background on a detached copy of the context, bounded
+	// and awaited by the router's task group. It returns an error when the
+	// group rejects the task.
+	Go(fn func(RouteContext)) error
+	// AddParamError records a parameter rejected by a typed accessor.
+	AddParamError(err ParamError)
+	// WriteParamErrors reports whether parameter errors are recorded and
+	// writes the aggregated 400 problem if nothing was written yet.
+	WriteParamErrors() bool
 
 	// Parameter methods
 	// ParamsSlice returns the optimized slice-based parameter storage.
@@ -201,6 +234,16 @@ func AcquireContext(w http.ResponseWriter, r *http.Request) *DefaultRouteContext
 	c.bodyLimitApplied = false
 	c.responseCommitted = false
 	c.responseStatus = 0
+	c.uploads = nil
+	c.uploadErr = nil
+	c.container = nil
+	c.scope = nil
+	c.scopeFailed = false
+	c.scopeWriter = statusWriter{}
+	c.tasks = nil
+	c.paramErrors = nil
+	c.requestID = ""
+	c.cspNonce = ""
 	// Acquire paramsSlice from pool for optimized parameter storage
 	c.paramsSlice = AcquireParams()
 	// Mark as pooled so ReleaseContext knows to return it
@@ -213,6 +256,8 @@ func ReleaseContext(c *DefaultRouteContext) {
 	if c == nil {
 		return
 	}
+	// Dispose request services while the response status is still known.
+	c.CloseScope()
 	// Clear references to avoid leaks between requests
 	c.Context = nil
 	c.response = nil
@@ -231,6 +276,11 @@ func ReleaseContext(c *DefaultRouteContext) {
 	c.bodyLimitApplied = false
 	c.responseCommitted = false
 	c.responseStatus = 0
+	c.tasks = nil
+	c.paramErrors = nil
+	c.requestID = ""
+	c.cspNonce = ""
+	c.ReleaseUploads()
 	// Only return to the pool if this instance was obtained from it.
 	if c.wasPooled {
 		// reset the flag to avoid double-put if ReleaseContext is called
@@ -257,6 +307,9 @@ func Detach(c RouteContext) *DefaultRouteContext {
 	if d.user != nil {
 		baseCtx = claims.WithUser(baseCtx, d.user)
 	}
+	if d.requestID != "" {
+		baseCtx = ContextWithRequestID(baseCtx, d.requestID)
+	}
 
 	var reqClone *http.Request
 	if d.request != nil {
@@ -271,9 +324,11 @@ func Detach(c RouteContext) *DefaultRouteContext {
 		request:           reqClone,
 		clientURL:         d.clientURL,
 		user:              d.user,
+		requestID:         d.requestID,
 		options:           d.options,
 		wasPooled:         false,
 		maxBodyBytes:      d.maxBodyBytes,
+		tasks:             d.tasks,
 		responseCommitted: false,
 		responseStatus:    0,
 	}
@@ -313,13 +368,33 @@ type DefaultRouteContext struct {
 	wasPooled bool
 	// runtime cache for quick parameter lookups (key: strings.ToLower(in+":"+name))
 	paramIndex map[string]*openapi.ParameterObject
-	// maxBodyBytes limits body size for bind operations. 0 means default (1MB).
+	// maxBodyBytes limits body size for bind operations. 0 means
+	// DefaultMaxBodyBytes.
 	maxBodyBytes int64
 	// bodyLimitApplied tracks whether MaxBytesReader has been applied to prevent double-wrapping.
 	bodyLimitApplied bool
 	// responseCommitted tracks whether a framework response helper has already committed headers.
 	responseCommitted bool
 	responseStatus    int
+	// uploads caches the multipart upload read by Files or Bind.
+	uploads   *Uploads
+	uploadErr error
+	// container and scope back Resolve; the scope is created lazily and
+	// disposed by CloseScope.
+	container   *di.Container
+	scope       *di.Scope
+	scopeFailed bool
+	// scopeWriter records the status of responses written without the
+	// response helpers, so CloseScope rolls back on them too.
+	scopeWriter statusWriter
+	// tasks runs work started with Go; nil uses a shared fallback group.
+	tasks *tasks.Group
+	// paramErrors collects parameters rejected by typed accessors.
+	paramErrors []ParamError
+	// requestID identifies the request in logs and problems.
+	requestID string
+	// cspNonce is the Content-Security-Policy nonce sent with the response.
+	cspNonce string
 }
 
 type detachedResponseWriter struct{ header http.Header }
@@ -378,10 +453,22 @@ func (c *DefaultRouteContext) SetClientURL(u *url.URL) {
 	c.clientURL = u
 }
 
+// DefaultMaxBodyBytes is the request body limit used when none is configured.
+const DefaultMaxBodyBytes int64 = 1 << 20
+
 // SetMaxBodyBytes sets the maximum allowed request body size for this context.
 // A value <= 0 causes a default of 1MB to be applied during binding.
 func (c *DefaultRouteContext) SetMaxBodyBytes(n int64) { c.maxBodyBytes = n }
 
+// MaxBodyBytes returns the request body limit applied during binding,
+// including the 1MB default.
+func (c *DefaultRouteContext) MaxBodyBytes() int64 {
+	if c.maxBodyBytes <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return c.maxBodyBytes
+}
+
 // ParamsSlice returns the optimized slice-based parameter storage.
 func (c *DefaultRouteContext) ParamsSlice() *Params {
 	return c.paramsSlice
@@ -446,9 +533,33 @@ func (c *DefaultRouteContext) GetService(key ServiceKey) (any, bool) {
 // such as GET, HEAD, and DELETE do not bind request bodies.
 //
 // If a request declares RequestBody.Required=true but sends an empty body, Bind returns ErrMissingBody.
+//
+// Structs whose fields declare explicit sources (`path:"id"`, `query:"page"`,
+// `header:"X-Tenant"`, `cookie:"sid"`, `body:""`) bind only those fields from
+// the named sources instead; see bindSources.
+//
+// Other struct targets are written field by field through a binding plan
+// compiled once per type (see binder.PlanFor), with the same precedence and
+// name matching as decoding the collected values with encoding/json.
+//
 // Bind does not write an error response itself; callers or higher-level middleware
 // are responsible for translating binding failures into HTTP responses.
 func (c *DefaultRouteContext) Bind(model any) error {
+	if rv := reflect.ValueOf(model); rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct {
+		if fields := binder.SourceFields(rv.Elem().Type()); len(fields) > 0 {
+			return c.bindSources(rv.Elem(), fields)
+		}
+		if handled, err := c.bindPlan(rv.Elem(), binder.PlanFor(rv.Elem().Type())); handled {
+			return err
+		}
+	}
+	return c.bindStaging(model)
+}
+
+// bindStaging collects request values into a staging map and decodes it into
+// model with encoding/json. It backs map, interface, and slice targets and
+// bodies whose JSON root is not an object.
+func (c *DefaultRouteContext) bindStaging(model any) error {
 	staging := make(map[string]any)
 
 	if err := c.collectRequestData(staging); err != nil {
@@ -605,38 +716,10 @@ func (c *DefaultRouteContext) collectQueryParams(staging map[string]any) error {
 }
 
 func (c *DefaultRouteContext) collectBodyData(staging map[string]any) error {
-	// Apply MaxBytesReader only once to prevent double-wrapping which can cause
-	// the effective limit to be applied multiple times incorrectly.
-	if !c.bodyLimitApplied {
-		maxBytes := c.maxBodyBytes
-		if maxBytes <= 0 {
-			maxBytes = 1 << 20 // 1MB default
-		}
-		c.request.Body = http.MaxBytesReader(c.Response(), c.request.Body, maxBytes)
-		c.bodyLimitApplied = true
-	}
-
-	// If the route explicitly requires a request body according to its
-	// OpenAPI RequestBody.Required flag, perform a light-weight presence
-	// check before attempting to parse the body. This gives a stable,
-	// descriptive error for completely empty bodies instead of low-level
-	// decoder errors like io.EOF. The presence check applies across
-	// content types (JSON and form) and only runs when RequestBody.Required
-	// is true.
-	if c.options != nil && c.options.RequestBody != nil && c.options.RequestBody.Required {
-		// Wrap the current Body in a buffered reader and attempt to Peek(1).
-		// If Peek returns io.EOF, the body is empty.
-		br := bufio.NewReader(c.request.Body)
-		if _, err := br.Peek(1); err != nil {
-			if err == io.EOF {
-				return ErrMissingBody
-			}
-			// Propagate other read errors
-			return err
-		}
-		// Put a nondestructive wrapper back so downstream readers (json.Decoder,
-		// ParseForm) can consume the body normally.
-		c.request.Body = io.NopCloser(br)
+	c.applyBodyLimit()
+
+	if err := c.checkRequiredBody(); err != nil {
+		return err
 	}
 
 	ct := c.request.Header.Get(common.HeaderContentType)
@@ -650,6 +733,42 @@ func (c *DefaultRouteContext) collectBodyData(staging map[string]any) error {
 	}
 }
 
+// checkRequiredBody performs a light-weight presence check when the route
+// explicitly requires a request body according to its OpenAPI
+// RequestBody.Required flag. This gives a stable, descriptive error for
+// completely empty bodies instead of low-level decoder errors like io.EOF.
+// The presence check applies across content types (JSON and form).
+func (c *DefaultRouteContext) checkRequiredBody() error {
+	if c.options == nil || c.options.RequestBody == nil || !c.options.RequestBody.Required {
+		return nil
+	}
+	// Wrap the current Body in a buffered reader and attempt to Peek(1).
+	// If Peek returns io.EOF, the body is empty.
+	br := bufio.NewReader(c.request.Body)
+	if _, err := br.Peek(1); err != nil {
+		if err == io.EOF {
+			return ErrMissingBody
+		}
+		// Propagate other read errors
+		return err
+	}
+	// Put a nondestructive wrapper back so downstream readers (json.Decoder,
+	// ParseForm) can consume the body normally.
+	c.request.Body = io.NopCloser(br)
+	return nil
+}
+
+// applyBodyLimit wraps the request body in a MaxBytesReader only once to
+// prevent double-wrapping which can cause the effective limit to be applied
+// multiple times incorrectly.
+func (c *DefaultRouteContext) applyBodyLimit() {
+	if c.bodyLimitApplied {
+		return
+	}
+	c.request.Body = http.MaxBytesReader(c.Response(), c.request.Body, c.MaxBodyBytes())
+	c.bodyLimitApplied = true
+}
+
 func (c *DefaultRouteContext) collectFormData(staging map[string]any) error {
 	ct := c.request.Header.Get(common.HeaderContentType)
 	if strings.HasPrefix(ct, common.MimeMultipartFormData) {
@@ -0,0 +1,372 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/binder"
+	"github.com/fgrzl/mux/internal/common"
+)
+
+// bindEntry is one request value destined for the bind target. Entries stand
+// in for the keys of the former staging map: deep entries carry a deepObject
+// path below key, and body entries remember that they came from a JSON body
+// so object members can merge with deep query values.
+type bindEntry struct {
+	key   string
+	path  []string
+	value any
+	body  bool
+}
+
+// bindEntries collects request values with the same precedence the staging
+// map applied: later sources replace earlier ones for the same key, deep
+// query values under one root accumulate, and JSON body objects merge into
+// them. Entries are grouped by key so a replacement costs no scan; a key
+// holds either one plain entry or only deep entries.
+type bindEntries struct {
+	byKey map[string][]bindEntry
+	list  []bindEntry
+}
+
+func (s *bindEntries) set(key string, value any) {
+	s.put(key, []bindEntry{{key: key, value: value}})
+}
+
+func (s *bindEntries) setDeep(key string, path []string, value any) {
+	current := s.byKey[key]
+	if len(current) == 1 && current[0].path == nil {
+		current = nil
+	}
+	s.put(key, append(current, bindEntry{key: key, path: path, value: value}))
+}
+
+func (s *bindEntries) put(key string, entries []bindEntry) {
+	if s.byKey == nil {
+		s.byKey = make(map[string][]bindEntry, 16)
+	}
+	s.byKey[key] = entries
+}
+
+// setBody adds a JSON body member. An object member merges with deep query
+// values already collected for key, mirroring mergeBindingValue.
+func (s *bindEntries) setBody(key string, raw binder.RawJSON) error {
+	var deep map[string]any
+	if isJSONObject(raw) {
+		for _, e := range s.byKey[key] {
+			if e.path != nil {
+				if deep == nil {
+					deep = map[string]any{}
+				}
+				setNestedMap(deep, key, e.path, e.value)
+			}
+		}
+	}
+	if deep == nil {
+		s.set(key, raw)
+		s.byKey[key][0].body = true
+		return nil
+	}
+	var incoming any
+	if err := json.Unmarshal(raw, &incoming); err != nil {
+		return err
+	}
+	s.put(key, []bindEntry{{key: key, value: mergeBindingValue(deep[key], incoming), body: true}})
+	return nil
+}
+
+// sort flattens the entries into list, ordered the way json.Marshal ordered
+// the staging map keys so values matching the same field are applied in the
+// same order.
+func (s *bindEntries) sort() {
+	keys := make([]string, 0, len(s.byKey))
+	n := 0
+	for key, entries := range s.byKey {
+		keys = append(keys, key)
+		n += len(entries)
+	}
+	sort.Strings(keys)
+	s.list = make([]bindEntry, 0, n)
+	for _, key := range keys {
+		entries := s.byKey[key]
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := entries[i].path, entries[j].path
+			for k := 0; k < len(a) && k < len(b); k++ {
+				if a[k] != b[k] {
+					return a[k] < b[k]
+				}
+			}
+			return len(a) < len(b)
+		})
+		s.list = append(s.list, entries...)
+	}
+}
+
+func isJSONObject(raw []byte) bool {
+	for _, b := range raw {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return b == '{'
+		}
+	}
+	return false
+}
+
+// bindPlan binds request values straight into target using the compiled plan
+// for its type. It reports false without consuming the request when the body
+// is not a JSON object, leaving those shapes to the staging path.
+func (c *DefaultRouteContext) bindPlan(target reflect.Value, plan *binder.Plan) (bool, error) {
+	entries := &bindEntries{}
+
+	if err := c.collectQueryEntries(entries); err != nil {
+		return true, err
+	}
+	var uploads *Uploads
+	if methodAllowsBodyBinding(c.request.Method) {
+		var ok bool
+		var err error
+		uploads, ok, err = c.collectBodyEntries(entries, plan.Accepts(fileHeaderType))
+		if !ok || err != nil {
+			return ok, err
+		}
+	}
+	if err := c.collectHeaderEntries(entries); err != nil {
+		return true, err
+	}
+	if err := c.collectParamEntries(entries); err != nil {
+		return true, err
+	}
+
+	entries.sort()
+	var saved error
+	list := entries.list
+	for i := 0; i < len(list); {
+		e := list[i]
+		if e.path == nil {
+			if binder.KeepFirstError(&saved, plan.Apply(target, e.key, e.value)) {
+				return true, saved
+			}
+			i++
+			continue
+		}
+		j := i
+		var leaves []binder.DeepValue
+		for j < len(list) && list[j].key == e.key && list[j].path != nil {
+			leaves = append(leaves, binder.DeepValue{Path: list[j].path, Value: list[j].value})
+			j++
+		}
+		if binder.KeepFirstError(&saved, plan.ApplyDeep(target, e.key, leaves)) {
+			return true, saved
+		}
+		i = j
+	}
+	if uploads != nil {
+		assigned := map[string]bool{}
+		for _, file := range uploads.Files {
+			if !assigned[file.Field] {
+				assigned[file.Field] = true
+				plan.Assign(target, file.Field, fileValues(uploads, file.Field))
+			}
+		}
+	}
+	return true, saved
+}
+
+var fileHeaderType = reflect.TypeOf(FileHeader{})
+
+// fileValues returns the files uploaded under field as reflect values for
+// binder.Plan.Assign.
+func fileValues(uploads *Uploads, field string) []reflect.Value {
+	files := uploads.All(field)
+	values := make([]reflect.Value, len(files))
+	for i, f := range files {
+		values[i] = reflect.ValueOf(f)
+	}
+	return values
+}
+
+func (c *DefaultRouteContext) collectQueryEntries(entries *bindEntries) error {
+	for rawKey, values := range c.request.URL.Query() {
+		// deep-object handling: dot-notation or bracket-notation
+		if root, path := parseDeepKey(rawKey); len(path) > 0 {
+			if param := c.lookupParameter(root, "query"); param != nil && isDeepObjectParameter(param) {
+				parsed, err := parseDeepQueryValue(param, path, values)
+				if err != nil {
+					return fmt.Errorf("query param %q: %w", rawKey, err)
+				}
+				entries.setDeep(root, path, parsed)
+				continue
+			}
+		}
+
+		if param := c.lookupParameter(rawKey, "query"); param != nil {
+			typed, handled, err := binder.ProcessParam(rawKey, values, "query", param)
+			if err != nil {
+				return err
+			}
+			if handled {
+				entries.set(rawKey, typed)
+				continue
+			}
+		}
+		entries.set(rawKey, singleOrSlice(values))
+	}
+	return nil
+}
+
+// collectBodyEntries mirrors collectBodyData. JSON objects are streamed member
+// by member into raw values so no intermediate document is built. Multipart
+// bodies go through the upload reader when the route configures upload
+// limits or the target has file fields; the uploads are returned so their
+// files can be assigned.
+func (c *DefaultRouteContext) collectBodyEntries(entries *bindEntries, fileFields bool) (*Uploads, bool, error) {
+	ct := c.request.Header.Get(common.HeaderContentType)
+	if strings.HasPrefix(ct, common.MimeMultipartFormData) && c.usesUploads(fileFields) {
+		if err := c.checkRequiredBody(); err != nil {
+			return nil, true, err
+		}
+		uploads, err := c.readUploads()
+		if err != nil {
+			return nil, true, err
+		}
+		for key, values := range uploads.Values {
+			entries.set(key, singleOrSlice(values))
+		}
+		return uploads, true, nil
+	}
+
+	c.applyBodyLimit()
+	if err := c.checkRequiredBody(); err != nil {
+		return nil, true, err
+	}
+	switch {
+	case strings.HasPrefix(ct, common.MimeFormURLEncoded), strings.HasPrefix(ct, common.MimeMultipartFormData):
+		return nil, true, c.collectFormEntries(entries, ct)
+	case strings.HasPrefix(ct, common.MimeJSON):
+		ok, err := c.collectJSONEntries(entries)
+		return nil, ok, err
+	default:
+		return nil, true, errors.New("unsupported content type")
+	}
+}
+
+func (c *DefaultRouteContext) collectFormEntries(entries *bindEntries, ct string) error {
+	var form map[string][]string
+	if strings.HasPrefix(ct, common.MimeMultipartFormData) {
+		if err := c.request.ParseMultipartForm(32 << 20); err != nil {
+			return err
+		}
+		if c.request.MultipartForm == nil {
+			return nil
+		}
+		form = c.request.MultipartForm.Value
+	} else {
+		if err := c.request.ParseForm(); err != nil {
+			return err
+		}
+		form = c.request.PostForm
+	}
+	for key, values := range form {
+		entries.set(key, singleOrSlice(values))
+	}
+	return nil
+}
+
+func (c *DefaultRouteContext) collectJSONEntries(entries *bindEntries) (bool, error) {
+	decoder := json.NewDecoder(c.request.Body)
+	// Numbers stay json.Number so a scalar root is replayed without losing
+	// precision; members are decoded as raw bytes and are unaffected.
+	decoder.UseNumber()
+	tok, err := decoder.Token()
+	if err == io.EOF {
+		// Empty bodies bind nothing, as with the staging path.
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	if tok != json.Delim('{') {
+		// Arrays and scalar roots keep their dedicated handling; replay the
+		// consumed token ahead of the unread body.
+		prefix := []byte("[")
+		if tok != json.Delim('[') {
+			if prefix, err = json.Marshal(tok); err != nil {
+				return true, err
+			}
+		}
+		c.request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(prefix), decoder.Buffered(), c.request.Body))
+		return false, nil
+	}
+
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return true, err
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return true, err
+		}
+		if err := entries.setBody(key, binder.RawJSON(raw)); err != nil {
+			return true, err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func (c *DefaultRouteContext) collectHeaderEntries(entries *bindEntries) error {
+	for key, headerValues := range c.request.Header {
+		if param := c.lookupParameter(key, "header"); param != nil {
+			typed, handled, err := binder.ProcessParam(key, headerValues, "header", param)
+			if err != nil {
+				return err
+			}
+			if handled {
+				entries.set(key, typed)
+				continue
+			}
+		}
+		entries.set(key, singleOrSlice(headerValues))
+	}
+	return nil
+}
+
+func (c *DefaultRouteContext) collectParamEntries(entries *bindEntries) error {
+	if c.paramsSlice == nil {
+		return nil
+	}
+	for i := 0; i < c.paramsSlice.Len(); i++ {
+		p := (*c.paramsSlice)[i]
+		if param := c.lookupParameter(p.Key, "path"); param != nil {
+			typed, handled, err := binder.ProcessParam(p.Key, []string{p.Value}, "path", param)
+			if err != nil {
+				return err
+			}
+			if handled {
+				entries.set(p.Key, typed)
+				continue
+			}
+		}
+		entries.set(p.Key, p.Value)
+	}
+	return nil
+}
+
+func singleOrSlice(values []string) any {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
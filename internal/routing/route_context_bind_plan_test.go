@@ -0,0 +1,219 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux/internal/binder"
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type planInner struct {
+	Status string  `json:"status"`
+	Limit  int     `json:"limit"`
+	Score  float32 `json:"score"`
+}
+
+type planEmbedded struct {
+	Region string `json:"region"`
+	Shadow string `json:"name"`
+}
+
+type planRequest struct {
+	planEmbedded
+	*planPointerEmbed
+	ID       int               `json:"id"`
+	Name     string            `json:"name"`
+	Count    *uint8            `json:"count"`
+	Enabled  bool              `json:"enabled"`
+	Ratio    float64           `json:"ratio"`
+	Tags     []string          `json:"tags"`
+	Numbers  []int             `json:"numbers"`
+	When     time.Time         `json:"when"`
+	Filter   planInner         `json:"filter"`
+	Nested   *planInner        `json:"nested"`
+	Meta     map[string]string `json:"meta"`
+	Any      any               `json:"any"`
+	Quoted   int               `json:"quoted,string"`
+	Raw      []byte            `json:"raw"`
+	Tenant   string            `json:"X-Tenant"`
+	Ignored  string            `json:"-"`
+	CaseOnly string
+}
+
+type planPointerEmbed struct {
+	Zone string `json:"zone"`
+}
+
+type planCase struct {
+	name    string
+	method  string
+	target  string
+	body    string
+	ct      string
+	headers map[string]string
+	params  map[string]string
+	declare []*openapi.ParameterObject
+}
+
+func newPlanCaseContext(t testing.TB, tc planCase) *DefaultRouteContext {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), tc.method, tc.target, strings.NewReader(tc.body))
+	if tc.ct != "" {
+		req.Header.Set(common.HeaderContentType, tc.ct)
+	}
+	for k, v := range tc.headers {
+		req.Header.Set(k, v)
+	}
+	c := NewRouteContext(httptest.NewRecorder(), req)
+	c.paramsSlice = &Params{}
+	for k, v := range tc.params {
+		c.paramsSlice.Set(k, v)
+	}
+	if len(tc.declare) > 0 {
+		opts := &RouteOptions{}
+		opts.Parameters = tc.declare
+		opts.ParamIndex = BuildParamIndex(tc.declare)
+		c.SetOptions(opts)
+	}
+	return c
+}
+
+func planCases() []planCase {
+	intParam := func(name, in string) *openapi.ParameterObject {
+		return &openapi.ParameterObject{Name: name, In: in, Example: 0, Converter: binder.MakeConverter(reflect.TypeOf(0), nil)}
+	}
+	filterParam := &openapi.ParameterObject{Name: "filter", In: "query", Example: planInner{}, Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"status": {Type: "string"},
+		"limit":  {Type: "integer"},
+		"score":  {Type: "number"},
+	}}}
+	metaParam := &openapi.ParameterObject{Name: "meta", In: "query", Schema: &openapi.Schema{Type: "object"}}
+	return []planCase{
+		{name: "query scalars", method: http.MethodGet, target: "/?name=gear&enabled=true&ratio=0.5&CaseOnly=x&ignored=y"},
+		{name: "query typed params", method: http.MethodGet, target: "/?id=7&numbers=1,2,3", declare: []*openapi.ParameterObject{
+			intParam("id", "query"),
+			{Name: "numbers", In: "query", Example: []int{}, Converter: binder.MakeConverter(reflect.TypeOf([]int{}), nil)},
+		}},
+		{name: "query repeated values", method: http.MethodGet, target: "/?tags=a&tags=b"},
+		{name: "query string into int", method: http.MethodGet, target: "/?id=7&name=ok"},
+		{name: "query quoted and time", method: http.MethodGet, target: "/?quoted=42&when=2024-01-02T03:04:05Z"},
+		{name: "deep object", method: http.MethodGet, target: "/?filter.status=open&filter[limit]=5&filter.score=1.5&nested.status=x", declare: []*openapi.ParameterObject{filterParam, {Name: "nested", In: "query", Schema: &openapi.Schema{Type: "object"}}}},
+		{name: "deep map", method: http.MethodGet, target: "/?meta.a=1&meta[b]=2", declare: []*openapi.ParameterObject{metaParam}},
+		{name: "deep merged with body", method: http.MethodPost, target: "/?filter.status=open&filter.limit=3", ct: common.MimeJSON, body: `{"filter":{"limit":9}}`, declare: []*openapi.ParameterObject{filterParam}},
+		{name: "json body", method: http.MethodPost, target: "/?name=query", ct: common.MimeJSON, body: `{"name":"body","id":1.0,"count":3,"tags":["x","y"],"any":{"k":[1,2]},"raw":"aGk=","nested":{"status":"s"},"region":"eu","zone":"z1","when":"2024-01-02T03:04:05Z","meta":{"a":"b"}}`},
+		{name: "json body null and case", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: ` {"NAME":"upper","name":"lower","nested":null,"Count":null}`},
+		{name: "json type mismatches", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `{"id":"seven","enabled":1,"count":300,"numbers":[1,"x",3],"name":true}`},
+		{name: "json fractional into int", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `{"id":1.5}`},
+		{name: "json syntax error", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `{"id":`},
+		{name: "json array root", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `[1,2]`},
+		{name: "json scalar root", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `"x"`},
+		{name: "json number root", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `12345678901234567891`},
+		{name: "json quoted body", method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `{"quoted":"42"}`},
+		{name: "empty json body", method: http.MethodPost, target: "/?name=q", ct: common.MimeJSON},
+		{name: "form body", method: http.MethodPost, target: "/?name=query", ct: common.MimeFormURLEncoded, body: "name=form&tags=a&tags=b&id=3"},
+		{name: "unsupported content type", method: http.MethodPost, target: "/", ct: "text/plain", body: "x"},
+		{name: "headers and path", method: http.MethodGet, target: "/?id=1", headers: map[string]string{"X-Tenant": "acme", "Id": "2"}, params: map[string]string{"id": "3", "name": "path"}, declare: []*openapi.ParameterObject{intParam("id", "path")}},
+		{name: "path conversion error", method: http.MethodGet, target: "/", params: map[string]string{"id": "x"}, declare: []*openapi.ParameterObject{intParam("id", "path")}},
+	}
+}
+
+func TestShouldMatchStagingBindGivenPlanBinding(t *testing.T) {
+	for _, tc := range planCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			planCtx := newPlanCaseContext(t, tc)
+			stagingCtx := newPlanCaseContext(t, tc)
+
+			// Act
+			var viaPlan, viaStaging planRequest
+			planErr := planCtx.Bind(&viaPlan)
+			stagingErr := stagingCtx.bindStaging(&viaStaging)
+
+			// Assert
+			if stagingErr != nil {
+				require.Error(t, planErr)
+				assert.Equal(t, stagingErr.Error(), planErr.Error())
+			} else {
+				require.NoError(t, planErr)
+			}
+			assert.Equal(t, viaStaging, viaPlan)
+		})
+	}
+}
+
+func TestShouldReportFirstTypeErrorGivenSeveralMismatches(t *testing.T) {
+	// Arrange
+	c := newPlanCaseContext(t, planCase{method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `{"name":"ok","id":"seven","enabled":"yes"}`})
+
+	// Act
+	var out planRequest
+	err := c.Bind(&out)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "planRequest.enabled")
+	assert.Equal(t, "ok", out.Name, "later fields are still bound")
+}
+
+func TestShouldBindStringOptionFieldGivenJSONBody(t *testing.T) {
+	// Arrange
+	c := newPlanCaseContext(t, planCase{method: http.MethodPost, target: "/", ct: common.MimeJSON, body: `{"quoted":"42","name":"ok"}`})
+
+	// Act
+	var out planRequest
+	err := c.Bind(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 42, out.Quoted)
+	assert.Equal(t, "ok", out.Name)
+}
+
+type planBenchRequest struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Enabled bool      `json:"enabled"`
+	Ratio   float64   `json:"ratio"`
+	Tags    []string  `json:"tags"`
+	Filter  planInner `json:"filter"`
+	Zone    string    `json:"zone"`
+}
+
+func benchmarkBind(b *testing.B, bind func(c *DefaultRouteContext, out *planBenchRequest) error) {
+	filterParam := &openapi.ParameterObject{Name: "filter", In: "query", Example: planInner{}}
+	tc := planCase{
+		method:  http.MethodPost,
+		target:  "/?filter.status=open&filter.limit=5&tags=a&tags=b",
+		ct:      common.MimeJSON,
+		body:    `{"name":"gear","id":7,"enabled":true,"ratio":0.25}`,
+		params:  map[string]string{"zone": "z"},
+		declare: []*openapi.ParameterObject{filterParam},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c := newPlanCaseContext(b, tc)
+		b.StartTimer()
+		var out planBenchRequest
+		if err := bind(c, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBindPlan(b *testing.B) {
+	benchmarkBind(b, func(c *DefaultRouteContext, out *planBenchRequest) error { return c.Bind(out) })
+}
+
+func BenchmarkBindStaging(b *testing.B) {
+	benchmarkBind(b, func(c *DefaultRouteContext, out *planBenchRequest) error { return c.bindStaging(out) })
+}
@@ -0,0 +1,114 @@
+package routing
+
+import (
+	"net/http"
+	"slices"
+)
+
+// ParamError describes one request parameter rejected by a typed accessor.
+type ParamError struct {
+	In     string `json:"in"`
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+// AddParamError records a rejected parameter. While any are recorded, any
+// non-error response, whether written by framework helpers or directly to
+// the ResponseWriter, is replaced by the aggregated validation problem.
+func (c *DefaultRouteContext) AddParamError(err ParamError) {
+	if slices.Contains(c.paramErrors, err) {
+		return
+	}
+	if len(c.paramErrors) == 0 && c.response != nil {
+		if _, guarded := c.response.(*paramErrorWriter); !guarded {
+			// Assigned directly: SetResponse would forget a committed response.
+			c.response = &paramErrorWriter{ResponseWriter: c.response, c: c}
+		}
+	}
+	c.paramErrors = append(c.paramErrors, err)
+}
+
+// ParamErrors returns the parameters rejected so far.
+func (c *DefaultRouteContext) ParamErrors() []ParamError {
+	return c.paramErrors
+}
+
+// WriteParamErrors reports whether any parameter errors are recorded and, if
+// no response has been committed yet, writes a 400 problem listing them in
+// its "errors" member.
+func (c *DefaultRouteContext) WriteParamErrors() bool {
+	if len(c.paramErrors) == 0 {
+		return false
+	}
+	if c.responseCommitted {
+		return true
+	}
+	errs := c.paramErrors
+	// Clear first so Problem is not intercepted by the pending-errors guard.
+	c.paramErrors = nil
+	c.Problem(&ProblemDetails{
+		Title:      http.StatusText(http.StatusBadRequest),
+		Detail:     "One or more request parameters are missing or invalid.",
+		Status:     http.StatusBadRequest,
+		Type:       ProblemTypeAboutBlank,
+		Instance:   getInstanceURI(c.Request()),
+		Extensions: map[string]any{"errors": errs},
+	})
+	c.paramErrors = errs
+	return true
+}
+
+// paramErrorWriter answers the first non-error response written directly to
+// the ResponseWriter with the parameter errors problem and discards the
+// handler's body, so a handler that forgets AbortOnParamErrors still rejects
+// the request.
+type paramErrorWriter struct {
+	http.ResponseWriter
+	c           *DefaultRouteContext
+	wroteHeader bool
+	rejected    bool
+}
+
+func (w *paramErrorWriter) WriteHeader(status int) {
+	if w.rejected || w.reject(status) {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *paramErrorWriter) Write(p []byte) (int, error) {
+	if w.rejected || w.reject(http.StatusOK) {
+		return len(p), nil
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *paramErrorWriter) Flush() {
+	if w.rejected || w.reject(http.StatusOK) {
+		return
+	}
+	w.wroteHeader = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (w *paramErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// reject writes the parameter errors problem in place of the first response
+// when it is not an error and reports whether it did.
+func (w *paramErrorWriter) reject(status int) bool {
+	if w.wroteHeader || status >= http.StatusBadRequest || w.c.responseCommitted || len(w.c.paramErrors) == 0 {
+		return false
+	}
+	// The problem is written through this writer while the errors are
+	// cleared, so it passes straight through.
+	w.c.WriteParamErrors()
+	w.rejected = true
+	return true
+}
@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReplaceSuccessResponseGivenRecordedParamErrors(t *testing.T) {
+	// Arrange
+	rr := httptest.NewRecorder()
+	c := NewRouteContext(rr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/items?limit=x", nil))
+	c.AddParamError(ParamError{In: "query", Name: "limit", Detail: "must be an integer"})
+	c.AddParamError(ParamError{In: "query", Name: "limit", Detail: "must be an integer"})
+	c.AddParamError(ParamError{In: "header", Name: "X-Tenant", Detail: "is required"})
+
+	// Act
+	c.OK(map[string]string{"status": "ok"})
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, common.MimeProblemJSON, rr.Header().Get(common.HeaderContentType))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "One or more request parameters are missing or invalid.",
+		"instance": "/items?limit=x",
+		"errors": [
+			{"in": "query", "name": "limit", "detail": "must be an integer"},
+			{"in": "header", "name": "X-Tenant", "detail": "is required"}
+		]
+	}`, rr.Body.String())
+	assert.True(t, c.WriteParamErrors(), "errors stay recorded after the problem is written")
+}
+
+func TestShouldKeepErrorResponseGivenRecordedParamErrors(t *testing.T) {
+	// Arrange
+	rr := httptest.NewRecorder()
+	c := NewRouteContext(rr, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil))
+	c.AddParamError(ParamError{In: "query", Name: "q", Detail: "is required"})
+
+	// Act
+	c.Conflict("Conflict", "already exists")
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestShouldSerializeExtensionMembersGivenProblemDetails(t *testing.T) {
+	// Arrange
+	problem := ProblemDetails{Type: ProblemTypeAboutBlank, Title: "Bad Request", Status: 400, Extensions: map[string]any{"traceId": "abc", "status": 999}}
+
+	// Act
+	b, err := json.Marshal(problem)
+
+	// Assert
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"","traceId":"abc"}`, string(b))
+}
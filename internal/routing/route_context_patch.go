@@ -0,0 +1,196 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/jsonpatch"
+)
+
+var (
+	// ErrUnsupportedPatchType is returned by BindPatch when the request is
+	// neither a JSON Merge Patch nor a JSON Patch document.
+	ErrUnsupportedPatchType = errors.New("unsupported patch media type")
+	// ErrInvalidPatch is returned by BindPatch for malformed patch documents.
+	ErrInvalidPatch = jsonpatch.ErrInvalidPatch
+	// ErrPatchPathNotFound is returned by BindPatch when a JSON Patch
+	// operation refers to a location that does not exist.
+	ErrPatchPathNotFound = jsonpatch.ErrPathNotFound
+	// ErrPatchTestFailed is returned by BindPatch when a JSON Patch "test"
+	// operation does not match.
+	ErrPatchTestFailed = jsonpatch.ErrTestFailed
+)
+
+// PatchResultError reports a patched document that no longer decodes into
+// the target type.
+type PatchResultError struct {
+	Err error
+}
+
+func (e *PatchResultError) Error() string {
+	return fmt.Sprintf("patched document is not valid for the target: %v", e.Err)
+}
+
+func (e *PatchResultError) Unwrap() error { return e.Err }
+
+// BindPatch applies the request body to current, which must be a non-nil
+// pointer. An application/merge-patch+json (or plain application/json) body
+// is applied as an RFC 7396 merge patch and an application/json-patch+json
+// body as an RFC 6902 operation list. current is only modified when the
+// whole patch applies; fields JSON does not encode, unexported or tagged
+// "-", are left untouched. On failure BindPatch writes a problem response and
+// returns the error: 415 for other media types, 400 for malformed patches,
+// 409 for a failed "test" operation, and 422 for operations on missing
+// locations or results that no longer fit the target type. When the failure
+// has a location, the problem's "errors" member lists it with its JSON
+// pointer, the operation, and the reason.
+func (c *DefaultRouteContext) BindPatch(current any) error {
+	err := c.bindPatch(current)
+	if err != nil {
+		c.patchProblem(err)
+	}
+	return err
+}
+
+func (c *DefaultRouteContext) bindPatch(current any) error {
+	target := reflect.ValueOf(current)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return errors.New("BindPatch target must be a non-nil pointer")
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.request.Header.Get(common.HeaderContentType))
+	if mediaType != common.MimeMergePatchJSON && mediaType != common.MimeJSONPatchJSON && mediaType != common.MimeJSON {
+		return fmt.Errorf("%w: %q", ErrUnsupportedPatchType, mediaType)
+	}
+	if c.request.Body == nil || c.request.Body == http.NoBody {
+		return ErrMissingBody
+	}
+	c.applyBodyLimit()
+	body, err := io.ReadAll(c.request.Body)
+	if err != nil {
+		return err
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	doc, err := jsonpatch.Decode(original)
+	if err != nil {
+		return err
+	}
+	if mediaType == common.MimeJSONPatchJSON {
+		ops, err := jsonpatch.ParseOperations(body)
+		if err != nil {
+			return err
+		}
+		if doc, err = jsonpatch.Apply(doc, ops); err != nil {
+			return err
+		}
+	} else {
+		patch, err := jsonpatch.Decode(body)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+		}
+		doc = jsonpatch.Merge(doc, patch)
+	}
+
+	patched, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	// Decode into a fresh value so removed members reset to their zero value.
+	result := reflect.New(target.Elem().Type())
+	if err := json.Unmarshal(patched, result.Interface()); err != nil {
+		return &PatchResultError{Err: err}
+	}
+	copyEncodedFields(target.Elem(), result.Elem())
+	return nil
+}
+
+// copyEncodedFields copies the patched value into current. For structs only
+// the fields JSON encodes are copied, including the exported fields of
+// embedded structs; unexported fields and fields tagged "-" cannot be
+// addressed by a patch and keep their values.
+func copyEncodedFields(current, result reflect.Value) {
+	if current.Kind() != reflect.Struct {
+		current.Set(result)
+		return
+	}
+	t := current.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Tag.Get("json") == "-":
+		case f.Anonymous && !f.IsExported() && f.Type.Kind() == reflect.Struct:
+			copyEncodedFields(current.Field(i), result.Field(i))
+		case f.IsExported():
+			current.Field(i).Set(result.Field(i))
+		}
+	}
+}
+
+// PatchFailure is one entry of the "errors" member of a BindPatch problem.
+type PatchFailure struct {
+	// Pointer is the JSON pointer of the location that failed.
+	Pointer string `json:"pointer"`
+	// Op is the JSON Patch operation, empty for merge patches.
+	Op     string `json:"op,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// patchFailures locates err in the patch document, or returns nil when it
+// has no location.
+func patchFailures(err error) []PatchFailure {
+	var opErr *jsonpatch.OperationError
+	if errors.As(err, &opErr) {
+		return []PatchFailure{{Pointer: opErr.Path, Op: opErr.Op, Detail: opErr.Err.Error()}}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		tokens := strings.Split(typeErr.Field, ".")
+		for i, token := range tokens {
+			tokens[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+		}
+		return []PatchFailure{{
+			Pointer: "/" + strings.Join(tokens, "/"),
+			Detail:  fmt.Sprintf("cannot use JSON %s as %s", typeErr.Value, typeErr.Type),
+		}}
+	}
+	return nil
+}
+
+func (c *DefaultRouteContext) patchProblem(err error) {
+	var maxErr *http.MaxBytesError
+	var resultErr *PatchResultError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUnsupportedPatchType):
+		status = http.StatusUnsupportedMediaType
+	case errors.As(err, &maxErr):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrPatchTestFailed):
+		status = http.StatusConflict
+	case errors.Is(err, ErrPatchPathNotFound), errors.As(err, &resultErr):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, ErrInvalidPatch), errors.Is(err, ErrMissingBody):
+		status = http.StatusBadRequest
+	}
+	problem := &ProblemDetails{
+		Title:    http.StatusText(status),
+		Detail:   err.Error(),
+		Status:   status,
+		Type:     ProblemTypeAboutBlank,
+		Instance: getInstanceURI(c.Request()),
+	}
+	if failures := patchFailures(err); failures != nil {
+		problem.Extensions = map[string]any{"errors": failures}
+	}
+	c.Problem(problem)
+}
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type patchWidget struct {
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags,omitempty"`
+	Price   *float64 `json:"price,omitempty"`
+	Version int      `json:"-"`
+	owner   string
+}
+
+func newPatchContext(ct, body string) (*DefaultRouteContext, *httptest.ResponseRecorder) {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPatch, "/widgets/1", strings.NewReader(body))
+	req.Header.Set(common.HeaderContentType, ct)
+	rr := httptest.NewRecorder()
+	return NewRouteContext(rr, req), rr
+}
+
+func TestShouldApplyMergePatchGivenMergePatchBody(t *testing.T) {
+	// Arrange
+	price := 9.5
+	current := patchWidget{Name: "gear", Tags: []string{"a"}, Price: &price, Version: 3, owner: "ops"}
+	c, rr := newPatchContext(common.MimeMergePatchJSON+"; charset=utf-8", `{"name":"cog","price":null}`)
+
+	// Act
+	err := c.BindPatch(&current)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, patchWidget{Name: "cog", Tags: []string{"a"}, Version: 3, owner: "ops"}, current)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestShouldApplyJSONPatchGivenOperationList(t *testing.T) {
+	// Arrange
+	current := patchWidget{Name: "gear", Tags: []string{"a", "b"}}
+	c, _ := newPatchContext(common.MimeJSONPatchJSON, `[
+		{"op":"test","path":"/name","value":"gear"},
+		{"op":"add","path":"/tags/0","value":"new"},
+		{"op":"remove","path":"/tags/2"},
+		{"op":"copy","from":"/name","path":"/tags/-"}
+	]`)
+
+	// Act
+	err := c.BindPatch(&current)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "a", "gear"}, current.Tags)
+}
+
+func TestShouldWriteProblemAndKeepCurrentGivenRejectedPatch(t *testing.T) {
+	cases := []struct {
+		name   string
+		ct     string
+		body   string
+		status int
+	}{
+		{"unsupported media type", common.MimeTextPlain, `name=x`, http.StatusUnsupportedMediaType},
+		{"malformed merge patch", common.MimeMergePatchJSON, `{"name":`, http.StatusBadRequest},
+		{"malformed operation", common.MimeJSONPatchJSON, `[{"op":"add","path":"/name"}]`, http.StatusBadRequest},
+		{"failed test", common.MimeJSONPatchJSON, `[{"op":"replace","path":"/name","value":"x"},{"op":"test","path":"/name","value":"y"}]`, http.StatusConflict},
+		{"missing path", common.MimeJSONPatchJSON, `[{"op":"replace","path":"/missing","value":1}]`, http.StatusUnprocessableEntity},
+		{"result type mismatch", common.MimeMergePatchJSON, `{"name":5}`, http.StatusUnprocessableEntity},
+		{"empty body", common.MimeMergePatchJSON, ``, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			current := patchWidget{Name: "gear"}
+			c, rr := newPatchContext(tc.ct, tc.body)
+
+			// Act
+			err := c.BindPatch(&current)
+
+			// Assert
+			require.Error(t, err)
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, common.MimeProblemJSON, rr.Header().Get(common.HeaderContentType))
+			assert.Equal(t, patchWidget{Name: "gear"}, current)
+		})
+	}
+}
+
+func TestShouldListFailedLocationGivenRejectedPatch(t *testing.T) {
+	cases := []struct {
+		name string
+		ct   string
+		body string
+		want PatchFailure
+	}{
+		{"missing path", common.MimeJSONPatchJSON, `[{"op":"replace","path":"/missing","value":1}]`, PatchFailure{Pointer: "/missing", Op: "replace", Detail: "path not found"}},
+		{"result type mismatch", common.MimeMergePatchJSON, `{"name":5}`, PatchFailure{Pointer: "/name", Detail: "cannot use JSON number as string"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			current := patchWidget{Name: "gear"}
+			c, rr := newPatchContext(tc.ct, tc.body)
+
+			// Act
+			err := c.BindPatch(&current)
+
+			// Assert
+			require.Error(t, err)
+			var problem struct {
+				Errors []PatchFailure `json:"errors"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, []PatchFailure{tc.want}, problem.Errors)
+		})
+	}
+}
@@ -21,6 +21,30 @@ type ProblemDetails struct {
 	Status   int     `json:"status"`
 	Detail   string  `json:"detail"`
 	Instance *string `json:"instance,omitempty"`
+	// Extensions holds RFC 9457 extension members, serialized alongside the
+	// standard members. Keys that collide with a standard member are ignored.
+	Extensions map[string]any `json:"-"`
+}
+
+// MarshalJSON writes the standard members followed by any extension members.
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	type standard ProblemDetails
+	b, err := json.Marshal(standard(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+	members := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	var std map[string]json.RawMessage
+	if err := json.Unmarshal(b, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		members[k] = v
+	}
+	return json.Marshal(members)
 }
 
 const ProblemTypeAboutBlank = "about:blank"
@@ -32,6 +56,9 @@ func (c *DefaultRouteContext) startResponse(status int) bool {
 	if c.responseCommitted {
 		return false
 	}
+	if status < http.StatusBadRequest && c.WriteParamErrors() {
+		return false
+	}
 	c.responseCommitted = true
 	c.responseStatus = status
 	return true
@@ -95,6 +122,7 @@ func (c *DefaultRouteContext) Problem(problem *ProblemDetails) {
 	if problem.Status == 0 {
 		problem.Status = http.StatusInternalServerError
 	}
+	problem = withRequestID(problem, c.requestID)
 	b, err := json.Marshal(problem)
 	if err != nil {
 		slog.ErrorContext(c, "failed to marshal problem response", responseLogArgs(c, problem.Status, "problem")...)
@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/fgrzl/mux/internal/di"
+)
+
+// SetContainer attaches the router's service container. The request scope is
+// created on the first Resolve, so requests that resolve nothing pay nothing.
+// The response writer is wrapped to record the status of responses written
+// directly to it, which CloseScope uses to commit or roll back.
+func (c *DefaultRouteContext) SetContainer(container *di.Container) {
+	c.container = container
+	if container != nil && c.response != nil && c.response != &c.scopeWriter {
+		c.scopeWriter = statusWriter{ResponseWriter: c.response}
+		c.response = &c.scopeWriter
+	}
+}
+
+// Resolve returns the service registered for t, creating the request scope on
+// first use. Scoped services are shared for the rest of the request.
+func (c *DefaultRouteContext) Resolve(t reflect.Type) (any, error) {
+	if c.scope == nil {
+		if c.container == nil {
+			return nil, fmt.Errorf("%w: %s", di.ErrNotRegistered, t)
+		}
+		c.scope = c.container.NewScope(c.Context)
+	}
+	return c.scope.Resolve(t)
+}
+
+// MarkFailed records that the request failed outside the framework response
+// helpers, such as a recovered panic, so CloseScope rolls back.
+func (c *DefaultRouteContext) MarkFailed() {
+	c.scopeFailed = true
+}
+
+// CloseScope disposes the request scope. Scoped services are committed when
+// the request neither panicked nor produced a 4xx or 5xx response, whether
+// written by a response helper or directly to the response writer, and are
+// rolled back otherwise. Disposal errors are logged because the response has
+// already been written.
+func (c *DefaultRouteContext) CloseScope() {
+	scope := c.scope
+	c.scope = nil
+	c.container = nil
+	failed := c.scopeFailed
+	c.scopeFailed = false
+	status := max(c.responseStatus, c.scopeWriter.status)
+	c.scopeWriter = statusWriter{}
+	if scope == nil {
+		return
+	}
+	succeeded := !failed && status < http.StatusBadRequest
+	if err := scope.Close(succeeded); err != nil {
+		logCtx := context.Background()
+		if c.request != nil {
+			logCtx = c.request.Context()
+		}
+		slog.ErrorContext(logCtx, "failed to dispose request services", "error", err, "committed", succeeded)
+	}
+}
+
+// statusWriter records the first status written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status and forwards it.
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records an implicit 200 and forwards p.
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer when it supports flushing.
+func (w *statusWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
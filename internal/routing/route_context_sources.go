@@ -0,0 +1,233 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/binder"
+	"github.com/fgrzl/mux/internal/common"
+)
+
+// sourceBody holds the request body decoded once for explicit source binding.
+type sourceBody struct {
+	raw     []byte
+	members map[string]json.RawMessage
+	form    map[string][]string
+	uploads *Uploads
+	present bool
+}
+
+// bindSources binds a struct whose fields declare explicit request sources.
+// Only tagged fields are bound; each field reads the first present source in
+// tag order, then falls back to its default, and fails when it is required.
+func (c *DefaultRouteContext) bindSources(target reflect.Value, fields []binder.SourceField) error {
+	var body *sourceBody
+	for i := range fields {
+		field := &fields[i]
+		fv := target.FieldByIndex(field.Index)
+
+		bound := false
+		for _, src := range field.Sources {
+			if src.In == binder.SourceBody {
+				if body == nil {
+					var err error
+					if body, err = c.readSourceBody(fields); err != nil {
+						return err
+					}
+				}
+				ok, err := bindBodySource(fv, field, src.Name, body)
+				if err != nil {
+					return fmt.Errorf("body %q: %w", src.Name, err)
+				}
+				if ok {
+					bound = true
+					break
+				}
+				continue
+			}
+
+			values, ok := c.sourceValues(src)
+			if !ok {
+				continue
+			}
+			if err := field.Set(fv, values); err != nil {
+				return fmt.Errorf("%s param %q: %w", src.In, src.Name, err)
+			}
+			bound = true
+			break
+		}
+		if bound {
+			continue
+		}
+
+		if field.HasDefault {
+			if err := field.Set(fv, []string{field.Default}); err != nil {
+				return fmt.Errorf("default for %s: %w", field.Name, err)
+			}
+			continue
+		}
+		if field.Required || field.Sources[0].In == binder.SourcePath {
+			src := field.Sources[0]
+			return fmt.Errorf("%w: %s %q", ErrMissingSource, src.In, src.Name)
+		}
+	}
+	return nil
+}
+
+// sourceValues returns the raw values for a non-body source and whether the
+// source was present on the request.
+func (c *DefaultRouteContext) sourceValues(src binder.SourceTag) ([]string, bool) {
+	switch src.In {
+	case binder.SourcePath:
+		if value, ok := c.Param(src.Name); ok {
+			return []string{value}, true
+		}
+	case binder.SourceQuery:
+		if values, ok := c.request.URL.Query()[src.Name]; ok && len(values) > 0 {
+			return values, true
+		}
+	case binder.SourceHeader:
+		if values := c.request.Header.Values(src.Name); len(values) > 0 {
+			return values, true
+		}
+	case binder.SourceCookie:
+		if cookie, err := c.request.Cookie(src.Name); err == nil {
+			return []string{cookie.Value}, true
+		}
+	}
+	return nil, false
+}
+
+// readSourceBody decodes the request body once for body-tagged fields. A
+// route that requires a body gets ErrMissingBody for an empty one.
+func (c *DefaultRouteContext) readSourceBody(fields []binder.SourceField) (*sourceBody, error) {
+	body := &sourceBody{}
+	if !methodAllowsBodyBinding(c.request.Method) || c.request.Body == nil {
+		return body, nil
+	}
+	if err := c.checkRequiredBody(); err != nil {
+		return nil, err
+	}
+	if c.request.Body == http.NoBody {
+		return body, nil
+	}
+	ct := c.request.Header.Get(common.HeaderContentType)
+	if strings.HasPrefix(ct, common.MimeMultipartFormData) && c.usesUploads(hasFileSource(fields)) {
+		uploads, err := c.readUploads()
+		if err != nil {
+			return nil, err
+		}
+		body.form = uploads.Values
+		body.uploads = uploads
+		body.present = len(uploads.Values) > 0 || len(uploads.Files) > 0
+		return body, nil
+	}
+	c.applyBodyLimit()
+
+	switch {
+	case strings.HasPrefix(ct, common.MimeFormURLEncoded), strings.HasPrefix(ct, common.MimeMultipartFormData):
+		if strings.HasPrefix(ct, common.MimeMultipartFormData) {
+			if err := c.request.ParseMultipartForm(32 << 20); err != nil {
+				return nil, err
+			}
+			if c.request.MultipartForm != nil {
+				body.form = c.request.MultipartForm.Value
+			}
+		} else {
+			if err := c.request.ParseForm(); err != nil {
+				return nil, err
+			}
+			body.form = c.request.PostForm
+		}
+		body.present = len(body.form) > 0
+		return body, nil
+	case strings.HasPrefix(ct, common.MimeJSON):
+		raw, err := io.ReadAll(c.request.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			return body, nil
+		}
+		body.raw = raw
+		body.present = true
+		return body, nil
+	case ct == "":
+		return body, nil
+	default:
+		return nil, errors.New("unsupported content type")
+	}
+}
+
+// bindBodySource binds either the whole body (name == "") or a single body
+// member into fv. It reports whether the body supplied a value.
+func bindBodySource(fv reflect.Value, field *binder.SourceField, name string, body *sourceBody) (bool, error) {
+	if !body.present {
+		return false, nil
+	}
+	if body.uploads != nil && name != "" {
+		if files := body.uploads.All(name); len(files) > 0 {
+			values := make([]reflect.Value, len(files))
+			for i, f := range files {
+				values[i] = reflect.ValueOf(f)
+			}
+			if binder.AssignConverted(fv, values) {
+				return true, nil
+			}
+		}
+	}
+	if body.form != nil {
+		if name == "" {
+			staging := make(map[string]any, len(body.form))
+			for key, values := range body.form {
+				addToStaging(staging, key, values)
+			}
+			raw, err := json.Marshal(staging)
+			if err != nil {
+				return false, err
+			}
+			return true, json.Unmarshal(raw, fv.Addr().Interface())
+		}
+		values, ok := body.form[name]
+		if !ok || len(values) == 0 {
+			return false, nil
+		}
+		return true, field.Set(fv, values)
+	}
+
+	if name == "" {
+		return true, json.Unmarshal(body.raw, fv.Addr().Interface())
+	}
+	if body.members == nil {
+		if err := json.Unmarshal(body.raw, &body.members); err != nil {
+			return false, err
+		}
+	}
+	member, ok := body.members[name]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(member, fv.Addr().Interface())
+}
+
+// hasFileSource reports whether any body-tagged field holds uploaded files.
+func hasFileSource(fields []binder.SourceField) bool {
+	for i := range fields {
+		t := fields[i].Type
+		if t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct && fileHeaderType.ConvertibleTo(t) {
+			return true
+		}
+	}
+	return false
+}
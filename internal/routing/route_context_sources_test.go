@@ -0,0 +1,172 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/fgrzl/mux/internal/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sourceWidgetBody struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type sourceWidgetRequest struct {
+	ID     int              `path:"id"`
+	Page   int              `query:"page" default:"1"`
+	Size   int              `query:"size" default:"20"`
+	Tenant string           `header:"X-Tenant" required:"true"`
+	Locale string           `query:"locale" header:"Accept-Language" default:"en"`
+	Tags   []string         `query:"tag"`
+	Body   sourceWidgetBody `body:""`
+}
+
+func newSourceContext(t *testing.T, method, target, body string) *DefaultRouteContext {
+	t.Helper()
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequestWithContext(context.Background(), method, target, nil)
+	} else {
+		req = httptest.NewRequestWithContext(context.Background(), method, target, strings.NewReader(body))
+		req.Header.Set(common.HeaderContentType, common.MimeJSON)
+	}
+	c := NewRouteContext(httptest.NewRecorder(), req)
+	c.paramsSlice = &Params{}
+	return c
+}
+
+func TestShouldBindExplicitSourcesGivenTaggedStruct(t *testing.T) {
+	// Arrange
+	c := newSourceContext(t, http.MethodPut, "/widgets/7?id=99&page=3&tag=a,b", `{"name":"gear","color":"red"}`)
+	c.paramsSlice.Set("id", "7")
+	c.request.Header.Set("X-Tenant", "acme")
+	c.request.Header.Set("Accept-Language", "fr")
+
+	// Act
+	var out sourceWidgetRequest
+	err := c.Bind(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 7, out.ID, "path source must not collide with query id")
+	assert.Equal(t, 3, out.Page)
+	assert.Equal(t, 20, out.Size)
+	assert.Equal(t, "acme", out.Tenant)
+	assert.Equal(t, "fr", out.Locale)
+	assert.Equal(t, []string{"a", "b"}, out.Tags)
+	assert.Equal(t, sourceWidgetBody{Name: "gear", Color: "red"}, out.Body)
+}
+
+func TestShouldPreferEarlierSourceGivenMultipleSourcesPresent(t *testing.T) {
+	// Arrange
+	c := newSourceContext(t, http.MethodGet, "/widgets/7?locale=de", "")
+	c.paramsSlice.Set("id", "7")
+	c.request.Header.Set("X-Tenant", "acme")
+	c.request.Header.Set("Accept-Language", "fr")
+
+	// Act
+	var out sourceWidgetRequest
+	err := c.Bind(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "de", out.Locale)
+	assert.Equal(t, 1, out.Page)
+}
+
+func TestShouldFailBindingGivenRequiredSourceMissing(t *testing.T) {
+	// Arrange
+	c := newSourceContext(t, http.MethodGet, "/widgets/7", "")
+	c.paramsSlice.Set("id", "7")
+
+	// Act
+	var out sourceWidgetRequest
+	err := c.Bind(&out)
+
+	// Assert
+	require.Error(t, err)
+	assert.True(t, IsMissingSourceError(err))
+	assert.Contains(t, err.Error(), `header "X-Tenant"`)
+}
+
+func TestShouldFailBindingGivenPathSourceMissing(t *testing.T) {
+	// Arrange
+	c := newSourceContext(t, http.MethodGet, "/widgets", "")
+	c.request.Header.Set("X-Tenant", "acme")
+
+	// Act
+	var out sourceWidgetRequest
+	err := c.Bind(&out)
+
+	// Assert
+	assert.True(t, IsMissingSourceError(err))
+}
+
+func TestShouldReturnConversionErrorGivenInvalidSourceValue(t *testing.T) {
+	// Arrange
+	c := newSourceContext(t, http.MethodGet, "/widgets/x", "")
+	c.paramsSlice.Set("id", "x")
+	c.request.Header.Set("X-Tenant", "acme")
+
+	// Act
+	var out sourceWidgetRequest
+	err := c.Bind(&out)
+
+	// Assert
+	require.Error(t, err)
+	assert.False(t, IsMissingSourceError(err))
+	assert.Contains(t, err.Error(), `path param "id"`)
+}
+
+func TestShouldBindCookieAndBodyMemberSourcesGivenTaggedFields(t *testing.T) {
+	// Arrange
+	type request struct {
+		Session string `cookie:"sid" required:"true"`
+		Name    string `body:"name"`
+		Count   *int   `body:"count"`
+	}
+	c := newSourceContext(t, http.MethodPost, "/widgets", `{"name":"gear","count":4}`)
+	c.request.AddCookie(&http.Cookie{Name: "sid", Value: "s-1"})
+
+	// Act
+	var out request
+	err := c.Bind(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "s-1", out.Session)
+	assert.Equal(t, "gear", out.Name)
+	require.NotNil(t, out.Count)
+	assert.Equal(t, 4, *out.Count)
+}
+
+func TestShouldReturnMissingBodyGivenRequiredBodyAndBodySource(t *testing.T) {
+	type request struct {
+		Name string `body:"name"`
+	}
+	for _, req := range []*http.Request{
+		httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/widgets", nil),
+		httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/widgets", strings.NewReader("")),
+	} {
+		// Arrange
+		req.Header.Set(common.HeaderContentType, common.MimeJSON)
+		c := NewRouteContext(httptest.NewRecorder(), req)
+		opts := &RouteOptions{}
+		opts.RequestBody = &openapi.RequestBodyObject{Required: true}
+		c.SetOptions(opts)
+
+		// Act
+		var out request
+		err := c.Bind(&out)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrMissingBody)
+	}
+}
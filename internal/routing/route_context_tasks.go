@@ -0,0 +1,96 @@
+package routing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fgrzl/claims"
+	"github.com/fgrzl/mux/internal/tasks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/fgrzl/mux"
+
+var (
+	fallbackTasksOnce sync.Once
+	fallbackTasks     *tasks.Group
+)
+
+// SetTaskGroup attaches the router's background task group used by Go.
+func (c *DefaultRouteContext) SetTaskGroup(group *tasks.Group) {
+	c.tasks = group
+}
+
+// Go runs fn in the background on a detached copy of the context. The copy
+// keeps the principal, params, services, and route options; its own service
+// scope is committed when fn returns and rolled back if fn panics. When the
+// request is traced, the task runs in a new root span linked to the request
+// span. Panics are recovered and logged. Tasks are bounded and awaited by the
+// router's task group; contexts created outside a router use a shared group.
+// Go returns tasks.ErrQueueFull or tasks.ErrClosed, without running fn, when
+// the group's queue is full or it is shutting down.
+func (c *DefaultRouteContext) Go(fn func(RouteContext)) error {
+	if fn == nil {
+		return nil
+	}
+	detached := Detach(c)
+	detached.container = c.container
+	link := trace.SpanContextFromContext(c.requestContext())
+
+	group := c.tasks
+	if group == nil {
+		fallbackTasksOnce.Do(func() { fallbackTasks = tasks.New(tasks.DefaultLimit, tasks.DefaultQueueLimit) })
+		group = fallbackTasks
+	}
+	return group.Go(func(ctx context.Context) {
+		runTask(ctx, detached, link, fn)
+	})
+}
+
+func runTask(ctx context.Context, c *DefaultRouteContext, link trace.SpanContext, fn func(RouteContext)) {
+	if c.user != nil {
+		ctx = claims.WithUser(ctx, c.user)
+	}
+	if c.requestID != "" {
+		ctx = ContextWithRequestID(ctx, c.requestID)
+	}
+	var span trace.Span
+	if link.IsValid() {
+		ctx, span = otel.Tracer(tracerName).Start(ctx, "background task",
+			trace.WithNewRoot(),
+			trace.WithLinks(trace.Link{SpanContext: link}),
+		)
+	}
+	c.Context = ctx
+	if c.request != nil {
+		c.request = c.request.WithContext(ctx)
+	}
+
+	completed := false
+	defer func() {
+		if !completed {
+			c.MarkFailed()
+			if span != nil {
+				span.SetStatus(codes.Error, "panic")
+			}
+		}
+		c.CloseScope()
+		if span != nil {
+			span.End()
+		}
+	}()
+	fn(c)
+	completed = true
+}
+
+func (c *DefaultRouteContext) requestContext() context.Context {
+	if c.request != nil && c.request.Context() != nil {
+		return c.request.Context()
+	}
+	if c.Context != nil {
+		return c.Context
+	}
+	return context.Background()
+}
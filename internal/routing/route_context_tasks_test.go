@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/fgrzl/claims"
+	"github.com/fgrzl/mux/internal/di"
+	"github.com/fgrzl/mux/internal/tasks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type taskTx struct{ log *[]string }
+
+func (tx *taskTx) Commit() error   { *tx.log = append(*tx.log, "commit"); return nil }
+func (tx *taskTx) Rollback() error { *tx.log = append(*tx.log, "rollback"); return nil }
+
+func TestShouldRunTaskOnDetachedContextGivenGo(t *testing.T) {
+	// Arrange
+	group := tasks.New(1, 0)
+	user := newMockPrincipal()
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(cancelCtx, http.MethodGet, "/jobs", nil)
+	c := NewRouteContext(httptest.NewRecorder(), req)
+	c.SetTaskGroup(group)
+	c.SetUser(user)
+	c.SetService("clock", "utc")
+	got := make(chan RouteContext, 1)
+
+	// Act
+	require.NoError(t, c.Go(func(task RouteContext) { got <- task }))
+	cancel()
+	require.NoError(t, group.Wait(context.Background()))
+	task := <-got
+
+	// Assert
+	assert.NotSame(t, c, task)
+	assert.Equal(t, testUserSubject, task.User().Subject())
+	svc, ok := task.GetService("clock")
+	assert.True(t, ok)
+	assert.Equal(t, "utc", svc)
+	fromCtx, ok := claims.UserFromContext(task)
+	require.True(t, ok, "principal travels in the task context")
+	assert.Equal(t, testUserSubject, fromCtx.Subject())
+}
+
+func TestShouldCommitTaskScopeGivenCompletedTaskAndRollBackGivenPanic(t *testing.T) {
+	// Arrange
+	var log []string
+	container := di.New()
+	require.NoError(t, container.Provide(di.Scoped, func() *taskTx { return &taskTx{log: &log} }))
+	group := tasks.New(1, 0)
+	c := NewRouteContext(httptest.NewRecorder(), httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil))
+	c.SetContainer(container)
+	c.SetTaskGroup(group)
+	resolve := func(task RouteContext) {
+		_, err := task.Resolve(reflect.TypeOf(&taskTx{}))
+		require.NoError(t, err)
+	}
+
+	// Act
+	c.Go(resolve)
+	c.Go(func(task RouteContext) {
+		resolve(task)
+		panic("boom")
+	})
+	require.NoError(t, group.Wait(context.Background()))
+
+	// Assert
+	assert.ElementsMatch(t, []string{"commit", "rollback"}, log)
+	assert.Nil(t, c.scope, "tasks do not share the request scope")
+}
+
+func TestShouldLinkTaskSpanGivenTracedRequest(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		_ = tp.Shutdown(t.Context())
+		otel.SetTracerProvider(prev)
+	})
+	reqCtx, reqSpan := tp.Tracer("test").Start(context.Background(), "request")
+	group := tasks.New(1, 0)
+	c := NewRouteContext(httptest.NewRecorder(), httptest.NewRequestWithContext(reqCtx, http.MethodGet, "/", nil))
+	c.SetTaskGroup(group)
+	var taskSpan trace.SpanContext
+
+	// Act
+	c.Go(func(task RouteContext) { taskSpan = trace.SpanContextFromContext(task) })
+	reqSpan.End()
+	require.NoError(t, group.Wait(context.Background()))
+
+	// Assert
+	ended := recorder.Ended()
+	require.Len(t, ended, 2)
+	task := ended[1]
+	assert.Equal(t, "background task", task.Name())
+	assert.Equal(t, taskSpan.TraceID(), task.SpanContext().TraceID())
+	assert.NotEqual(t, reqSpan.SpanContext().TraceID(), task.SpanContext().TraceID())
+	require.Len(t, task.Links(), 1)
+	assert.Equal(t, reqSpan.SpanContext().SpanID(), task.Links()[0].SpanContext.SpanID())
+}
+
+func TestShouldReturnQueueFullGivenGroupQueueFull(t *testing.T) {
+	// Arrange
+	group := tasks.New(1, 1)
+	c := NewRouteContext(httptest.NewRecorder(), httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil))
+	c.SetTaskGroup(group)
+	release := make(chan struct{})
+	require.NoError(t, c.Go(func(RouteContext) { <-release }))
+	require.NoError(t, c.Go(func(RouteContext) {}))
+
+	// Act
+	err := c.Go(func(RouteContext) {})
+	close(release)
+
+	// Assert
+	assert.ErrorIs(t, err, tasks.ErrQueueFull)
+	require.NoError(t, group.Wait(context.Background()))
+}
@@ -0,0 +1,549 @@
+package routing
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fgrzl/mux/internal/common"
+)
+
+// Upload limit defaults applied when a route does not configure its own.
+const (
+	DefaultMaxUploadPartBytes  int64 = 10 << 20
+	DefaultMaxUploadTotalBytes int64 = 32 << 20
+	DefaultMaxUploadFiles            = 10
+)
+
+// multipartOverhead is the slack allowed on top of MaxTotalBytes for part
+// headers and boundaries when the route has no explicit body limit.
+const multipartOverhead = 1 << 20
+
+// sniffLen is the number of leading bytes inspected by http.DetectContentType.
+const sniffLen = 512
+
+var (
+	// ErrUploadTooLarge is returned when a multipart upload exceeds the
+	// per-part, total, or file-count limits configured for the route.
+	ErrUploadTooLarge = errors.New("upload exceeds configured limits")
+	// ErrUploadTypeNotAllowed is returned when the sniffed content type of an
+	// uploaded file is not in the route's allowed types.
+	ErrUploadTypeNotAllowed = errors.New("upload content type not allowed")
+	// ErrFileNotRetained is returned by FileHeader.Open when the sink that
+	// stored the file does not keep its content.
+	ErrFileNotRetained = errors.New("uploaded file content was not retained")
+)
+
+// UploadLimits bounds multipart uploads on a route. Zero values fall back to
+// DefaultMaxUploadPartBytes, DefaultMaxUploadTotalBytes, DefaultMaxUploadFiles,
+// and MemorySink.
+type UploadLimits struct {
+	// MaxPartBytes caps the size of any single part, file or field.
+	MaxPartBytes int64
+	// MaxTotalBytes caps the combined size of all parts.
+	MaxTotalBytes int64
+	// MaxFiles caps the number of file parts.
+	MaxFiles int
+	// AllowedTypes lists permitted sniffed media types for file parts. Entries
+	// may use wildcards such as "image/*". Empty allows every type.
+	AllowedTypes []string
+	// Sink stores file parts read by Files.
+	Sink FileSink
+}
+
+func (l *UploadLimits) withDefaults() UploadLimits {
+	var out UploadLimits
+	if l != nil {
+		out = *l
+	}
+	if out.MaxPartBytes <= 0 {
+		out.MaxPartBytes = DefaultMaxUploadPartBytes
+	}
+	if out.MaxTotalBytes <= 0 {
+		out.MaxTotalBytes = DefaultMaxUploadTotalBytes
+	}
+	if out.MaxFiles <= 0 {
+		out.MaxFiles = DefaultMaxUploadFiles
+	}
+	if out.Sink == nil {
+		out.Sink = MemorySink()
+	}
+	return out
+}
+
+func (l UploadLimits) allows(mediaType string) bool {
+	if len(l.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range l.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "*/*" || allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// FileHeader describes an uploaded file. ContentType is sniffed from the
+// leading bytes of the file; DeclaredContentType is what the client sent.
+// Depending on the sink, the content is kept in Data or at Path.
+type FileHeader struct {
+	Field               string
+	Filename            string
+	ContentType         string
+	DeclaredContentType string
+	Size                int64
+	Header              textproto.MIMEHeader
+	Path                string
+	Data                []byte
+}
+
+// Open returns a reader over the stored file content.
+func (h *FileHeader) Open() (io.ReadCloser, error) {
+	switch {
+	case h.Path != "":
+		return os.Open(h.Path)
+	case h.Data != nil:
+		return io.NopCloser(bytes.NewReader(h.Data)), nil
+	default:
+		return nil, ErrFileNotRetained
+	}
+}
+
+// Remove deletes a file stored on disk. It is a no-op for in-memory files and
+// for files that were already moved or removed.
+func (h *FileHeader) Remove() error {
+	if h.Path == "" {
+		return nil
+	}
+	if err := os.Remove(h.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// FileSink stores the content of uploaded file parts. Store must consume r
+// and record where the content lives on header (Data or Path), if anywhere.
+type FileSink interface {
+	Store(header *FileHeader, r io.Reader) error
+}
+
+type memorySink struct{}
+
+// MemorySink keeps uploaded files in memory.
+func MemorySink() FileSink { return memorySink{} }
+
+func (memorySink) Store(header *FileHeader, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	header.Data = data
+	return nil
+}
+
+type tempDirSink struct{ dir string }
+
+// TempDirSink writes uploaded files to temporary files in dir (os.TempDir when
+// empty). Files still in place when the request completes are removed, so
+// handlers that keep an upload should move it.
+func TempDirSink(dir string) FileSink { return tempDirSink{dir: dir} }
+
+func (s tempDirSink) Store(header *FileHeader, r io.Reader) error {
+	f, err := os.CreateTemp(s.dir, "mux-upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	header.Path = f.Name()
+	return nil
+}
+
+type writerSink struct {
+	open func(*FileHeader) (io.WriteCloser, error)
+}
+
+// WriterSink streams each uploaded file into the writer returned by open,
+// such as an object-storage upload. The content is not retained, so
+// FileHeader.Open returns ErrFileNotRetained.
+func WriterSink(open func(header *FileHeader) (io.WriteCloser, error)) FileSink {
+	return writerSink{open: open}
+}
+
+func (s writerSink) Store(header *FileHeader, r io.Reader) error {
+	w, err := s.open(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Uploads holds the files and plain form values of a multipart request.
+type Uploads struct {
+	Files  []*FileHeader
+	Values map[string][]string
+}
+
+// File returns the first file uploaded under field.
+func (u *Uploads) File(field string) (*FileHeader, bool) {
+	for _, f := range u.Files {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// All returns every file uploaded under field.
+func (u *Uploads) All(field string) []*FileHeader {
+	var files []*FileHeader
+	for _, f := range u.Files {
+		if f.Field == field {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Value returns the first plain form value for name.
+func (u *Uploads) Value(name string) string {
+	if values := u.Values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// RemoveAll deletes every upload stored on disk.
+func (u *Uploads) RemoveAll() error {
+	if u == nil {
+		return nil
+	}
+	var errs []error
+	for _, f := range u.Files {
+		errs = append(errs, f.Remove())
+	}
+	return errors.Join(errs...)
+}
+
+// MultipartReader streams the parts of a multipart request while enforcing
+// the route's upload limits.
+type MultipartReader struct {
+	reader  *multipart.Reader
+	pending []pendingPart
+	current *MultipartPart
+	limits  UploadLimits
+	files   int
+	total   int64
+}
+
+// pendingPart replays a form already parsed by ParseMultipartForm.
+type pendingPart struct {
+	formName string
+	fileName string
+	header   textproto.MIMEHeader
+	value    string
+	file     *multipart.FileHeader
+}
+
+// MultipartPart is a single part of a multipart request. Reads fail with
+// ErrUploadTooLarge once the part or request exceeds its limits.
+type MultipartPart struct {
+	FormName            string
+	FileName            string
+	ContentType         string
+	DeclaredContentType string
+	Header              textproto.MIMEHeader
+	r                   io.Reader
+	closer              io.Closer
+}
+
+// Read reads the part content.
+func (p *MultipartPart) Read(b []byte) (int, error) { return p.r.Read(b) }
+
+func (p *MultipartPart) close() {
+	if p.closer != nil {
+		_ = p.closer.Close()
+		p.closer = nil
+	}
+}
+
+// NextPart returns the next part, or io.EOF when there are no more parts.
+// File parts beyond MaxFiles fail with ErrUploadTooLarge and file parts whose
+// sniffed type is not allowed fail with ErrUploadTypeNotAllowed. The previous
+// part is closed when the next one is requested.
+func (m *MultipartReader) NextPart() (*MultipartPart, error) {
+	if m.current != nil {
+		m.current.close()
+		m.current = nil
+	}
+	var part *MultipartPart
+	var src io.Reader
+	if m.reader == nil {
+		if len(m.pending) == 0 {
+			return nil, io.EOF
+		}
+		next := m.pending[0]
+		m.pending = m.pending[1:]
+		part = &MultipartPart{FormName: next.formName, FileName: next.fileName, Header: next.header}
+		if next.file == nil {
+			src = strings.NewReader(next.value)
+		} else {
+			f, err := next.file.Open()
+			if err != nil {
+				return nil, err
+			}
+			src, part.closer = f, f
+		}
+	} else {
+		p, err := m.reader.NextPart()
+		if err != nil {
+			return nil, uploadReadError(err)
+		}
+		part = &MultipartPart{FormName: p.FormName(), FileName: p.FileName(), Header: p.Header}
+		src = p
+	}
+	part.DeclaredContentType = part.Header.Get(common.HeaderContentType)
+	limited := &uploadLimitReader{r: src, m: m}
+	part.r = limited
+	if part.FileName == "" {
+		m.current = part
+		return part, nil
+	}
+
+	m.files++
+	if m.files > m.limits.MaxFiles {
+		part.close()
+		return nil, fmt.Errorf("%w: more than %d files", ErrUploadTooLarge, m.limits.MaxFiles)
+	}
+	br := bufio.NewReaderSize(limited, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		part.close()
+		return nil, err
+	}
+	part.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	if !m.limits.allows(part.ContentType) {
+		part.close()
+		return nil, fmt.Errorf("%w: %s", ErrUploadTypeNotAllowed, part.ContentType)
+	}
+	part.r = br
+	m.current = part
+	return part, nil
+}
+
+// uploadLimitReader enforces the per-part and total byte limits.
+type uploadLimitReader struct {
+	r io.Reader
+	m *MultipartReader
+	n int64
+}
+
+func (l *uploadLimitReader) Read(b []byte) (int, error) {
+	n, err := l.r.Read(b)
+	l.n += int64(n)
+	l.m.total += int64(n)
+	if l.n > l.m.limits.MaxPartBytes {
+		return n, fmt.Errorf("%w: part exceeds %d bytes", ErrUploadTooLarge, l.m.limits.MaxPartBytes)
+	}
+	if l.m.total > l.m.limits.MaxTotalBytes {
+		return n, fmt.Errorf("%w: upload exceeds %d bytes", ErrUploadTooLarge, l.m.limits.MaxTotalBytes)
+	}
+	return n, uploadReadError(err)
+}
+
+func uploadReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return fmt.Errorf("%w: %w", ErrUploadTooLarge, err)
+	}
+	return err
+}
+
+// uploadLimits returns the effective upload limits for the current route.
+func (c *DefaultRouteContext) uploadLimits() UploadLimits {
+	if c.options == nil {
+		return (*UploadLimits)(nil).withDefaults()
+	}
+	return c.options.Uploads.withDefaults()
+}
+
+// MultipartReader returns a streaming reader over the multipart request body
+// that enforces the route's upload limits without buffering parts.
+func (c *DefaultRouteContext) MultipartReader() (*MultipartReader, error) {
+	limits := c.uploadLimits()
+	if form := c.request.MultipartForm; form != nil {
+		return &MultipartReader{pending: pendingParts(form), limits: limits}, nil
+	}
+	if !c.bodyLimitApplied {
+		maxBytes := c.maxBodyBytes
+		if maxBytes <= 0 {
+			maxBytes = limits.MaxTotalBytes + multipartOverhead
+		}
+		c.request.Body = http.MaxBytesReader(c.Response(), c.request.Body, maxBytes)
+		c.bodyLimitApplied = true
+	}
+	reader, err := c.request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartReader{reader: reader, limits: limits}, nil
+}
+
+// pendingParts lists the parsed form's values, then its files, sorted by form
+// name so replay does not depend on map order. Parts sharing a name keep their
+// original order.
+func pendingParts(form *multipart.Form) []pendingPart {
+	var parts []pendingPart
+	for name, values := range form.Value {
+		for _, value := range values {
+			parts = append(parts, pendingPart{formName: name, header: textproto.MIMEHeader{}, value: value})
+		}
+	}
+	for name, files := range form.File {
+		for _, file := range files {
+			parts = append(parts, pendingPart{formName: name, fileName: file.Filename, header: file.Header, file: file})
+		}
+	}
+	slices.SortStableFunc(parts, func(a, b pendingPart) int {
+		return strings.Compare(a.formName, b.formName)
+	})
+	return parts
+}
+
+// Files reads the multipart request once, storing file parts in the route's
+// sink and enforcing its upload limits. When the upload is rejected Files
+// writes a problem response (413 for limits, 415 for disallowed types, 400
+// otherwise) and returns the error.
+func (c *DefaultRouteContext) Files() (*Uploads, error) {
+	uploads, err := c.readUploads()
+	if err != nil {
+		c.uploadProblem(err)
+	}
+	return uploads, err
+}
+
+func (c *DefaultRouteContext) readUploads() (*Uploads, error) {
+	if c.uploads == nil && c.uploadErr == nil {
+		c.uploads, c.uploadErr = c.parseUploads()
+	}
+	return c.uploads, c.uploadErr
+}
+
+func (c *DefaultRouteContext) parseUploads() (*Uploads, error) {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	uploads := &Uploads{Values: map[string][]string{}}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return uploads, nil
+		}
+		if err != nil {
+			_ = uploads.RemoveAll()
+			return nil, err
+		}
+		if err := storePart(uploads, part, reader.limits.Sink); err != nil {
+			_ = uploads.RemoveAll()
+			return nil, err
+		}
+	}
+}
+
+func storePart(uploads *Uploads, part *MultipartPart, sink FileSink) error {
+	defer part.close()
+	if part.FileName == "" {
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		uploads.Values[part.FormName] = append(uploads.Values[part.FormName], string(value))
+		return nil
+	}
+	header := &FileHeader{
+		Field:               part.FormName,
+		Filename:            part.FileName,
+		ContentType:         part.ContentType,
+		DeclaredContentType: part.DeclaredContentType,
+		Header:              part.Header,
+	}
+	counter := &countingReader{r: part}
+	if err := sink.Store(header, counter); err != nil {
+		_ = header.Remove()
+		return err
+	}
+	header.Size = counter.n
+	uploads.Files = append(uploads.Files, header)
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *DefaultRouteContext) uploadProblem(err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrUploadTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUploadTypeNotAllowed):
+		status = http.StatusUnsupportedMediaType
+	}
+	c.Problem(&ProblemDetails{
+		Title:    http.StatusText(status),
+		Detail:   err.Error(),
+		Status:   status,
+		Type:     ProblemTypeAboutBlank,
+		Instance: getInstanceURI(c.Request()),
+	})
+}
+
+// ReleaseUploads removes uploads a TempDirSink left on disk and forgets the
+// parsed upload. The router calls it once the handler has returned.
+func (c *DefaultRouteContext) ReleaseUploads() {
+	_ = c.uploads.RemoveAll()
+	c.uploads = nil
+	c.uploadErr = nil
+}
+
+// usesUploads reports whether multipart binding should go through the
+// streaming upload reader instead of ParseMultipartForm.
+func (c *DefaultRouteContext) usesUploads(hasFileFields bool) bool {
+	return hasFileFields || c.uploads != nil || (c.options != nil && c.options.Uploads != nil)
+}
@@ -0,0 +1,323 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+type uploadPart struct {
+	field    string
+	filename string
+	data     []byte
+}
+
+func newUploadContext(t *testing.T, limits *UploadLimits, parts ...uploadPart) (*DefaultRouteContext, *httptest.ResponseRecorder) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		if p.filename == "" {
+			require.NoError(t, writer.WriteField(p.field, string(p.data)))
+			continue
+		}
+		w, err := writer.CreateFormFile(p.field, p.filename)
+		require.NoError(t, err)
+		_, err = w.Write(p.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/", &buf)
+	req.Header.Set(common.HeaderContentType, writer.FormDataContentType())
+	rr := httptest.NewRecorder()
+	c := NewRouteContext(rr, req)
+	if limits != nil {
+		c.SetOptions(&RouteOptions{Uploads: limits})
+	}
+	return c, rr
+}
+
+func TestShouldReadFilesAndValuesGivenMultipartUpload(t *testing.T) {
+	// Arrange
+	c, _ := newUploadContext(t, nil,
+		uploadPart{field: "title", data: []byte("holiday")},
+		uploadPart{field: "photo", filename: "a.png", data: pngHeader},
+		uploadPart{field: "photo", filename: "b.txt", data: []byte("plain text")},
+	)
+
+	// Act
+	uploads, err := c.Files()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "holiday", uploads.Value("title"))
+	files := uploads.All("photo")
+	require.Len(t, files, 2)
+	assert.Equal(t, "a.png", files[0].Filename)
+	assert.Equal(t, "image/png", files[0].ContentType)
+	assert.Equal(t, "application/octet-stream", files[0].DeclaredContentType)
+	assert.Equal(t, int64(len(pngHeader)), files[0].Size)
+	assert.Equal(t, "text/plain", files[1].ContentType)
+	rc, err := files[1].Open()
+	require.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	assert.Equal(t, "plain text", string(data))
+}
+
+func TestShouldWriteRequestEntityTooLargeGivenOversizedPart(t *testing.T) {
+	// Arrange
+	c, rr := newUploadContext(t, &UploadLimits{MaxPartBytes: 8},
+		uploadPart{field: "doc", filename: "big.bin", data: bytes.Repeat([]byte("x"), 64)},
+	)
+
+	// Act
+	_, err := c.Files()
+
+	// Assert
+	require.ErrorIs(t, err, ErrUploadTooLarge)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Contains(t, rr.Body.String(), "part exceeds 8 bytes")
+}
+
+func TestShouldRejectUploadGivenTooManyFilesOrTotalBytes(t *testing.T) {
+	// Arrange
+	part := uploadPart{field: "doc", filename: "a.txt", data: []byte("hello")}
+	tooMany, _ := newUploadContext(t, &UploadLimits{MaxFiles: 1}, part, part)
+	tooBig, _ := newUploadContext(t, &UploadLimits{MaxTotalBytes: 8}, part, part)
+
+	// Act
+	_, manyErr := tooMany.Files()
+	_, bigErr := tooBig.Files()
+
+	// Assert
+	assert.ErrorIs(t, manyErr, ErrUploadTooLarge)
+	assert.ErrorIs(t, bigErr, ErrUploadTooLarge)
+}
+
+func TestShouldWriteUnsupportedMediaTypeGivenDisallowedSniffedType(t *testing.T) {
+	// Arrange
+	c, rr := newUploadContext(t, &UploadLimits{AllowedTypes: []string{"image/*"}},
+		uploadPart{field: "photo", filename: "fake.png", data: []byte("not an image")},
+	)
+
+	// Act
+	_, err := c.Files()
+
+	// Assert
+	require.ErrorIs(t, err, ErrUploadTypeNotAllowed)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+}
+
+func TestShouldStoreFilesOnDiskAndRemoveThemGivenTempDirSink(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	c, _ := newUploadContext(t, &UploadLimits{Sink: TempDirSink(dir)},
+		uploadPart{field: "doc", filename: "a.txt", data: []byte("on disk")},
+	)
+
+	// Act
+	uploads, err := c.Files()
+	require.NoError(t, err)
+	file, ok := uploads.File("doc")
+	require.True(t, ok)
+	data, readErr := os.ReadFile(file.Path)
+	c.ReleaseUploads()
+
+	// Assert
+	require.NoError(t, readErr)
+	assert.Equal(t, "on disk", string(data))
+	assert.Nil(t, file.Data)
+	_, statErr := os.Stat(file.Path)
+	assert.True(t, errors.Is(statErr, os.ErrNotExist))
+}
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestShouldStreamToWriterGivenWriterSink(t *testing.T) {
+	// Arrange
+	dst := &closeBuffer{}
+	sink := WriterSink(func(header *FileHeader) (io.WriteCloser, error) { return dst, nil })
+	c, _ := newUploadContext(t, &UploadLimits{Sink: sink},
+		uploadPart{field: "doc", filename: "a.txt", data: []byte("streamed")},
+	)
+
+	// Act
+	uploads, err := c.Files()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", dst.String())
+	assert.True(t, dst.closed)
+	file, _ := uploads.File("doc")
+	assert.Equal(t, int64(8), file.Size)
+	_, openErr := file.Open()
+	assert.ErrorIs(t, openErr, ErrFileNotRetained)
+}
+
+func TestShouldStreamPartsGivenMultipartReader(t *testing.T) {
+	// Arrange
+	c, _ := newUploadContext(t, nil,
+		uploadPart{field: "title", data: []byte("t")},
+		uploadPart{field: "photo", filename: "a.png", data: pngHeader},
+	)
+
+	// Act
+	reader, err := c.MultipartReader()
+	require.NoError(t, err)
+	var names, types []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, part.FormName)
+		types = append(types, part.ContentType)
+	}
+
+	// Assert
+	assert.Equal(t, []string{"title", "photo"}, names)
+	assert.Equal(t, []string{"", "image/png"}, types)
+}
+
+func TestShouldReplayParsedFormGivenMultipartAlreadyParsed(t *testing.T) {
+	// Arrange
+	c, _ := newUploadContext(t, nil,
+		uploadPart{field: "title", data: []byte("parsed")},
+		uploadPart{field: "doc", filename: "a.txt", data: []byte("content")},
+	)
+	require.NoError(t, c.Request().ParseMultipartForm(1<<20))
+
+	// Act
+	uploads, err := c.Files()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "parsed", uploads.Value("title"))
+	file, ok := uploads.File("doc")
+	require.True(t, ok)
+	assert.Equal(t, "content", string(file.Data))
+}
+
+func TestShouldReplayPartsInFormNameOrderGivenMultipartAlreadyParsed(t *testing.T) {
+	// Arrange
+	c, _ := newUploadContext(t, nil,
+		uploadPart{field: "zeta", data: []byte("z")},
+		uploadPart{field: "docs", filename: "b.txt", data: []byte("b")},
+		uploadPart{field: "alpha", data: []byte("a")},
+		uploadPart{field: "docs", filename: "a.txt", data: []byte("a")},
+		uploadPart{field: "mid", data: []byte("m")},
+	)
+	require.NoError(t, c.Request().ParseMultipartForm(1<<20))
+
+	// Act
+	var parts []string
+	for range 10 {
+		reader, err := c.MultipartReader()
+		require.NoError(t, err)
+		var order []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			order = append(order, part.FormName+":"+part.FileName)
+		}
+		parts = append(parts, strings.Join(order, ","))
+	}
+
+	// Assert
+	for _, order := range parts {
+		assert.Equal(t, "alpha:,docs:b.txt,docs:a.txt,mid:,zeta:", order)
+	}
+}
+
+type uploadModel struct {
+	Title  string        `json:"title"`
+	Avatar *FileHeader   `json:"avatar"`
+	Docs   []FileHeader  `json:"docs"`
+	Extra  []*FileHeader `json:"extra"`
+}
+
+func TestShouldBindFileFieldsGivenMultipartUpload(t *testing.T) {
+	// Arrange
+	c, _ := newUploadContext(t, nil,
+		uploadPart{field: "title", data: []byte("profile")},
+		uploadPart{field: "avatar", filename: "me.png", data: pngHeader},
+		uploadPart{field: "docs", filename: "a.txt", data: []byte("a")},
+		uploadPart{field: "docs", filename: "b.txt", data: []byte("b")},
+	)
+
+	// Act
+	var out uploadModel
+	err := c.Bind(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "profile", out.Title)
+	require.NotNil(t, out.Avatar)
+	assert.Equal(t, "image/png", out.Avatar.ContentType)
+	require.Len(t, out.Docs, 2)
+	assert.Equal(t, "b.txt", out.Docs[1].Filename)
+	assert.Empty(t, out.Extra)
+}
+
+func TestShouldReturnUploadErrorFromBindGivenLimitExceeded(t *testing.T) {
+	// Arrange
+	c, rr := newUploadContext(t, &UploadLimits{MaxPartBytes: 4},
+		uploadPart{field: "avatar", filename: "me.png", data: pngHeader},
+	)
+
+	// Act
+	var out uploadModel
+	err := c.Bind(&out)
+
+	// Assert
+	require.ErrorIs(t, err, ErrUploadTooLarge)
+	assert.Equal(t, http.StatusOK, rr.Code, "Bind leaves the response to the handler")
+}
+
+func TestShouldBindFileSourceGivenBodyTag(t *testing.T) {
+	// Arrange
+	type sourceUpload struct {
+		Title  string      `body:"title"`
+		Avatar *FileHeader `body:"avatar" required:"true"`
+	}
+	c, _ := newUploadContext(t, nil,
+		uploadPart{field: "title", data: []byte("profile")},
+		uploadPart{field: "avatar", filename: "me.png", data: pngHeader},
+	)
+
+	// Act
+	var out sourceUpload
+	err := c.Bind(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "profile", out.Title)
+	require.NotNil(t, out.Avatar)
+	assert.Equal(t, "me.png", out.Avatar.Filename)
+}
@@ -11,3 +11,13 @@ var ErrMissingBody = errors.New("request body required")
 func IsMissingBodyError(err error) bool {
 	return errors.Is(err, ErrMissingBody)
 }
+
+// ErrMissingSource is returned when a field with explicit source tags is
+// required but none of its sources are present on the request.
+var ErrMissingSource = errors.New("required binding source missing")
+
+// IsMissingSourceError returns true when the given error (or any wrapped error)
+// indicates that a required binding source was absent.
+func IsMissingSourceError(err error) bool {
+	return errors.Is(err, ErrMissingSource)
+}
@@ -1,6 +1,8 @@
 package routing
 
 import (
+	"maps"
+	"net/http"
 	"strings"
 	"time"
 
@@ -42,6 +44,35 @@ type RouteOptions struct {
 	RateLimit      int
 	RateInterval   time.Duration
 	MaxBodyBytes   int64
+	// Uploads bounds multipart uploads read by Files, MultipartReader, and
+	// Bind. Nil applies the default limits.
+	Uploads *UploadLimits
+	// RateLimits are enforced by the rate limiting middleware, including
+	// policies inherited from enclosing RouteGroups.
+	RateLimits []RateLimitPolicy
+	// RateLimitQuotas are the quota tiers a route is limited by. The first
+	// quota matching a request applies alongside RateLimits.
+	RateLimitQuotas []RateLimitQuota
+	// DisableCompression opts the route out of response compression.
+	DisableCompression bool
+	// DisableCSRF exempts the route from the CSRF middleware, such as for
+	// webhooks authenticated by signatures.
+	DisableCSRF bool
+	// DisableHTTPSEnforcement lets the route answer plain HTTP requests, such
+	// as health probes from a load balancer.
+	DisableHTTPSEnforcement bool
+	// Priority orders the route's requests under load shedding.
+	Priority Priority
+	// InFlight bounds the requests served at once by the routes sharing it.
+	// Nil leaves only the load shedder's global limit.
+	InFlight *InFlightLimit
+	// Timeout bounds how long the route may take to commit a response. Zero
+	// disables the route timeout.
+	Timeout time.Duration
+	// SecurityHeaders overrides the headers set by the security headers
+	// middleware, keyed by canonical header name. An empty value omits the
+	// header.
+	SecurityHeaders map[string]string
 
 	// ---- OpenAPI documentation ----
 	openapi.Operation
@@ -187,3 +218,18 @@ func BuildParamIndex(params []*openapi.ParameterObject) map[string]*openapi.Para
 	}
 	return idx
 }
+
+// MergeSecurityHeaders returns a copy of base with overrides applied, keyed
+// by canonical header name. It never modifies base, which groups share with
+// their routes.
+func MergeSecurityHeaders(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	maps.Copy(merged, base)
+	for name, value := range overrides {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	return merged
+}
@@ -0,0 +1,29 @@
+package routing
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type timeoutStatusKey struct{}
+
+// ContextWithTimeoutStatus returns a copy of ctx whose TimeoutStatus is read
+// from status, which the route timeout sets when it answers the request.
+func ContextWithTimeoutStatus(ctx context.Context, status *atomic.Int32) context.Context {
+	return context.WithValue(ctx, timeoutStatusKey{}, status)
+}
+
+// TimeoutStatus returns the status of the timeout response sent for the
+// request, or 0 if its route timeout has not answered it. The timeout
+// response bypasses response writers installed by middleware, so middleware
+// that report the status, such as metrics, check it after next returns.
+func TimeoutStatus(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	status, _ := ctx.Value(timeoutStatusKey{}).(*atomic.Int32)
+	if status == nil {
+		return 0
+	}
+	return int(status.Load())
+}
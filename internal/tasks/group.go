@@ -0,0 +1,142 @@
+// Package tasks runs background work started from request handlers with
+// bounded concurrency, panic recovery, and a graceful drain on shutdown.
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// DefaultLimit is the number of tasks a group runs at once when no limit is
+// configured.
+const DefaultLimit = 64
+
+// DefaultQueueLimit is the number of tasks that may wait for a free slot
+// when no queue limit is configured. Go rejects further tasks with
+// ErrQueueFull.
+const DefaultQueueLimit = 1024
+
+// ErrClosed is returned by Go once the group has started shutting down.
+var ErrClosed = errors.New("task group is closed")
+
+// ErrQueueFull is returned by Go when the queue limit is reached.
+var ErrQueueFull = errors.New("task queue is full")
+
+// Group runs tasks on a context that outlives individual requests and is
+// canceled only when Wait gives up on a shutdown deadline. At most limit
+// goroutines run tasks; waiting tasks are queued without a goroutine each.
+type Group struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	limit      int
+	queueLimit int
+	wg         sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	running int
+	queue   []func(ctx context.Context)
+}
+
+// New returns a group that runs at most limit tasks concurrently; up to
+// queueLimit further tasks wait for a free slot. Zero or less uses
+// DefaultLimit and DefaultQueueLimit.
+func New(limit, queueLimit int) *Group {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if queueLimit <= 0 {
+		queueLimit = DefaultQueueLimit
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel, limit: limit, queueLimit: queueLimit}
+}
+
+// Go schedules fn without blocking the caller. fn receives the group's
+// context, which is canceled if shutdown times out. Panics are recovered and
+// logged. Go returns ErrClosed after Wait has been called and ErrQueueFull
+// when the queue limit is reached.
+func (g *Group) Go(fn func(ctx context.Context)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if g.running == g.limit {
+		if len(g.queue) >= g.queueLimit {
+			return ErrQueueFull
+		}
+		g.wg.Add(1)
+		g.queue = append(g.queue, fn)
+		return nil
+	}
+	g.wg.Add(1)
+	g.running++
+	go g.work(fn)
+	return nil
+}
+
+// work runs fn, then queued tasks until the queue is empty. Queued tasks
+// are dropped once the group's context is canceled.
+func (g *Group) work(fn func(ctx context.Context)) {
+	for fn != nil {
+		g.run(fn)
+		fn = g.next()
+	}
+}
+
+func (g *Group) next() func(ctx context.Context) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.ctx.Err() != nil {
+		for range g.queue {
+			g.wg.Done()
+		}
+		g.queue = nil
+	}
+	if len(g.queue) == 0 {
+		g.running--
+		return nil
+	}
+	fn := g.queue[0]
+	g.queue[0] = nil
+	g.queue = g.queue[1:]
+	return fn
+}
+
+func (g *Group) run(fn func(ctx context.Context)) {
+	defer g.wg.Done()
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(g.ctx, "panic recovered in background task", "error", rec, "stack", string(debug.Stack()))
+		}
+	}()
+	fn(g.ctx)
+}
+
+// Wait stops accepting tasks and blocks until every scheduled task finishes
+// or ctx is done. When ctx ends first, the tasks' context is canceled so they
+// can stop early, and the context error is returned.
+func (g *Group) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		g.cancel()
+		return nil
+	case <-ctx.Done():
+		g.cancel()
+		return fmt.Errorf("waiting for background tasks: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,99 @@
+package tasks
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldBoundConcurrencyGivenLimit(t *testing.T) {
+	// Arrange
+	g := New(2, 0)
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+
+	// Act
+	for range 6 {
+		require.NoError(t, g.Go(func(context.Context) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+		}))
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	err := g.Wait(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestShouldRecoverPanicGivenFailingTask(t *testing.T) {
+	// Arrange
+	g := New(1, 0)
+	var ran atomic.Bool
+
+	// Act
+	require.NoError(t, g.Go(func(context.Context) { panic("boom") }))
+	require.NoError(t, g.Go(func(context.Context) { ran.Store(true) }))
+	err := g.Wait(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, ran.Load(), "a panicking task must not stop later tasks")
+}
+
+func TestShouldCancelTasksGivenShutdownDeadline(t *testing.T) {
+	// Arrange
+	g := New(1, 0)
+	canceled := make(chan struct{})
+	require.NoError(t, g.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := g.Wait(ctx)
+
+	// Assert
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	<-canceled
+	assert.ErrorIs(t, g.Go(func(context.Context) {}), ErrClosed)
+}
+
+func TestShouldRejectTasksGivenFullQueue(t *testing.T) {
+	// Arrange
+	g := New(1, 0)
+	release := make(chan struct{})
+	var ran atomic.Int32
+	require.NoError(t, g.Go(func(context.Context) { <-release }))
+	for range DefaultQueueLimit {
+		require.NoError(t, g.Go(func(context.Context) { ran.Add(1) }))
+	}
+	goroutines := runtime.NumGoroutine()
+
+	// Act
+	err := g.Go(func(context.Context) { ran.Add(1) })
+	close(release)
+	waitErr := g.Wait(context.Background())
+
+	// Assert
+	require.ErrorIs(t, err, ErrQueueFull)
+	require.NoError(t, waitErr)
+	assert.Equal(t, int32(DefaultQueueLimit), ran.Load())
+	assert.Less(t, goroutines, DefaultQueueLimit, "queued tasks must not hold a goroutine each")
+}
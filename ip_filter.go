@@ -0,0 +1,112 @@
+package mux
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	internalipfilter "github.com/fgrzl/mux/internal/middleware/ipfilter"
+)
+
+// IPFilter allows or blocks requests by client address using CIDR allow and
+// deny lists, such as VPN ranges for admin endpoints or abusive networks.
+// Deny ranges take precedence; when allow ranges are set, every other client
+// is blocked with 403 Forbidden. It uses the address UseForwardedHeaders
+// resolves from trusted proxies. Attach it with UseIPFilter,
+// RouteGroup.WithIPFilter or RouteBuilder.WithIPFilter. It is safe for
+// concurrent use.
+type IPFilter struct {
+	inner *internalipfilter.IPFilter
+}
+
+// IPFilterOption configures NewIPFilter.
+type IPFilterOption struct {
+	apply internalipfilter.IPFilterOption
+}
+
+// IPFilterRules are CIDR ranges or single addresses, IPv4 or IPv6. Rules
+// files use the JSON field names.
+type IPFilterRules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// WithIPFilterName names the filter in decision logs, such as "admin".
+func WithIPFilterName(name string) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithName(name)}
+}
+
+// WithIPAllow allows only clients in the given ranges, such as
+// "10.8.0.0/16" or "fd00:8::/32".
+func WithIPAllow(cidrs ...string) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithAllow(cidrs...)}
+}
+
+// WithIPDeny blocks clients in the given ranges.
+func WithIPDeny(cidrs ...string) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithDeny(cidrs...)}
+}
+
+// WithIPFilterRulesFile adds the rules of a JSON file, such as
+// {"allow": ["10.8.0.0/16"], "deny": ["10.8.6.0/24"]}. The file is reloaded
+// on SIGHUP, and a reload that fails keeps the previous rules.
+func WithIPFilterRulesFile(path string) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithRulesFile(path)}
+}
+
+// WithIPFilterReloadSignals replaces SIGHUP as the signals that reload the
+// rules file. Passing none disables reloading on signals.
+func WithIPFilterReloadSignals(signals ...os.Signal) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithReloadSignals(signals...)}
+}
+
+// WithIPFilterReloadInterval reloads the rules file when its modification
+// time changes, checked every d.
+func WithIPFilterReloadInterval(d time.Duration) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithReloadInterval(d)}
+}
+
+// WithIPFilterLogger logs decisions to logger instead of slog.Default:
+// blocked requests at warning level and allowed requests at debug level.
+func WithIPFilterLogger(logger *slog.Logger) IPFilterOption {
+	return IPFilterOption{apply: internalipfilter.WithLogger(logger)}
+}
+
+// NewIPFilter builds a filter. It fails on invalid ranges or an unreadable
+// rules file. Close it to stop reloading the rules file.
+func NewIPFilter(opts ...IPFilterOption) (*IPFilter, error) {
+	internalOpts := make([]internalipfilter.IPFilterOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	f, err := internalipfilter.NewIPFilter(internalOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &IPFilter{inner: f}, nil
+}
+
+// Allowed reports whether the rules allow the client address ip.
+func (f *IPFilter) Allowed(ip string) bool {
+	return f.inner.Decide(ip).Allowed
+}
+
+// Rules returns the active rules, including those of the rules file.
+func (f *IPFilter) Rules() IPFilterRules {
+	r := f.inner.Rules()
+	return IPFilterRules{Allow: slices.Clone(r.Allow), Deny: slices.Clone(r.Deny)}
+}
+
+// Reload reads the rules file again. On error the previous rules stay
+// active.
+func (f *IPFilter) Reload() error {
+	return f.inner.Reload()
+}
+
+// Close stops reloading. The last rules stay active.
+func (f *IPFilter) Close() error {
+	return f.inner.Close()
+}
@@ -0,0 +1,54 @@
+package mux
+
+import (
+	internalloadshed "github.com/fgrzl/mux/internal/middleware/loadshed"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// RequestPriority orders requests waiting for capacity under
+// UseLoadShedding. Higher priorities are admitted first and displace lower
+// priorities from a full queue.
+type RequestPriority int
+
+const (
+	// PriorityLow suits batch and background work that can wait.
+	PriorityLow = RequestPriority(internalrouting.PriorityLow)
+	// PriorityNormal is the default.
+	PriorityNormal = RequestPriority(internalrouting.PriorityNormal)
+	// PriorityHigh suits administrative and control-plane routes.
+	PriorityHigh = RequestPriority(internalrouting.PriorityHigh)
+	// PriorityCritical requests are never queued or shed. The Healthz,
+	// Livez, Readyz, and Startupz probes use it.
+	PriorityCritical = RequestPriority(internalrouting.PriorityCritical)
+)
+
+func (p RequestPriority) String() string {
+	return internalrouting.Priority(p).String()
+}
+
+// LoadSheddingStats is a snapshot of a LoadShedder's state and counters.
+type LoadSheddingStats struct {
+	// Limit is the current global limit, or zero without one.
+	Limit int
+	// InFlight is the number of requests being served.
+	InFlight int
+	// Waiting is the number of requests queued for capacity.
+	Waiting int
+	// Admitted counts requests served.
+	Admitted uint64
+	// Queued counts requests that waited for capacity.
+	Queued uint64
+	// Shed counts requests rejected with 503.
+	Shed uint64
+}
+
+func fromInternalLoadSheddingStats(s internalloadshed.Stats) LoadSheddingStats {
+	return LoadSheddingStats{
+		Limit:    s.Limit,
+		InFlight: s.InFlight,
+		Waiting:  s.Waiting,
+		Admitted: s.Admitted,
+		Queued:   s.Queued,
+		Shed:     s.Shed,
+	}
+}
@@ -0,0 +1,40 @@
+package mux
+
+import (
+	"context"
+
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// Rejection names why middleware refused a request. UseMetrics counts
+// rejections by reason.
+type Rejection string
+
+const (
+	// RejectionRateLimited is a request refused by a rate limit.
+	RejectionRateLimited = Rejection(internalrouting.RejectionRateLimited)
+	// RejectionLoadShed is a request shed by the load shedder.
+	RejectionLoadShed = Rejection(internalrouting.RejectionLoadShed)
+	// RejectionUnauthenticated is a request without valid credentials.
+	RejectionUnauthenticated = Rejection(internalrouting.RejectionUnauthenticated)
+	// RejectionForbidden is an authenticated request lacking the required
+	// roles, scopes, or permissions, or failing a CSRF check.
+	RejectionForbidden = Rejection(internalrouting.RejectionForbidden)
+)
+
+// ReportRejection reports that custom middleware refused the request for
+// reason, so UseMetrics counts it alongside the built-in rejections. ctx is
+// the request's RouteContext or its request context. It does nothing when
+// metrics are not installed.
+func ReportRejection(ctx context.Context, reason Rejection) {
+	internalrouting.ReportRejection(ctx, internalrouting.Rejection(reason))
+}
+
+// TimeoutStatus returns the status of the timeout response sent for the
+// request, or 0 if its route timeout (see RouteBuilder.WithTimeout) has not
+// answered it. The timeout response bypasses response writers installed by
+// middleware, so custom middleware that reports the status checks it after
+// next returns. ctx is the request's RouteContext or its request context.
+func TimeoutStatus(ctx context.Context) int {
+	return internalrouting.TimeoutStatus(ctx)
+}
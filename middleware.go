@@ -2,22 +2,38 @@ package mux
 
 import (
 	"context"
+	"io"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/fgrzl/claims"
+	internalaccesslog "github.com/fgrzl/mux/internal/middleware/accesslog"
 	internalauthentication "github.com/fgrzl/mux/internal/middleware/authentication"
 	internalauthorization "github.com/fgrzl/mux/internal/middleware/authorization"
 	internalcompression "github.com/fgrzl/mux/internal/middleware/compression"
 	internalcors "github.com/fgrzl/mux/internal/middleware/cors"
+	internalcsrf "github.com/fgrzl/mux/internal/middleware/csrf"
+	internaldecompression "github.com/fgrzl/mux/internal/middleware/decompression"
 	internalenforcehttps "github.com/fgrzl/mux/internal/middleware/enforcehttps"
 	internalexportcontrol "github.com/fgrzl/mux/internal/middleware/exportcontrol"
 	internalforwardheaders "github.com/fgrzl/mux/internal/middleware/forwardheaders"
+	internalipfilter "github.com/fgrzl/mux/internal/middleware/ipfilter"
+	internalloadshed "github.com/fgrzl/mux/internal/middleware/loadshed"
 	internallogging "github.com/fgrzl/mux/internal/middleware/logging"
+	internalmetrics "github.com/fgrzl/mux/internal/middleware/metrics"
 	internalopentelemetry "github.com/fgrzl/mux/internal/middleware/opentelemetry"
 	internalratelimit "github.com/fgrzl/mux/internal/middleware/ratelimit"
+	internalrequestid "github.com/fgrzl/mux/internal/middleware/requestid"
+	internalsecurityheaders "github.com/fgrzl/mux/internal/middleware/securityheaders"
 	internalopenapi "github.com/fgrzl/mux/internal/openapi"
 	internalrouting "github.com/fgrzl/mux/internal/routing"
 	"github.com/oschwald/geoip2-golang"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type GeneratorOption struct {
@@ -46,12 +62,170 @@ func NewGenerator(opts ...GeneratorOption) *Generator {
 	return &Generator{inner: internalopenapi.NewGenerator(internalOpts...)}
 }
 
-func UseLogging(rtr *Router) {
-	internallogging.UseLogging(rtr.inner)
+type LoggingOption struct {
+	apply internallogging.LoggingOption
 }
 
-func UseCompression(rtr *Router) {
-	internalcompression.UseCompression(rtr.inner)
+func WithLogger(logger *slog.Logger) LoggingOption {
+	return LoggingOption{apply: internallogging.WithLogger(logger)}
+}
+
+func WithLoggingSkip(fn func(c RouteContext) bool) LoggingOption {
+	if fn == nil {
+		return LoggingOption{}
+	}
+	return LoggingOption{apply: internallogging.WithSkip(func(c internalrouting.RouteContext) bool {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithLoggingSkipPaths(paths ...string) LoggingOption {
+	return LoggingOption{apply: internallogging.WithSkipPaths(paths...)}
+}
+
+func WithLoggingSampling(rate float64) LoggingOption {
+	return LoggingOption{apply: internallogging.WithSampling(rate)}
+}
+
+func WithLoggingSubject() LoggingOption {
+	return LoggingOption{apply: internallogging.WithSubject()}
+}
+
+func WithLoggingClaims(names ...string) LoggingOption {
+	return LoggingOption{apply: internallogging.WithClaims(names...)}
+}
+
+func WithLoggingPathParams() LoggingOption {
+	return LoggingOption{apply: internallogging.WithPathParams()}
+}
+
+func WithLoggingAttributes(fn func(c RouteContext) []slog.Attr) LoggingOption {
+	if fn == nil {
+		return LoggingOption{}
+	}
+	return LoggingOption{apply: internallogging.WithAttributes(func(c internalrouting.RouteContext) []slog.Attr {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithLoggingHeaders(names ...string) LoggingOption {
+	return LoggingOption{apply: internallogging.WithHeaders(names...)}
+}
+
+func WithLoggingRedactedHeaders(names ...string) LoggingOption {
+	return LoggingOption{apply: internallogging.WithRedactedHeaders(names...)}
+}
+
+func WithLoggingQuery() LoggingOption {
+	return LoggingOption{apply: internallogging.WithQuery()}
+}
+
+func WithLoggingRedactedQuery(names ...string) LoggingOption {
+	return LoggingOption{apply: internallogging.WithRedactedQuery(names...)}
+}
+
+func WithLoggingBodyCapture(maxBytes int) LoggingOption {
+	return LoggingOption{apply: internallogging.WithBodyCapture(maxBytes)}
+}
+
+func WithLoggingSlowThreshold(d time.Duration) LoggingOption {
+	return LoggingOption{apply: internallogging.WithSlowThreshold(d)}
+}
+
+func UseLogging(rtr *Router, opts ...LoggingOption) {
+	internalOpts := make([]internallogging.LoggingOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internallogging.UseLogging(rtr.inner, internalOpts...)
+}
+
+type AccessLogOption struct {
+	apply internalaccesslog.AccessLogOption
+}
+
+func WithAccessLogWriter(w io.Writer) AccessLogOption {
+	if f, ok := w.(*RotatingFile); ok && f != nil {
+		return AccessLogOption{apply: internalaccesslog.WithWriter(f.inner)}
+	}
+	return AccessLogOption{apply: internalaccesslog.WithWriter(w)}
+}
+
+func WithAccessLogFormat(format AccessLogFormat) AccessLogOption {
+	return AccessLogOption{apply: internalaccesslog.WithFormat(format.inner)}
+}
+
+func UseAccessLog(rtr *Router, opts ...AccessLogOption) {
+	internalOpts := make([]internalaccesslog.AccessLogOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internalaccesslog.UseAccessLog(rtr.inner, internalOpts...)
+}
+
+type CompressionOption struct {
+	apply internalcompression.CompressionOption
+}
+
+func WithCompressionMinSize(n int) CompressionOption {
+	return CompressionOption{apply: internalcompression.WithMinSize(n)}
+}
+
+func WithCompressionLevel(level int) CompressionOption {
+	return CompressionOption{apply: internalcompression.WithLevel(level)}
+}
+
+func WithCompressionContentTypes(types ...string) CompressionOption {
+	return CompressionOption{apply: internalcompression.WithContentTypes(types...)}
+}
+
+func WithCompressionExcludedContentTypes(types ...string) CompressionOption {
+	return CompressionOption{apply: internalcompression.WithExcludedContentTypes(types...)}
+}
+
+func UseCompression(rtr *Router, opts ...CompressionOption) {
+	internalOpts := make([]internalcompression.CompressionOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internalcompression.UseCompression(rtr.inner, internalOpts...)
+}
+
+// ErrDecompressionLimit is returned while reading a request body decoded by
+// UseDecompression once it exceeds the decompressed-size or ratio limit. It
+// also matches *http.MaxBytesError.
+var ErrDecompressionLimit = internaldecompression.ErrDecompressionLimit
+
+type DecompressionOption struct {
+	apply internaldecompression.DecompressionOption
+}
+
+func WithDecompressionMaxBytes(n int64) DecompressionOption {
+	return DecompressionOption{apply: internaldecompression.WithMaxBytes(n)}
+}
+
+func WithDecompressionMaxRatio(ratio int) DecompressionOption {
+	return DecompressionOption{apply: internaldecompression.WithMaxRatio(ratio)}
+}
+
+func WithDecompressionDecoder(encoding string, decoder func(io.Reader) (io.ReadCloser, error)) DecompressionOption {
+	return DecompressionOption{apply: internaldecompression.WithDecoder(encoding, decoder)}
+}
+
+func UseDecompression(rtr *Router, opts ...DecompressionOption) {
+	internalOpts := make([]internaldecompression.DecompressionOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internaldecompression.UseDecompression(rtr.inner, internalOpts...)
 }
 
 type CORSOption struct {
@@ -130,6 +304,74 @@ func WithTelemetryOperation(operation string) OpenTelemetryOption {
 	return OpenTelemetryOption{apply: internalopentelemetry.WithOperation(operation)}
 }
 
+func WithTelemetryTracerProvider(tp trace.TracerProvider) OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithTracerProvider(tp)}
+}
+
+func WithTelemetryMeterProvider(mp metric.MeterProvider) OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithMeterProvider(mp)}
+}
+
+func WithTelemetryPropagator(p propagation.TextMapPropagator) OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithPropagator(p)}
+}
+
+func WithTelemetrySpanNameFormatter(fn func(c RouteContext) string) OpenTelemetryOption {
+	if fn == nil {
+		return OpenTelemetryOption{}
+	}
+	return OpenTelemetryOption{apply: internalopentelemetry.WithSpanNameFormatter(func(c internalrouting.RouteContext) string {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithTelemetrySkip(fn func(c RouteContext) bool) OpenTelemetryOption {
+	if fn == nil {
+		return OpenTelemetryOption{}
+	}
+	return OpenTelemetryOption{apply: internalopentelemetry.WithSkip(func(c internalrouting.RouteContext) bool {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithTelemetrySkipPaths(paths ...string) OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithSkipPaths(paths...)}
+}
+
+func WithTelemetryPathParams() OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithPathParamAttributes()}
+}
+
+func WithTelemetrySubject() OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithSubjectAttribute()}
+}
+
+func WithTelemetryClaims(names ...string) OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithClaimAttributes(names...)}
+}
+
+func WithTelemetryTenantClaim(name string) OpenTelemetryOption {
+	return OpenTelemetryOption{apply: internalopentelemetry.WithTenantClaim(name)}
+}
+
+func WithTelemetryAttributes(fn func(c RouteContext) []attribute.KeyValue) OpenTelemetryOption {
+	if fn == nil {
+		return OpenTelemetryOption{}
+	}
+	return OpenTelemetryOption{apply: internalopentelemetry.WithAttributes(func(c internalrouting.RouteContext) []attribute.KeyValue {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithTelemetrySpanStatus(fn func(c RouteContext, status int) (codes.Code, string)) OpenTelemetryOption {
+	if fn == nil {
+		return OpenTelemetryOption{}
+	}
+	return OpenTelemetryOption{apply: internalopentelemetry.WithSpanStatus(func(c internalrouting.RouteContext, status int) (codes.Code, string) {
+		return fn(wrapRouteContext(c), status)
+	})}
+}
+
 func UseOpenTelemetry(rtr *Router, opts ...OpenTelemetryOption) {
 	internalOpts := make([]internalopentelemetry.OpenTelemetryOption, 0, len(opts))
 	for _, opt := range opts {
@@ -268,8 +510,181 @@ func UseAuthorization(rtr *Router, opts ...AuthorizationOption) {
 	internalauthorization.UseAuthorization(rtr.inner, internalOpts...)
 }
 
-func UseEnforceHTTPS(rtr *Router) {
-	internalenforcehttps.UseEnforceHTTPS(rtr.inner)
+type SecurityHeadersOption struct {
+	apply internalsecurityheaders.SecurityHeadersOption
+}
+
+func WithSecurityHeader(name, value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithHeader(name, value)}
+}
+
+func WithSecurityHSTS(maxAge time.Duration, includeSubDomains, preload bool) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithHSTS(maxAge, includeSubDomains, preload)}
+}
+
+func WithContentSecurityPolicy(policy string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithContentSecurityPolicy(policy)}
+}
+
+func WithContentSecurityPolicyReportOnly(policy string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithContentSecurityPolicyReportOnly(policy)}
+}
+
+func WithCSPNonce() SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithCSPNonce()}
+}
+
+func WithCSPReportURI(uri string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithCSPReportURI(uri)}
+}
+
+func WithFrameOptions(value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithFrameOptions(value)}
+}
+
+func WithReferrerPolicy(value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithReferrerPolicy(value)}
+}
+
+func WithPermissionsPolicy(value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithPermissionsPolicy(value)}
+}
+
+func WithCrossOriginOpenerPolicy(value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithCrossOriginOpenerPolicy(value)}
+}
+
+func WithCrossOriginEmbedderPolicy(value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithCrossOriginEmbedderPolicy(value)}
+}
+
+func WithCrossOriginResourcePolicy(value string) SecurityHeadersOption {
+	return SecurityHeadersOption{apply: internalsecurityheaders.WithCrossOriginResourcePolicy(value)}
+}
+
+func UseSecurityHeaders(rtr *Router, opts ...SecurityHeadersOption) {
+	internalsecurityheaders.UseSecurityHeaders(rtr.inner, toInternalSecurityHeadersOptions(opts)...)
+}
+
+func toInternalSecurityHeadersOptions(opts []SecurityHeadersOption) []internalsecurityheaders.SecurityHeadersOption {
+	internalOpts := make([]internalsecurityheaders.SecurityHeadersOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	return internalOpts
+}
+
+type CSRFOption struct {
+	apply internalcsrf.CSRFOption
+}
+
+func WithCSRFSecret(key []byte) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithSecret(key)}
+}
+
+func WithCSRFSessionID(fn func(c RouteContext) string) CSRFOption {
+	if fn == nil {
+		return CSRFOption{}
+	}
+	return CSRFOption{apply: internalcsrf.WithSessionID(func(c internalrouting.RouteContext) string {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithCSRFSynchronizerTokens(store CSRFTokenStore) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithSynchronizerTokens(store)}
+}
+
+func WithCSRFOriginCheckOnly() CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithOriginCheckOnly()}
+}
+
+func WithCSRFTrustedOrigins(origins ...string) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithTrustedOrigins(origins...)}
+}
+
+func WithCSRFCookieName(name string) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithCookieName(name)}
+}
+
+func WithCSRFHeaderName(name string) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithHeaderName(name)}
+}
+
+func WithCSRFFieldName(name string) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithFieldName(name)}
+}
+
+func WithCSRFSkip(fn func(c RouteContext) bool) CSRFOption {
+	if fn == nil {
+		return CSRFOption{}
+	}
+	return CSRFOption{apply: internalcsrf.WithSkip(func(c internalrouting.RouteContext) bool {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithCSRFExemptPaths(paths ...string) CSRFOption {
+	return CSRFOption{apply: internalcsrf.WithExemptPaths(paths...)}
+}
+
+func UseCSRF(rtr *Router, opts ...CSRFOption) {
+	internalOpts := make([]internalcsrf.CSRFOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internalcsrf.UseCSRF(rtr.inner, internalOpts...)
+}
+
+type EnforceHTTPSOption struct {
+	apply internalenforcehttps.EnforceHTTPSOption
+}
+
+func WithHTTPSRedirectStatus(status int) EnforceHTTPSOption {
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithRedirectStatus(status)}
+}
+
+func WithHTTPSReject() EnforceHTTPSOption {
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithReject()}
+}
+
+func WithHTTPSHSTS(maxAge time.Duration, includeSubDomains, preload bool) EnforceHTTPSOption {
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithHSTS(maxAge, includeSubDomains, preload)}
+}
+
+func WithHTTPSPortMapping(httpPort, httpsPort int) EnforceHTTPSOption {
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithPortMapping(httpPort, httpsPort)}
+}
+
+func WithHTTPSTrustedProxies(proxies ...string) EnforceHTTPSOption {
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithTrustedProxies(proxies...)}
+}
+
+func WithHTTPSSkip(fn func(c RouteContext) bool) EnforceHTTPSOption {
+	if fn == nil {
+		return EnforceHTTPSOption{}
+	}
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithSkip(func(c internalrouting.RouteContext) bool {
+		return fn(wrapRouteContext(c))
+	})}
+}
+
+func WithHTTPSExemptPaths(paths ...string) EnforceHTTPSOption {
+	return EnforceHTTPSOption{apply: internalenforcehttps.WithExemptPaths(paths...)}
+}
+
+func UseEnforceHTTPS(rtr *Router, opts ...EnforceHTTPSOption) {
+	internalOpts := make([]internalenforcehttps.EnforceHTTPSOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internalenforcehttps.UseEnforceHTTPS(rtr.inner, internalOpts...)
 }
 
 type ExportControlOption struct {
@@ -280,6 +695,40 @@ func WithExportControlGeoIPDatabase(db *geoip2.Reader) ExportControlOption {
 	return ExportControlOption{apply: internalexportcontrol.WithGeoIPDatabase(db)}
 }
 
+func WithExportControlLocator(l ExportControlLocator) ExportControlOption {
+	if l == nil {
+		return ExportControlOption{}
+	}
+	return ExportControlOption{apply: internalexportcontrol.WithLocator(toInternalLocator(l))}
+}
+
+func WithExportControlPolicyFile(f *ExportControlPolicyFile) ExportControlOption {
+	if f == nil {
+		return ExportControlOption{}
+	}
+	return ExportControlOption{apply: internalexportcontrol.WithPolicyFile(f.inner)}
+}
+
+func WithExportControlTrustedProxies(proxies ...string) ExportControlOption {
+	return ExportControlOption{apply: internalexportcontrol.WithTrustedProxies(proxies...)}
+}
+
+func WithExportControlAudit(fn func(ctx context.Context, e ExportControlEvent)) ExportControlOption {
+	if fn == nil {
+		return ExportControlOption{}
+	}
+	return ExportControlOption{apply: internalexportcontrol.WithAudit(exportControlAudit(fn))}
+}
+
+func WithExportControlResponse(fn func(c RouteContext, e ExportControlEvent)) ExportControlOption {
+	if fn == nil {
+		return ExportControlOption{}
+	}
+	return ExportControlOption{apply: internalexportcontrol.WithResponse(func(c internalrouting.RouteContext, e internalexportcontrol.Event) {
+		fn(wrapRouteContext(c), fromInternalExportControlEvent(e))
+	})}
+}
+
 func UseExportControl(rtr *Router, opts ...ExportControlOption) {
 	internalOpts := make([]internalexportcontrol.ExportControlOption, 0, len(opts))
 	for _, opt := range opts {
@@ -290,6 +739,15 @@ func UseExportControl(rtr *Router, opts ...ExportControlOption) {
 	internalexportcontrol.UseExportControl(rtr.inner, internalOpts...)
 }
 
+// UseIPFilter adds f to every route of rtr, ahead of group and route
+// filters. A nil filter is ignored.
+func UseIPFilter(rtr *Router, f *IPFilter) {
+	if f == nil {
+		return
+	}
+	internalipfilter.UseIPFilter(rtr.inner, f.inner)
+}
+
 type RateLimiterOption struct {
 	apply internalratelimit.RateLimiterOption
 }
@@ -298,6 +756,14 @@ func WithRateLimitCleanupInterval(interval time.Duration) RateLimiterOption {
 	return RateLimiterOption{apply: internalratelimit.WithCleanupInterval(interval)}
 }
 
+func WithRateLimitStore(store RateLimitStore) RateLimiterOption {
+	return RateLimiterOption{apply: internalratelimit.WithStore(store)}
+}
+
+func WithRateLimitDefaultKey(key RateLimitKeyFunc) RateLimiterOption {
+	return RateLimiterOption{apply: internalratelimit.WithDefaultKey(key.toInternal())}
+}
+
 type RateLimiter struct {
 	inner *internalratelimit.SelectiveRateLimiter
 }
@@ -345,4 +811,177 @@ func (r *RateLimiter) Invoke(c MutableRouteContext, next HandlerFunc) {
 
 func UseRateLimiter(rtr *Router, opts ...RateLimiterOption) {
 	rtr.Use(NewRateLimiter(opts...))
+	rtr.inner.DocumentOperations(internalratelimit.Document)
+}
+
+type LoadSheddingOption struct {
+	apply internalloadshed.LoadSheddingOption
+}
+
+func WithLoadSheddingMaxInFlight(n int) LoadSheddingOption {
+	return LoadSheddingOption{apply: internalloadshed.WithMaxInFlight(n)}
+}
+
+func WithLoadSheddingQueue(size int, timeout time.Duration) LoadSheddingOption {
+	return LoadSheddingOption{apply: internalloadshed.WithQueue(size, timeout)}
+}
+
+func WithLoadSheddingRetryAfter(d time.Duration) LoadSheddingOption {
+	return LoadSheddingOption{apply: internalloadshed.WithRetryAfter(d)}
+}
+
+func WithLoadSheddingPriorityFunc(fn func(RouteContext) (RequestPriority, bool)) LoadSheddingOption {
+	if fn == nil {
+		return LoadSheddingOption{apply: internalloadshed.WithPriorityFunc(nil)}
+	}
+	return LoadSheddingOption{apply: internalloadshed.WithPriorityFunc(func(c internalrouting.RouteContext) (internalrouting.Priority, bool) {
+		p, ok := fn(wrapRouteContext(c))
+		return internalrouting.Priority(p), ok
+	})}
+}
+
+func WithLoadSheddingAIMD(minLimit, maxLimit int, target time.Duration) LoadSheddingOption {
+	return LoadSheddingOption{apply: internalloadshed.WithAIMD(minLimit, maxLimit, target)}
+}
+
+func WithLoadSheddingGradient(minLimit, maxLimit int) LoadSheddingOption {
+	return LoadSheddingOption{apply: internalloadshed.WithGradient(minLimit, maxLimit)}
+}
+
+type LoadShedder struct {
+	inner *internalloadshed.LoadShedder
+}
+
+func NewLoadShedder(opts ...LoadSheddingOption) *LoadShedder {
+	internalOpts := make([]internalloadshed.LoadSheddingOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	return &LoadShedder{inner: internalloadshed.NewLoadShedder(internalOpts...)}
+}
+
+func (s *LoadShedder) Stats() LoadSheddingStats {
+	if s == nil || s.inner == nil {
+		return LoadSheddingStats{}
+	}
+	return fromInternalLoadSheddingStats(s.inner.Stats())
+}
+
+func (s *LoadShedder) Invoke(c MutableRouteContext, next HandlerFunc) {
+	if s == nil || s.inner == nil {
+		next(c)
+		return
+	}
+	innerCtx := unwrapRouteContext(c)
+	if innerCtx == nil {
+		next(c)
+		return
+	}
+	s.inner.Invoke(innerCtx, func(nextCtx internalrouting.RouteContext) {
+		next(wrapRouteContext(nextCtx))
+	})
+}
+
+func UseLoadShedding(rtr *Router, opts ...LoadSheddingOption) *LoadShedder {
+	shedder := NewLoadShedder(opts...)
+	rtr.Use(shedder)
+	return shedder
+}
+
+type MetricsOption struct {
+	apply internalmetrics.MetricsOption
+}
+
+func WithMetricsMeterProvider(mp metric.MeterProvider) MetricsOption {
+	return MetricsOption{apply: internalmetrics.WithMeterProvider(mp)}
+}
+
+func WithMetricsDurationBuckets(bounds ...float64) MetricsOption {
+	return MetricsOption{apply: internalmetrics.WithDurationBuckets(bounds...)}
+}
+
+func WithMetricsSizeBuckets(bounds ...float64) MetricsOption {
+	return MetricsOption{apply: internalmetrics.WithSizeBuckets(bounds...)}
+}
+
+func WithMetricsLoadShedder(s *LoadShedder) MetricsOption {
+	if s == nil || s.inner == nil {
+		return MetricsOption{}
+	}
+	return MetricsOption{apply: internalmetrics.WithLoadShedder(s.inner)}
+}
+
+type Metrics struct {
+	inner *internalmetrics.Metrics
+}
+
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	internalOpts := make([]internalmetrics.MetricsOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	return &Metrics{inner: internalmetrics.NewMetrics(internalOpts...)}
+}
+
+func (m *Metrics) Invoke(c MutableRouteContext, next HandlerFunc) {
+	if m == nil || m.inner == nil {
+		next(c)
+		return
+	}
+	innerCtx := unwrapRouteContext(c)
+	if innerCtx == nil {
+		next(c)
+		return
+	}
+	m.inner.Invoke(innerCtx, func(nextCtx internalrouting.RouteContext) {
+		next(wrapRouteContext(nextCtx))
+	})
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.inner.ServeHTTP(w, r)
+}
+
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	return m.inner.WritePrometheus(w)
+}
+
+func UseMetrics(rtr *Router, opts ...MetricsOption) *Metrics {
+	metrics := NewMetrics(opts...)
+	rtr.Use(metrics)
+	return metrics
+}
+
+type RequestIDOption struct {
+	apply internalrequestid.RequestIDOption
+}
+
+func WithRequestIDHeader(name string) RequestIDOption {
+	return RequestIDOption{apply: internalrequestid.WithHeader(name)}
+}
+
+func WithRequestIDGenerator(fn func() string) RequestIDOption {
+	return RequestIDOption{apply: internalrequestid.WithGenerator(fn)}
+}
+
+func WithRequestIDValidator(fn func(id string) bool) RequestIDOption {
+	return RequestIDOption{apply: internalrequestid.WithValidator(fn)}
+}
+
+func WithRequestIDTrustIncoming(trust bool) RequestIDOption {
+	return RequestIDOption{apply: internalrequestid.WithTrustIncoming(trust)}
+}
+
+func UseRequestID(rtr *Router, opts ...RequestIDOption) {
+	internalOpts := make([]internalrequestid.RequestIDOption, 0, len(opts))
+	for _, opt := range opts {
+		if opt.apply != nil {
+			internalOpts = append(internalOpts, opt.apply)
+		}
+	}
+	internalrequestid.UseRequestID(rtr.inner, internalOpts...)
 }
@@ -120,9 +120,15 @@ func (ws *WebServer) Listen(ctx context.Context) error {
 	return ws.run(ctx, ln)
 }
 
-// Stop shuts down the HTTP server gracefully using the provided context.
+// Stop shuts down the HTTP server gracefully using the provided context, then
+// waits for background tasks started with RouteContext.Go. Tasks still running
+// when ctx is done have their context canceled.
 func (ws *WebServer) Stop(ctx context.Context) error {
-	return ws.srv.Shutdown(ctx)
+	shutdownErr := ws.srv.Shutdown(ctx)
+	if ws.rtr == nil {
+		return shutdownErr
+	}
+	return errors.Join(shutdownErr, ws.rtr.inner.WaitTasks(ctx))
 }
 
 // --- internals ---
@@ -3,6 +3,8 @@ package mux
 import (
 	"context"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"sync/atomic"
@@ -173,6 +175,57 @@ func TestShouldStopServerGracefully(t *testing.T) {
 	cancel()
 }
 
+func TestShouldWaitForBackgroundTasksWhenStopping(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	var finished atomic.Bool
+	rtr.GET("/jobs", func(c RouteContext) {
+		c.Go(func(RouteContext) {
+			time.Sleep(50 * time.Millisecond)
+			finished.Store(true)
+		})
+		c.Accepted(nil)
+	})
+	server := NewServer(testAddrLocal, rtr)
+	rec := httptest.NewRecorder()
+	rtr.ServeHTTP(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/jobs", nil))
+
+	// Act
+	err := server.Stop(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.True(t, finished.Load())
+}
+
+func TestShouldCancelBackgroundTasksWhenStopDeadlineExpires(t *testing.T) {
+	// Arrange
+	rtr := NewRouter()
+	canceled := make(chan struct{})
+	rtr.GET("/jobs", func(c RouteContext) {
+		c.Go(func(task RouteContext) {
+			<-task.Done()
+			close(canceled)
+		})
+	})
+	server := NewServer(testAddrLocal, rtr)
+	rtr.ServeHTTP(httptest.NewRecorder(), httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/jobs", nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := server.Stop(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("background task context was not canceled")
+	}
+}
+
 func TestShouldReturnFromListenWhenContextIsCancelled(t *testing.T) {
 	// Arrange
 	rtr := NewRouter()
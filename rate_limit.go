@@ -0,0 +1,194 @@
+package mux
+
+import (
+	"context"
+	"time"
+
+	internalratelimit "github.com/fgrzl/mux/internal/middleware/ratelimit"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// RateLimitAlgorithm selects how a RateLimitPolicy counts requests.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket refills Limit tokens per Period into a bucket holding at
+	// most Burst tokens. It is the default.
+	TokenBucket = RateLimitAlgorithm(internalrouting.TokenBucket)
+	// SlidingWindowLog allows Limit requests in any Period by recording each
+	// request time. State grows with Limit, so it suits small limits.
+	SlidingWindowLog = RateLimitAlgorithm(internalrouting.SlidingWindowLog)
+	// GCRA spaces requests Period/Limit apart and allows up to Burst at
+	// once, storing a single timestamp per client.
+	GCRA = RateLimitAlgorithm(internalrouting.GCRA)
+)
+
+func (a RateLimitAlgorithm) String() string {
+	return internalrouting.RateLimitAlgorithm(a).String()
+}
+
+// RateLimitKeyFunc identifies the client a request is counted against. ok is
+// false when the request carries no such identity, in which case the client
+// IP is used.
+type RateLimitKeyFunc func(c RouteContext) (key string, ok bool)
+
+// RateLimitPolicy describes a limit enforced by UseRateLimiter. Attach it
+// with RouteGroup.WithRateLimitPolicy or RouteBuilder.WithRateLimitPolicy.
+type RateLimitPolicy struct {
+	// Name makes routes with the same name share one budget. Empty gives
+	// each route its own budget. A route policy replaces inherited group
+	// policies with the same name.
+	Name string
+	// Limit is the number of requests allowed per Period.
+	Limit int
+	// Period is the window the Limit applies to.
+	Period time.Duration
+	// Burst is the most requests allowed at once by TokenBucket and GCRA.
+	// Zero means Limit.
+	Burst int
+	// Algorithm selects the counting algorithm.
+	Algorithm RateLimitAlgorithm
+	// Key identifies the client. Nil uses the limiter's default key, which
+	// is RateLimitByClientIP unless WithRateLimitDefaultKey says otherwise.
+	Key RateLimitKeyFunc
+}
+
+func (p RateLimitPolicy) toInternal() internalrouting.RateLimitPolicy {
+	return internalrouting.RateLimitPolicy{
+		Name:      p.Name,
+		Limit:     p.Limit,
+		Period:    p.Period,
+		Burst:     p.Burst,
+		Algorithm: internalrouting.RateLimitAlgorithm(p.Algorithm),
+		Key:       p.Key.toInternal(),
+	}
+}
+
+// RateLimitQuota is a named tier of limits enforced together by
+// UseRateLimiter, such as a free plan allowing 100 requests per minute and
+// 10,000 per day. Attach it with RouteGroup.WithRateLimitQuota or
+// RouteBuilder.WithRateLimitQuota; routes carrying the same quota share its
+// budgets.
+type RateLimitQuota struct {
+	// Name identifies the quota in budgets and response headers.
+	Name string
+	// Windows are the limits enforced together. Each window is reported in
+	// the RateLimit headers as "<quota>.<window>", where an unnamed window is
+	// named for its period in seconds, such as "free.60s".
+	Windows []RateLimitPolicy
+	// Match reports whether the quota applies to a request, such as a
+	// request from a client on the plan. Nil matches every request. The
+	// first matching quota on a route applies, so list a catch-all quota
+	// last.
+	Match func(c RouteContext) bool
+}
+
+func (q RateLimitQuota) toInternal() internalrouting.RateLimitQuota {
+	quota := internalrouting.RateLimitQuota{Name: q.Name}
+	for _, window := range q.Windows {
+		quota.Windows = append(quota.Windows, window.toInternal())
+	}
+	if q.Match != nil {
+		match := q.Match
+		quota.Match = func(c internalrouting.RouteContext) bool {
+			return match(wrapRouteContext(c))
+		}
+	}
+	return quota
+}
+
+func (k RateLimitKeyFunc) toInternal() internalrouting.RateLimitKeyFunc {
+	if k == nil {
+		return nil
+	}
+	return func(c internalrouting.RouteContext) (string, bool) {
+		return k(wrapRouteContext(c))
+	}
+}
+
+func fromInternalKeyFunc(k internalrouting.RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(c RouteContext) (string, bool) {
+		inner := unwrapRouteContext(c)
+		if inner == nil {
+			return "", false
+		}
+		return k(inner)
+	}
+}
+
+// RateLimitByClientIP counts requests per client IP. Behind a proxy,
+// register UseForwardedHeaders before UseRateLimiter so the IP comes from
+// trusted forwarding headers.
+func RateLimitByClientIP() RateLimitKeyFunc {
+	return fromInternalKeyFunc(internalratelimit.KeyByClientIP())
+}
+
+// RateLimitBySubject counts requests per authenticated principal.
+func RateLimitBySubject() RateLimitKeyFunc {
+	return fromInternalKeyFunc(internalratelimit.KeyBySubject())
+}
+
+// RateLimitByHeader counts requests per value of the named header, such as
+// an API key.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return fromInternalKeyFunc(internalratelimit.KeyByHeader(name))
+}
+
+// RateLimitByClaim counts requests per value of the principal's named
+// claim, such as a tenant identifier.
+func RateLimitByClaim(name string) RateLimitKeyFunc {
+	return fromInternalKeyFunc(internalratelimit.KeyByClaim(name))
+}
+
+// RateLimitByPathParams counts requests per combination of the named path
+// parameter values.
+func RateLimitByPathParams(names ...string) RateLimitKeyFunc {
+	return fromInternalKeyFunc(internalratelimit.KeyByPathParams(names...))
+}
+
+// RateLimitStore persists rate limit state. Replicas that share a store
+// share budgets. State is opaque to the store; implementations must make
+// CompareAndSwap atomic.
+type RateLimitStore interface {
+	// Get returns the state stored under key and its version. A missing or
+	// expired key returns a nil value and version 0.
+	Get(ctx context.Context, key string) (value []byte, version uint64, err error)
+	// CompareAndSwap stores value under key if the key is still at version,
+	// where version 0 means the key must not exist. The entry expires after
+	// ttl. It reports whether the value was stored.
+	CompareAndSwap(ctx context.Context, key string, version uint64, value []byte, ttl time.Duration) (bool, error)
+}
+
+// NewMemoryRateLimitStore returns an in-process RateLimitStore. It is the
+// default and does not share state across processes.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return internalratelimit.NewMemoryStore()
+}
+
+// MemcachedRateLimitStore is a reference RateLimitStore backed by memcached,
+// using its gets and cas commands for atomic updates.
+type MemcachedRateLimitStore struct {
+	inner *internalratelimit.MemcachedStore
+}
+
+// NewMemcachedRateLimitStore returns a store that talks to the memcached
+// server at addr, such as "localhost:11211". timeout bounds each command;
+// zero means one second.
+func NewMemcachedRateLimitStore(addr string, timeout time.Duration) *MemcachedRateLimitStore {
+	return &MemcachedRateLimitStore{inner: internalratelimit.NewMemcachedStore(addr, timeout)}
+}
+
+// Get implements RateLimitStore.
+func (s *MemcachedRateLimitStore) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	return s.inner.Get(ctx, key)
+}
+
+// CompareAndSwap implements RateLimitStore.
+func (s *MemcachedRateLimitStore) CompareAndSwap(ctx context.Context, key string, version uint64, value []byte, ttl time.Duration) (bool, error) {
+	return s.inner.CompareAndSwap(ctx, key, version, value, ttl)
+}
+
+// Close closes idle connections.
+func (s *MemcachedRateLimitStore) Close() error {
+	return s.inner.Close()
+}
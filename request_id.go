@@ -0,0 +1,29 @@
+package mux
+
+import (
+	"context"
+	"log/slog"
+
+	internalrequestid "github.com/fgrzl/mux/internal/middleware/requestid"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// RequestIDFromContext returns the request ID assigned by UseRequestID to the
+// request ctx belongs to, or "" if there is none. Use it to pass the ID on
+// to downstream services.
+func RequestIDFromContext(ctx context.Context) string {
+	return internalrouting.RequestIDFromContext(ctx)
+}
+
+// NewRequestIDLogHandler wraps inner so every record logged with a request's
+// context, such as slog.InfoContext(c, ...) in a handler, carries a
+// "request_id" attribute:
+//
+//	slog.SetDefault(slog.New(mux.NewRequestIDLogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+//
+// Wrap a concrete handler rather than slog.Default().Handler(): the built-in
+// default handler writes through the log package, which SetDefault routes
+// back into slog.
+func NewRequestIDLogHandler(inner slog.Handler) slog.Handler {
+	return internalrequestid.NewLogHandler(inner)
+}
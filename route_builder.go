@@ -5,6 +5,8 @@ import (
 
 	internalbuilder "github.com/fgrzl/mux/internal/builder"
 	internalcommon "github.com/fgrzl/mux/internal/common"
+	internalsecurityheaders "github.com/fgrzl/mux/internal/middleware/securityheaders"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
 )
 
 // RouteBuilder decorates a registered route with middleware, auth
@@ -83,6 +85,54 @@ func (b *RouteBuilder) WithRateLimit(limit int, interval time.Duration) *RouteBu
 	return b
 }
 
+// WithRateLimitPolicy adds a rate limit policy enforced by UseRateLimiter to
+// this route, replacing an inherited group policy with the same name.
+func (b *RouteBuilder) WithRateLimitPolicy(policy RateLimitPolicy) *RouteBuilder {
+	b.inner.WithRateLimitPolicy(policy.toInternal())
+	return b
+}
+
+// WithRateLimitQuota adds a quota tier enforced by UseRateLimiter to this
+// route, replacing an inherited group quota with the same name.
+func (b *RouteBuilder) WithRateLimitQuota(quota RateLimitQuota) *RouteBuilder {
+	b.inner.WithRateLimitQuota(quota.toInternal())
+	return b
+}
+
+// WithPriority sets how this route's requests are ordered and shed by
+// UseLoadShedding.
+func (b *RouteBuilder) WithPriority(priority RequestPriority) *RouteBuilder {
+	b.inner.WithPriority(internalrouting.Priority(priority))
+	return b
+}
+
+// WithMaxInFlight bounds the requests this route serves at once under
+// UseLoadShedding, replacing any group limit. A value <= 0 leaves the route
+// under the group limit.
+func (b *RouteBuilder) WithMaxInFlight(n int) *RouteBuilder {
+	b.inner.WithMaxInFlight(n)
+	return b
+}
+
+// WithTimeout bounds how long this route may take to respond, overriding the
+// group timeout. The handler's context carries the deadline; if it passes
+// before the handler commits a response, the client gets a 503 problem (see
+// WithTimeoutStatus) and later writes fail with http.ErrHandlerTimeout. The
+// handler runs on its own goroutine so the timeout response is sent on time,
+// but one that ignores its context holds its resources until it returns. A
+// value <= 0 leaves the group timeout in effect.
+func (b *RouteBuilder) WithTimeout(d time.Duration) *RouteBuilder {
+	b.inner.WithTimeout(d)
+	return b
+}
+
+// WithSecurityHeaders overrides the headers UseSecurityHeaders sets on this
+// route, on top of any group overrides. An empty value omits a header.
+func (b *RouteBuilder) WithSecurityHeaders(opts ...SecurityHeadersOption) *RouteBuilder {
+	b.inner.WithSecurityHeaders(internalsecurityheaders.Overrides(toInternalSecurityHeadersOptions(opts)...))
+	return b
+}
+
 // WithMaxBodyBytes overrides the router-wide request-body size limit
 // (mux.WithMaxBodyBytes) for this single route. Bind rejects bodies larger than
 // n with the standard "request body too large" error. Use it for routes that
@@ -93,6 +143,46 @@ func (b *RouteBuilder) WithMaxBodyBytes(n int64) *RouteBuilder {
 	return b
 }
 
+// WithUploadLimits bounds multipart uploads on this route: the size of each
+// part, the total upload size, the number of files, and the allowed sniffed
+// content types. It also selects the sink that stores files for Files and
+// Bind. Exceeding a limit yields ErrUploadTooLarge, which Files reports as a
+// 413 problem.
+func (b *RouteBuilder) WithUploadLimits(limits UploadLimits) *RouteBuilder {
+	b.inner.WithUploadLimits(limits.toInternal())
+	return b
+}
+
+// WithoutCompression opts this route out of UseCompression, for example for
+// responses that are already compressed or must stream byte-for-byte.
+func (b *RouteBuilder) WithoutCompression() *RouteBuilder {
+	b.inner.WithoutCompression()
+	return b
+}
+
+// WithoutCSRF exempts this route from UseCSRF, for example for webhooks that
+// authenticate with a signature instead of a browser session.
+func (b *RouteBuilder) WithoutCSRF() *RouteBuilder {
+	b.inner.WithoutCSRF()
+	return b
+}
+
+// WithoutHTTPSEnforcement lets this route answer plain HTTP requests despite
+// UseEnforceHTTPS, for example health probes sent by a load balancer.
+func (b *RouteBuilder) WithoutHTTPSEnforcement() *RouteBuilder {
+	b.inner.WithoutHTTPSEnforcement()
+	return b
+}
+
+// WithIPFilter blocks requests to this route from clients the filter does
+// not allow, in addition to any group filters.
+func (b *RouteBuilder) WithIPFilter(f *IPFilter) *RouteBuilder {
+	if f != nil {
+		b.inner.Use(f.inner)
+	}
+	return b
+}
+
 // WithOperationID sets a stable, unique OpenAPI operationId for this route.
 // Provide one for every documented route so generators and AI tooling can
 // refer to the operation consistently.
@@ -179,6 +269,14 @@ func (b *RouteBuilder) WithRequiredCookieParam(name, description string, example
 	return b.addRouteParam(name, "cookie", description, example, true)
 }
 
+// WithBinding documents the path, query, header, and cookie parameters and the
+// request body declared by explicit source tags on model's fields, such as
+// `path:"id"`, `query:"page" default:"20"`, or `body:""`.
+func (b *RouteBuilder) WithBinding(model any) *RouteBuilder {
+	b.inner.WithBinding(model)
+	return b
+}
+
 // WithJSONBody documents a required application/json request body using
 // example to infer both schema and example payload. Prefer concrete structs
 // over generic maps when you want stable generated schemas.
@@ -222,6 +320,14 @@ func (b *RouteBuilder) WithMultipartBody(example any) *RouteBuilder {
 	return b
 }
 
+// WithPatchBody documents a PATCH request body accepted by BindPatch: an
+// application/merge-patch+json document shaped like example and an
+// application/json-patch+json operation list.
+func (b *RouteBuilder) WithPatchBody(example any) *RouteBuilder {
+	b.inner.WithPatchBody(example)
+	return b
+}
+
 // WithResponse documents a response for the given status code. When example is
 // nil the response is documented without a body; otherwise the example drives
 // schema inference and example generation. Prefer a named helper when one
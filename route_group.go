@@ -2,9 +2,12 @@ package mux
 
 import (
 	"net/http"
+	"time"
 
 	internalcommon "github.com/fgrzl/mux/internal/common"
+	internalsecurityheaders "github.com/fgrzl/mux/internal/middleware/securityheaders"
 	internalrouter "github.com/fgrzl/mux/internal/router"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
 )
 
 // RouteGroup registers a set of routes that share a path prefix and inherited
@@ -160,6 +163,82 @@ func (g *RouteGroup) Deprecated() *RouteGroup {
 	return g
 }
 
+// WithRateLimitPolicy adds a rate limit policy enforced by UseRateLimiter to
+// every route in the group. Routes and nested groups inherit it unless they
+// add a policy with the same name.
+func (g *RouteGroup) WithRateLimitPolicy(policy RateLimitPolicy) *RouteGroup {
+	g.inner.WithRateLimitPolicy(policy.toInternal())
+	return g
+}
+
+// WithRateLimitQuota adds a quota tier enforced by UseRateLimiter to every
+// route in the group. Add one quota per tier; the first whose Match accepts
+// a request applies.
+func (g *RouteGroup) WithRateLimitQuota(quota RateLimitQuota) *RouteGroup {
+	g.inner.WithRateLimitQuota(quota.toInternal())
+	return g
+}
+
+// WithPriority sets how the group's requests are ordered and shed by
+// UseLoadShedding, such as PriorityHigh for administrative routes.
+func (g *RouteGroup) WithPriority(priority RequestPriority) *RouteGroup {
+	g.inner.WithPriority(internalrouting.Priority(priority))
+	return g
+}
+
+// WithMaxInFlight bounds the requests served at once across the group's
+// routes under UseLoadShedding, so one busy area cannot take all capacity.
+// Nested groups created afterwards share the limit. A value <= 0 removes
+// it.
+func (g *RouteGroup) WithMaxInFlight(n int) *RouteGroup {
+	g.inner.WithMaxInFlight(n)
+	return g
+}
+
+// WithTimeout sets the request timeout of the group's routes, including
+// nested groups created afterwards. Routes can override it with
+// RouteBuilder.WithTimeout. A value <= 0 removes the group timeout.
+func (g *RouteGroup) WithTimeout(d time.Duration) *RouteGroup {
+	g.inner.WithTimeout(d)
+	return g
+}
+
+// WithSecurityHeaders overrides the headers UseSecurityHeaders sets on the
+// group's routes, including nested groups created afterwards, such as a
+// looser Content-Security-Policy for documentation pages. An empty value
+// omits a header. Nonce and report options only apply to
+// UseSecurityHeaders.
+func (g *RouteGroup) WithSecurityHeaders(opts ...SecurityHeadersOption) *RouteGroup {
+	g.inner.WithSecurityHeaders(internalsecurityheaders.Overrides(toInternalSecurityHeadersOptions(opts)...))
+	return g
+}
+
+// WithoutCSRF exempts the group's routes from UseCSRF, including nested
+// groups created afterwards, such as public form endpoints protected by
+// other means or webhook receivers.
+func (g *RouteGroup) WithoutCSRF() *RouteGroup {
+	g.inner.WithoutCSRF()
+	return g
+}
+
+// WithoutHTTPSEnforcement lets the group's routes, including nested groups
+// created afterwards, answer plain HTTP requests despite UseEnforceHTTPS.
+func (g *RouteGroup) WithoutHTTPSEnforcement() *RouteGroup {
+	g.inner.WithoutHTTPSEnforcement()
+	return g
+}
+
+// WithIPFilter blocks requests to the group's routes, including nested
+// groups created afterwards, from clients the filter does not allow. Like
+// Use, it applies to routes registered on the group after the call, so call
+// it before adding routes.
+func (g *RouteGroup) WithIPFilter(f *IPFilter) *RouteGroup {
+	if f != nil {
+		g.inner.Use(f.inner)
+	}
+	return g
+}
+
 // Group creates a nested route group beneath prefix. Child groups inherit the
 // parent prefix, middleware, services, auth requirements, and metadata.
 func (g *RouteGroup) Group(prefix string) *RouteGroup {
@@ -1,6 +1,9 @@
 package mux
 
-import internalrouter "github.com/fgrzl/mux/internal/router"
+import (
+	internalrouter "github.com/fgrzl/mux/internal/router"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
 
 // RouterOption configures router behavior or top-level OpenAPI info metadata.
 type RouterOption struct {
@@ -69,6 +72,111 @@ func WithMaxBodyBytes(n int64) RouterOption {
 	return RouterOption{apply: internalrouter.WithMaxBodyBytes(n)}
 }
 
+// WithMaxBackgroundTasks bounds how many tasks started with RouteContext.Go
+// run at once; further tasks wait for a free slot, up to the limit set with
+// WithMaxQueuedBackgroundTasks. The default is 64.
+func WithMaxBackgroundTasks(n int) RouterOption {
+	return RouterOption{apply: internalrouter.WithMaxBackgroundTasks(n)}
+}
+
+// WithMaxQueuedBackgroundTasks bounds how many tasks started with
+// RouteContext.Go wait for a free slot. Go rejects tasks beyond that with
+// ErrBackgroundQueueFull. The default is 1024.
+func WithMaxQueuedBackgroundTasks(n int) RouterOption {
+	return RouterOption{apply: internalrouter.WithMaxQueuedBackgroundTasks(n)}
+}
+
+// WithTimeoutStatus sets the status answered when a route timeout set with
+// WithTimeout passes before the handler responds: http.StatusServiceUnavailable
+// (the default) or http.StatusGatewayTimeout. Other values are ignored.
+func WithTimeoutStatus(status int) RouterOption {
+	return RouterOption{apply: internalrouter.WithTimeoutStatus(status)}
+}
+
+// PanicHandler responds to a panic recovered while serving a request.
+// recovered is the value passed to panic and stack the stack at the point of
+// the panic. A PanicHandler that panics itself is not recovered, which lets
+// tests re-raise panics.
+type PanicHandler func(c RouteContext, recovered any, stack []byte)
+
+// DefaultPanicHandler logs the panic with its stack and writes a generic 500
+// problem without either. Custom handlers can call it after reporting the
+// panic elsewhere.
+func DefaultPanicHandler(c RouteContext, recovered any, stack []byte) {
+	internalrouter.DefaultPanicHandler(unwrapRouteContext(c), recovered, stack)
+}
+
+// WithPanicHandler replaces the response to panics recovered while serving
+// requests, for example to report them to an error tracker or attach a trace
+// ID to the problem.
+func WithPanicHandler(handler PanicHandler) RouterOption {
+	if handler == nil {
+		return RouterOption{apply: internalrouter.WithPanicHandler(nil)}
+	}
+	return RouterOption{apply: internalrouter.WithPanicHandler(func(c internalrouting.RouteContext, recovered any, stack []byte) {
+		handler(wrapRouteContext(c), recovered, stack)
+	})}
+}
+
+// WithDevelopmentErrors renders recovered panics with their stack and a dump
+// of the request: an HTML page for browsers and a problem with "panic",
+// "stack", and "request" members for API clients. Credentials in request
+// headers are redacted. Without this option, panic responses never include
+// stacks, so enable it only for local development.
+func WithDevelopmentErrors() RouterOption {
+	return RouterOption{apply: internalrouter.WithDevelopmentErrors()}
+}
+
+// ResponseContractMode controls how the router reacts to responses that do not
+// match the responses declared on the route.
+type ResponseContractMode int
+
+const (
+	// ResponseContractOff disables response verification.
+	ResponseContractOff ResponseContractMode = iota
+	// ResponseContractLog reports violations and passes the original response
+	// through. Suited to staging environments.
+	ResponseContractLog
+	// ResponseContractStrict replaces violating responses with a 500 problem,
+	// dropping the headers the handler set. Suited to tests.
+	ResponseContractStrict
+)
+
+// ResponseContractViolation describes a response that did not match the
+// route's declared responses.
+type ResponseContractViolation struct {
+	Method      string
+	Route       string
+	OperationID string
+	Status      int
+	ContentType string
+	Problems    []string
+}
+
+// WithResponseContract buffers responses for routes that declare responses and
+// verifies the status code, Content-Type, and JSON body against them.
+func WithResponseContract(mode ResponseContractMode) RouterOption {
+	return RouterOption{apply: internalrouter.WithResponseContract(internalrouter.ResponseContractMode(mode))}
+}
+
+// WithResponseContractHandler registers a callback that receives every
+// response contract violation, in addition to the structured slog record.
+func WithResponseContractHandler(handler func(ResponseContractViolation)) RouterOption {
+	if handler == nil {
+		return RouterOption{apply: internalrouter.WithResponseContractHandler(nil)}
+	}
+	return RouterOption{apply: internalrouter.WithResponseContractHandler(func(v internalrouter.ResponseContractViolation) {
+		handler(ResponseContractViolation{
+			Method:      v.Method,
+			Route:       v.Route,
+			OperationID: v.OperationID,
+			Status:      v.Status,
+			ContentType: v.ContentType,
+			Problems:    v.Problems,
+		})
+	})}
+}
+
 func toInternalRouterOptions(opts []RouterOption) []internalrouter.RouterOption {
 	if len(opts) == 0 {
 		return nil
@@ -0,0 +1,63 @@
+package mux
+
+import (
+	internalsecurityheaders "github.com/fgrzl/mux/internal/middleware/securityheaders"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// CSPReport is a Content-Security-Policy violation report, normalized from
+// the legacy application/csp-report format and the Reporting API format.
+type CSPReport struct {
+	DocumentURI        string
+	Referrer           string
+	BlockedURI         string
+	EffectiveDirective string
+	ViolatedDirective  string
+	OriginalPolicy     string
+	// Disposition is "enforce" or "report".
+	Disposition string
+	StatusCode  int
+	SourceFile  string
+	Line        int
+	Column      int
+	Sample      string
+	// UserAgent is only present in Reporting API payloads.
+	UserAgent string
+}
+
+func fromInternalCSPReport(r internalsecurityheaders.CSPReport) CSPReport {
+	return CSPReport{
+		DocumentURI:        r.DocumentURI,
+		Referrer:           r.Referrer,
+		BlockedURI:         r.BlockedURI,
+		EffectiveDirective: r.EffectiveDirective,
+		ViolatedDirective:  r.ViolatedDirective,
+		OriginalPolicy:     r.OriginalPolicy,
+		Disposition:        r.Disposition,
+		StatusCode:         r.StatusCode,
+		SourceFile:         r.SourceFile,
+		Line:               r.Line,
+		Column:             r.Column,
+		Sample:             r.Sample,
+		UserAgent:          r.UserAgent,
+	}
+}
+
+// NewCSPReportHandler returns a handler for the endpoint named by
+// WithCSPReportURI. It accepts application/csp-report and
+// application/reports+json bodies up to 64 KiB, calls fn for each
+// Content-Security-Policy violation, and answers 204 No Content. A nil fn
+// logs each report at warning level. Browsers send reports without
+// credentials, so register the route with AllowAnonymous.
+func NewCSPReportHandler(fn func(c RouteContext, report CSPReport)) HandlerFunc {
+	var inner func(internalrouting.RouteContext, internalsecurityheaders.CSPReport)
+	if fn != nil {
+		inner = func(c internalrouting.RouteContext, report internalsecurityheaders.CSPReport) {
+			fn(wrapRouteContext(c), fromInternalCSPReport(report))
+		}
+	}
+	handler := internalsecurityheaders.NewReportHandler(inner)
+	return func(c RouteContext) {
+		handler(unwrapRouteContext(c))
+	}
+}
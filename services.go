@@ -0,0 +1,98 @@
+package mux
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fgrzl/mux/internal/di"
+)
+
+// Lifetime controls how long a service registered with Router.Provide is
+// reused.
+type Lifetime int
+
+const (
+	// Singleton services are created once and shared by every request.
+	Singleton Lifetime = iota
+	// Scoped services are created once per request and disposed when the
+	// request completes.
+	Scoped
+	// Transient services are created every time they are resolved.
+	Transient
+)
+
+// String returns the lowercase name of the lifetime.
+func (l Lifetime) String() string {
+	return di.Lifetime(l).String()
+}
+
+var (
+	// ErrServiceNotRegistered is returned by Resolve when no provider is
+	// registered for the requested type.
+	ErrServiceNotRegistered = di.ErrNotRegistered
+	// ErrMissingDependency is reported by Configure when a provider depends
+	// on a type that has no provider.
+	ErrMissingDependency = di.ErrMissingDependency
+	// ErrDependencyCycle is reported by Configure when providers depend on
+	// each other.
+	ErrDependencyCycle = di.ErrDependencyCycle
+	// ErrLifetimeMismatch is reported by Configure when a singleton depends
+	// on a scoped service, directly or through transient services.
+	ErrLifetimeMismatch = di.ErrLifetimeMismatch
+	// ErrInvalidProvider is reported when a factory has an unsupported
+	// signature or its type is already registered.
+	ErrInvalidProvider = di.ErrInvalidProvider
+)
+
+// Committer is implemented by scoped or transient services that finish a unit
+// of work, such as *sql.Tx. When the request completes, Commit is called if
+// the handler neither panicked nor responded with a 4xx or 5xx status through
+// the RouteContext helpers, and Rollback is called otherwise. Services that
+// implement io.Closer are closed afterwards. Services are disposed in reverse
+// creation order.
+type Committer interface {
+	Commit() error
+	Rollback() error
+}
+
+// Provide registers a typed service factory with the given lifetime. factory
+// must be a function returning T or (T, error); each parameter is resolved
+// from the container, except context.Context, which receives the request
+// context for scoped and transient services and context.Background for
+// singletons. Missing dependencies, cycles, and singletons that depend on
+// scoped services are reported by Configure.
+func (r *Router) Provide(lifetime Lifetime, factory any) *Router {
+	r.inner.Provide(di.Lifetime(lifetime), factory)
+	return r
+}
+
+// Resolve returns the service of type T registered with Router.Provide.
+// Scoped services are created on first use within the request and shared for
+// the rest of it.
+func Resolve[T any](c RouteContext) (T, error) {
+	var zero T
+	t := reflect.TypeFor[T]()
+	inner := unwrapRouteContext(c)
+	if inner == nil {
+		return zero, fmt.Errorf("%w: %s", ErrServiceNotRegistered, t)
+	}
+	v, err := inner.Resolve(t)
+	if err != nil {
+		return zero, err
+	}
+	if v == nil {
+		return zero, nil
+	}
+	return v.(T), nil
+}
+
+// MustResolve is like Resolve but panics when the service cannot be resolved.
+// The router's panic recovery turns this into a 500 response and rolls back
+// the request's scoped services.
+func MustResolve[T any](c RouteContext) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldWriteAccessLogGivenTemplateAndForwardedClient(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	format, err := mux.ParseAccessLogFormat(`%a %{c}a "%r" %>s %b %R`)
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	mux.UseAccessLog(router, mux.WithAccessLogWriter(&buf), mux.WithAccessLogFormat(format))
+	mux.UseForwardedHeaders(router, mux.WithForwardedTrustAll())
+	router.GET("/orders/{id}", func(c mux.RouteContext) { c.OK("ok") })
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/7", nil)
+	req.RemoteAddr = "10.0.0.1:443"
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	assert.Equal(t, "203.0.113.5 10.0.0.1 \"GET /orders/7 HTTP/1.1\" 200 4 /orders/{id}\n", buf.String())
+}
+
+func TestShouldWriteW3CHeaderPerFileGivenRotatingFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "access.log")
+	file, err := mux.NewRotatingFile(path, mux.WithRotateReopenSignals())
+	require.NoError(t, err)
+	defer file.Close()
+	router := mux.NewRouter()
+	mux.UseAccessLog(router,
+		mux.WithAccessLogWriter(file),
+		mux.WithAccessLogFormat(mux.W3CAccessLogFormat("cs-method", "cs-uri-stem", "sc-status")),
+	)
+	router.GET("/", func(c mux.RouteContext) { c.NoContent() })
+	serve := func() {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Act
+	serve()
+	require.NoError(t, file.Rotate())
+	serve()
+
+	// Assert
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "#Version: 1.0", lines[0])
+	assert.Equal(t, "#Fields: cs-method cs-uri-stem sc-status", lines[2])
+	assert.Equal(t, "GET / 204", lines[3])
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindSourceWidget struct {
+	Name string `json:"name"`
+}
+
+type bindSourceRequest struct {
+	ID     int              `path:"id"`
+	Limit  int              `query:"limit" default:"20"`
+	Tenant string           `header:"X-Tenant" required:"true"`
+	Body   bindSourceWidget `body:""`
+}
+
+func TestShouldBindTaggedSourcesGivenPublicRouter(t *testing.T) {
+	// Arrange
+	var got bindSourceRequest
+	var bindErr error
+	router := mux.NewRouter()
+	router.PUT("/widgets/{id}", func(c mux.RouteContext) {
+		bindErr = c.Bind(&got)
+		c.NoContent()
+	}).WithBinding(bindSourceRequest{})
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/widgets/5?id=9", strings.NewReader(`{"name":"gear"}`))
+	req.Header.Set(mux.HeaderContentType, mux.MimeJSON)
+	req.Header.Set("X-Tenant", "acme")
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	require.NoError(t, bindErr)
+	assert.Equal(t, bindSourceRequest{ID: 5, Limit: 20, Tenant: "acme", Body: bindSourceWidget{Name: "gear"}}, got)
+}
+
+func TestShouldReturnMissingSourceGivenRequiredHeaderAbsent(t *testing.T) {
+	// Arrange
+	var bindErr error
+	router := mux.NewRouter()
+	router.GET("/widgets/{id}", func(c mux.RouteContext) {
+		var in struct {
+			ID     int    `path:"id"`
+			Tenant string `header:"X-Tenant" required:"true"`
+		}
+		bindErr = c.Bind(&in)
+		c.NoContent()
+	})
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/widgets/5", nil)
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	assert.True(t, errors.Is(bindErr, mux.ErrMissingSource))
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var csrfPage = template.Must(template.New("page").
+	Funcs(mux.CSRFTemplateFuncs(nil)).
+	Parse(`<form method="post">{{ csrfField }}</form>`))
+
+func TestShouldProtectFormPostGivenUseCSRF(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseCSRF(router, mux.WithCSRFSecret([]byte("test-secret")))
+	router.GET("/contact", func(c mux.RouteContext) {
+		var out strings.Builder
+		require.NoError(t, template.Must(csrfPage.Clone()).Funcs(mux.CSRFTemplateFuncs(c)).Execute(&out, nil))
+		c.HTML(http.StatusOK, out.String())
+	})
+	router.POST("/contact", func(c mux.RouteContext) { c.NoContent() })
+
+	page := httptest.NewRecorder()
+	router.ServeHTTP(page, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/contact", nil))
+	cookies := page.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Contains(t, page.Body.String(), `value="`+cookies[0].Value+`"`)
+
+	submit := func(token, origin string) int {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/contact",
+			strings.NewReader(url.Values{"csrf_token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", origin)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Act
+	valid := submit(cookies[0].Value, "http://example.com")
+	missing := submit("", "http://example.com")
+	crossOrigin := submit(cookies[0].Value, "https://evil.example")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, valid)
+	assert.Equal(t, http.StatusForbidden, missing)
+	assert.Equal(t, http.StatusForbidden, crossOrigin)
+}
+
+func TestShouldBindSynchronizerTokenToSessionGivenStore(t *testing.T) {
+	// Arrange
+	store := mux.NewMemoryCSRFTokenStore()
+	router := mux.NewRouter()
+	mux.UseCSRF(router,
+		mux.WithCSRFSynchronizerTokens(store),
+		mux.WithCSRFSessionID(func(c mux.RouteContext) string { return c.Request().Header.Get("X-Session") }),
+	)
+	router.GET("/token", func(c mux.RouteContext) { c.OK(mux.CSRFToken(c)) })
+	router.POST("/transfer", func(c mux.RouteContext) { c.NoContent() })
+	router.POST("/webhooks/billing", func(c mux.RouteContext) { c.NoContent() }).WithoutCSRF()
+
+	tokenReq := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/token", nil)
+	tokenReq.Header.Set("X-Session", "alice")
+	tokenRec := httptest.NewRecorder()
+	router.ServeHTTP(tokenRec, tokenReq)
+	token, ok, err := store.Get(context.Background(), "alice")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	transfer := func(session string) int {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/transfer", nil)
+		req.Header.Set("X-Session", session)
+		req.Header.Set("X-CSRF-Token", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	webhook := httptest.NewRecorder()
+
+	// Act
+	own := transfer("alice")
+	other := transfer("mallory")
+	router.ServeHTTP(webhook, httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/webhooks/billing", nil))
+
+	// Assert
+	assert.Contains(t, tokenRec.Body.String(), token)
+	assert.Empty(t, tokenRec.Result().Cookies())
+	assert.Equal(t, http.StatusNoContent, own)
+	assert.Equal(t, http.StatusForbidden, other)
+	assert.Equal(t, http.StatusNoContent, webhook.Code)
+}
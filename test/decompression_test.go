@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipJSON(t *testing.T, body string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return &buf
+}
+
+func postGzipJSON(t *testing.T, router *mux.Router, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, gzipJSON(t, body))
+	req.Header.Set(mux.HeaderContentType, mux.MimeJSON)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldBindGzipBodyGivenDecompression(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseDecompression(router)
+	router.POST("/readings", maxBodyEcho).WithJSONBody(maxBodyPayload{})
+
+	// Act
+	rec := postGzipJSON(t, router, "/readings", `{"data":"sensor-7"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"len":8}`, rec.Body.String())
+}
+
+func TestShouldApplyRouteBodyLimitToDecodedBodyGivenDecompression(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseDecompression(router, mux.WithDecompressionMaxRatio(0))
+	router.POST("/readings", maxBodyEcho).WithJSONBody(maxBodyPayload{}).WithMaxBodyBytes(1 << 10)
+
+	// Act
+	rec := postGzipJSON(t, router, "/readings", jsonBodyOfSize(t, 2<<10))
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
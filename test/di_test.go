@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type diStore struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (s *diStore) append(entry string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.log = append(s.log, entry)
+}
+
+func (s *diStore) entries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.log)
+}
+
+type diTx struct {
+	id    int
+	store *diStore
+}
+
+func (tx *diTx) Commit() error   { tx.store.append("commit"); return nil }
+func (tx *diTx) Rollback() error { tx.store.append("rollback"); return nil }
+
+type diRepo struct{ tx *diTx }
+
+func newDIRouter(t *testing.T, opts ...mux.RouterOption) (*mux.Router, *diStore) {
+	t.Helper()
+	store := &diStore{}
+	next := 0
+	router := mux.NewRouter(opts...)
+	err := router.Configure(func(r *mux.Router) {
+		r.Provide(mux.Singleton, func() *diStore { return store })
+		r.Provide(mux.Scoped, func(s *diStore) *diTx {
+			next++
+			return &diTx{id: next, store: s}
+		})
+		r.Provide(mux.Transient, func(tx *diTx) *diRepo { return &diRepo{tx: tx} })
+		r.GET("/ok", func(c mux.RouteContext) {
+			a := mux.MustResolve[*diRepo](c)
+			b := mux.MustResolve[*diRepo](c)
+			c.OK(map[string]any{"sameTx": a.tx == b.tx, "sameRepo": a == b, "tx": a.tx.id})
+		})
+		r.GET("/fail", func(c mux.RouteContext) {
+			mux.MustResolve[*diTx](c)
+			c.BadRequest("Bad Request", "rejected")
+		})
+		r.GET("/panic", func(c mux.RouteContext) {
+			mux.MustResolve[*diTx](c)
+			panic("boom")
+		})
+		r.GET("/raw", func(c mux.RouteContext) {
+			mux.MustResolve[*diTx](c)
+			c.Response().WriteHeader(http.StatusBadGateway)
+		})
+		r.GET("/slow", func(c mux.RouteContext) {
+			mux.MustResolve[*diTx](c)
+			<-c.Done()
+		}).WithTimeout(10 * time.Millisecond)
+	})
+	require.NoError(t, err)
+	return router, store
+}
+
+func serveDI(router *mux.Router, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil))
+	return rec
+}
+
+func TestShouldShareScopedServicesWithinRequestGivenProviders(t *testing.T) {
+	// Arrange
+	router, store := newDIRouter(t)
+
+	// Act
+	first := serveDI(router, "/ok")
+	second := serveDI(router, "/ok")
+
+	// Assert
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.JSONEq(t, `{"sameTx":true,"sameRepo":false,"tx":1}`, first.Body.String())
+	assert.JSONEq(t, `{"sameTx":true,"sameRepo":false,"tx":2}`, second.Body.String())
+	assert.Equal(t, []string{"commit", "commit"}, store.entries())
+}
+
+func TestShouldRollBackScopedServicesGivenFailedRequest(t *testing.T) {
+	for _, opts := range [][]mux.RouterOption{nil, {mux.WithContextPooling()}} {
+		// Arrange
+		router, store := newDIRouter(t, opts...)
+
+		// Act
+		bad := serveDI(router, "/fail")
+		crashed := serveDI(router, "/panic")
+		raw := serveDI(router, "/raw")
+		slow := serveDI(router, "/slow")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, bad.Code)
+		assert.Equal(t, http.StatusInternalServerError, crashed.Code)
+		assert.Equal(t, http.StatusBadGateway, raw.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, slow.Code)
+		// The timed-out handler's scope closes when the handler returns,
+		// which may be after ServeHTTP.
+		assert.Eventually(t, func() bool { return len(store.entries()) == 4 }, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"rollback", "rollback", "rollback", "rollback"}, store.entries())
+	}
+}
+
+func TestShouldReturnConfigureErrorGivenInvalidServiceGraph(t *testing.T) {
+	// Arrange
+	type clock struct{}
+	type cache struct{}
+	router := mux.NewRouter()
+
+	// Act
+	err := router.Configure(func(r *mux.Router) {
+		r.Provide(mux.Singleton, func(*clock) *cache { return &cache{} })
+		r.Provide(mux.Scoped, func(*cache) *diRepo { return nil })
+		r.Provide(mux.Scoped, "not a factory")
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, mux.ErrMissingDependency)
+	assert.ErrorIs(t, err, mux.ErrInvalidProvider)
+}
+
+func TestShouldReturnLifetimeMismatchGivenSingletonCapturingScopedThroughTransient(t *testing.T) {
+	// Arrange
+	type service struct{ repo *diRepo }
+	router := mux.NewRouter()
+
+	// Act
+	err := router.Configure(func(r *mux.Router) {
+		r.Provide(mux.Singleton, func() *diStore { return &diStore{} })
+		r.Provide(mux.Scoped, func(s *diStore) *diTx { return &diTx{store: s} })
+		r.Provide(mux.Transient, func(tx *diTx) *diRepo { return &diRepo{tx: tx} })
+		r.Provide(mux.Singleton, func(repo *diRepo) *service { return &service{repo: repo} })
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, mux.ErrLifetimeMismatch)
+	assert.ErrorContains(t, err, "through transient *test.diRepo")
+}
+
+func TestShouldReturnNotRegisteredGivenUnknownService(t *testing.T) {
+	// Arrange
+	c := mux.NewRouteContext(httptest.NewRecorder(), httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil))
+
+	// Act
+	_, err := mux.Resolve[*diStore](c)
+
+	// Assert
+	assert.ErrorIs(t, err, mux.ErrServiceNotRegistered)
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldEnforceHTTPSWithOptionsGivenUseEnforceHTTPS(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseForwardedHeaders(router, mux.WithForwardedTrustedProxies("10.0.0.0/8"))
+	mux.UseEnforceHTTPS(router,
+		mux.WithHTTPSRedirectStatus(http.StatusPermanentRedirect),
+		mux.WithHTTPSHSTS(365*24*time.Hour, true, true),
+		mux.WithHTTPSPortMapping(8080, 8443),
+	)
+	router.Healthz().WithoutHTTPSEnforcement()
+	router.POST("/orders", func(c mux.RouteContext) { c.NoContent() })
+
+	plain := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "http://shop.example:8080/orders", nil)
+	spoofed := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "http://shop.example/orders", nil)
+	spoofed.RemoteAddr = "203.0.113.7:1234"
+	spoofed.Header.Set("X-Forwarded-Proto", "https")
+	proxied := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "http://shop.example/orders", nil)
+	proxied.RemoteAddr = "10.0.0.5:1234"
+	proxied.Header.Set("X-Forwarded-Proto", "https")
+	secure := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "https://shop.example/orders", nil)
+	secure.TLS = &tls.ConnectionState{}
+	probe := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "http://shop.example/healthz", nil)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	plainRec := serve(plain)
+	spoofedRec := serve(spoofed)
+	proxiedRec := serve(proxied)
+	secureRec := serve(secure)
+	probeRec := serve(probe)
+
+	// Assert
+	assert.Equal(t, http.StatusPermanentRedirect, plainRec.Code)
+	assert.Equal(t, "https://shop.example:8443/orders", plainRec.Header().Get("Location"))
+	assert.Equal(t, http.StatusPermanentRedirect, spoofedRec.Code)
+	assert.Equal(t, http.StatusNoContent, proxiedRec.Code)
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", secureRec.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, http.StatusOK, probeRec.Code)
+}
+
+func TestShouldRejectPlainHTTPGivenWithHTTPSReject(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseEnforceHTTPS(router, mux.WithHTTPSReject(), mux.WithHTTPSExemptPaths("/public/*"))
+	router.GET("/api/orders", func(c mux.RouteContext) { c.NoContent() })
+	router.GET("/public/logo", func(c mux.RouteContext) { c.NoContent() })
+
+	api := httptest.NewRecorder()
+	public := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(api, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com/api/orders", nil))
+	router.ServeHTTP(public, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "http://example.com/public/logo", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, api.Code)
+	assert.Contains(t, api.Header().Get("Content-Type"), "application/problem+json")
+	assert.Equal(t, http.StatusNoContent, public.Code)
+}
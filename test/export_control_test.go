@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportControlLocator = mux.ExportControlLocatorFunc(func(ip net.IP) (mux.ExportControlLocation, error) {
+	switch ip.String() {
+	case "198.51.100.1":
+		return mux.ExportControlLocation{Country: "UA", Subdivisions: []string{"UA-43"}}, nil
+	case "198.51.100.2":
+		return mux.ExportControlLocation{Country: "DE"}, nil
+	}
+	return mux.ExportControlLocation{}, errors.New("address not found")
+})
+
+func serveExportControl(router *mux.Router, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/report", nil)
+	req.RemoteAddr = remoteAddr + ":1234"
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldBlockSubdivisionAndAuditGivenExportControlPolicy(t *testing.T) {
+	// Arrange
+	var events []mux.ExportControlEvent
+	router := mux.NewRouter()
+	mux.UseExportControl(router,
+		mux.WithExportControlLocator(exportControlLocator),
+		mux.WithExportControlPolicy(mux.ExportControlPolicy{Subdivisions: []string{"UA-43"}}),
+		mux.WithExportControlTrustedProxies("10.0.0.0/8"),
+		mux.WithExportControlAudit(func(_ context.Context, e mux.ExportControlEvent) { events = append(events, e) }),
+		mux.WithExportControlResponse(func(c mux.RouteContext, e mux.ExportControlEvent) {
+			c.Response().WriteHeader(http.StatusUnavailableForLegalReasons)
+		}),
+	)
+	router.GET("/report", func(c mux.RouteContext) { c.NoContent() })
+
+	// Act
+	proxied := serveExportControl(router, "10.0.0.2", "198.51.100.1")
+	untrustedProxy := serveExportControl(router, "192.168.0.2", "198.51.100.2")
+	spoofed := serveExportControl(router, "198.51.100.1", "198.51.100.2")
+	allowed := serveExportControl(router, "198.51.100.2", "")
+
+	// Assert
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, proxied.Code)
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, spoofed.Code)
+	assert.Equal(t, http.StatusNoContent, allowed.Code)
+	assert.Equal(t, http.StatusNoContent, untrustedProxy.Code, "an untrusted proxy's clients cannot be located, and the policy fails open")
+	require.Len(t, events, 2)
+	assert.Equal(t, mux.ExportControlDenied, events[0].Reason)
+	assert.Equal(t, "UA-43", events[0].Rule)
+	assert.Equal(t, "198.51.100.1", events[0].ClientIP)
+}
+
+func TestShouldApplyReloadedPolicyGivenExportControlPolicyFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "export-policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"mode":"allow","countries":["DE"],"failClosed":true}`), 0o600))
+	file, err := mux.OpenExportControlPolicyFile(path, mux.WithExportControlReloadSignals())
+	require.NoError(t, err)
+	defer file.Close()
+	router := mux.NewRouter()
+	mux.UseExportControl(router,
+		mux.WithExportControlLocator(exportControlLocator),
+		mux.WithExportControlPolicyFile(file),
+	)
+	router.GET("/report", func(c mux.RouteContext) { c.NoContent() })
+	before := serveExportControl(router, "198.51.100.2", "")
+	unknown := serveExportControl(router, "203.0.113.9", "")
+
+	// Act
+	require.NoError(t, os.WriteFile(path, []byte(`{"mode":"allow","countries":["US"]}`), 0o600))
+	require.NoError(t, file.Reload())
+	after := serveExportControl(router, "198.51.100.2", "")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, before.Code)
+	assert.Equal(t, http.StatusForbidden, unknown.Code)
+	assert.Equal(t, http.StatusForbidden, after.Code)
+	assert.Equal(t, mux.ExportControlAllow, file.Policy().Mode)
+	assert.Contains(t, mux.DefaultExportControlPolicy().Countries, "IR")
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveFrom(router *mux.Router, path, remoteAddr string, headers map[string]string) int {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestShouldRestrictAdminGroupToVPNGivenIPFilter(t *testing.T) {
+	// Arrange
+	vpn, err := mux.NewIPFilter(mux.WithIPFilterName("admin"), mux.WithIPAllow("10.8.0.0/16", "fd00:8::/32"))
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	mux.UseForwardedHeaders(router, mux.WithForwardedTrustedProxies("10.0.0.0/24"))
+	router.GET("/status", func(c mux.RouteContext) { c.NoContent() })
+	admin := router.Group("/admin").WithIPFilter(vpn)
+	admin.GET("/users", func(c mux.RouteContext) { c.NoContent() })
+
+	// Act
+	fromVPN := serveFrom(router, "/admin/users", "[fd00:8::5]:443", nil)
+	viaProxy := serveFrom(router, "/admin/users", "10.0.0.2:443", map[string]string{"X-Forwarded-For": "10.8.4.4"})
+	outside := serveFrom(router, "/admin/users", "203.0.113.9:443", map[string]string{"X-Forwarded-For": "10.8.4.4"})
+	public := serveFrom(router, "/status", "203.0.113.9:443", nil)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, fromVPN)
+	assert.Equal(t, http.StatusNoContent, viaProxy)
+	assert.Equal(t, http.StatusForbidden, outside)
+	assert.Equal(t, http.StatusNoContent, public)
+}
+
+func TestShouldBlockReloadedDenyRangeGivenRouteIPFilter(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "blocked.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["198.51.100.0/24"]}`), 0o600))
+	blocked, err := mux.NewIPFilter(mux.WithIPFilterRulesFile(path), mux.WithIPFilterReloadSignals())
+	require.NoError(t, err)
+	defer blocked.Close()
+	router := mux.NewRouter()
+	router.POST("/comments", func(c mux.RouteContext) { c.NoContent() }).WithIPFilter(blocked)
+	post := func(remoteAddr string) int {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/comments", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	before := post("203.0.113.7:1234")
+
+	// Act
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["198.51.100.0/24","203.0.113.0/24"]}`), 0o600))
+	require.NoError(t, blocked.Reload())
+	after := post("203.0.113.7:1234")
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, before)
+	assert.Equal(t, http.StatusForbidden, after)
+	assert.Equal(t, http.StatusForbidden, post("198.51.100.1:1234"))
+	assert.False(t, blocked.Allowed("203.0.113.7"))
+	assert.Equal(t, []string{"198.51.100.0/24", "203.0.113.0/24"}, blocked.Rules().Deny)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getPath(router *mux.Router, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldShedAndServeProbesGivenSaturatedLoadShedder(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	shedder := mux.UseLoadShedding(router,
+		mux.WithLoadSheddingMaxInFlight(1),
+		mux.WithLoadSheddingQueue(0, 0),
+		mux.WithLoadSheddingRetryAfter(2*time.Second),
+	)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router.GET("/slow", func(c mux.RouteContext) {
+		close(entered)
+		<-release
+		c.OK("slow")
+	})
+	router.GET("/fast", func(c mux.RouteContext) { c.OK("fast") })
+	router.Healthz()
+	held := make(chan *httptest.ResponseRecorder, 1)
+	go func() { held <- getPath(router, "/slow") }()
+	<-entered
+
+	// Act
+	shed := getPath(router, "/fast")
+	probe := getPath(router, "/healthz")
+	close(release)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, shed.Code)
+	assert.Equal(t, "2", shed.Header().Get(mux.HeaderRetryAfter))
+	assert.Equal(t, http.StatusOK, probe.Code)
+	assert.Equal(t, http.StatusOK, (<-held).Code)
+	stats := shedder.Stats()
+	assert.Equal(t, 1, stats.Limit)
+	assert.Equal(t, uint64(1), stats.Shed)
+	assert.Equal(t, uint64(2), stats.Admitted)
+}
+
+func TestShouldLimitGroupGivenMaxInFlight(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	shedder := mux.UseLoadShedding(router, mux.WithLoadSheddingQueue(0, 0))
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	reports := router.Group("/reports").WithMaxInFlight(1).WithPriority(mux.PriorityLow)
+	reports.GET("/slow", func(c mux.RouteContext) {
+		close(entered)
+		<-release
+		c.OK("slow")
+	})
+	reports.GET("/fast", func(c mux.RouteContext) { c.OK("fast") })
+	router.GET("/admin", func(c mux.RouteContext) { c.OK("admin") }).WithPriority(mux.PriorityHigh)
+	held := make(chan *httptest.ResponseRecorder, 1)
+	go func() { held <- getPath(router, "/reports/slow") }()
+	<-entered
+
+	// Act
+	sameGroup := getPath(router, "/reports/fast")
+	other := getPath(router, "/admin")
+	close(release)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, sameGroup.Code)
+	assert.Equal(t, http.StatusOK, other.Code)
+	require.Equal(t, http.StatusOK, (<-held).Code)
+	assert.Equal(t, uint64(1), shedder.Stats().Shed)
+	assert.Equal(t, "low", mux.PriorityLow.String())
+}
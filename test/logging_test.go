@@ -0,0 +1,53 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldLogWithConfiguredOptionsGivenUseLogging(t *testing.T) {
+	// Arrange
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	router := mux.NewRouter()
+	mux.UseLogging(router,
+		mux.WithLogger(logger),
+		mux.WithLoggingSkipPaths("/healthz"),
+		mux.WithLoggingPathParams(),
+		mux.WithLoggingHeaders(mux.HeaderAuthorization),
+		mux.WithLoggingQuery(),
+		mux.WithLoggingAttributes(func(c mux.RouteContext) []slog.Attr {
+			return []slog.Attr{slog.String("handler", "orders")}
+		}),
+	)
+	router.GET("/healthz", func(c mux.RouteContext) { c.NoContent() })
+	router.GET("/orders/{id}", func(c mux.RouteContext) { c.NoContent() })
+
+	// Act
+	for _, target := range []string{"/healthz", "/orders/7?token=abc&expand=lines"} {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, target, nil)
+		req.Header.Set(mux.HeaderAuthorization, "Bearer abc")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Assert
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 1)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "/orders/{id}", record["route"])
+	assert.Equal(t, map[string]any{"id": "7"}, record["params"])
+	assert.Equal(t, map[string]any{mux.HeaderAuthorization: "[redacted]"}, record["headers"])
+	assert.Equal(t, "expand=lines&token=%5Bredacted%5D", record["query"])
+	assert.Equal(t, "orders", record["handler"])
+}
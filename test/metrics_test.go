@@ -0,0 +1,48 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+type apiKeyMiddleware struct{}
+
+func (apiKeyMiddleware) Invoke(c mux.MutableRouteContext, next mux.HandlerFunc) {
+	if c.Request().Header.Get("X-Api-Key") == "" {
+		mux.ReportRejection(c, mux.RejectionUnauthenticated)
+		c.Unauthorized()
+		return
+	}
+	next(c)
+}
+
+func TestShouldServePrometheusMetricsGivenUseMetrics(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	metrics := mux.UseMetrics(router, mux.WithMetricsMeterProvider(noop.NewMeterProvider()))
+	router.Use(apiKeyMiddleware{})
+	router.GET("/items/{id}", func(c mux.RouteContext) { c.NoContent() })
+	admin := http.NewServeMux()
+	admin.Handle("/metrics", metrics)
+
+	// Act
+	for _, key := range []string{"secret", ""} {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/items/9", nil)
+		req.Header.Set("X-Api-Key", key)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/metrics", nil))
+
+	// Assert
+	body := rec.Body.String()
+	assert.Contains(t, body, `http_server_request_duration_seconds_count{method="GET",route="/items/{id}",status="204"} 1`)
+	assert.Contains(t, body, `http_server_request_duration_seconds_count{method="GET",route="/items/{id}",status="401"} 1`)
+	assert.Contains(t, body, `http_server_rejected_requests_total{reason="unauthenticated",route="/items/{id}"} 1`)
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp, recorder
+}
+
+func TestShouldTraceWithConfiguredProviderGivenTelemetryOptions(t *testing.T) {
+	// Arrange
+	tp, recorder := newTestTracerProvider(t)
+	router := mux.NewRouter()
+	mux.UseOpenTelemetry(router,
+		mux.WithTelemetryTracerProvider(tp),
+		mux.WithTelemetrySkipPaths("/healthz"),
+		mux.WithTelemetryPathParams(),
+		mux.WithTelemetrySpanNameFormatter(func(c mux.RouteContext) string {
+			return "orders.get"
+		}),
+		mux.WithTelemetryAttributes(func(c mux.RouteContext) []attribute.KeyValue {
+			return []attribute.KeyValue{attribute.String("app.tier", "gold")}
+		}),
+	)
+	router.GET("/healthz", func(c mux.RouteContext) { c.NoContent() })
+	router.GET("/orders/{id}", func(c mux.RouteContext) { c.OK("ok") })
+
+	// Act
+	for _, path := range []string{"/healthz", "/orders/7"} {
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "orders.get", spans[0].Name())
+		attrs := map[string]string{}
+		for _, kv := range spans[0].Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		assert.Equal(t, "7", attrs["mux.path_param.id"])
+		assert.Equal(t, "gold", attrs["app.tier"])
+	}
+}
+
+func TestShouldMapSpanStatusGivenWithTelemetrySpanStatus(t *testing.T) {
+	// Arrange
+	tp, recorder := newTestTracerProvider(t)
+	router := mux.NewRouter()
+	mux.UseOpenTelemetry(router,
+		mux.WithTelemetryTracerProvider(tp),
+		mux.WithTelemetrySpanStatus(func(c mux.RouteContext, status int) (codes.Code, string) {
+			if status == http.StatusNotFound {
+				return codes.Error, "missing order"
+			}
+			return codes.Unset, ""
+		}),
+	)
+	router.GET("/orders/{id}", func(c mux.RouteContext) { c.NotFound() })
+
+	// Act
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/7", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "missing order", spans[0].Status().Description)
+	}
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func servePanicRoute(router *mux.Router, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldReportAndFallBackGivenCustomPanicHandler(t *testing.T) {
+	// Arrange
+	var reported []any
+	router := mux.NewRouter(mux.WithPanicHandler(func(c mux.RouteContext, recovered any, stack []byte) {
+		reported = append(reported, recovered)
+		mux.DefaultPanicHandler(c, recovered, stack)
+	}))
+	router.GET("/panic", func(c mux.RouteContext) { panic("boom") })
+
+	// Act
+	rec := servePanicRoute(router, "application/json")
+
+	// Assert
+	assert.Equal(t, []any{"boom"}, reported)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "boom")
+}
+
+func TestShouldExposeStackOnlyGivenDevelopmentErrors(t *testing.T) {
+	// Arrange
+	production := mux.NewRouter()
+	development := mux.NewRouter(mux.WithDevelopmentErrors())
+	for _, router := range []*mux.Router{production, development} {
+		router.GET("/panic", func(c mux.RouteContext) { panic("boom") })
+	}
+
+	// Act
+	prodPage := servePanicRoute(production, "text/html")
+	devPage := servePanicRoute(development, "text/html")
+	devProblem := servePanicRoute(development, "application/json")
+
+	// Assert
+	assert.NotContains(t, prodPage.Body.String(), "goroutine")
+	assert.Equal(t, "text/html", devPage.Header().Get("Content-Type"))
+	assert.Contains(t, devPage.Body.String(), "goroutine")
+	assert.Equal(t, "application/problem+json", devProblem.Header().Get("Content-Type"))
+	assert.Contains(t, devProblem.Body.String(), `"stack"`)
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type patchItem struct {
+	Name  string `json:"name"`
+	Stock int    `json:"stock"`
+}
+
+func newPatchRouter(stored *patchItem) *mux.Router {
+	router := mux.NewRouter(mux.WithTitle("patch"), mux.WithVersion("1.0.0"))
+	router.PATCH("/items/{id}", func(c mux.RouteContext) {
+		if err := c.BindPatch(stored); err != nil {
+			return
+		}
+		c.OK(stored)
+	}).WithOperationID("patchItem").WithPathParam("id", "item id", 1).WithPatchBody(patchItem{})
+	return router
+}
+
+func TestShouldPatchResourceGivenPublicRouter(t *testing.T) {
+	// Arrange
+	stored := &patchItem{Name: "gear", Stock: 3}
+	router := newPatchRouter(stored)
+	merge := httptest.NewRequestWithContext(context.Background(), http.MethodPatch, "/items/1", strings.NewReader(`{"stock":5}`))
+	merge.Header.Set(mux.HeaderContentType, mux.MimeMergePatchJSON)
+	ops := httptest.NewRequestWithContext(context.Background(), http.MethodPatch, "/items/1", strings.NewReader(`[{"op":"replace","path":"/name","value":"cog"}]`))
+	ops.Header.Set(mux.HeaderContentType, mux.MimeJSONPatchJSON)
+	mergeRec, opsRec := httptest.NewRecorder(), httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(mergeRec, merge)
+	router.ServeHTTP(opsRec, ops)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, mergeRec.Code)
+	assert.Equal(t, http.StatusOK, opsRec.Code)
+	assert.Equal(t, patchItem{Name: "cog", Stock: 5}, *stored)
+}
+
+func TestShouldWriteConflictProblemGivenFailedPatchTest(t *testing.T) {
+	// Arrange
+	stored := &patchItem{Name: "gear"}
+	router := newPatchRouter(stored)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPatch, "/items/1", strings.NewReader(`[{"op":"test","path":"/name","value":"cog"}]`))
+	req.Header.Set(mux.HeaderContentType, mux.MimeJSONPatchJSON)
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, mux.MimeProblemJSON, rec.Header().Get(mux.HeaderContentType))
+	assert.Equal(t, patchItem{Name: "gear"}, *stored)
+}
+
+func TestShouldDocumentPatchMediaTypesGivenOpenAPISpec(t *testing.T) {
+	// Arrange
+	router := newPatchRouter(&patchItem{})
+
+	// Act
+	spec, err := mux.GenerateSpecWithGenerator(mux.NewGenerator(), router)
+	require.NoError(t, err)
+	data, err := spec.MarshalJSON()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"application/merge-patch+json"`)
+	assert.Contains(t, string(data), `"application/json-patch+json"`)
+	require.NoError(t, spec.Validate())
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getWithAPIKey(router *mux.Router, path, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	req.Header.Set("X-API-Key", apiKey)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestShouldEnforceGroupPolicyGivenRateLimiter(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseRateLimiter(router,
+		mux.WithRateLimitStore(mux.NewMemoryRateLimitStore()),
+		mux.WithRateLimitDefaultKey(mux.RateLimitByHeader("X-API-Key")),
+	)
+	api := router.Group("/api").WithRateLimitPolicy(mux.RateLimitPolicy{
+		Name:      "api",
+		Limit:     2,
+		Period:    time.Minute,
+		Algorithm: mux.GCRA,
+	})
+	api.GET("/orders", func(c mux.RouteContext) { c.OK("orders") })
+	api.GET("/invoices", func(c mux.RouteContext) { c.OK("invoices") })
+
+	// Act
+	first := getWithAPIKey(router, "/api/orders", "k1")
+	second := getWithAPIKey(router, "/api/invoices", "k1")
+	third := getWithAPIKey(router, "/api/orders", "k1")
+	otherKey := getWithAPIKey(router, "/api/orders", "k2")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, http.StatusTooManyRequests, third.Code)
+	assert.NotEmpty(t, third.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, otherKey.Code)
+}
+
+func TestShouldOverrideGroupPolicyGivenRoutePolicyWithSameName(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseRateLimiter(router, mux.WithRateLimitDefaultKey(mux.RateLimitByHeader("X-API-Key")))
+	api := router.Group("/api").WithRateLimitPolicy(mux.RateLimitPolicy{Name: "api", Limit: 1, Period: time.Minute})
+	api.GET("/search", func(c mux.RouteContext) { c.OK("results") }).
+		WithRateLimitPolicy(mux.RateLimitPolicy{Name: "api", Limit: 2, Period: time.Minute, Algorithm: mux.SlidingWindowLog})
+
+	// Act
+	codes := []int{
+		getWithAPIKey(router, "/api/search", "k1").Code,
+		getWithAPIKey(router, "/api/search", "k1").Code,
+		getWithAPIKey(router, "/api/search", "k1").Code,
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestShouldReportQuotaHeadersGivenPlanTiers(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseRateLimiter(router, mux.WithRateLimitDefaultKey(mux.RateLimitByHeader("X-API-Key")))
+	router.Group("/api").
+		WithRateLimitQuota(mux.RateLimitQuota{
+			Name:    "pro",
+			Windows: []mux.RateLimitPolicy{{Name: "minute", Limit: 1000, Period: time.Minute}},
+			Match:   func(c mux.RouteContext) bool { return c.Request().Header.Get("X-API-Key") == "pro-key" },
+		}).
+		WithRateLimitQuota(mux.RateLimitQuota{
+			Name: "free",
+			Windows: []mux.RateLimitPolicy{
+				{Name: "minute", Limit: 100, Period: time.Minute},
+				{Name: "day", Limit: 1, Period: 24 * time.Hour, Algorithm: mux.SlidingWindowLog},
+			},
+		}).
+		GET("/orders", func(c mux.RouteContext) { c.OK("orders") })
+
+	// Act
+	free := getWithAPIKey(router, "/api/orders", "free-key")
+	exhausted := getWithAPIKey(router, "/api/orders", "free-key")
+	pro := getWithAPIKey(router, "/api/orders", "pro-key")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, free.Code)
+	assert.Equal(t, `"free.minute";q=100;w=60, "free.day";q=1;w=86400`, free.Header().Get(mux.HeaderRateLimitPolicy))
+	assert.Equal(t, `"free.minute";r=99;t=1, "free.day";r=0;t=86400`, free.Header().Get(mux.HeaderRateLimit))
+	assert.Equal(t, http.StatusTooManyRequests, exhausted.Code)
+	assert.Equal(t, "86400", exhausted.Header().Get(mux.HeaderRetryAfter))
+	assert.Equal(t, http.StatusOK, pro.Code)
+	assert.Equal(t, `"pro.minute";q=1000;w=60`, pro.Header().Get(mux.HeaderRateLimitPolicy))
+}
+
+func TestShouldDocumentRateLimitsGivenOpenAPISpec(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter(mux.WithTitle("Search API"), mux.WithVersion("1.0.0"))
+	mux.UseRateLimiter(router)
+	router.GET("/search", func(c mux.RouteContext) { c.OK("results") }).
+		WithOperationID("search").
+		WithOKResponse("results").
+		WithRateLimitPolicy(mux.RateLimitPolicy{Name: "search", Limit: 10, Period: time.Second})
+
+	// Act
+	spec, err := mux.GenerateSpecWithGenerator(mux.NewGenerator(), router)
+	require.NoError(t, err)
+	data, err := json.Marshal(spec)
+	require.NoError(t, err)
+
+	// Assert
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Headers map[string]any `json:"headers"`
+			} `json:"responses"`
+			RateLimit map[string][]map[string]any `json:"x-ratelimit"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	op := doc.Paths["/search"]["get"]
+	assert.Contains(t, op.Responses["200"].Headers, mux.HeaderRateLimit)
+	assert.Contains(t, op.Responses["429"].Headers, mux.HeaderRetryAfter)
+	require.Len(t, op.RateLimit["policies"], 1)
+	assert.Equal(t, "search", op.RateLimit["policies"][0]["name"])
+}
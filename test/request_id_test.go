@@ -0,0 +1,84 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldPropagateRequestIDGivenUseRequestID(t *testing.T) {
+	// Arrange
+	var logs bytes.Buffer
+	logger := slog.New(mux.NewRequestIDLogHandler(slog.NewJSONHandler(&logs, nil)))
+	router := mux.NewRouter()
+	mux.UseRequestID(router)
+	router.GET("/orders/{id}", func(c mux.RouteContext) {
+		logger.InfoContext(c, "loading order")
+		assert.Equal(t, c.RequestID(), mux.RequestIDFromContext(c.Request().Context()))
+		c.BadRequest("Bad Request", "unknown order")
+	})
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/7", nil)
+	req.Header.Set(mux.HeaderXRequestID, "req-42")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, "req-42", rec.Header().Get(mux.HeaderXRequestID))
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, "req-42", problem["requestId"])
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "req-42", record["request_id"])
+}
+
+func TestShouldGenerateRequestIDGivenCustomHeaderAndNoIncomingID(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseRequestID(router,
+		mux.WithRequestIDHeader("X-Correlation-Id"),
+		mux.WithRequestIDGenerator(func() string { return "corr-1" }),
+	)
+	router.GET("/", func(c mux.RouteContext) { c.OK(c.RequestID()) })
+
+	// Act
+	rec := getPath(router, "/")
+
+	// Assert
+	assert.Equal(t, "corr-1", rec.Header().Get("X-Correlation-Id"))
+	assert.JSONEq(t, `"corr-1"`, rec.Body.String())
+}
+
+func TestShouldKeepRequestIDGivenTimedOutRouteBehindLogging(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseLogging(router)
+	mux.UseRequestID(router)
+	router.GET("/slow", func(c mux.RouteContext) {
+		<-c.Done()
+	}).WithTimeout(10 * time.Millisecond)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/slow", nil)
+	req.Header.Set(mux.HeaderXRequestID, "req-7")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "req-7", rec.Header().Get(mux.HeaderXRequestID))
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, "req-7", problem["requestId"])
+}
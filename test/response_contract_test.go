@@ -0,0 +1,46 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type contractOrder struct {
+	ID    string  `json:"id"`
+	Total float64 `json:"total"`
+}
+
+func TestShouldReplaceResponseGivenStrictContractWhenHandlerReturnsWrongShape(t *testing.T) {
+	// Arrange
+	var violations []mux.ResponseContractViolation
+	router := mux.NewRouter(
+		mux.WithResponseContract(mux.ResponseContractStrict),
+		mux.WithResponseContractHandler(func(v mux.ResponseContractViolation) {
+			violations = append(violations, v)
+		}),
+	)
+	router.GET("/orders/{id}", func(c mux.RouteContext) {
+		c.OK(map[string]any{"id": 42})
+	}).WithOperationID("getOrder").WithOKResponse(contractOrder{})
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/orders/42", nil)
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, mux.MimeProblemJSON, rec.Header().Get(mux.HeaderContentType))
+	require.Len(t, violations, 1)
+	assert.Equal(t, http.MethodGet, violations[0].Method)
+	assert.Equal(t, "/orders/{id}", violations[0].Route)
+	assert.Equal(t, "getOrder", violations[0].OperationID)
+	assert.NotEmpty(t, violations[0].Problems)
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldAnswerTimeoutGivenSlowRouteInGroupWithTimeout(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter(mux.WithTimeoutStatus(http.StatusGatewayTimeout))
+	reports := router.Group("/reports").WithTimeout(20 * time.Millisecond)
+	reports.GET("/slow", func(c mux.RouteContext) {
+		<-c.Done()
+		c.OK("too late")
+	})
+	reports.GET("/fast", func(c mux.RouteContext) { c.OK("fast") })
+	router.GET("/lookup", func(c mux.RouteContext) {
+		_, hasDeadline := c.Deadline()
+		c.OK(hasDeadline)
+	})
+
+	// Act
+	slow := getPath(router, "/reports/slow")
+	fast := getPath(router, "/reports/fast")
+	lookup := getPath(router, "/lookup")
+
+	// Assert
+	assert.Equal(t, http.StatusGatewayTimeout, slow.Code)
+	assert.Equal(t, "application/problem+json", slow.Header().Get("Content-Type"))
+	assert.NotContains(t, slow.Body.String(), "too late")
+	assert.Equal(t, http.StatusOK, fast.Code)
+	assert.JSONEq(t, "false", lookup.Body.String(), "routes without a timeout get no deadline")
+}
+
+func TestShouldDocumentTimeoutGivenRouteWithTimeout(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter(mux.WithTitle("Timeouts"), mux.WithVersion("1.0.0"))
+	router.GET("/reports", func(c mux.RouteContext) { c.OK("ok") }).
+		WithOperationID("reports").
+		WithTimeout(30 * time.Second)
+
+	// Act
+	spec, err := mux.GenerateSpecWithGenerator(mux.NewGenerator(), router)
+	require.NoError(t, err)
+	raw, err := json.Marshal(spec)
+	require.NoError(t, err)
+
+	// Assert
+	var doc struct {
+		Paths map[string]map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	op := doc.Paths["/reports"]["get"]
+	assert.Equal(t, "30s", op["x-timeout"])
+	assert.Contains(t, op["responses"], "503")
+}
+
+func TestShouldFailLateWriteGivenTimedOutRoute(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	writeErr := make(chan error, 1)
+	router.GET("/slow", func(c mux.RouteContext) {
+		<-c.Done()
+		_, err := c.Response().Write([]byte("late"))
+		writeErr <- err
+	}).WithTimeout(10 * time.Millisecond)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/slow", nil)
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.ErrorIs(t, <-writeErr, http.ErrHandlerTimeout)
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSetSecurityHeadersWithGroupOverridesGivenUseSecurityHeaders(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	mux.UseSecurityHeaders(router, mux.WithCSPNonce(), mux.WithReferrerPolicy("no-referrer"))
+	var nonce string
+	router.GET("/page", func(c mux.RouteContext) {
+		nonce = c.CSPNonce()
+		c.HTML(http.StatusOK, `<script nonce="`+c.CSPNonce()+`"></script>`)
+	})
+	docs := router.Group("/docs").WithSecurityHeaders(mux.WithFrameOptions("SAMEORIGIN"))
+	docs.GET("/", func(c mux.RouteContext) { c.NoContent() })
+
+	// Act
+	page := httptest.NewRecorder()
+	router.ServeHTTP(page, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/page", nil))
+	doc := httptest.NewRecorder()
+	router.ServeHTTP(doc, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/docs/", nil))
+
+	// Assert
+	require.NotEmpty(t, nonce)
+	assert.Contains(t, page.Header().Get("Content-Security-Policy"), "'nonce-"+nonce+"'")
+	assert.Contains(t, page.Body.String(), nonce)
+	assert.Equal(t, "no-referrer", page.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "nosniff", page.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", page.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "SAMEORIGIN", doc.Header().Get("X-Frame-Options"))
+}
+
+func TestShouldReceiveViolationsGivenCSPReportHandler(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	var reports []mux.CSPReport
+	router.POST("/csp-reports", mux.NewCSPReportHandler(func(c mux.RouteContext, report mux.CSPReport) {
+		reports = append(reports, report)
+	})).AllowAnonymous()
+	body := `{"csp-report":{"document-uri":"https://example.com/","effective-directive":"img-src","blocked-uri":"https://evil.example/a.png","disposition":"report"}}`
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/csp-reports", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/csp-report")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, "img-src", reports[0].EffectiveDirective)
+		assert.Equal(t, "https://evil.example/a.png", reports[0].BlockedURI)
+	}
+}
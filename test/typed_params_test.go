@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sortDir string
+
+type listQuery struct {
+	Since  time.Time     `json:"since"`
+	Window time.Duration `json:"window"`
+	Sort   sortDir       `json:"sort"`
+	Tags   []string      `json:"tags"`
+	IDs    []int         `json:"ids"`
+	Client netip.Addr    `json:"client"`
+	Limit  int           `json:"limit"`
+	Tenant string        `json:"tenant"`
+}
+
+func newTypedParamsRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.GET("/items", func(c mux.RouteContext) {
+		q := listQuery{
+			Since:  mux.RequireQuery[time.Time](c, "since", mux.WithTimeLayouts(time.RFC3339, time.DateOnly)),
+			Window: mux.RequireQuery[time.Duration](c, "window"),
+			Sort:   mux.RequireQuery[sortDir](c, "sort", mux.WithEnum("asc", "desc")),
+			Tags:   mux.RequireQuery[[]string](c, "tags", mux.WithStyle(mux.StylePipeDelimited)),
+			IDs:    mux.RequireHeader[[]int](c, "X-IDs"),
+			Client: mux.RequireCookie[netip.Addr](c, "client"),
+			Tenant: mux.RequireHeader[string](c, "X-Tenant"),
+		}
+		q.Limit, _ = mux.Query[int](c, "limit")
+		if mux.AbortOnParamErrors(c) {
+			return
+		}
+		c.OK(q)
+	})
+	router.GET("/unchecked", func(c mux.RouteContext) {
+		mux.RequireQuery[int](c, "page")
+		c.OK("handler forgot to check")
+	})
+	router.GET("/raw", func(c mux.RouteContext) {
+		page := mux.RequireQuery[int](c, "page")
+		_, _ = fmt.Fprintf(c.Response(), "page %d", page)
+	})
+	return router
+}
+
+func TestShouldParseTypedParamsGivenValidRequest(t *testing.T) {
+	// Arrange
+	router := newTypedParamsRouter()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/items?since=2026-01-02&window=90m&sort=desc&tags=a|b&limit=25", nil)
+	req.Header.Set("X-IDs", "3, 4")
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "client", Value: "10.0.0.1"})
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{
+		"since": "2026-01-02T00:00:00Z", "window": 5400000000000, "sort": "desc",
+		"tags": ["a","b"], "ids": [3,4], "client": "10.0.0.1", "limit": 25, "tenant": "acme"
+	}`, rec.Body.String())
+}
+
+func TestShouldListEveryBadParamGivenInvalidRequest(t *testing.T) {
+	// Arrange
+	router := newTypedParamsRouter()
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/items?since=yesterday&window=90m&sort=up&tags=a&limit=many", nil)
+	req.Header.Set("X-IDs", "3,x")
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, req)
+
+	// Assert
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, mux.MimeProblemJSON, rec.Header().Get(mux.HeaderContentType))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`{"in":"query","name":"since","detail":"must be a time in the format 2006-01-02T15:04:05Z07:00 or 2006-01-02"}`,
+		`{"in":"query","name":"sort","detail":"must be one of asc, desc"}`,
+		`{"in":"header","name":"X-IDs","detail":"must be an integer"}`,
+		`{"in":"cookie","name":"client","detail":"is required"}`,
+		`{"in":"header","name":"X-Tenant","detail":"is required"}`,
+		`{"in":"query","name":"limit","detail":"must be an integer"}`,
+	} {
+		assert.Contains(t, body, want)
+	}
+	assert.Equal(t, 6, strings.Count(body, `"in":`))
+}
+
+func TestShouldRejectRequestGivenHandlerIgnoresParamErrors(t *testing.T) {
+	// Arrange
+	router := newTypedParamsRouter()
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/unchecked", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"page"`)
+}
+
+func TestShouldRejectRequestGivenHandlerWritesResponseDirectly(t *testing.T) {
+	// Arrange
+	router := newTypedParamsRouter()
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/raw", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"page"`)
+	assert.NotContains(t, rec.Body.String(), "page 0")
+}
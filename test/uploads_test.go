@@ -0,0 +1,113 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/fgrzl/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUploadRequest(t *testing.T, path string, files map[string][]byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	require.NoError(t, writer.WriteField("title", "report"))
+	for name, data := range files {
+		w, err := writer.CreateFormFile("doc", name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, path, &buf)
+	req.Header.Set(mux.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+type recordingSink struct {
+	names []string
+}
+
+func (s *recordingSink) Store(header *mux.FileHeader, r io.Reader) error {
+	s.names = append(s.names, header.Filename)
+	data, err := io.ReadAll(r)
+	header.Data = data
+	return err
+}
+
+func TestShouldBindUploadedFilesGivenPublicRouter(t *testing.T) {
+	// Arrange
+	type uploadForm struct {
+		Title string          `json:"title"`
+		Doc   *mux.FileHeader `json:"doc"`
+	}
+	sink := &recordingSink{}
+	var got uploadForm
+	var bindErr error
+	router := mux.NewRouter()
+	router.POST("/docs", func(c mux.RouteContext) {
+		bindErr = c.Bind(&got)
+		c.NoContent()
+	}).WithMultipartBody(uploadForm{}).WithUploadLimits(mux.UploadLimits{Sink: sink})
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), newUploadRequest(t, "/docs", map[string][]byte{"a.txt": []byte("hello")}))
+
+	// Assert
+	require.NoError(t, bindErr)
+	assert.Equal(t, "report", got.Title)
+	require.NotNil(t, got.Doc)
+	assert.Equal(t, "text/plain", got.Doc.ContentType)
+	assert.Equal(t, []byte("hello"), got.Doc.Data)
+	assert.Equal(t, []string{"a.txt"}, sink.names)
+}
+
+func TestShouldWriteProblemGivenUploadOverLimit(t *testing.T) {
+	// Arrange
+	router := mux.NewRouter()
+	router.POST("/docs", func(c mux.RouteContext) {
+		if _, err := c.Files(); err != nil {
+			return
+		}
+		c.NoContent()
+	}).WithUploadLimits(mux.UploadLimits{MaxPartBytes: 4})
+	rec := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rec, newUploadRequest(t, "/docs", map[string][]byte{"a.txt": []byte("too large")}))
+
+	// Assert
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, mux.MimeProblemJSON, rec.Header().Get(mux.HeaderContentType))
+}
+
+func TestShouldRemoveTempFilesGivenRequestCompleted(t *testing.T) {
+	// Arrange
+	var path string
+	router := mux.NewRouter()
+	router.POST("/docs", func(c mux.RouteContext) {
+		uploads, err := c.Files()
+		if err != nil {
+			return
+		}
+		file, _ := uploads.File("doc")
+		path = file.Path
+		c.NoContent()
+	}).WithUploadLimits(mux.UploadLimits{Sink: mux.TempDirSink(t.TempDir())})
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), newUploadRequest(t, "/docs", map[string][]byte{"a.txt": []byte("hello")}))
+
+	// Assert
+	require.NotEmpty(t, path)
+	_, err := os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
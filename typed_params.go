@@ -0,0 +1,199 @@
+package mux
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	internalbinder "github.com/fgrzl/mux/internal/binder"
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// ParamStyle is the OpenAPI serialization style used to split a parameter
+// into slice elements.
+type ParamStyle string
+
+const (
+	// StyleForm splits comma-separated values, as in ?ids=1,2,3. Without a
+	// style option, query and form slices instead take one element per
+	// repeated key, as in ?ids=1&ids=2.
+	StyleForm ParamStyle = "form"
+	// StyleSimple splits comma-separated values. It is the default for
+	// header slices.
+	StyleSimple ParamStyle = "simple"
+	// StylePipeDelimited splits values on "|".
+	StylePipeDelimited ParamStyle = "pipeDelimited"
+	// StyleSpaceDelimited splits values on " ".
+	StyleSpaceDelimited ParamStyle = "spaceDelimited"
+)
+
+// ValueOption configures how the typed accessors parse a value.
+type ValueOption struct {
+	apply func(*internalbinder.TextOptions)
+}
+
+// WithTimeLayouts sets the layouts tried, in order, for time.Time values.
+// The default is time.RFC3339.
+func WithTimeLayouts(layouts ...string) ValueOption {
+	return ValueOption{apply: func(o *internalbinder.TextOptions) {
+		o.Layouts = append([]string(nil), layouts...)
+	}}
+}
+
+// WithEnum restricts the raw value, or each slice element, to values.
+func WithEnum(values ...string) ValueOption {
+	return ValueOption{apply: func(o *internalbinder.TextOptions) {
+		o.Enum = append([]string(nil), values...)
+	}}
+}
+
+// WithStyle sets how slice values are split.
+func WithStyle(style ParamStyle) ValueOption {
+	return ValueOption{apply: func(o *internalbinder.TextOptions) {
+		o.Delimiter = styleDelimiter(style)
+	}}
+}
+
+func styleDelimiter(style ParamStyle) string {
+	switch style {
+	case StyleForm, StyleSimple:
+		return ","
+	case StylePipeDelimited:
+		return "|"
+	case StyleSpaceDelimited:
+		return " "
+	default:
+		return ""
+	}
+}
+
+// Query returns the query parameter name converted to T. T may be any
+// encoding.TextUnmarshaler, time.Time (see WithTimeLayouts), time.Duration,
+// a string, bool, integer, or float type, a pointer to one of those, or a
+// slice of them (see WithStyle). ok is false when the parameter is absent or
+// invalid; an invalid value is also recorded so the request is rejected with
+// a 400 problem, as described on AbortOnParamErrors.
+func Query[T any](c RouteContext, name string, opts ...ValueOption) (T, bool) {
+	values, ok := queryValues(c.Request(), name)
+	return typedParam[T](c, "query", name, values, ok, false, opts)
+}
+
+// RequireQuery is like Query but also records an error when the parameter is
+// absent.
+func RequireQuery[T any](c RouteContext, name string, opts ...ValueOption) T {
+	values, ok := queryValues(c.Request(), name)
+	v, _ := typedParam[T](c, "query", name, values, ok, true, opts)
+	return v
+}
+
+// Header returns the request header name converted to T, with the same
+// types and error handling as Query. Slices split comma-separated values and
+// times default to the HTTP date format, then RFC 3339.
+func Header[T any](c RouteContext, name string, opts ...ValueOption) (T, bool) {
+	values, ok := headerValues(c.Request(), name)
+	return typedParam[T](c, "header", name, values, ok, false, opts)
+}
+
+// RequireHeader is like Header but also records an error when the header is
+// absent.
+func RequireHeader[T any](c RouteContext, name string, opts ...ValueOption) T {
+	values, ok := headerValues(c.Request(), name)
+	v, _ := typedParam[T](c, "header", name, values, ok, true, opts)
+	return v
+}
+
+// Cookie returns the request cookie name converted to T, with the same types
+// and error handling as Query.
+func Cookie[T any](c RouteContext, name string, opts ...ValueOption) (T, bool) {
+	values, ok := cookieValues(c.Request(), name)
+	return typedParam[T](c, "cookie", name, values, ok, false, opts)
+}
+
+// RequireCookie is like Cookie but also records an error when the cookie is
+// absent.
+func RequireCookie[T any](c RouteContext, name string, opts ...ValueOption) T {
+	values, ok := cookieValues(c.Request(), name)
+	v, _ := typedParam[T](c, "cookie", name, values, ok, true, opts)
+	return v
+}
+
+// Form returns the form field name converted to T, with the same types and
+// error handling as Query.
+func Form[T any](c RouteContext, name string, opts ...ValueOption) (T, bool) {
+	values, ok := formValues(c.Request(), name)
+	return typedParam[T](c, "form", name, values, ok, false, opts)
+}
+
+// RequireForm is like Form but also records an error when the field is
+// absent.
+func RequireForm[T any](c RouteContext, name string, opts ...ValueOption) T {
+	values, ok := formValues(c.Request(), name)
+	v, _ := typedParam[T](c, "form", name, values, ok, true, opts)
+	return v
+}
+
+// AbortOnParamErrors reports whether any typed accessor rejected a parameter.
+// If so, it writes a 400 problem whose "errors" member lists every rejected
+// parameter with its location, name, and reason, and the handler should
+// return. Calling it is optional: until then, the first success response,
+// whether written through the RouteContext helpers or directly to
+// c.Response(), is replaced by that problem, and handlers that write nothing
+// get it when they return.
+func AbortOnParamErrors(c RouteContext) bool {
+	inner := unwrapRouteContext(c)
+	if inner == nil {
+		return false
+	}
+	return inner.WriteParamErrors()
+}
+
+func typedParam[T any](c RouteContext, in, name string, raw []string, present, required bool, opts []ValueOption) (T, bool) {
+	var zero T
+	if !present || len(raw) == 0 {
+		if required {
+			recordParamError(c, in, name, "is required")
+		}
+		return zero, false
+	}
+	options := internalbinder.TextOptions{}
+	if in == "header" {
+		options.Delimiter = ","
+		options.Layouts = []string{http.TimeFormat, time.RFC3339}
+	}
+	for _, opt := range opts {
+		if opt.apply != nil {
+			opt.apply(&options)
+		}
+	}
+	v, err := internalbinder.ParseText(reflect.TypeFor[T](), raw, options)
+	if err != nil {
+		recordParamError(c, in, name, err.Error())
+		return zero, false
+	}
+	return v.Interface().(T), true
+}
+
+func recordParamError(c RouteContext, in, name, detail string) {
+	if inner := unwrapRouteContext(c); inner != nil {
+		inner.AddParamError(internalrouting.ParamError{In: in, Name: name, Detail: detail})
+	}
+}
+
+func headerValues(r *http.Request, name string) ([]string, bool) {
+	if r == nil {
+		return nil, false
+	}
+	values := r.Header.Values(name)
+	return values, len(values) > 0
+}
+
+func cookieValues(r *http.Request, name string) ([]string, bool) {
+	if r == nil {
+		return nil, false
+	}
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, false
+	}
+	return []string{cookie.Value}, true
+}
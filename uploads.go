@@ -0,0 +1,223 @@
+package mux
+
+import (
+	"io"
+	"net/textproto"
+
+	internalrouting "github.com/fgrzl/mux/internal/routing"
+)
+
+// Upload limit defaults applied when a route does not configure its own.
+const (
+	DefaultMaxUploadPartBytes  = internalrouting.DefaultMaxUploadPartBytes
+	DefaultMaxUploadTotalBytes = internalrouting.DefaultMaxUploadTotalBytes
+	DefaultMaxUploadFiles      = internalrouting.DefaultMaxUploadFiles
+)
+
+var (
+	// ErrUploadTooLarge is returned when a multipart upload exceeds the
+	// per-part, total, or file-count limits configured for the route.
+	ErrUploadTooLarge = internalrouting.ErrUploadTooLarge
+	// ErrUploadTypeNotAllowed is returned when the sniffed content type of an
+	// uploaded file is not in the route's allowed types.
+	ErrUploadTypeNotAllowed = internalrouting.ErrUploadTypeNotAllowed
+	// ErrFileNotRetained is returned by FileHeader.Open when the sink that
+	// stored the file does not keep its content.
+	ErrFileNotRetained = internalrouting.ErrFileNotRetained
+)
+
+// UploadLimits bounds multipart uploads on a route. Zero values fall back to
+// DefaultMaxUploadPartBytes, DefaultMaxUploadTotalBytes, DefaultMaxUploadFiles,
+// and MemorySink.
+type UploadLimits struct {
+	// MaxPartBytes caps the size of any single part, file or field.
+	MaxPartBytes int64
+	// MaxTotalBytes caps the combined size of all parts.
+	MaxTotalBytes int64
+	// MaxFiles caps the number of file parts.
+	MaxFiles int
+	// AllowedTypes lists permitted sniffed media types for file parts, such as
+	// "image/png" or "image/*". Empty allows every type.
+	AllowedTypes []string
+	// Sink stores file parts read by Files and Bind.
+	Sink FileSink
+}
+
+func (l UploadLimits) toInternal() internalrouting.UploadLimits {
+	return internalrouting.UploadLimits{
+		MaxPartBytes:  l.MaxPartBytes,
+		MaxTotalBytes: l.MaxTotalBytes,
+		MaxFiles:      l.MaxFiles,
+		AllowedTypes:  append([]string(nil), l.AllowedTypes...),
+		Sink:          toInternalFileSink(l.Sink),
+	}
+}
+
+// FileHeader describes an uploaded file. ContentType is sniffed from the
+// leading bytes of the file; DeclaredContentType is what the client sent.
+// Depending on the sink, the content is kept in Data or at Path.
+//
+// Bind assigns uploads to struct fields of type FileHeader, *FileHeader,
+// []FileHeader, or []*FileHeader, and WithMultipartBody and WithBinding
+// document them as `format: binary`.
+type FileHeader struct {
+	Field               string
+	Filename            string
+	ContentType         string
+	DeclaredContentType string
+	Size                int64
+	Header              textproto.MIMEHeader
+	Path                string
+	Data                []byte
+}
+
+// Open returns a reader over the stored file content.
+func (h *FileHeader) Open() (io.ReadCloser, error) {
+	return (*internalrouting.FileHeader)(h).Open()
+}
+
+// Remove deletes a file stored on disk. It is a no-op for in-memory files and
+// for files that were already moved or removed.
+func (h *FileHeader) Remove() error {
+	return (*internalrouting.FileHeader)(h).Remove()
+}
+
+// FileSink stores the content of uploaded file parts. Store must consume r
+// and record where the content lives on header (Data or Path), if anywhere.
+type FileSink interface {
+	Store(header *FileHeader, r io.Reader) error
+}
+
+type builtinFileSink struct {
+	inner internalrouting.FileSink
+}
+
+func (s builtinFileSink) Store(header *FileHeader, r io.Reader) error {
+	return s.inner.Store((*internalrouting.FileHeader)(header), r)
+}
+
+type fileSinkAdapter struct {
+	sink FileSink
+}
+
+func (s fileSinkAdapter) Store(header *internalrouting.FileHeader, r io.Reader) error {
+	return s.sink.Store((*FileHeader)(header), r)
+}
+
+func toInternalFileSink(sink FileSink) internalrouting.FileSink {
+	switch s := sink.(type) {
+	case nil:
+		return nil
+	case builtinFileSink:
+		return s.inner
+	default:
+		return fileSinkAdapter{sink: sink}
+	}
+}
+
+// MemorySink keeps uploaded files in memory. It is the default sink.
+func MemorySink() FileSink {
+	return builtinFileSink{inner: internalrouting.MemorySink()}
+}
+
+// TempDirSink writes uploaded files to temporary files in dir (os.TempDir when
+// empty). Files still in place when the request completes are removed, so
+// handlers that keep an upload should move it.
+func TempDirSink(dir string) FileSink {
+	return builtinFileSink{inner: internalrouting.TempDirSink(dir)}
+}
+
+// WriterSink streams each uploaded file into the writer returned by open,
+// such as an object-storage upload. The content is not retained, so
+// FileHeader.Open returns ErrFileNotRetained.
+func WriterSink(open func(header *FileHeader) (io.WriteCloser, error)) FileSink {
+	return builtinFileSink{inner: internalrouting.WriterSink(func(header *internalrouting.FileHeader) (io.WriteCloser, error) {
+		return open((*FileHeader)(header))
+	})}
+}
+
+// Uploads holds the files and plain form values of a multipart request.
+type Uploads struct {
+	Files  []*FileHeader
+	Values map[string][]string
+}
+
+func wrapUploads(inner *internalrouting.Uploads) *Uploads {
+	if inner == nil {
+		return nil
+	}
+	files := make([]*FileHeader, len(inner.Files))
+	for i, f := range inner.Files {
+		files[i] = (*FileHeader)(f)
+	}
+	return &Uploads{Files: files, Values: inner.Values}
+}
+
+// File returns the first file uploaded under field.
+func (u *Uploads) File(field string) (*FileHeader, bool) {
+	for _, f := range u.Files {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// All returns every file uploaded under field.
+func (u *Uploads) All(field string) []*FileHeader {
+	var files []*FileHeader
+	for _, f := range u.Files {
+		if f.Field == field {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Value returns the first plain form value for name.
+func (u *Uploads) Value(name string) string {
+	if values := u.Values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// MultipartReader streams the parts of a multipart request while enforcing
+// the route's upload limits.
+type MultipartReader struct {
+	inner *internalrouting.MultipartReader
+}
+
+// NextPart returns the next part, or io.EOF when there are no more parts.
+// File parts beyond MaxFiles fail with ErrUploadTooLarge and file parts whose
+// sniffed type is not allowed fail with ErrUploadTypeNotAllowed. The previous
+// part is closed when the next one is requested.
+func (m *MultipartReader) NextPart() (*MultipartPart, error) {
+	part, err := m.inner.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartPart{
+		FormName:            part.FormName,
+		FileName:            part.FileName,
+		ContentType:         part.ContentType,
+		DeclaredContentType: part.DeclaredContentType,
+		Header:              part.Header,
+		inner:               part,
+	}, nil
+}
+
+// MultipartPart is a single part of a multipart request. ContentType is
+// sniffed for file parts. Reads fail with ErrUploadTooLarge once the part or
+// request exceeds its limits.
+type MultipartPart struct {
+	FormName            string
+	FileName            string
+	ContentType         string
+	DeclaredContentType string
+	Header              textproto.MIMEHeader
+	inner               *internalrouting.MultipartPart
+}
+
+// Read reads the part content.
+func (p *MultipartPart) Read(b []byte) (int, error) { return p.inner.Read(b) }